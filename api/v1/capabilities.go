@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capability is a Quay feature which is only available in some versions.
+type Capability string
+
+const (
+	CapabilityJWTAuthentication  Capability = "JWTAuthentication"
+	CapabilityAppSpecificTokens  Capability = "AppSpecificTokens"
+	CapabilityStorageReplication Capability = "StorageReplication"
+	CapabilityProxyCache         Capability = "ProxyCache"
+	CapabilityImmutableTags      Capability = "ImmutableTags"
+	CapabilityAutoPrune          Capability = "AutoPrune"
+	CapabilityRedisSentinel      Capability = "RedisSentinel"
+	CapabilityRestrictedUsers    Capability = "RestrictedUsers"
+)
+
+// capabilities is the set of capabilities supported by each Quay version the Operator can deploy.
+var capabilities = map[QuayVersion][]Capability{
+	QuayVersionQuiGon: {
+		CapabilityJWTAuthentication,
+		CapabilityAppSpecificTokens,
+		CapabilityStorageReplication,
+	},
+	QuayVersionVader: {
+		CapabilityJWTAuthentication,
+		CapabilityAppSpecificTokens,
+		CapabilityStorageReplication,
+	},
+	QuayVersionDev: {
+		CapabilityJWTAuthentication,
+		CapabilityAppSpecificTokens,
+		CapabilityStorageReplication,
+		CapabilityProxyCache,
+		CapabilityImmutableTags,
+		CapabilityAutoPrune,
+		CapabilityRedisSentinel,
+		CapabilityRestrictedUsers,
+	},
+}
+
+// Supports returns true if the given Quay version has the capability.
+func Supports(version QuayVersion, capability Capability) bool {
+	for _, c := range capabilities[version] {
+		if c == capability {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionsSupporting returns the Quay versions which have the capability.
+func versionsSupporting(capability Capability) []string {
+	versions := []string{}
+	for version := range capabilities {
+		if Supports(version, capability) {
+			versions = append(versions, string(version))
+		}
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+// UnsupportedCapabilityError describes a field which requires a capability the desired Quay version lacks.
+type UnsupportedCapabilityError struct {
+	Field      string
+	Capability Capability
+	Version    QuayVersion
+}
+
+func (e UnsupportedCapabilityError) Error() string {
+	return fmt.Sprintf("%s requires %s, which Quay version `%s` does not support (supported by: %s)",
+		e.Field, e.Capability, e.Version, strings.Join(versionsSupporting(e.Capability), ", "))
+}
+
+// specCapabilities returns the capabilities required by the fields set in the spec, keyed by field path.
+func specCapabilities(quay *QuayRegistry) map[string]Capability {
+	required := map[string]Capability{}
+
+	if auth := quay.Spec.Authentication; auth != nil {
+		if auth.Type == AuthenticationTypeJWT {
+			required["`spec.authentication.jwt`"] = CapabilityJWTAuthentication
+		}
+		if auth.AppTokens != nil && auth.AppTokens.Enabled {
+			required["`spec.authentication.appTokens`"] = CapabilityAppSpecificTokens
+		}
+		if auth.RestrictedUsers != nil && auth.RestrictedUsers.Enabled {
+			required["`spec.authentication.restrictedUsers`"] = CapabilityRestrictedUsers
+		}
+	}
+	if quay.Spec.Redis != nil && quay.Spec.Redis.Sentinel != nil {
+		required["`spec.redis.sentinel`"] = CapabilityRedisSentinel
+	}
+	if quay.Spec.StorageMigration != nil {
+		required["`spec.storageMigration`"] = CapabilityStorageReplication
+	}
+	if policy := quay.Spec.TagPolicy; policy != nil {
+		if len(policy.ImmutablePatterns) > 0 {
+			required["`spec.tagPolicy.immutablePatterns`"] = CapabilityImmutableTags
+		}
+		if policy.AutoPrune != nil {
+			required["`spec.tagPolicy.autoPrune`"] = CapabilityAutoPrune
+		}
+	}
+
+	return required
+}
+
+// configCapabilities are the config bundle fields which enable a capability when set to `true`.
+var configCapabilities = map[string]Capability{
+	"FEATURE_PROXY_CACHE":         CapabilityProxyCache,
+	"FEATURE_AUTO_PRUNE":          CapabilityAutoPrune,
+	"FEATURE_IMMUTABLE_TAGS":      CapabilityImmutableTags,
+	"FEATURE_STORAGE_REPLICATION": CapabilityStorageReplication,
+	"FEATURE_APP_SPECIFIC_TOKENS": CapabilityAppSpecificTokens,
+	"FEATURE_RESTRICTED_USERS":    CapabilityRestrictedUsers,
+}
+
+// ValidateCapabilities returns an error for every spec field, or enabled feature in the given Quay config, which the
+// desired Quay version does not support.
+func ValidateCapabilities(quay *QuayRegistry, config map[string]interface{}) []error {
+	required := specCapabilities(quay)
+	for field, capability := range configCapabilities {
+		if enabled, ok := config[field].(bool); ok && enabled {
+			required["`"+field+"` in the config bundle"] = capability
+		}
+	}
+
+	fields := []string{}
+	for field := range required {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	errs := []error{}
+	for _, field := range fields {
+		if !Supports(quay.Spec.DesiredVersion, required[field]) {
+			errs = append(errs, UnsupportedCapabilityError{Field: field, Capability: required[field], Version: quay.Spec.DesiredVersion})
+		}
+	}
+
+	return errs
+}
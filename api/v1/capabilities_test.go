@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var validateCapabilitiesTests = []struct {
+	name     string
+	quay     QuayRegistry
+	config   map[string]interface{}
+	expected []string
+}{
+	{
+		"NothingRequired",
+		QuayRegistry{Spec: QuayRegistrySpec{DesiredVersion: QuayVersionVader}},
+		map[string]interface{}{},
+		[]string{},
+	},
+	{
+		"SupportedSpecFields",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				DesiredVersion: QuayVersionVader,
+				Authentication: &Authentication{AppTokens: &AppTokens{Enabled: true}},
+			},
+		},
+		map[string]interface{}{},
+		[]string{},
+	},
+	{
+		"UnsupportedSpecAndConfigFields",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				DesiredVersion: QuayVersionVader,
+				TagPolicy:      &TagPolicy{AutoPrune: &AutoPrunePolicy{Method: AutoPruneMethodNumberOfTags, Value: "10"}},
+			},
+		},
+		map[string]interface{}{"FEATURE_PROXY_CACHE": true, "FEATURE_IMMUTABLE_TAGS": false},
+		[]string{
+			"`FEATURE_PROXY_CACHE` in the config bundle requires ProxyCache, which Quay version `vader` does not support (supported by: dev)",
+			"`spec.tagPolicy.autoPrune` requires AutoPrune, which Quay version `vader` does not support (supported by: dev)",
+		},
+	},
+	{
+		"RedisSentinel",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				DesiredVersion: QuayVersionVader,
+				Redis:          &RedisSettings{Sentinel: &RedisSentinel{}},
+			},
+		},
+		map[string]interface{}{},
+		[]string{
+			"`spec.redis.sentinel` requires RedisSentinel, which Quay version `vader` does not support (supported by: dev)",
+		},
+	},
+	{
+		"RestrictedUsers",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				DesiredVersion: QuayVersionVader,
+				Authentication: &Authentication{RestrictedUsers: &RestrictedUsers{Enabled: true}},
+			},
+		},
+		map[string]interface{}{"FEATURE_RESTRICTED_USERS": true},
+		[]string{
+			"`FEATURE_RESTRICTED_USERS` in the config bundle requires RestrictedUsers, which Quay version `vader` does not support (supported by: dev)",
+			"`spec.authentication.restrictedUsers` requires RestrictedUsers, which Quay version `vader` does not support (supported by: dev)",
+		},
+	},
+	{
+		"DevSupportsEverything",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				DesiredVersion: QuayVersionDev,
+				TagPolicy:      &TagPolicy{ImmutablePatterns: []string{"^v1$"}},
+			},
+		},
+		map[string]interface{}{"FEATURE_PROXY_CACHE": true},
+		[]string{},
+	},
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range validateCapabilitiesTests {
+		messages := []string{}
+		for _, err := range ValidateCapabilities(&test.quay, test.config) {
+			messages = append(messages, err.Error())
+		}
+
+		assert.Equal(test.expected, messages, test.name)
+	}
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"errors"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrorReason is a machine-readable code for why a `QuayRegistry` could not be fully reconciled.
+type ErrorReason string
+
+const (
+	// ErrorReasonStorageAuthFailed means the credentials of unmanaged object storage were rejected.
+	ErrorReasonStorageAuthFailed ErrorReason = "StorageAuthFailed"
+	// ErrorReasonDBUnreachable means the unmanaged database could not be connected to.
+	ErrorReasonDBUnreachable ErrorReason = "DBUnreachable"
+	// ErrorReasonConfigConflict means the spec and config bundle are invalid or contradict each other.
+	ErrorReasonConfigConflict ErrorReason = "ConfigConflict"
+	// ErrorReasonCertInvalid means the TLS certificate/key pair in the config bundle is invalid.
+	ErrorReasonCertInvalid ErrorReason = "CertInvalid"
+	// ErrorReasonMigrationPending means the database migration for an upgrade has not completed.
+	ErrorReasonMigrationPending ErrorReason = "MigrationPending"
+	// ErrorReasonPolicyViolation means the registry violates a policy enforced by the `QuayOperatorConfig`.
+	ErrorReasonPolicyViolation ErrorReason = "PolicyViolation"
+	// ErrorReasonSecretKeysRestoreFailed means the secret keys could not be restored from `spec.secretKeysBackup`.
+	ErrorReasonSecretKeysRestoreFailed ErrorReason = "SecretKeysRestoreFailed"
+	// ErrorReasonUnknown is used for any other error.
+	ErrorReasonUnknown ErrorReason = "Unknown"
+)
+
+// LastError is the most recent error which prevented a `QuayRegistry` from being fully reconciled.
+type LastError struct {
+	// Reason is a machine-readable code for the error.
+	Reason ErrorReason `json:"reason"`
+	// Message is a human-readable description of the error.
+	Message string `json:"message"`
+	// LastTransitionTime is when the error was first observed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ReasonedError is an error with an `ErrorReason` to report in `status.lastError`.
+type ReasonedError struct {
+	Reason ErrorReason
+	Err    error
+}
+
+func (e *ReasonedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReasonedError) Unwrap() error {
+	return e.Err
+}
+
+// NewReasonedError returns an error which is reported with the given reason.
+func NewReasonedError(reason ErrorReason, err error) error {
+	return &ReasonedError{Reason: reason, Err: err}
+}
+
+// ErrorReasonFor returns the reason of the given error, or the fallback if it does not have one.
+func ErrorReasonFor(err error, fallback ErrorReason) ErrorReason {
+	var reasoned *ReasonedError
+	if errors.As(err, &reasoned) {
+		return reasoned.Reason
+	}
+
+	return fallback
+}
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+var errorReasonForTests = []struct {
+	name     string
+	err      error
+	expected ErrorReason
+}{
+	{
+		"Plain",
+		errors.New("something went wrong"),
+		ErrorReasonConfigConflict,
+	},
+	{
+		"Reasoned",
+		NewReasonedError(ErrorReasonCertInvalid, errors.New("x509: certificate is valid for example.com")),
+		ErrorReasonCertInvalid,
+	},
+	{
+		"Wrapped",
+		fmt.Errorf("preflight failed: %w", NewReasonedError(ErrorReasonDBUnreachable, errors.New("connection refused"))),
+		ErrorReasonDBUnreachable,
+	},
+	{
+		"Aggregate",
+		utilerrors.NewAggregate([]error{errors.New("first"), errors.New("second")}),
+		ErrorReasonConfigConflict,
+	},
+}
+
+func TestErrorReasonFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range errorReasonForTests {
+		assert.Equal(test.expected, ErrorReasonFor(test.err, ErrorReasonConfigConflict), test.name)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// QuayOperatorConfigName is the name of the `QuayOperatorConfig` read by the Operator. Any other is ignored.
+const QuayOperatorConfigName = "cluster"
+
+// SecurityPolicy is a rule the config of every registry must satisfy before it is rolled out.
+// +kubebuilder:validation:Enum=RequireClairTLS;ForbidDefaultDatabasePassword;RequireTLS;RequireStorageEncryption
+type SecurityPolicy string
+
+const (
+	// SecurityPolicyRequireClairTLS forbids connecting to Clair over plain HTTP.
+	SecurityPolicyRequireClairTLS SecurityPolicy = "RequireClairTLS"
+	// SecurityPolicyForbidDefaultDatabasePassword forbids a database with an empty or default password.
+	SecurityPolicyForbidDefaultDatabasePassword SecurityPolicy = "ForbidDefaultDatabasePassword"
+	// SecurityPolicyRequireTLS forbids serving Quay over plain HTTP.
+	SecurityPolicyRequireTLS SecurityPolicy = "RequireTLS"
+	// SecurityPolicyRequireStorageEncryption forbids unmanaged object storage which is not accessed over TLS.
+	SecurityPolicyRequireStorageEncryption SecurityPolicy = "RequireStorageEncryption"
+)
+
+// ImageOverrides are the container images used in place of the defaults for the components of every registry.
+type ImageOverrides struct {
+	// Quay is the image of the Quay app, mirror and upgrade `Deployments`.
+	Quay string `json:"quay,omitempty"`
+	// Clair is the image of the managed Clair `Deployment`.
+	Clair string `json:"clair,omitempty"`
+	// Postgres is the image of the managed Quay and Clair databases.
+	Postgres string `json:"postgres,omitempty"`
+	// Redis is the image of the managed Redis `Deployment`.
+	Redis string `json:"redis,omitempty"`
+}
+
+// QuayOperatorConfigSpec defines the fleet-wide defaults inherited by every QuayRegistry.
+type QuayOperatorConfigSpec struct {
+	// Images replace the default container images of every registry.
+	Images *ImageOverrides `json:"images,omitempty"`
+	// StorageClassName is the `StorageClass` of the volumes of managed databases. The cluster default is used if
+	// unset.
+	StorageClassName string `json:"storageClassName,omitempty"`
+	// AllowedStorageBackends are the `DISTRIBUTED_STORAGE_CONFIG` drivers registries may use, such as `S3Storage`.
+	// Any driver is allowed if empty.
+	AllowedStorageBackends []string `json:"allowedStorageBackends,omitempty"`
+	// Config are Quay config fields set for every registry, which replace the same fields in its config bundle.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Config *runtime.RawExtension `json:"config,omitempty"`
+	// OverridableConfigFields are the fields of `config` a registry may set in its config bundle, for which `config`
+	// only provides the default.
+	OverridableConfigFields []string `json:"overridableConfigFields,omitempty"`
+	// Policies are enforced for every registry. A registry which violates any of them is not rolled out.
+	Policies []SecurityPolicy `json:"policies,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// QuayOperatorConfig is the Schema for the quayoperatorconfigs API.
+type QuayOperatorConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec QuayOperatorConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// QuayOperatorConfigList contains a list of QuayOperatorConfig.
+type QuayOperatorConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []QuayOperatorConfig `json:"items"`
+}
+
+// ConfigFields returns the Quay config fields set by `spec.config`, or nil if it is unset.
+func (spec *QuayOperatorConfigSpec) ConfigFields() (map[string]interface{}, error) {
+	if spec == nil || spec.Config == nil || len(spec.Config.Raw) == 0 {
+		return nil, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(spec.Config.Raw, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// ConfigFieldOverridable returns true if a registry may set the given field of `spec.config` in its config bundle.
+func (spec *QuayOperatorConfigSpec) ConfigFieldOverridable(field string) bool {
+	if spec == nil {
+		return true
+	}
+
+	for _, overridable := range spec.OverridableConfigFields {
+		if overridable == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StorageBackendAllowed returns true if registries may use the given `DISTRIBUTED_STORAGE_CONFIG` driver.
+func (spec *QuayOperatorConfigSpec) StorageBackendAllowed(driver string) bool {
+	if spec == nil || len(spec.AllowedStorageBackends) == 0 {
+		return true
+	}
+
+	for _, allowed := range spec.AllowedStorageBackends {
+		if allowed == driver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EnforcedPolicies returns the security policies every registry must satisfy.
+func (spec *QuayOperatorConfigSpec) EnforcedPolicies() []SecurityPolicy {
+	if spec == nil {
+		return nil
+	}
+
+	return spec.Policies
+}
+
+func init() {
+	SchemeBuilder.Register(&QuayOperatorConfig{}, &QuayOperatorConfigList{})
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var operatorConfigTests = []struct {
+	name                string
+	spec                *QuayOperatorConfigSpec
+	field               string
+	driver              string
+	expectedFields      map[string]interface{}
+	expectedOverridable bool
+	expectedAllowed     bool
+}{
+	{
+		"NoOperatorConfig",
+		nil,
+		"FEATURE_USER_CREATION",
+		"LocalStorage",
+		nil,
+		true,
+		true,
+	},
+	{
+		"ForcedField",
+		&QuayOperatorConfigSpec{
+			Config:                 &runtime.RawExtension{Raw: []byte(`{"FEATURE_USER_CREATION": false}`)},
+			AllowedStorageBackends: []string{"S3Storage"},
+		},
+		"FEATURE_USER_CREATION",
+		"LocalStorage",
+		map[string]interface{}{"FEATURE_USER_CREATION": false},
+		false,
+		false,
+	},
+	{
+		"OverridableField",
+		&QuayOperatorConfigSpec{
+			Config:                  &runtime.RawExtension{Raw: []byte(`{"DEFAULT_TAG_EXPIRATION": "2w"}`)},
+			OverridableConfigFields: []string{"DEFAULT_TAG_EXPIRATION"},
+			AllowedStorageBackends:  []string{"S3Storage", "RadosGWStorage"},
+		},
+		"DEFAULT_TAG_EXPIRATION",
+		"RadosGWStorage",
+		map[string]interface{}{"DEFAULT_TAG_EXPIRATION": "2w"},
+		true,
+		true,
+	},
+}
+
+func TestQuayOperatorConfigSpec(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range operatorConfigTests {
+		fields, err := test.spec.ConfigFields()
+		assert.Nil(err, test.name)
+		assert.Equal(test.expectedFields, fields, test.name)
+		assert.Equal(test.expectedOverridable, test.spec.ConfigFieldOverridable(test.field), test.name)
+		assert.Equal(test.expectedAllowed, test.spec.StorageBackendAllowed(test.driver), test.name)
+	}
+}
@@ -18,15 +18,27 @@ package v1
 
 import (
 	"errors"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type QuayVersion string
 
 const (
-	SupportsRoutesAnnotation  = "supports-routes"
+	SupportsRoutesAnnotation = "supports-routes"
+	// SupportsPostgresClustersAnnotation is set when the `PostgresCluster` API of the Crunchy Postgres Operator is
+	// available, so the managed `postgres` component can be deployed with it.
+	SupportsPostgresClustersAnnotation = "supports-postgresclusters"
+	// SupportsServiceMonitorsAnnotation is set when the `ServiceMonitor` API of the Prometheus Operator is available, so
+	// the metrics of managed components can be scraped.
+	SupportsServiceMonitorsAnnotation = "supports-servicemonitors"
+	// ClusterHostnameAnnotation overrides the detected ingress domain of the cluster used to build hostnames.
 	ClusterHostnameAnnotation = "router-canonical-hostname"
 
 	SupportsObjectStorageAnnotation = "supports-object-storage"
@@ -34,6 +46,39 @@ const (
 	StorageBucketNameAnnotation     = "storage-bucketname"
 	StorageAccessKeyAnnotation      = "storage-access-key"
 	StorageSecretKeyAnnotation      = "storage-secret-key"
+	StorageSASTokenAnnotation       = "storage-sas-token"
+	StorageTempURLKeyAnnotation     = "storage-temp-url-key"
+
+	MinIOAccessKeyAnnotation = "minio-access-key"
+	MinIOSecretKeyAnnotation = "minio-secret-key"
+
+	// DatabasePasswordAnnotation holds the password of the managed `postgres` component while the registry is rendered.
+	DatabasePasswordAnnotation = "database-password"
+	// ClairDatabasePasswordAnnotation holds the password of the database of the managed `clair` component while the
+	// registry is rendered.
+	ClairDatabasePasswordAnnotation = "clair-database-password"
+	// RedisPasswordAnnotation holds the password of the managed `redis` component while the registry is rendered.
+	RedisPasswordAnnotation = "redis-password"
+
+	// PausedComponentsAnnotation is a comma-separated list of managed components which the Operator will stop
+	// reconciling, allowing them to be modified by hand while the rest of the registry remains managed.
+	PausedComponentsAnnotation = "paused-components"
+
+	// ApprovedChangesAnnotation holds the approval ID of the pending changes to the components of
+	// `spec.protectedComponents` which may be applied, as reported by the `ChangesPendingApproval` condition.
+	ApprovedChangesAnnotation = "approved-changes"
+
+	// ReloadConfigAnnotation requests that the Operator re-reads the config bundle and re-applies every managed
+	// object right away, rather than on its next periodic reconcile. It is removed once the reload has been requested.
+	ReloadConfigAnnotation = "reload-config"
+
+	// ObjectBucketClaimFinalizer keeps a deleted `QuayRegistry` until the `ObjectBucketClaim` of its managed
+	// `objectstorage` component is gone, so that its provisioner releases the bucket.
+	ObjectBucketClaimFinalizer = "quay.redhat.com/objectbucketclaim"
+
+	// BuildersFinalizer keeps a deleted `QuayRegistry` until its builds are cancelled and the builder `Jobs` started by
+	// its build manager are gone, so that no build workers are orphaned when the Quay app is torn down.
+	BuildersFinalizer = "quay.redhat.com/builders"
 )
 
 const (
@@ -62,19 +107,56 @@ func mostRecentVersion() QuayVersion {
 	return mostRecent
 }
 
+// maxUpgradeSkew is how many versions newer than `status.currentVersion` Quay can be upgraded to at once, since each
+// version only migrates the database of the versions shortly before it.
+const maxUpgradeSkew = 1
+
+// upgradePathFor returns the versions of the given ranking which must be upgraded to in turn before upgrading from
+// current to desired, which is not included. `dev` is never part of an upgrade path.
+func upgradePathFor(ranks map[QuayVersion]int, current, desired QuayVersion) []QuayVersion {
+	if current == "" || current == QuayVersionDev || desired == QuayVersionDev {
+		return nil
+	}
+
+	between := []QuayVersion{}
+	for version, rank := range ranks {
+		if version != QuayVersionDev && rank > ranks[current] && rank < ranks[desired] {
+			between = append(between, version)
+		}
+	}
+	sort.Slice(between, func(i, j int) bool { return ranks[between[i]] < ranks[between[j]] })
+
+	path := []QuayVersion{}
+	for i := maxUpgradeSkew - 1; i < len(between); i += maxUpgradeSkew {
+		path = append(path, between[i])
+	}
+
+	return path
+}
+
+// UpgradePathFor returns the versions Quay must be upgraded to in turn before it can be upgraded from the current
+// version to the desired one, or none if it can be upgraded directly.
+func UpgradePathFor(current, desired QuayVersion) []QuayVersion {
+	return upgradePathFor(quayVersions, current, desired)
+}
+
 var allComponents = []string{
 	"postgres",
 	"clair",
 	"redis",
 	"horizontalpodautoscaler",
 	"objectstorage",
+	"minio",
+	"localstorage",
+	"pgbouncer",
 	"route",
 }
 
 // QuayRegistrySpec defines the desired state of QuayRegistry.
 type QuayRegistrySpec struct {
 	// DesiredVersion declares the version of Quay that should deployed and managed.
-	// Upgrading Quay is accomplished by modifying this field. Runtime validation will prevent upgrading backwards.
+	// Upgrading Quay is accomplished by modifying this field. Runtime validation will prevent upgrading backwards,
+	// or skipping versions which the database must be migrated through.
 	// If any unmanaged components are incompatible with the value of this field, the Operator will not upgrade.
 	// If omitted, will default to the latest version that the Operator knows how to manage.
 	DesiredVersion QuayVersion `json:"desiredVersion,omitempty"`
@@ -82,6 +164,1067 @@ type QuayRegistrySpec struct {
 	ConfigBundleSecret string `json:"configBundleSecret,omitempty"`
 	// Components declare how the Operator should handle backing Quay services.
 	Components []Component `json:"components,omitempty"`
+	// Notifications declare webhooks which the Operator calls when the registry changes state.
+	Notifications []NotificationWebhook `json:"notifications,omitempty"`
+	// DriftPolicy declares what the Operator does when managed objects are modified outside of the Operator.
+	// `Remediate` (the default) reverts the changes, `DetectOnly` only reports them.
+	// +kubebuilder:validation:Enum=Remediate;DetectOnly
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// StorageMigration moves all blobs to a different storage location, switching the registry to use it once every
+	// blob has been copied.
+	StorageMigration *StorageMigration `json:"storageMigration,omitempty"`
+	// BlobVerification verifies a random sample of blobs in object storage against their digests in the database on a
+	// schedule, such as after a storage migration.
+	BlobVerification *BlobVerification `json:"blobVerification,omitempty"`
+	// Profile sets defaults for replicas, worker counts, database connection pool size and resource requests based
+	// on the expected size of the registry. If omitted, the defaults of the manifests are used.
+	// +kubebuilder:validation:Enum=small;medium;large
+	Profile Profile `json:"profile,omitempty"`
+	// ProfileOverrides replace individual values chosen by `profile`.
+	ProfileOverrides *ProfileOverrides `json:"profileOverrides,omitempty"`
+	// PodOverrides replace how the pods of individual `Deployments` are stopped, such as so that a managed database
+	// shuts down cleanly when its node is drained.
+	PodOverrides []PodOverride `json:"podOverrides,omitempty"`
+	// Authentication configures how users log in to Quay. Fields set here take precedence over the config bundle.
+	Authentication *Authentication `json:"authentication,omitempty"`
+	// TagPolicy sets registry-wide defaults for tag expiration, immutability and pruning.
+	TagPolicy *TagPolicy `json:"tagPolicy,omitempty"`
+	// Repositories sets registry-wide defaults for repositories and organizations created by pushing to them.
+	Repositories *RepositoryPolicy `json:"repositories,omitempty"`
+	// PodAntiAffinity declares how strictly replicas of Quay and Clair are spread across nodes.
+	// `Preferred` (the default) spreads them where possible, `Required` will not schedule two replicas on one node.
+	// +kubebuilder:validation:Enum=Preferred;Required
+	PodAntiAffinity PodAntiAffinityMode `json:"podAntiAffinity,omitempty"`
+	// StorageAffinity schedules the Quay app pods on the nodes of an in-cluster storage gateway, such as NooBaa
+	// endpoints or Ceph RGW, so that blobs proxied by Quay do not cross nodes.
+	StorageAffinity *StorageAffinity `json:"storageAffinity,omitempty"`
+	// ClairUpdaters selects which vulnerability sources the managed Clair fetches, and how often.
+	ClairUpdaters *ClairUpdaters `json:"clairUpdaters,omitempty"`
+	// Clair configures how the managed Clair is served.
+	Clair *ClairSettings `json:"clair,omitempty"`
+	// Mode selects what the Operator deploys. `Registry` (the default) deploys a complete registry. `MirrorWorkers` only
+	// deploys repository mirroring workers, which use the database, storage and secret keys of an existing registry
+	// given in the config bundle.
+	// +kubebuilder:validation:Enum=Registry;MirrorWorkers
+	Mode RegistryMode `json:"mode,omitempty"`
+	// DryRun renders and validates the registry and reports the changes which would be made in `status.plannedChanges`,
+	// without creating, updating or deleting anything.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Suspend scales every component of the registry to zero, keeping its config, `Secrets` and volumes, until it is
+	// set back to false. Suited to registries which are only needed some of the time, such as for development.
+	Suspend bool `json:"suspend,omitempty"`
+	// Route configures the router timeout, HSTS, rate limiting and allowed source ranges of the managed Quay `Route`.
+	Route *RouteSettings `json:"route,omitempty"`
+	// Exposure controls how the registry is reached. `External` (the default) uses a `Route` where available.
+	// `Internal` creates no `Route` and only `ClusterIP` `Services`, with `SERVER_HOSTNAME` defaulting to the
+	// in-cluster DNS name of the Quay `Service`.
+	// +kubebuilder:validation:Enum=External;Internal
+	Exposure ExposureMode `json:"exposure,omitempty"`
+	// ServiceType is the type of the Quay and config editor `Services`, such as `NodePort`. If omitted, it is
+	// `LoadBalancer` unless the registry is exposed using a `Route` or `exposure` is `Internal`.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// Endpoint sets the port of the Quay `Service` and where TLS is terminated, such as to serve plain HTTP on port 80
+	// behind an external TLS terminator. Requires the `route` component to be unmanaged.
+	Endpoint *EndpointSettings `json:"endpoint,omitempty"`
+	// ExternalDNS annotates the managed `Route`, or the Quay `Service` if the `route` component is unmanaged, so that
+	// external-dns publishes `SERVER_HOSTNAME`. The registry is not reported as `Available` until the hostname resolves.
+	ExternalDNS *ExternalDNSSettings `json:"externalDNS,omitempty"`
+	// CertificateExpiryThreshold is how long before a certificate used by the registry expires that it is reported
+	// as expiring. Defaults to 30 days.
+	CertificateExpiryThreshold *metav1.Duration `json:"certificateExpiryThreshold,omitempty"`
+	// SecretKeysBackup keeps an encrypted backup of the generated `SECRET_KEY` and `DATABASE_SECRET_KEY`, without which
+	// the database cannot be read, and restores them from it if they are lost.
+	SecretKeysBackup *SecretKeysBackup `json:"secretKeysBackup,omitempty"`
+	// BuildTriggers configures the Git providers which start builds from webhooks, and how their webhooks reach
+	// Quay. Requires `FEATURE_BUILD_SUPPORT`.
+	BuildTriggers *BuildTriggers `json:"buildTriggers,omitempty"`
+	// ObjectStorage configures the storage backend used when the `objectstorage` component is managed. If omitted,
+	// a bucket is claimed with an `ObjectBucketClaim`.
+	ObjectStorage *ObjectStorage `json:"objectStorage,omitempty"`
+	// StorageUploads tunes how Quay uploads image layers in chunks to the S3-compatible locations in
+	// `DISTRIBUTED_STORAGE_CONFIG`, including the managed one.
+	StorageUploads []StorageUploadSettings `json:"storageUploads,omitempty"`
+	// TempStorage mounts a volume for the temporary files of a component, such as the layers of images being pushed,
+	// instead of using the ephemeral storage of its containers.
+	TempStorage []TempStorage `json:"tempStorage,omitempty"`
+	// EphemeralStorage sets the ephemeral storage requests and limits of a component, so its pods are scheduled on
+	// nodes with enough disk and are not the first to be evicted under disk pressure.
+	EphemeralStorage []EphemeralStorage `json:"ephemeralStorage,omitempty"`
+	// Postgres configures the database of the managed `postgres` component.
+	Postgres *ManagedPostgres `json:"postgres,omitempty"`
+	// PersistentVolumeRetentionPolicy controls whether the volumes of a managed database are deleted or kept when
+	// the `QuayRegistry` is deleted or the component is no longer managed.
+	PersistentVolumeRetentionPolicy []PersistentVolumeRetention `json:"persistentVolumeRetentionPolicy,omitempty"`
+	// Redis configures how Quay connects to Redis, so that it recovers quickly when Redis is briefly unavailable.
+	Redis *RedisSettings `json:"redis,omitempty"`
+	// ExternalDatabase connects Quay to a database which is not managed by the Operator, using the `DB_URI` in a
+	// `Secret`. The `postgres` component is unmanaged unless `spec.components` says otherwise, which is an error.
+	ExternalDatabase *ExternalDatabase `json:"externalDatabase,omitempty"`
+	// ExternalRedis connects Quay to a Redis which is not managed by the Operator, using the host, port and password in
+	// a `Secret`. The `redis` component is unmanaged unless `spec.components` says otherwise, which is an error.
+	ExternalRedis *ExternalRedis `json:"externalRedis,omitempty"`
+	// DatabaseReadReplicas are read replicas of the database of Quay, which Quay spreads read queries across with
+	// `DB_READ_REPLICAS`.
+	DatabaseReadReplicas *DatabaseReadReplicas `json:"databaseReadReplicas,omitempty"`
+	// ProtectedComponents are the components whose existing objects are only updated once the changes are approved
+	// with the `approved-changes` annotation, protecting them from accidental edits to the spec or config bundle.
+	ProtectedComponents []string `json:"protectedComponents,omitempty"`
+	// PgBouncer configures the connection pooler of the managed `pgbouncer` component.
+	PgBouncer *PgBouncerSettings `json:"pgBouncer,omitempty"`
+}
+
+// PersistentVolumeRetentionPolicy is what happens to the volumes of a database once it is no longer managed.
+type PersistentVolumeRetentionPolicy string
+
+const (
+	// PersistentVolumeRetentionPolicyRetain keeps the volumes when the `QuayRegistry` is deleted or the component is
+	// no longer managed.
+	PersistentVolumeRetentionPolicyRetain PersistentVolumeRetentionPolicy = "Retain"
+	// PersistentVolumeRetentionPolicyDelete deletes the volumes when the `QuayRegistry` is deleted or the component
+	// is no longer managed.
+	PersistentVolumeRetentionPolicyDelete PersistentVolumeRetentionPolicy = "Delete"
+)
+
+// PersistentVolumeRetention describes what happens to the volumes of a managed database.
+type PersistentVolumeRetention struct {
+	// Component is `postgres` for the Quay database, or `clair` for the Clair database.
+	// +kubebuilder:validation:Enum=postgres;clair
+	Component string `json:"component"`
+	// Policy is `Retain` or `Delete`. If a component is not listed, its volumes are deleted with the `QuayRegistry`
+	// but kept when the component is no longer managed.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	Policy PersistentVolumeRetentionPolicy `json:"policy"`
+}
+
+// PostgresVersion is a major version of Postgres.
+type PostgresVersion string
+
+const (
+	PostgresVersion10 PostgresVersion = "10"
+	PostgresVersion12 PostgresVersion = "12"
+	PostgresVersion13 PostgresVersion = "13"
+
+	// DefaultPostgresVersion is the major version of a managed database if `spec.postgres.version` is omitted.
+	DefaultPostgresVersion = PostgresVersion13
+)
+
+// postgresImages are the images of the latest minor release of each supported major version. When an Operator
+// release updates one of them, every registry using it backs up its database before the new image is rolled out.
+var postgresImages = map[PostgresVersion]string{
+	PostgresVersion10: "postgres:10.23",
+	PostgresVersion12: "postgres:12.17",
+	PostgresVersion13: "postgres:13.13",
+}
+
+// ManagedPostgres describes the database of the managed `postgres` component.
+type ManagedPostgres struct {
+	// Version is the major version of Postgres. Defaults to 13. Raising the version of an existing database stops
+	// Quay, dumps the database and restores it with the new version. It cannot be lowered.
+	// +kubebuilder:validation:Enum="10";"12";"13"
+	Version PostgresVersion `json:"version,omitempty"`
+	// TLS serves the managed databases of Quay and Clair over TLS, with certificates generated and rotated by the
+	// Operator. Quay and Clair connect with `sslmode=verify-full`.
+	TLS bool `json:"tls,omitempty"`
+	// Provider is what deploys the database of Quay. `Deployment` (the default) runs a single Postgres pod.
+	// `PostgresCluster` creates a highly available cluster with the Crunchy Postgres Operator, which must be installed.
+	// +kubebuilder:validation:Enum=Deployment;PostgresCluster
+	Provider PostgresProvider `json:"provider,omitempty"`
+	// Instances is the number of Postgres instances of a `PostgresCluster`, one of which is the primary. Defaults to 2.
+	// +kubebuilder:validation:Minimum=1
+	Instances *int32 `json:"instances,omitempty"`
+	// Backups dumps the database on a schedule and uploads the dumps to the object storage of Quay.
+	Backups *PostgresBackups `json:"backups,omitempty"`
+	// Resources are the compute resources of the Postgres container, replacing those of the manifests. With the
+	// `PostgresCluster` provider, they apply to each instance.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Metrics exports the health of the database, such as its connections, locks and replication, to Prometheus.
+	Metrics *PostgresMetrics `json:"metrics,omitempty"`
+}
+
+// PostgresMetrics describes how the metrics of the managed database are exported.
+type PostgresMetrics struct {
+	// Interval is how often Prometheus scrapes the metrics, such as `30s`. Defaults to `30s`.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(ms|s|m|h))+$`
+	Interval string `json:"interval,omitempty"`
+}
+
+// DefaultPostgresMetricsInterval is how often the metrics of the managed database are scraped if
+// `spec.postgres.metrics.interval` is omitted.
+const DefaultPostgresMetricsInterval = "30s"
+
+// PostgresBackups describes the scheduled backups of the managed database.
+type PostgresBackups struct {
+	// Schedule is when the database is backed up, in cron format, such as `0 3 * * *` for every day at 03:00 UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Retention is the number of backups kept in object storage. Older backups are deleted after each backup.
+	// Defaults to 7.
+	// +kubebuilder:validation:Minimum=1
+	Retention *int32 `json:"retention,omitempty"`
+}
+
+// DefaultPostgresBackupRetention is the number of scheduled backups kept if `spec.postgres.backups.retention` is
+// omitted.
+const DefaultPostgresBackupRetention = int32(7)
+
+// PostgresProvider is what deploys the database of the managed `postgres` component.
+type PostgresProvider string
+
+const (
+	PostgresProviderDeployment      PostgresProvider = "Deployment"
+	PostgresProviderPostgresCluster PostgresProvider = "PostgresCluster"
+
+	// DefaultPostgresClusterInstances is the number of instances of a `PostgresCluster` if `spec.postgres.instances` is
+	// omitted.
+	DefaultPostgresClusterInstances = int32(2)
+)
+
+// ExternalDatabase references the connection to a database which is not managed by the Operator.
+type ExternalDatabase struct {
+	// CredentialsSecret is the name of a `Secret` with the `DB_URI` of the database, and optionally the `ca.crt` its
+	// certificate is verified with. The `Secret` must be in the namespace of the `QuayRegistry`.
+	CredentialsSecret string `json:"credentialsSecret"`
+	// SSLMode is how the certificate of the database is verified with the `ca.crt` of the `credentialsSecret`.
+	// `verify-full` (the default) also verifies that it is issued for the host of `DB_URI`, `verify-ca` does not.
+	// +kubebuilder:validation:Enum=verify-full;verify-ca
+	SSLMode DatabaseSSLMode `json:"sslMode,omitempty"`
+}
+
+// ExternalRedis references the connection to a Redis which is not managed by the Operator.
+type ExternalRedis struct {
+	// CredentialsSecret is the name of a `Secret` with the `host` of Redis, and optionally its `port` and `password`.
+	// The `Secret` must be in the namespace of the `QuayRegistry`.
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// DatabaseReadReplicas references the read replicas of the database of Quay.
+type DatabaseReadReplicas struct {
+	// CredentialsSecrets are the names of `Secrets` with the `DB_URI` of each read replica, in the namespace of the
+	// `QuayRegistry`. The replicas of the managed `PostgresCluster` are used if omitted, which requires more than one
+	// instance.
+	CredentialsSecrets []string `json:"credentialsSecrets,omitempty"`
+}
+
+// PgBouncerPoolMode is when PgBouncer returns a server connection to the pool.
+type PgBouncerPoolMode string
+
+const (
+	PgBouncerPoolModeSession     PgBouncerPoolMode = "session"
+	PgBouncerPoolModeTransaction PgBouncerPoolMode = "transaction"
+
+	// DefaultPgBouncerPoolSize is the number of server connections PgBouncer opens if `spec.pgBouncer.defaultPoolSize`
+	// is omitted.
+	DefaultPgBouncerPoolSize = int32(20)
+)
+
+// PgBouncerSettings describes how the managed `pgbouncer` component pools the connections of Quay to its database.
+type PgBouncerSettings struct {
+	// PoolMode is `transaction` (the default) to return a server connection to the pool after each transaction, or
+	// `session` to keep it until the client disconnects.
+	// +kubebuilder:validation:Enum=session;transaction
+	PoolMode PgBouncerPoolMode `json:"poolMode,omitempty"`
+	// DefaultPoolSize is how many server connections PgBouncer opens to the database. Defaults to 20.
+	// +kubebuilder:validation:Minimum=1
+	DefaultPoolSize *int32 `json:"defaultPoolSize,omitempty"`
+}
+
+// DatabaseSSLMode is the `sslmode` Quay connects to a database with.
+type DatabaseSSLMode string
+
+const (
+	DatabaseSSLModeVerifyFull DatabaseSSLMode = "verify-full"
+	DatabaseSSLModeVerifyCA   DatabaseSSLMode = "verify-ca"
+)
+
+// RedisSettings describes the connection options Quay uses for both `BUILDLOGS_REDIS` and `USER_EVENTS_REDIS`.
+type RedisSettings struct {
+	// ConnectTimeout is how long Quay waits to connect to Redis. Defaults to 5s.
+	ConnectTimeout *metav1.Duration `json:"connectTimeout,omitempty"`
+	// Timeout is how long Quay waits for Redis to respond to a command. Defaults to 5s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// HealthCheckInterval is how long a connection may be idle before it is checked before use. Defaults to 30s.
+	HealthCheckInterval *metav1.Duration `json:"healthCheckInterval,omitempty"`
+	// RetryOnTimeout retries a command once on a new connection if it times out. Defaults to true.
+	RetryOnTimeout *bool `json:"retryOnTimeout,omitempty"`
+	// Port is the port Quay connects to Redis on. For the managed `redis` component, it is the port of its `Service`.
+	// For an unmanaged Redis, it replaces the port of the config bundle. Cannot be used with `spec.externalRedis`,
+	// whose `Secret` sets the port, or with `sentinel`. Defaults to 6379.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+	// Database is the index of the Redis database Quay uses, so a Redis can be shared with other applications.
+	// Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	Database *int32 `json:"database,omitempty"`
+	// TLS connects to an unmanaged Redis over TLS. The managed `redis` component is only served without TLS.
+	TLS *RedisTLS `json:"tls,omitempty"`
+	// Sentinel connects Quay to Redis through Sentinel, which promotes a replica when the master fails, so build logs
+	// and user events survive the loss of a Redis node. The managed `redis` component is then deployed as a
+	// replicated Redis with its own Sentinels.
+	Sentinel *RedisSentinel `json:"sentinel,omitempty"`
+}
+
+// RedisSentinel describes a Redis master and its replicas monitored by Sentinel.
+type RedisSentinel struct {
+	// Replicas is the number of pods of the managed `redis` component, each running Redis and Sentinel. Defaults to 3.
+	// +kubebuilder:validation:Minimum=3
+	Replicas *int32 `json:"replicas,omitempty"`
+	// MasterName is the name the Sentinels monitor the master under. Defaults to `quay` for the managed `redis`
+	// component, and is required for an unmanaged Redis.
+	MasterName string `json:"masterName,omitempty"`
+	// Hosts are the `host:port` addresses of the Sentinels of an unmanaged Redis. The port defaults to 26379.
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+// RedisTLS describes the TLS connection to an unmanaged Redis.
+type RedisTLS struct {
+	// InsecureSkipVerify does not verify the certificate of Redis.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// Defaults of the Redis connection options when `spec.redis` omits them.
+const (
+	DefaultRedisConnectTimeout      = 5 * time.Second
+	DefaultRedisTimeout             = 5 * time.Second
+	DefaultRedisHealthCheckInterval = 30 * time.Second
+)
+
+// Defaults of the managed `redis` component when `spec.redis.sentinel` omits them.
+const (
+	DefaultRedisSentinelReplicas   = int32(3)
+	DefaultRedisSentinelMasterName = "quay"
+)
+
+type PostgresUpdatePhase string
+
+const (
+	// PostgresUpdatePhaseCurrent means the database runs the image of its major version.
+	PostgresUpdatePhaseCurrent PostgresUpdatePhase = "Current"
+	// PostgresUpdatePhaseBackingUp means the database is being backed up before a new image is rolled out.
+	PostgresUpdatePhaseBackingUp PostgresUpdatePhase = "BackingUp"
+	// PostgresUpdatePhaseFailed means the backup failed, and the database keeps running its previous image.
+	PostgresUpdatePhaseFailed PostgresUpdatePhase = "Failed"
+	// PostgresUpdatePhaseBlocked means the database was backed up, but keeps running its previous image since its
+	// major version is unknown, until `spec.postgres.version` declares it.
+	PostgresUpdatePhaseBlocked PostgresUpdatePhase = "Blocked"
+	// PostgresUpdatePhaseRestoring means the database was backed up before upgrading it to a new major version, and
+	// the backup is being restored into a new data directory with the new image.
+	PostgresUpdatePhaseRestoring PostgresUpdatePhase = "Restoring"
+	// PostgresUpdatePhaseRestoreFailed means restoring the backup of a major version upgrade failed, and Quay remains
+	// stopped until the restore is retried or `spec.postgres.version` is set back to the recorded version.
+	PostgresUpdatePhaseRestoreFailed PostgresUpdatePhase = "RestoreFailed"
+)
+
+// PostgresStatus is the image of the managed database, and the progress of updating it.
+type PostgresStatus struct {
+	// Version is the major version of the database, if known.
+	Version PostgresVersion `json:"version,omitempty"`
+	// Image is the image the database runs.
+	Image string `json:"image"`
+	// TargetImage is the image the database is being updated to.
+	TargetImage string `json:"targetImage,omitempty"`
+	// Phase is the current step of the update.
+	Phase PostgresUpdatePhase `json:"phase"`
+	// Message describes the result of the last step.
+	Message string `json:"message,omitempty"`
+	// DataDirectory is the directory of the volume of the database its data is in. Each major version upgrade restores
+	// the database into a new directory, keeping the previous one. Defaults to `pgdata`.
+	DataDirectory string `json:"dataDirectory,omitempty"`
+}
+
+// PostgresBackupStatus is the result of the scheduled backups of the managed database.
+type PostgresBackupStatus struct {
+	// LastSuccessfulTime is when the last scheduled backup which succeeded completed.
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+	// LastJob is the `Job` which ran the last completed scheduled backup, whether or not it succeeded.
+	LastJob string `json:"lastJob,omitempty"`
+	// Failed is true if the last completed scheduled backup failed.
+	Failed bool `json:"failed,omitempty"`
+	// Message describes the result of the last completed scheduled backup.
+	Message string `json:"message,omitempty"`
+}
+
+// EphemeralStorage describes the ephemeral storage resources of a component.
+type EphemeralStorage struct {
+	// Component is `quay` for the Quay app pods, or `clair` for the managed Clair pods.
+	// +kubebuilder:validation:Enum=quay;clair
+	Component string `json:"component"`
+	// Request is the ephemeral storage requested by each container. Replaces the default of the manifests.
+	Request *resource.Quantity `json:"request,omitempty"`
+	// Limit is the ephemeral storage above which a container is evicted. If omitted, there is no limit.
+	Limit *resource.Quantity `json:"limit,omitempty"`
+}
+
+// TempStorageBacking is the kind of volume temporary files are written to.
+type TempStorageBacking string
+
+const (
+	TempStorageBackingEmptyDir              TempStorageBacking = "EmptyDir"
+	TempStorageBackingPersistentVolumeClaim TempStorageBacking = "PersistentVolumeClaim"
+)
+
+// TempStorage describes the volume for the temporary files of a component.
+type TempStorage struct {
+	// Component is `quay` for the Quay app pods, or `clair` for the managed Clair pods.
+	// +kubebuilder:validation:Enum=quay;clair
+	Component string `json:"component"`
+	// Backing is `EmptyDir` (the default) for a volume on the node, or `PersistentVolumeClaim` for a volume claimed
+	// by the Operator and shared by the replicas of the component.
+	// +kubebuilder:validation:Enum=EmptyDir;PersistentVolumeClaim
+	Backing TempStorageBacking `json:"backing,omitempty"`
+	// Size is the size of the volume. An `EmptyDir` volume is limited to it and requests it as ephemeral storage.
+	// Required for `PersistentVolumeClaim`.
+	Size *resource.Quantity `json:"size,omitempty"`
+	// StorageClassName is the `StorageClass` of a `PersistentVolumeClaim`, which must support `ReadWriteMany`. If
+	// omitted, the one from the `QuayOperatorConfig` or the cluster default is used.
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+// StorageUploadSettings are the chunked upload arguments of a storage location.
+type StorageUploadSettings struct {
+	// Location is the name of the location in `DISTRIBUTED_STORAGE_CONFIG` the settings apply to. If omitted, they
+	// apply to every location which is not listed separately.
+	Location string `json:"location,omitempty"`
+	// MinimumChunkSizeMB is the size in MiB below which an upload is not split into parts. Must be at least 5, the
+	// smallest part S3 accepts.
+	MinimumChunkSizeMB *int32 `json:"minimumChunkSizeMB,omitempty"`
+	// MaximumChunkSizeMB is the largest part in MiB an upload is split into. Must be at most 5120, the largest part
+	// S3 accepts.
+	MaximumChunkSizeMB *int32 `json:"maximumChunkSizeMB,omitempty"`
+	// ServerSideAssembly assembles the parts of an upload with a multipart copy in the storage provider, instead of
+	// Quay downloading and re-uploading them. Disable it for providers which do not support multipart copies.
+	ServerSideAssembly *bool `json:"serverSideAssembly,omitempty"`
+}
+
+// ObjectStorage describes the bucket the managed `objectstorage` component stores images in.
+type ObjectStorage struct {
+	// S3 stores images in an existing AWS S3 bucket instead of claiming one, so the `ObjectBucketClaims` API is not
+	// required. Only one of `s3`, `gcs`, `azure`, `swift` and `ibmcos` may be set.
+	S3 *S3Storage `json:"s3,omitempty"`
+	// GCS stores images in an existing Google Cloud Storage bucket instead of claiming one, so the
+	// `ObjectBucketClaims` API is not required.
+	GCS *GCSStorage `json:"gcs,omitempty"`
+	// Azure stores images in an existing Azure Blob Storage container instead of claiming a bucket, so the
+	// `ObjectBucketClaims` API is not required.
+	Azure *AzureStorage `json:"azure,omitempty"`
+	// Swift stores images in an existing OpenStack Swift container instead of claiming a bucket, so the
+	// `ObjectBucketClaims` API is not required.
+	Swift *SwiftStorage `json:"swift,omitempty"`
+	// IBMCOS stores images in an existing IBM Cloud Object Storage bucket instead of claiming one, such as on Red Hat
+	// OpenShift on IBM Cloud.
+	IBMCOS *IBMCOSStorage `json:"ibmcos,omitempty"`
+	// Locations are additional existing buckets or containers of a geo-replicated registry, which blobs are
+	// replicated to with `FEATURE_STORAGE_REPLICATION`. The bucket above, or the claimed bucket, is named `local_us`.
+	Locations []StorageLocation `json:"locations,omitempty"`
+	// Preference is the order of the locations Quay reads blobs from. Defaults to `local_us` followed by
+	// `locations` in order.
+	Preference []string `json:"preference,omitempty"`
+	// DefaultLocations are the locations every blob is replicated to. Defaults to every location.
+	DefaultLocations []string `json:"defaultLocations,omitempty"`
+	// DegradedLocations are locations which are temporarily unavailable. Quay fails over to the other locations: a
+	// degraded location is read from last, and blobs are not replicated to it until it is removed from this list.
+	DegradedLocations []string `json:"degradedLocations,omitempty"`
+	// CABundle is a bundle of the private CAs which the TLS certificates of the storage endpoints are issued by, such
+	// as those of an on-premise RadosGW. It is trusted by the Quay pods in addition to the system CAs.
+	CABundle *StorageCABundle `json:"caBundle,omitempty"`
+}
+
+// DefaultStorageCABundleKey is the key of the CA bundle in its `Secret` or `ConfigMap` if none is given.
+const DefaultStorageCABundleKey = "ca-bundle.crt"
+
+// StorageCABundle references a CA bundle in PEM format. Exactly one of `secret` and `configMap` must be set.
+type StorageCABundle struct {
+	// Secret is the name of a `Secret` with the CA bundle.
+	Secret string `json:"secret,omitempty"`
+	// ConfigMap is the name of a `ConfigMap` with the CA bundle.
+	ConfigMap string `json:"configMap,omitempty"`
+	// Key is the key of the CA bundle. Defaults to `ca-bundle.crt`.
+	Key string `json:"key,omitempty"`
+}
+
+// StorageLocation is a named location in `DISTRIBUTED_STORAGE_CONFIG`, in an existing bucket or container. Exactly one
+// of `s3`, `gcs`, `azure`, `swift` and `ibmcos` must be set.
+type StorageLocation struct {
+	// Name is the name of the location, such as `eu_west`.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9_]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// S3 is an existing AWS S3, or S3-compatible, bucket. A `roleARN` can only be assumed with a
+	// `credentialsSecret`, and `cloudFront` is not supported.
+	S3 *S3Storage `json:"s3,omitempty"`
+	// GCS is an existing Google Cloud Storage bucket.
+	GCS *GCSStorage `json:"gcs,omitempty"`
+	// Azure is an existing Azure Blob Storage container.
+	Azure *AzureStorage `json:"azure,omitempty"`
+	// Swift is an existing OpenStack Swift container.
+	Swift *SwiftStorage `json:"swift,omitempty"`
+	// IBMCOS is an existing IBM Cloud Object Storage bucket.
+	IBMCOS *IBMCOSStorage `json:"ibmcos,omitempty"`
+}
+
+// IBMCOSStorage describes an IBM Cloud Object Storage bucket, accessed with its S3-compatible API.
+type IBMCOSStorage struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+	// Endpoint is the hostname of the regional endpoint of the bucket, such as
+	// `s3.us-south.cloud-object-storage.appdomain.cloud`, or its private endpoint from inside IBM Cloud.
+	Endpoint string `json:"endpoint"`
+	// Port is the port of the `endpoint`. Defaults to 443.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+	// StoragePath is the prefix of the objects Quay stores in the bucket. Defaults to `/datastorage/registry`.
+	StoragePath string `json:"storagePath,omitempty"`
+	// CredentialsSecret is the name of a `Secret` with the `accessKey` and `secretKey` of the HMAC credentials of a
+	// service ID which can read and write the bucket.
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// SwiftStorage describes an OpenStack Swift container.
+type SwiftStorage struct {
+	// AuthURL is the URL of the OpenStack identity service, such as `https://keystone.example.com:5000/v3`.
+	AuthURL string `json:"authURL"`
+	// AuthVersion is the version of the identity API. Defaults to 2.
+	// +kubebuilder:validation:Enum=1;2;3
+	AuthVersion *int32 `json:"authVersion,omitempty"`
+	// Container is the name of the container.
+	Container string `json:"container"`
+	// StoragePath is the prefix of the objects Quay stores in the container. Defaults to `/datastorage/registry`.
+	StoragePath string `json:"storagePath,omitempty"`
+	// OSOptions are passed to the identity service, such as `project_name`, `user_domain_name` and `region_name`.
+	OSOptions map[string]string `json:"osOptions,omitempty"`
+	// CredentialsSecret is the name of a `Secret` with the `user` and `password` of the OpenStack account, and
+	// optionally the `tempURLKey` of the account, which lets clients download images directly from Swift.
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// AzureStorage describes an Azure Blob Storage container.
+type AzureStorage struct {
+	// AccountName is the name of the storage account.
+	AccountName string `json:"accountName"`
+	// Container is the name of the container in the storage account.
+	Container string `json:"container"`
+	// StoragePath is the prefix of the blobs Quay stores in the container. Defaults to `/datastorage/registry`.
+	StoragePath string `json:"storagePath,omitempty"`
+	// CredentialsSecret is the name of a `Secret` with either the `accountKey` of the storage account, or a
+	// `sasToken` which can read, write and delete blobs in the container.
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// GCSStorage describes a Google Cloud Storage bucket, accessed with the XML API.
+type GCSStorage struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+	// StoragePath is the prefix of the objects Quay stores in the bucket. Defaults to `/datastorage/registry`.
+	StoragePath string `json:"storagePath,omitempty"`
+	// CredentialsSecret is the name of a `Secret` with the `accessKey` and `secretKey` of an HMAC key of a service
+	// account which can read and write the bucket.
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// S3Storage describes an AWS S3, or S3-compatible, bucket.
+type S3Storage struct {
+	// Bucket is the name of the bucket.
+	Bucket string `json:"bucket"`
+	// Region is the AWS region of the bucket, such as `us-east-1`.
+	Region string `json:"region,omitempty"`
+	// Endpoint is the hostname of an S3-compatible service. If omitted, the AWS endpoint of the region is used.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Port is the port of the `endpoint`. Defaults to 443, or 80 if `insecure` is set.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+	// Insecure connects to the `endpoint` over plain HTTP, such as an on-premise RadosGW without TLS. It can only be
+	// set with `serverSideEncryption` disabled.
+	Insecure bool `json:"insecure,omitempty"`
+	// StoragePath is the prefix of the objects Quay stores in the bucket. Defaults to `/datastorage/registry`.
+	StoragePath string `json:"storagePath,omitempty"`
+	// CredentialsSecret is the name of a `Secret` with the `accessKey` and `secretKey` of the bucket. If omitted,
+	// the Quay pods use their default AWS credentials, such as an IAM role.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// RoleARN is an IAM role the Quay pods assume to access the bucket, so that no long-lived keys of the bucket
+	// are stored. With a `credentialsSecret`, its keys only allow assuming the role with STS. Without, the role is
+	// assumed with the web identity of the Quay pods' `ServiceAccount` (IRSA), which the Operator annotates with it.
+	RoleARN string `json:"roleARN,omitempty"`
+	// ServerSideEncryption requests that S3 encrypts stored objects with AES256. Defaults to true. It can only be
+	// disabled with an `endpoint`, for S3-compatible services which do not support it.
+	ServerSideEncryption *bool `json:"serverSideEncryption,omitempty"`
+	// CloudFront serves image layers from a CloudFront distribution in front of the bucket, with URLs signed by the
+	// Quay pods.
+	CloudFront *CloudFrontDistribution `json:"cloudFront,omitempty"`
+}
+
+// CloudFrontDistribution describes a CloudFront distribution with an S3 bucket as its origin.
+type CloudFrontDistribution struct {
+	// Domain is the domain name of the distribution, such as `d111111abcdef8.cloudfront.net`.
+	Domain string `json:"domain"`
+	// KeyID is the ID of the public key CloudFront verifies signed URLs with.
+	KeyID string `json:"keyID"`
+	// SigningKeySecret is the name of a `Secret` with the private key URLs are signed with, in PEM format, under
+	// `cloudfront-signing-key.pem`.
+	SigningKeySecret string `json:"signingKeySecret"`
+}
+
+// BuildTriggers describes the Git providers build triggers can be set up with.
+type BuildTriggers struct {
+	// GitHub enables build triggers from GitHub or GitHub Enterprise.
+	GitHub *BuildTriggerProvider `json:"github,omitempty"`
+	// GitLab enables build triggers from GitLab.
+	GitLab *BuildTriggerProvider `json:"gitlab,omitempty"`
+	// Bitbucket enables build triggers from Bitbucket Cloud.
+	Bitbucket *BuildTriggerProvider `json:"bitbucket,omitempty"`
+	// WebhookHostname is a public hostname at which webhooks reach Quay, for when `SERVER_HOSTNAME` is not reachable
+	// from the Git providers. The Operator creates a `Route` for it which only exposes `/webhooks`. Requires the
+	// `route` component to be managed.
+	WebhookHostname string `json:"webhookHostname,omitempty"`
+}
+
+// BuildTriggerProvider describes the OAuth application registered with a Git provider for Quay.
+type BuildTriggerProvider struct {
+	// CredentialsSecret is the name of a `Secret` with the `clientId` and `clientSecret` of the OAuth application.
+	CredentialsSecret string `json:"credentialsSecret"`
+	// Endpoint is the URL of a self-hosted GitHub Enterprise or GitLab instance. If omitted, the public service is
+	// used. Not supported for Bitbucket.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// SecretKeysBackup configures the backup of the secret keys generated by the Operator.
+type SecretKeysBackup struct {
+	// PassphraseSecret is the name of a `Secret` whose `passphrase` key encrypts the backup. Keep a copy of the
+	// passphrase outside of the cluster, since the backup cannot be restored without it.
+	PassphraseSecret string `json:"passphraseSecret"`
+}
+
+type ExposureMode string
+
+const (
+	ExposureExternal ExposureMode = "External"
+	ExposureInternal ExposureMode = "Internal"
+)
+
+// RouteSettings configures the OpenShift router for the managed Quay `Route` using HAProxy annotations.
+type RouteSettings struct {
+	// Timeout is how long the router waits for Quay to respond, such as `10m`. Large image pushes frequently need
+	// longer than the router default of 30 seconds.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// HSTS is the `Strict-Transport-Security` header added to responses. The router can only add headers to routes
+	// it terminates TLS for, so this has no effect on the default `passthrough` route.
+	HSTS *RouteHSTS `json:"hsts,omitempty"`
+	// RateLimit limits the connections accepted from each client IP address.
+	RateLimit *RouteRateLimit `json:"rateLimit,omitempty"`
+	// AllowedSourceRanges are the IP addresses and CIDR ranges, such as `203.0.113.0/24`, the router accepts
+	// connections from. Connections from any other address are refused. If omitted, every address is allowed.
+	AllowedSourceRanges []string `json:"allowedSourceRanges,omitempty"`
+	// RegistryAPI serves the registry API (`/v2`) from its own `Route`, so that policies such as rate limits can
+	// differ from those of the web UI. The other settings then only apply to the web UI `Route`, except for HSTS and
+	// the allowed source ranges which apply to both.
+	RegistryAPI *RegistryAPIRoute `json:"registryAPI,omitempty"`
+}
+
+// RegistryAPIRoute describes the `Route` of the registry API.
+type RegistryAPIRoute struct {
+	// Hostname serves the registry API at a different hostname than `SERVER_HOSTNAME`. If omitted, the registry API
+	// is served at `SERVER_HOSTNAME`, split from the web UI by path.
+	Hostname string `json:"hostname,omitempty"`
+	// Timeout is how long the router waits for Quay to respond to registry API requests, such as `10m`.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// RateLimit limits the connections to the registry API accepted from each client IP address.
+	RateLimit *RouteRateLimit `json:"rateLimit,omitempty"`
+}
+
+// RouteHSTS describes an HTTP Strict Transport Security policy.
+type RouteHSTS struct {
+	// MaxAge is how long clients should only connect to the registry using HTTPS.
+	MaxAge metav1.Duration `json:"maxAge"`
+	// IncludeSubDomains applies the policy to every subdomain of the registry hostname.
+	IncludeSubDomains bool `json:"includeSubDomains,omitempty"`
+	// Preload allows the registry hostname to be included in browser HSTS preload lists.
+	Preload bool `json:"preload,omitempty"`
+}
+
+// RouteRateLimit limits the TCP connections accepted by the router from each client IP address.
+type RouteRateLimit struct {
+	// ConcurrentConnections is the maximum number of open connections from a single IP address.
+	// +kubebuilder:validation:Minimum=1
+	ConcurrentConnections *int32 `json:"concurrentConnections,omitempty"`
+	// ConnectionRate is the maximum number of connections a single IP address can open every 3 seconds.
+	// +kubebuilder:validation:Minimum=1
+	ConnectionRate *int32 `json:"connectionRate,omitempty"`
+}
+
+// TLSTermination is where TLS connections to the registry are terminated.
+// +kubebuilder:validation:Enum=Quay;External;None
+type TLSTermination string
+
+const (
+	// TLSTerminationQuay serves HTTPS from Quay itself.
+	TLSTerminationQuay TLSTermination = "Quay"
+	// TLSTerminationExternal serves plain HTTP to a load balancer or proxy which terminates TLS for clients.
+	TLSTerminationExternal TLSTermination = "External"
+	// TLSTerminationNone serves the registry over plain HTTP.
+	TLSTerminationNone TLSTermination = "None"
+)
+
+// EndpointSettings configures how the Quay `Service` is exposed.
+type EndpointSettings struct {
+	// Port is the port of the Quay `Service`. Defaults to 443 if Quay terminates TLS, otherwise 80.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port,omitempty"`
+	// TLSTermination is where TLS is terminated. Defaults to `Quay`.
+	TLSTermination TLSTermination `json:"tlsTermination,omitempty"`
+}
+
+// ExternalDNSSettings configures the DNS records published by external-dns for the registry hostname.
+type ExternalDNSSettings struct {
+	// TTL of the published DNS records. The default of the external-dns provider is used if omitted.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+type RegistryMode string
+
+const (
+	RegistryModeRegistry      RegistryMode = "Registry"
+	RegistryModeMirrorWorkers RegistryMode = "MirrorWorkers"
+)
+
+// ClairUpdaters configures the updaters which fetch vulnerability data into the managed Clair.
+type ClairUpdaters struct {
+	// Sets are the updater sets to run. If omitted, every updater set is run.
+	Sets []ClairUpdaterSet `json:"sets,omitempty"`
+	// Period is how often the updaters are run, such as `6h`. Defaults to Clair's own default of 30 minutes.
+	Period *metav1.Duration `json:"period,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=alpine;aws;debian;oracle;photon;pyupio;rhel;suse;ubuntu
+type ClairUpdaterSet string
+
+// ClairSettings describes how the managed Clair is served.
+type ClairSettings struct {
+	// TLS serves the Clair API over HTTPS, with a certificate generated and rotated by the Operator which Quay trusts.
+	// The introspection endpoint used for health checks and metrics is always served over HTTP.
+	TLS bool `json:"tls,omitempty"`
+	// Replicas is the number of Clair pods. Replicas coordinate indexing and updater runs with locks in the managed
+	// Clair database, so each manifest is indexed and each updater is run by one replica at a time. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Metrics selects the backend the managed Clair reports its metrics to. Defaults to Prometheus, scraped from the
+	// introspection endpoint.
+	Metrics *ClairMetrics `json:"metrics,omitempty"`
+}
+
+// ClairMetricsBackend is the backend the managed Clair reports its metrics to.
+// +kubebuilder:validation:Enum=prometheus;dogstatsd
+type ClairMetricsBackend string
+
+const (
+	ClairMetricsPrometheus ClairMetricsBackend = "prometheus"
+	ClairMetricsDogstatsd  ClairMetricsBackend = "dogstatsd"
+)
+
+// ClairMetrics configures the metrics of the managed Clair.
+type ClairMetrics struct {
+	// Name is the metrics backend, `prometheus` or `dogstatsd`. Defaults to `prometheus`.
+	Name ClairMetricsBackend `json:"name,omitempty"`
+	// Endpoint is the path Prometheus scrapes on the introspection endpoint, such as `/metrics`, with the `prometheus`
+	// backend, or the URL of the DogStatsD agent, such as `udp://datadog-agent.datadog.svc:8125`, which the
+	// `dogstatsd` backend requires.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+type PodAntiAffinityMode string
+
+const (
+	PodAntiAffinityPreferred PodAntiAffinityMode = "Preferred"
+	PodAntiAffinityRequired  PodAntiAffinityMode = "Required"
+)
+
+// StorageAffinity selects the pods of the storage gateway which the Quay app pods are scheduled next to.
+type StorageAffinity struct {
+	// MatchLabels are the labels of the pods of the storage gateway.
+	// +kubebuilder:validation:MinProperties=1
+	MatchLabels map[string]string `json:"matchLabels"`
+	// Namespaces are the namespaces of the pods of the storage gateway. Defaults to the namespace of the
+	// `QuayRegistry`.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Mode is `Preferred` (the default) to schedule Quay next to the gateway where possible, or `Required` to only
+	// schedule Quay on nodes running the gateway.
+	// +kubebuilder:validation:Enum=Preferred;Required
+	Mode StorageAffinityMode `json:"mode,omitempty"`
+}
+
+type StorageAffinityMode string
+
+const (
+	StorageAffinityPreferred StorageAffinityMode = "Preferred"
+	StorageAffinityRequired  StorageAffinityMode = "Required"
+)
+
+// TagPolicy describes registry-wide defaults for tags. Fields which are not supported by the deployed Quay version
+// prevent the registry from being reconciled.
+type TagPolicy struct {
+	// DefaultExpiration is how long deleted tags can be restored for (`DEFAULT_TAG_EXPIRATION`), such as `2w`.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(w|d|h|m|s)$`
+	DefaultExpiration string `json:"defaultExpiration,omitempty"`
+	// ExpirationOptions are the values users may choose from for tag expiration (`TAG_EXPIRATION_OPTIONS`).
+	ExpirationOptions []string `json:"expirationOptions,omitempty"`
+	// ImmutablePatterns are regular expressions matching tags which cannot be overwritten or deleted in any repository.
+	ImmutablePatterns []string `json:"immutablePatterns,omitempty"`
+	// AutoPrune is the default policy used to delete old tags from every namespace.
+	AutoPrune *AutoPrunePolicy `json:"autoPrune,omitempty"`
+}
+
+// RepositoryVisibility is whether a repository can be pulled by anyone or only by users granted access.
+type RepositoryVisibility string
+
+const (
+	RepositoryVisibilityPublic  RepositoryVisibility = "Public"
+	RepositoryVisibilityPrivate RepositoryVisibility = "Private"
+)
+
+// RepositoryPolicy describes registry-wide defaults for creating repositories. Fields set here take precedence over
+// the config bundle.
+type RepositoryPolicy struct {
+	// DefaultVisibility is the visibility of repositories created by pushing to them (`CREATE_PRIVATE_REPO_ON_PUSH`).
+	// If omitted, the config bundle decides, and Quay otherwise creates private repositories.
+	// +kubebuilder:validation:Enum=Public;Private
+	DefaultVisibility RepositoryVisibility `json:"defaultVisibility,omitempty"`
+	// CreateNamespaceOnPush creates the organization of a repository pushed to if it does not exist yet
+	// (`CREATE_NAMESPACE_ON_PUSH`). If omitted, the config bundle decides, and Quay otherwise refuses the push.
+	CreateNamespaceOnPush *bool `json:"createNamespaceOnPush,omitempty"`
+}
+
+type AutoPruneMethod string
+
+const (
+	AutoPruneMethodNumberOfTags AutoPruneMethod = "number_of_tags"
+	AutoPruneMethodCreationDate AutoPruneMethod = "creation_date"
+)
+
+// AutoPrunePolicy describes which tags are deleted by the auto-prune worker.
+type AutoPrunePolicy struct {
+	// Method is how tags are selected for pruning.
+	// +kubebuilder:validation:Enum=number_of_tags;creation_date
+	Method AutoPruneMethod `json:"method"`
+	// Value is the number of tags to keep (`number_of_tags`) or the maximum age of tags, such as `30d` (`creation_date`).
+	Value string `json:"value"`
+	// Workers is the number of pods of a dedicated `<name>-quay-pruner` `Deployment` running the auto-prune worker,
+	// which then no longer runs in the Quay app pods. By default every Quay app pod runs the worker.
+	// +kubebuilder:validation:Minimum=1
+	Workers *int32 `json:"workers,omitempty"`
+	// ApplyToExistingOrganizations also creates the policy for organizations which already existed without one,
+	// through the Quay API. Quay only applies the default policy to namespaces created after it was set.
+	ApplyToExistingOrganizations bool `json:"applyToExistingOrganizations,omitempty"`
+}
+
+type AuthenticationType string
+
+const (
+	AuthenticationTypeDatabase AuthenticationType = "Database"
+	AuthenticationTypeJWT      AuthenticationType = "JWT"
+)
+
+// Authentication describes how Quay authenticates users.
+type Authentication struct {
+	// Type is the `AUTHENTICATION_TYPE` Quay uses. If omitted, the config bundle decides.
+	// +kubebuilder:validation:Enum=Database;JWT
+	Type AuthenticationType `json:"type,omitempty"`
+	// JWT configures an external token issuer. Required when `type` is `JWT`.
+	JWT *JWTAuthentication `json:"jwt,omitempty"`
+	// AppTokens configures application-specific tokens, which users generate to log in from the Docker CLI.
+	AppTokens *AppTokens `json:"appTokens,omitempty"`
+	// Usernames configures how Quay usernames are derived from LDAP and OIDC logins.
+	Usernames *FederatedUsernames `json:"usernames,omitempty"`
+	// RestrictedUsers configures which users may only create content in organizations they are a member of.
+	RestrictedUsers *RestrictedUsers `json:"restrictedUsers,omitempty"`
+}
+
+// FederatedUsernames describes how Quay usernames are derived from the identities of external authentication. Quay
+// normalizes the derived username into a valid one, adding a number if it is already taken.
+type FederatedUsernames struct {
+	// Confirm lets users change their derived username at their first login. Disable it so the same identity gets the
+	// same username on every registry. If omitted, the config bundle decides.
+	Confirm *bool `json:"confirm,omitempty"`
+	// LDAPAttribute is the LDAP attribute usernames are derived from. Requires `AUTHENTICATION_TYPE: LDAP` in the
+	// config bundle.
+	LDAPAttribute string `json:"ldapAttribute,omitempty"`
+	// OIDCClaim is the claim usernames are derived from, for every OIDC provider in the config bundle.
+	OIDCClaim string `json:"oidcClaim,omitempty"`
+}
+
+// RestrictedUsers describes which users may not create content in their own user namespace.
+type RestrictedUsers struct {
+	// Enabled restricts every user who is not exempt.
+	Enabled bool `json:"enabled"`
+	// Exempt are the usernames which are not restricted. Requires `enabled`.
+	Exempt []string `json:"exempt,omitempty"`
+}
+
+// AppTokens describes how application-specific tokens may be used.
+type AppTokens struct {
+	// Enabled allows users to create application-specific tokens.
+	Enabled bool `json:"enabled"`
+	// RequiredForCLI rejects account passwords for Docker CLI logins, so users must use an app token (or encrypted
+	// password). Requires `enabled`.
+	RequiredForCLI bool `json:"requiredForCLI,omitempty"`
+	// Expiration is how long tokens are valid for, such as `90d`. If omitted, tokens do not expire.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(w|d|h|m|s)$`
+	Expiration string `json:"expiration,omitempty"`
+}
+
+// JWTAuthentication describes an external identity broker which issues JWTs that Quay verifies.
+type JWTAuthentication struct {
+	// Issuer is the expected `iss` claim of tokens.
+	Issuer string `json:"issuer"`
+	// Audience is the expected `aud` claim of tokens. Quay requires this to be its `SERVER_HOSTNAME`, which is
+	// used if omitted.
+	Audience string `json:"audience,omitempty"`
+	// VerifyEndpoint is the URL which Quay calls to verify a username and password.
+	VerifyEndpoint string `json:"verifyEndpoint"`
+	// GetUserEndpoint is the URL which Quay calls to look up a user.
+	GetUserEndpoint string `json:"getUserEndpoint,omitempty"`
+	// QueryEndpoint is the URL which Quay calls to search for users.
+	QueryEndpoint string `json:"queryEndpoint,omitempty"`
+	// PublicKeySecret is the name of a Kubernetes `Secret` in the same namespace containing the issuer's public key
+	// under `jwt-authn.cert`.
+	PublicKeySecret string `json:"publicKeySecret"`
+}
+
+type Profile string
+
+const (
+	ProfileSmall  Profile = "small"
+	ProfileMedium Profile = "medium"
+	ProfileLarge  Profile = "large"
+)
+
+// ProfileOverrides replace values chosen by the registry size profile.
+type ProfileOverrides struct {
+	// Replicas is the number of Quay app pods. When `horizontalpodautoscaler` is managed, this is the minimum.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// MaxReplicas is the maximum number of Quay app pods when `horizontalpodautoscaler` is managed.
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// WorkerCounts is the number of worker processes of each type in every Quay app pod.
+	WorkerCounts *WorkerCounts `json:"workerCounts,omitempty"`
+	// Resources are the compute resources of the Quay app container.
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PodOverride replaces how the pods of one `Deployment` are stopped.
+type PodOverride struct {
+	// Component is the `quay-component` label of the `Deployment`, such as `quay-app` or `postgres`.
+	// +kubebuilder:validation:Enum=quay-app;quay-app-upgrade;quay-config-editor;quay-mirror;quay-pruner;clair;clair-postgres;postgres;redis;minio;pgbouncer
+	Component string `json:"component"`
+	// TerminationGracePeriodSeconds is how long the pods may take to stop after their containers are sent `SIGTERM`,
+	// including the `preStop` hook, before they are killed.
+	// +kubebuilder:validation:Minimum=0
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// Lifecycle replaces the hooks of the main container of the pods, such as a `preStop` command run before it is
+	// sent `SIGTERM`.
+	Lifecycle *corev1.Lifecycle `json:"lifecycle,omitempty"`
+}
+
+// WorkerCounts is the number of worker processes of each type run by Quay.
+type WorkerCounts struct {
+	Web      *int32 `json:"web,omitempty"`
+	Registry *int32 `json:"registry,omitempty"`
+	Secscan  *int32 `json:"secscan,omitempty"`
+}
+
+type DriftPolicy string
+
+const (
+	DriftPolicyRemediate  DriftPolicy = "Remediate"
+	DriftPolicyDetectOnly DriftPolicy = "DetectOnly"
+)
+
+// StorageMigration describes a migration of all blobs from the current storage location(s) to a new one.
+type StorageMigration struct {
+	// TargetLocation is the name of the location in `DISTRIBUTED_STORAGE_CONFIG` of the config bundle to migrate to.
+	TargetLocation string `json:"targetLocation"`
+}
+
+type StorageMigrationPhase string
+
+const (
+	// StorageMigrationPhaseReplicating means Quay writes new blobs to both locations while existing blobs are copied.
+	StorageMigrationPhaseReplicating StorageMigrationPhase = "Replicating"
+	// StorageMigrationPhaseVerifying means every blob is being checked for a copy in the target location.
+	StorageMigrationPhaseVerifying StorageMigrationPhase = "Verifying"
+	// StorageMigrationPhaseComplete means the target location is preferred for all reads and writes.
+	StorageMigrationPhaseComplete StorageMigrationPhase = "Complete"
+	// StorageMigrationPhaseFailed means a migration step failed and requires intervention.
+	StorageMigrationPhaseFailed StorageMigrationPhase = "Failed"
+)
+
+// StorageMigrationStatus is the progress of a storage migration.
+type StorageMigrationStatus struct {
+	// TargetLocation is the storage location being migrated to.
+	TargetLocation string `json:"targetLocation"`
+	// Phase is the current step of the migration.
+	Phase StorageMigrationPhase `json:"phase"`
+	// Message describes the result of the last step.
+	Message string `json:"message,omitempty"`
+}
+
+// BlobVerification describes the scheduled verification of blobs in object storage.
+type BlobVerification struct {
+	// Schedule is when blobs are verified, in cron format, such as `0 4 * * 0` for every Sunday at 04:00 UTC.
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// SampleSize is the number of blobs, chosen at random, verified each time. Defaults to 1000.
+	// +kubebuilder:validation:Minimum=1
+	SampleSize *int32 `json:"sampleSize,omitempty"`
+}
+
+// DefaultBlobVerificationSampleSize is the number of blobs verified each time if `spec.blobVerification.sampleSize` is
+// omitted.
+const DefaultBlobVerificationSampleSize = int32(1000)
+
+// BlobVerificationStatus is the result of the last scheduled verification of blobs.
+type BlobVerificationStatus struct {
+	// LastCompletionTime is when the last verification which ran to completion finished.
+	LastCompletionTime *metav1.Time `json:"lastCompletionTime,omitempty"`
+	// LastJob is the `Job` which ran the last completed verification, whether or not it succeeded.
+	LastJob string `json:"lastJob,omitempty"`
+	// Verified is the number of blobs whose contents matched their digest in the last verification.
+	Verified int32 `json:"verified,omitempty"`
+	// Corrupted is the number of blobs whose contents did not match their digest in the last verification.
+	Corrupted int32 `json:"corrupted,omitempty"`
+	// Missing is the number of blobs which were not found in any of their storage locations in the last verification.
+	Missing int32 `json:"missing,omitempty"`
+	// Failed is true if the last verification could not run to completion.
+	Failed bool `json:"failed,omitempty"`
+	// Message describes the result of the last verification.
+	Message string `json:"message,omitempty"`
+}
+
+// BuildStatus summarizes the build queue of a registry, as reported by Quay's metrics.
+type BuildStatus struct {
+	// Queued is the number of builds waiting for a builder.
+	Queued int32 `json:"queued"`
+	// Active is the number of builds currently running.
+	Active int32 `json:"active"`
+	// BuildersAvailable is false when builds are queued but none are running, which usually means no builder is
+	// able to pick them up.
+	BuildersAvailable bool `json:"buildersAvailable"`
+}
+
+type PlannedAction string
+
+const (
+	PlannedActionCreate PlannedAction = "Create"
+	PlannedActionUpdate PlannedAction = "Update"
+)
+
+// PlannedChange describes a change the Operator would make to a managed object if `spec.dryRun` was not set.
+type PlannedChange struct {
+	// Action is what would be done to the object.
+	Action PlannedAction `json:"action"`
+	// Object is the kind and name of the object, such as `Deployment/example-quay-app`.
+	Object string `json:"object"`
+	// Fields are the paths of the fields which would be changed by an update.
+	Fields []string `json:"fields,omitempty"`
 }
 
 // Component describes how the Operator should handle a backing Quay service.
@@ -93,17 +1236,166 @@ type Component struct {
 	Managed bool `json:"managed"`
 }
 
+type NotificationEvent string
+
+const (
+	NotificationEventAvailable       NotificationEvent = "Available"
+	NotificationEventDegraded        NotificationEvent = "Degraded"
+	NotificationEventUpgradeComplete NotificationEvent = "UpgradeComplete"
+)
+
+// NotificationWebhook describes an HTTP endpoint which is sent a `POST` request when the registry changes state.
+type NotificationWebhook struct {
+	// URL is the endpoint which will receive the notification.
+	URL string `json:"url"`
+	// Events limits which events are sent to this webhook. If omitted, all events are sent.
+	Events []NotificationEvent `json:"events,omitempty"`
+	// SigningSecret is the name of a Kubernetes `Secret` in the same namespace containing a `key` used to sign
+	// the request body with HMAC-SHA256. If omitted, requests are not signed.
+	SigningSecret string `json:"signingSecret,omitempty"`
+	// AllowInternal permits a URL using plain HTTP or pointing to a loopback, link-local, private or cluster-internal
+	// address, which are otherwise rejected.
+	AllowInternal bool `json:"allowInternal,omitempty"`
+}
+
+type ConditionType string
+
+const (
+	ConditionTypeAvailable ConditionType = "Available"
+	ConditionTypeDegraded  ConditionType = "Degraded"
+	ConditionTypeDrifted   ConditionType = "Drifted"
+
+	ConditionTypeDatabaseHealthy ConditionType = "DatabaseHealthy"
+	ConditionTypeRedisHealthy    ConditionType = "RedisHealthy"
+	ConditionTypeStorageHealthy  ConditionType = "StorageHealthy"
+	ConditionTypeAuthHealthy     ConditionType = "AuthHealthy"
+
+	ConditionTypePolicyViolated ConditionType = "PolicyViolated"
+
+	ConditionTypeDNSPropagated ConditionType = "DNSPropagated"
+
+	ConditionTypeConfigDeprecated ConditionType = "ConfigDeprecated"
+
+	ConditionTypeChangesPendingApproval ConditionType = "ChangesPendingApproval"
+
+	ConditionTypeDatabaseUpgrading ConditionType = "DatabaseUpgrading"
+)
+
+const (
+	ConditionReasonComponentsCreationSuccess = "ComponentsCreationSuccess"
+	ConditionReasonComponentCreationFailed   = "ComponentCreationFailed"
+	ConditionReasonUpgradeComplete           = "UpgradeComplete"
+	ConditionReasonManagedObjectsDrifted     = "ManagedObjectsDrifted"
+	ConditionReasonDriftRemediated           = "DriftRemediated"
+	ConditionReasonNoDrift                   = "NoDrift"
+	ConditionReasonInvalidConfiguration      = "InvalidConfiguration"
+	ConditionReasonHealthCheckPassed         = "HealthCheckPassed"
+	ConditionReasonHealthCheckFailed         = "HealthCheckFailed"
+	ConditionReasonHealthCheckUnavailable    = "HealthCheckUnavailable"
+	ConditionReasonPolicyViolation           = "PolicyViolation"
+	ConditionReasonPoliciesSatisfied         = "PoliciesSatisfied"
+	ConditionReasonCertificateExpiring       = "CertificateExpiring"
+	ConditionReasonDNSPropagationPending     = "DNSPropagationPending"
+	ConditionReasonDNSPropagated             = "DNSPropagated"
+	ConditionReasonExternalDNSDisabled       = "ExternalDNSDisabled"
+	ConditionReasonLocalStorage              = "LocalStorage"
+	ConditionReasonDeprecatedConfig          = "DeprecatedConfig"
+	ConditionReasonNoDeprecatedConfig        = "NoDeprecatedConfig"
+	ConditionReasonApprovalRequired          = "ApprovalRequired"
+	ConditionReasonChangesApproved           = "ChangesApproved"
+	ConditionReasonDatabaseBackingUp         = "DatabaseBackingUp"
+	ConditionReasonDatabaseRestoring         = "DatabaseRestoring"
+	ConditionReasonDatabaseUpgradeFailed     = "DatabaseUpgradeFailed"
+	ConditionReasonDatabaseUpgraded          = "DatabaseUpgraded"
+	ConditionReasonRegistrySuspended         = "RegistrySuspended"
+	ConditionReasonUnsupportedUpgradePath    = "UnsupportedUpgradePath"
+)
+
+// RegistryHealth summarizes the conditions of a registry, so the registries managed by the Operator can be monitored
+// at a glance.
+type RegistryHealth string
+
+const (
+	// RegistryHealthAvailable is a registry which is running without problems.
+	RegistryHealthAvailable RegistryHealth = "Available"
+	// RegistryHealthSuspended is a registry scaled to zero by `spec.suspend`.
+	RegistryHealthSuspended RegistryHealth = "Suspended"
+	// RegistryHealthProgressing is a registry which has not become available yet.
+	RegistryHealthProgressing RegistryHealth = "Progressing"
+	// RegistryHealthUpgrading is a registry being upgraded to its desired version.
+	RegistryHealthUpgrading RegistryHealth = "Upgrading"
+	// RegistryHealthDegraded is a registry which cannot be rolled out, violates a policy, or depends on an unhealthy
+	// service.
+	RegistryHealthDegraded RegistryHealth = "Degraded"
+)
+
+// RegistryHealths are every `RegistryHealth`, in order of increasing severity.
+var RegistryHealths = []RegistryHealth{
+	RegistryHealthAvailable,
+	RegistryHealthSuspended,
+	RegistryHealthProgressing,
+	RegistryHealthUpgrading,
+	RegistryHealthDegraded,
+}
+
+// Condition is a summary of some aspect of the `QuayRegistry` state.
+type Condition struct {
+	Type               ConditionType          `json:"type"`
+	Status             metav1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+}
+
 // QuayRegistryStatus defines the observed state of QuayRegistry.
 type QuayRegistryStatus struct {
 	// CurrentVersion is the actual version of Quay that is actively deployed.
 	CurrentVersion QuayVersion `json:"currentVersion,omitempty"`
 	// RegistryEndpoint is the external access point for the Quay registry.
 	RegistryEndpoint string `json:"registryEndpoint,omitempty"`
+	// ClusterHostname is the ingress domain of the cluster which generated hostnames are built from, detected from
+	// the OpenShift ingress configuration.
+	ClusterHostname string `json:"clusterHostname,omitempty"`
 	// LastUpdate is the timestamp when the Operator last processed this instance.
 	LastUpdate string `json:"lastUpdated,omitempty"`
 	// ConfigEditorEndpoint is the external access point for a web-based reconfiguration interface
 	// for the Quay registry instance.
 	ConfigEditorEndpoint string `json:"configEditorEndpoint,omitempty"`
+	// Conditions represent the latest available observations of the registry's state.
+	Conditions []Condition `json:"conditions,omitempty"`
+	// StorageMigration is the progress of the storage migration declared in `spec.storageMigration`.
+	StorageMigration *StorageMigrationStatus `json:"storageMigration,omitempty"`
+	// BlobVerification is the result of the last scheduled verification of blobs declared in `spec.blobVerification`.
+	BlobVerification *BlobVerificationStatus `json:"blobVerification,omitempty"`
+	// Builds is the state of the build queue, reported when `FEATURE_BUILD_SUPPORT` is enabled.
+	Builds *BuildStatus `json:"builds,omitempty"`
+	// PlannedChanges are the changes the Operator would make to managed objects, reported while `spec.dryRun` is set
+	// or while changes to `spec.protectedComponents` await approval.
+	PlannedChanges []PlannedChange `json:"plannedChanges,omitempty"`
+	// LastError is the most recent error which prevented the registry from being fully reconciled, cleared once it
+	// is resolved.
+	LastError *LastError `json:"lastError,omitempty"`
+	// OperatorConfig are the fleet-wide defaults from the `QuayOperatorConfig` which the registry inherits.
+	OperatorConfig *QuayOperatorConfigSpec `json:"operatorConfig,omitempty"`
+	// DatabaseSecretKeyFingerprint identifies the `DATABASE_SECRET_KEY` the database is encrypted with, so that an
+	// accidental change to it is refused.
+	DatabaseSecretKeyFingerprint string `json:"databaseSecretKeyFingerprint,omitempty"`
+	// StorageCredentialsFingerprint identifies the object storage credentials rendered into the config bundle, so that
+	// their rotation is reported.
+	StorageCredentialsFingerprint string `json:"storageCredentialsFingerprint,omitempty"`
+	// Postgres is the image of the managed database, and the progress of updating it to the image of its version.
+	Postgres *PostgresStatus `json:"postgres,omitempty"`
+	// ClairPostgresImage is the image of the database of the managed `clair` component, recorded once it is deployed,
+	// so that a database deployed before its password was generated keeps the password it was initialized with.
+	ClairPostgresImage string `json:"clairPostgresImage,omitempty"`
+	// PostgresBackup is the result of the last scheduled backup of the managed database.
+	PostgresBackup *PostgresBackupStatus `json:"postgresBackup,omitempty"`
+	// RetainedVolumes are the `PersistentVolumeClaims` of databases which are kept when the `QuayRegistry` is
+	// deleted, or were kept when their component was no longer managed.
+	RetainedVolumes []string `json:"retainedVolumes,omitempty"`
+	// AutoPrunePolicy is the `<method>:<value>` of the auto-prune policy last created for existing organizations,
+	// so that it is only applied again when `spec.tagPolicy.autoPrune` changes.
+	AutoPrunePolicy string `json:"autoPrunePolicy,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -135,15 +1427,45 @@ func EnsureDefaultComponents(quay *QuayRegistry) (*QuayRegistry, error) {
 		updatedQuay.Spec.Components = []Component{}
 	}
 
-	for _, component := range quay.Spec.Components {
-		if component.Kind == "route" && component.Managed && !supportsRoutes(quay) {
-			return nil, errors.New("cannot use `route` component when `Route` API not available")
+	for _, protected := range quay.Spec.ProtectedComponents {
+		known := false
+		for _, component := range allComponents {
+			known = known || protected == component
 		}
-		if component.Kind == "objectstorage" && component.Managed && !supportsObjectBucketClaims(quay) {
-			return nil, errors.New("cannot use `objectstorage` component when `ObjectBucketClaims` API not available")
+		if !known {
+			return nil, errors.New("unknown component `" + protected + "` in `spec.protectedComponents`")
 		}
 	}
 
+	for _, component := range quay.Spec.Components {
+		if component.Kind == "route" && component.Managed && !supportsRoutes(quay) {
+			return nil, errors.New("cannot use `route` component when `Route` API not available")
+		}
+		if component.Kind == "route" && component.Managed && quay.Spec.Exposure == ExposureInternal {
+			return nil, errors.New("cannot use `route` component with `exposure: Internal`")
+		}
+		if component.Kind == "objectstorage" && component.Managed && !supportsObjectBucketClaims(quay) && ClaimsObjectBucket(quay) {
+			return nil, errors.New("cannot use `objectstorage` component when `ObjectBucketClaims` API not available")
+		}
+		if component.Kind == "minio" && component.Managed && ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+			return nil, errors.New("cannot use both `objectstorage` and `minio` components")
+		}
+		if component.Kind == "postgres" && component.Managed && quay.Spec.ExternalDatabase != nil {
+			return nil, errors.New("cannot use managed `postgres` component with `spec.externalDatabase`")
+		}
+		if component.Kind == "redis" && component.Managed && quay.Spec.ExternalRedis != nil {
+			return nil, errors.New("cannot use managed `redis` component with `spec.externalRedis`")
+		}
+		if component.Kind == "localstorage" && component.Managed {
+			for _, other := range []string{"objectstorage", "minio", "horizontalpodautoscaler"} {
+				if ComponentIsManaged(quay.Spec.Components, other) {
+					return nil, errors.New("cannot use both `" + other + "` and `localstorage` components")
+				}
+			}
+		}
+	}
+
+	localStorage := ComponentIsManaged(quay.Spec.Components, "localstorage")
 	for _, component := range allComponents {
 		found := false
 		for _, definedComponent := range quay.Spec.Components {
@@ -157,17 +1479,60 @@ func EnsureDefaultComponents(quay *QuayRegistry) (*QuayRegistry, error) {
 			if component == "route" && !supportsRoutes(quay) {
 				continue
 			}
-			if component == "objectstorage" && !supportsObjectBucketClaims(quay) {
+			if component == "objectstorage" && !supportsObjectBucketClaims(quay) && ClaimsObjectBucket(quay) {
 				continue
 			}
 
-			updatedQuay.Spec.Components = append(updatedQuay.Spec.Components, Component{Kind: component, Managed: true})
+			// Mirror workers use the backing services of an existing registry, so nothing is managed by default.
+			managed := quay.Spec.Mode != RegistryModeMirrorWorkers
+			if component == "route" && quay.Spec.Exposure == ExposureInternal {
+				managed = false
+			}
+			if component == "minio" && !defaultsToMinIO(quay) {
+				managed = false
+			}
+			// Local storage is only for evaluation, so it is never managed by default, and replaces the object storage
+			// and autoscaling of the single Quay app pod which can mount its volume.
+			if component == "localstorage" {
+				managed = false
+			}
+			if component == "postgres" && quay.Spec.ExternalDatabase != nil {
+				managed = false
+			}
+			if component == "redis" && quay.Spec.ExternalRedis != nil {
+				managed = false
+			}
+			// The connection pooler is only needed once Quay runs enough replicas to exhaust the database connections.
+			if component == "pgbouncer" {
+				managed = false
+			}
+			if localStorage && (component == "objectstorage" || component == "minio" || component == "horizontalpodautoscaler") {
+				managed = false
+			}
+			updatedQuay.Spec.Components = append(updatedQuay.Spec.Components, Component{Kind: component, Managed: managed})
 		}
 	}
 
+	if UsesPostgresCluster(updatedQuay) && !supportsPostgresClusters(quay) {
+		return nil, errors.New("cannot use `spec.postgres.provider: PostgresCluster` when `PostgresCluster` API not available")
+	}
+
 	return updatedQuay, nil
 }
 
+// defaultsToMinIO returns true if the registry stores images in a managed MinIO instance unless `spec.components`
+// says otherwise, because there is no other object storage which the Operator can manage. Registries which are
+// already deployed keep the storage from their config bundle.
+func defaultsToMinIO(quay *QuayRegistry) bool {
+	for _, component := range quay.Spec.Components {
+		if component.Kind == "objectstorage" {
+			return false
+		}
+	}
+
+	return quay.Status.CurrentVersion == "" && !supportsObjectBucketClaims(quay) && ClaimsObjectBucket(quay)
+}
+
 // ComponentsMatch returns true if both set of components are equivalent, and false otherwise.
 func ComponentsMatch(firstComponents, secondComponents []Component) bool {
 	if len(firstComponents) != len(secondComponents) {
@@ -190,12 +1555,16 @@ func ComponentsMatch(firstComponents, secondComponents []Component) bool {
 }
 
 // EnsureDesiredVersion validates that the Operator can managed the `Spec.DesiredVersion` indicated,
-// or else sets it to the latest version it can manage if unset.
+// or else sets it to the latest version it can manage if unset. The latest version is only chosen if the current
+// version can be upgraded to it directly, otherwise the first version of the upgrade path is.
 func EnsureDesiredVersion(quay *QuayRegistry) (*QuayRegistry, error) {
 	updatedQuay := quay.DeepCopy()
 
 	if updatedQuay.Spec.DesiredVersion == "" {
 		updatedQuay.Spec.DesiredVersion = mostRecentVersion()
+		if path := UpgradePathFor(quay.Status.CurrentVersion, updatedQuay.Spec.DesiredVersion); len(path) > 0 {
+			updatedQuay.Spec.DesiredVersion = path[0]
+		}
 
 		return updatedQuay, nil
 	}
@@ -208,15 +1577,39 @@ func EnsureDesiredVersion(quay *QuayRegistry) (*QuayRegistry, error) {
 		return updatedQuay, errors.New("invalid `desiredVersion`: " + string(updatedQuay.Spec.DesiredVersion))
 	}
 
+	if err := ValidateUpgradePath(quay); err != nil {
+		return updatedQuay, err
+	}
+
 	return updatedQuay, nil
 }
 
+// ValidateUpgradePath returns an error if `spec.desiredVersion` cannot be upgraded to directly from
+// `status.currentVersion`, naming the versions to upgrade to in turn first.
+func ValidateUpgradePath(quay *QuayRegistry) error {
+	path := UpgradePathFor(quay.Status.CurrentVersion, quay.Spec.DesiredVersion)
+	if len(path) == 0 {
+		return nil
+	}
+
+	steps := []string{}
+	for _, version := range path {
+		steps = append(steps, "`"+string(version)+"`")
+	}
+
+	return errors.New("cannot upgrade from `currentVersion` " + string(quay.Status.CurrentVersion) + " to " +
+		string(quay.Spec.DesiredVersion) + " directly: set `desiredVersion` to " + strings.Join(steps, ", then ") +
+		" first, waiting for each upgrade to complete")
+}
+
 // EnsureRegistryEndpoint sets the `status.registryEndpoint` field and returns `ok` if it was changed.
 func EnsureRegistryEndpoint(quay *QuayRegistry) (*QuayRegistry, bool) {
 	updatedQuay := quay.DeepCopy()
 
-	if supportsRoutes(quay) {
-		clusterHostname := quay.GetAnnotations()[ClusterHostnameAnnotation]
+	if quay.Spec.Exposure == ExposureInternal {
+		updatedQuay.Status.RegistryEndpoint = EndpointHostFor(quay, InternalHostnameFor(quay, "quay-app"))
+	} else if supportsRoutes(quay) {
+		clusterHostname := ClusterHostnameFor(quay)
 		updatedQuay.Status.RegistryEndpoint = strings.Join([]string{
 			strings.Join([]string{quay.GetName(), "quay", quay.GetNamespace()}, "-"),
 			clusterHostname},
@@ -231,8 +1624,10 @@ func EnsureRegistryEndpoint(quay *QuayRegistry) (*QuayRegistry, bool) {
 func EnsureConfigEditorEndpoint(quay *QuayRegistry) (*QuayRegistry, bool) {
 	updatedQuay := quay.DeepCopy()
 
-	if supportsRoutes(quay) {
-		clusterHostname := quay.GetAnnotations()[ClusterHostnameAnnotation]
+	if quay.Spec.Exposure == ExposureInternal {
+		updatedQuay.Status.ConfigEditorEndpoint = InternalHostnameFor(quay, "quay-config-editor")
+	} else if supportsRoutes(quay) {
+		clusterHostname := ClusterHostnameFor(quay)
 		updatedQuay.Status.ConfigEditorEndpoint = strings.Join([]string{
 			strings.Join([]string{quay.GetName(), "quay-config-editor", quay.GetNamespace()}, "-"),
 			clusterHostname},
@@ -243,6 +1638,669 @@ func EnsureConfigEditorEndpoint(quay *QuayRegistry) (*QuayRegistry, bool) {
 	return updatedQuay, quay.Status.ConfigEditorEndpoint == updatedQuay.Status.ConfigEditorEndpoint
 }
 
+// ClusterHostnameFor returns the ingress domain of the cluster, preferring the `router-canonical-hostname`
+// annotation over the detected `status.clusterHostname`.
+func ClusterHostnameFor(quay *QuayRegistry) string {
+	if hostname := quay.GetAnnotations()[ClusterHostnameAnnotation]; hostname != "" {
+		return hostname
+	}
+
+	return quay.Status.ClusterHostname
+}
+
+// TLSTerminationFor returns where TLS connections to the registry are terminated.
+func TLSTerminationFor(quay *QuayRegistry) TLSTermination {
+	if quay.Spec.Endpoint == nil || quay.Spec.Endpoint.TLSTermination == "" {
+		return TLSTerminationQuay
+	}
+
+	return quay.Spec.Endpoint.TLSTermination
+}
+
+// ServicePortsFor returns the ports of the Quay `Service` which serve HTTPS and plain HTTP, or 0 if there is none.
+// Plain HTTP only redirects to HTTPS if Quay terminates TLS.
+func ServicePortsFor(quay *QuayRegistry) (int32, int32) {
+	var port int32
+	if quay.Spec.Endpoint != nil {
+		port = quay.Spec.Endpoint.Port
+	}
+
+	if TLSTerminationFor(quay) == TLSTerminationQuay {
+		if port == 0 {
+			port = 443
+		}
+		return port, 80
+	}
+
+	if port == 0 {
+		port = 80
+	}
+	return 0, port
+}
+
+// EndpointHostFor returns the host clients use to reach the registry at the given hostname, which includes the port
+// of the Quay `Service` unless it is the default for the scheme. An external TLS terminator chooses its own port, so
+// the hostname is returned as is.
+func EndpointHostFor(quay *QuayRegistry, hostname string) string {
+	if hostname == "" || strings.Contains(hostname, ":") || TLSTerminationFor(quay) == TLSTerminationExternal {
+		return hostname
+	}
+
+	httpsPort, httpPort := ServicePortsFor(quay)
+	switch {
+	case httpsPort != 0 && httpsPort != 443:
+		return hostname + ":" + strconv.Itoa(int(httpsPort))
+	case httpsPort == 0 && httpPort != 80:
+		return hostname + ":" + strconv.Itoa(int(httpPort))
+	}
+
+	return hostname
+}
+
+// InternalHostnameFor returns the in-cluster DNS name of the given `Service` of a `QuayRegistry`.
+func InternalHostnameFor(quay *QuayRegistry, service string) string {
+	return strings.Join([]string{quay.GetName() + "-" + service, quay.GetNamespace(), "svc"}, ".")
+}
+
+// ComponentIsManaged returns true if the given component is declared and managed by the Operator.
+func ComponentIsManaged(components []Component, kind string) bool {
+	for _, component := range components {
+		if component.Kind == kind {
+			return component.Managed
+		}
+	}
+
+	return false
+}
+
+// S3StorageFor returns the S3 bucket the managed `objectstorage` component uses instead of an `ObjectBucketClaim`, or
+// nil if it does not use one.
+func S3StorageFor(quay *QuayRegistry) *S3Storage {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.S3
+}
+
+// GCSStorageFor returns the Google Cloud Storage bucket the managed `objectstorage` component uses instead of an
+// `ObjectBucketClaim`, or nil if it does not use one.
+func GCSStorageFor(quay *QuayRegistry) *GCSStorage {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.GCS
+}
+
+// AzureStorageFor returns the Azure Blob Storage container the managed `objectstorage` component uses instead of an
+// `ObjectBucketClaim`, or nil if it does not use one.
+func AzureStorageFor(quay *QuayRegistry) *AzureStorage {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.Azure
+}
+
+// SwiftStorageFor returns the OpenStack Swift container the managed `objectstorage` component uses instead of an
+// `ObjectBucketClaim`, or nil if it does not use one.
+func SwiftStorageFor(quay *QuayRegistry) *SwiftStorage {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.Swift
+}
+
+// IBMCOSStorageFor returns the IBM Cloud Object Storage bucket the managed `objectstorage` component uses instead of
+// an `ObjectBucketClaim`, or nil if it does not use one.
+func IBMCOSStorageFor(quay *QuayRegistry) *IBMCOSStorage {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.IBMCOS
+}
+
+// ClaimsObjectBucket returns true if the managed `objectstorage` component claims a bucket with an
+// `ObjectBucketClaim`, rather than using the existing bucket in `spec.objectStorage`.
+func ClaimsObjectBucket(quay *QuayRegistry) bool {
+	return S3StorageFor(quay) == nil && GCSStorageFor(quay) == nil && AzureStorageFor(quay) == nil &&
+		SwiftStorageFor(quay) == nil && IBMCOSStorageFor(quay) == nil
+}
+
+// PrimaryStorageLocation is the name of the location in `DISTRIBUTED_STORAGE_CONFIG` of the claimed bucket, or the
+// existing bucket in `spec.objectStorage`.
+const PrimaryStorageLocation = "local_us"
+
+// PrimaryStorageLocationFor returns the existing bucket in `spec.objectStorage` as a location, or nil if a bucket is
+// claimed instead.
+func PrimaryStorageLocationFor(quay *QuayRegistry) *StorageLocation {
+	if ClaimsObjectBucket(quay) {
+		return nil
+	}
+
+	return &StorageLocation{
+		Name:   PrimaryStorageLocation,
+		S3:     S3StorageFor(quay),
+		GCS:    GCSStorageFor(quay),
+		Azure:  AzureStorageFor(quay),
+		Swift:  SwiftStorageFor(quay),
+		IBMCOS: IBMCOSStorageFor(quay),
+	}
+}
+
+// StorageLocationsFor returns the additional locations in `spec.objectStorage.locations`.
+func StorageLocationsFor(quay *QuayRegistry) []StorageLocation {
+	if quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.Locations
+}
+
+// StoragePreferenceFor returns the order of the locations Quay reads blobs from, with degraded locations moved to the
+// end.
+func StoragePreferenceFor(quay *QuayRegistry) []string {
+	preference := []string{PrimaryStorageLocation}
+	if quay.Spec.ObjectStorage != nil && len(quay.Spec.ObjectStorage.Preference) > 0 {
+		preference = quay.Spec.ObjectStorage.Preference
+	} else {
+		for _, location := range StorageLocationsFor(quay) {
+			preference = append(preference, location.Name)
+		}
+	}
+
+	available, degraded := []string{}, []string{}
+	for _, name := range preference {
+		if StorageLocationDegraded(quay, name) {
+			degraded = append(degraded, name)
+		} else {
+			available = append(available, name)
+		}
+	}
+
+	return append(available, degraded...)
+}
+
+// StorageDefaultLocationsFor returns the locations every blob is replicated to, without degraded locations.
+func StorageDefaultLocationsFor(quay *QuayRegistry) []string {
+	defaultLocations := []string{PrimaryStorageLocation}
+	if quay.Spec.ObjectStorage != nil && len(quay.Spec.ObjectStorage.DefaultLocations) > 0 {
+		defaultLocations = quay.Spec.ObjectStorage.DefaultLocations
+	} else {
+		for _, location := range StorageLocationsFor(quay) {
+			defaultLocations = append(defaultLocations, location.Name)
+		}
+	}
+
+	available := []string{}
+	for _, name := range defaultLocations {
+		if !StorageLocationDegraded(quay, name) {
+			available = append(available, name)
+		}
+	}
+
+	return available
+}
+
+// StorageLocationDegraded returns true if the given location is listed in `spec.objectStorage.degradedLocations`.
+func StorageLocationDegraded(quay *QuayRegistry, name string) bool {
+	if quay.Spec.ObjectStorage == nil {
+		return false
+	}
+	for _, degraded := range quay.Spec.ObjectStorage.DegradedLocations {
+		if degraded == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StorageLocationAnnotation returns the annotation the controller copies a credential of an additional location to,
+// such as `storage-access-key.eu_west`.
+func StorageLocationAnnotation(annotation, location string) string {
+	return annotation + "." + location
+}
+
+// CredentialsSecret returns the name of the `Secret` with the credentials of the location, or an empty string if
+// there is none.
+func (l StorageLocation) CredentialsSecret() string {
+	switch {
+	case l.S3 != nil:
+		return l.S3.CredentialsSecret
+	case l.GCS != nil:
+		return l.GCS.CredentialsSecret
+	case l.Azure != nil:
+		return l.Azure.CredentialsSecret
+	case l.Swift != nil:
+		return l.Swift.CredentialsSecret
+	case l.IBMCOS != nil:
+		return l.IBMCOS.CredentialsSecret
+	}
+
+	return ""
+}
+
+// StorageCABundleFor returns the CA bundle of the storage endpoints of the managed `objectstorage` component, if any.
+func StorageCABundleFor(quay *QuayRegistry) *StorageCABundle {
+	if !ComponentIsManaged(quay.Spec.Components, "objectstorage") || quay.Spec.ObjectStorage == nil {
+		return nil
+	}
+
+	return quay.Spec.ObjectStorage.CABundle
+}
+
+// KeyOrDefault returns the key of the CA bundle in its `Secret` or `ConfigMap`.
+func (b StorageCABundle) KeyOrDefault() string {
+	if b.Key == "" {
+		return DefaultStorageCABundleKey
+	}
+
+	return b.Key
+}
+
+// ObjectBucketClaimNameFor returns the name of the `ObjectBucketClaim` of the managed `objectstorage` component, which
+// is also the name of the `Secret` and `ConfigMap` its provisioner creates.
+func ObjectBucketClaimNameFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-datastore"
+}
+
+// NeedsObjectBucketClaimFinalizer returns true if the registry must not be removed before its `ObjectBucketClaim`.
+func NeedsObjectBucketClaimFinalizer(quay *QuayRegistry) bool {
+	return ComponentIsManaged(quay.Spec.Components, "objectstorage") && ClaimsObjectBucket(quay)
+}
+
+// PersistentVolumeRetentionPolicyFor returns the retention policy of the volumes of the given database component, or
+// an empty policy if none is set.
+func PersistentVolumeRetentionPolicyFor(quay *QuayRegistry, component string) PersistentVolumeRetentionPolicy {
+	for _, retention := range quay.Spec.PersistentVolumeRetentionPolicy {
+		if retention.Component == component {
+			return retention.Policy
+		}
+	}
+
+	return ""
+}
+
+// HasFinalizer returns true if the given finalizer is set on the `QuayRegistry`.
+func HasFinalizer(quay *QuayRegistry, finalizer string) bool {
+	for _, existing := range quay.GetFinalizers() {
+		if existing == finalizer {
+			return true
+		}
+	}
+
+	return false
+}
+
+// S3WebIdentityRoleFor returns the IAM role the Quay pods assume with their `ServiceAccount`, or an empty string if
+// they do not.
+func S3WebIdentityRoleFor(quay *QuayRegistry) string {
+	if s3 := S3StorageFor(quay); s3 != nil && s3.CredentialsSecret == "" {
+		return s3.RoleARN
+	}
+
+	return ""
+}
+
+// MinIOCredentialsSecretFor returns the name of the `Secret` with the credentials generated for the managed `minio`
+// component.
+func MinIOCredentialsSecretFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-minio-credentials"
+}
+
+// ClairTLSSecretFor returns the name of the `Secret` in which the generated certificate of the managed Clair is stored.
+func ClairTLSSecretFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-clair-tls"
+}
+
+// DatabaseTLSSecretFor returns the name of the `Secret` in which the generated certificate of the given managed
+// database, `quay-postgres` or `clair-postgres`, is stored.
+func DatabaseTLSSecretFor(quay *QuayRegistry, database string) string {
+	return quay.GetName() + "-" + database + "-tls"
+}
+
+// PostgresTLSEnabled returns true if the managed databases are served over TLS.
+func PostgresTLSEnabled(quay *QuayRegistry) bool {
+	return quay.Spec.Postgres != nil && quay.Spec.Postgres.TLS
+}
+
+// UsesPostgresCluster returns true if the database of the managed `postgres` component is a `PostgresCluster`.
+func UsesPostgresCluster(quay *QuayRegistry) bool {
+	return quay.Spec.Postgres != nil && quay.Spec.Postgres.Provider == PostgresProviderPostgresCluster &&
+		ComponentIsManaged(quay.Spec.Components, "postgres")
+}
+
+// PostgresClusterNameFor returns the name of the `PostgresCluster` of the managed `postgres` component.
+func PostgresClusterNameFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-database"
+}
+
+// ClairTLSEnabled returns true if the managed Clair is served over HTTPS.
+func ClairTLSEnabled(quay *QuayRegistry) bool {
+	return quay.Spec.Clair != nil && quay.Spec.Clair.TLS && ComponentIsManaged(quay.Spec.Components, "clair")
+}
+
+// MigratedStorageCredentialsSecretFor returns the name of the `Secret` which the credentials of a bucket configured
+// with the `storage-*` annotations are moved into, when they are migrated to `spec.objectStorage`.
+func MigratedStorageCredentialsSecretFor(quay *QuayRegistry) string {
+	return quay.GetName() + "-quay-storage-credentials"
+}
+
+// ObjectStorageCredentialsSecretFor returns the name of the `Secret` with the credentials of the existing bucket in
+// `spec.objectStorage`, or an empty string if there is none.
+func ObjectStorageCredentialsSecretFor(quay *QuayRegistry) string {
+	if s3 := S3StorageFor(quay); s3 != nil {
+		return s3.CredentialsSecret
+	}
+	if gcs := GCSStorageFor(quay); gcs != nil {
+		return gcs.CredentialsSecret
+	}
+	if azure := AzureStorageFor(quay); azure != nil {
+		return azure.CredentialsSecret
+	}
+	if swift := SwiftStorageFor(quay); swift != nil {
+		return swift.CredentialsSecret
+	}
+	if ibmcos := IBMCOSStorageFor(quay); ibmcos != nil {
+		return ibmcos.CredentialsSecret
+	}
+
+	return ""
+}
+
+// ReferencedSecrets returns the names of the `Secrets` in the same namespace which the `QuayRegistry` is rendered from.
+func ReferencedSecrets(quay *QuayRegistry) []string {
+	secrets := []string{}
+	if quay.Spec.ConfigBundleSecret != "" {
+		secrets = append(secrets, quay.Spec.ConfigBundleSecret)
+	}
+	if ComponentIsManaged(quay.Spec.Components, "minio") {
+		secrets = append(secrets, MinIOCredentialsSecretFor(quay))
+	}
+	if ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		if ClaimsObjectBucket(quay) {
+			secrets = append(secrets, ObjectBucketClaimNameFor(quay))
+		} else if credentialsSecret := ObjectStorageCredentialsSecretFor(quay); credentialsSecret != "" {
+			secrets = append(secrets, credentialsSecret)
+		}
+		for _, location := range StorageLocationsFor(quay) {
+			if credentialsSecret := location.CredentialsSecret(); credentialsSecret != "" {
+				secrets = append(secrets, credentialsSecret)
+			}
+		}
+	}
+	for _, webhook := range quay.Spec.Notifications {
+		if webhook.SigningSecret != "" {
+			secrets = append(secrets, webhook.SigningSecret)
+		}
+	}
+	if ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		if s3 := S3StorageFor(quay); s3 != nil && s3.CloudFront != nil && s3.CloudFront.SigningKeySecret != "" {
+			secrets = append(secrets, s3.CloudFront.SigningKeySecret)
+		}
+		if caBundle := StorageCABundleFor(quay); caBundle != nil && caBundle.Secret != "" {
+			secrets = append(secrets, caBundle.Secret)
+		}
+	}
+	if auth := quay.Spec.Authentication; auth != nil && auth.JWT != nil && auth.JWT.PublicKeySecret != "" {
+		secrets = append(secrets, auth.JWT.PublicKeySecret)
+	}
+	if quay.Spec.SecretKeysBackup != nil && quay.Spec.SecretKeysBackup.PassphraseSecret != "" {
+		secrets = append(secrets, quay.Spec.SecretKeysBackup.PassphraseSecret)
+	}
+	if triggers := quay.Spec.BuildTriggers; triggers != nil {
+		for _, provider := range []*BuildTriggerProvider{triggers.GitHub, triggers.GitLab, triggers.Bitbucket} {
+			if provider != nil && provider.CredentialsSecret != "" {
+				secrets = append(secrets, provider.CredentialsSecret)
+			}
+		}
+	}
+	if quay.Spec.ExternalRedis != nil {
+		secrets = append(secrets, quay.Spec.ExternalRedis.CredentialsSecret)
+	}
+
+	return secrets
+}
+
+// ReferencedConfigMaps returns the names of the `ConfigMaps` in the same namespace which the `QuayRegistry` is rendered from.
+func ReferencedConfigMaps(quay *QuayRegistry) []string {
+	configMaps := []string{}
+	if ComponentIsManaged(quay.Spec.Components, "objectstorage") && ClaimsObjectBucket(quay) {
+		configMaps = append(configMaps, ObjectBucketClaimNameFor(quay))
+	}
+	if caBundle := StorageCABundleFor(quay); caBundle != nil && caBundle.ConfigMap != "" {
+		configMaps = append(configMaps, caBundle.ConfigMap)
+	}
+
+	return configMaps
+}
+
+// PausedComponents returns the valid component kinds listed in the `paused-components` annotation.
+func PausedComponents(quay *QuayRegistry) []string {
+	paused := []string{}
+	for _, kind := range strings.Split(quay.GetAnnotations()[PausedComponentsAnnotation], ",") {
+		kind = strings.TrimSpace(kind)
+		for _, component := range allComponents {
+			if kind == component {
+				paused = append(paused, kind)
+			}
+		}
+	}
+
+	return paused
+}
+
+// ComponentIsPaused returns true if reconciliation of the given component kind has been paused.
+func ComponentIsPaused(quay *QuayRegistry, kind string) bool {
+	for _, paused := range PausedComponents(quay) {
+		if paused == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ComponentIsProtected returns true if changes to the given component kind must be approved before they are applied.
+func ComponentIsProtected(quay *QuayRegistry, kind string) bool {
+	for _, protected := range quay.Spec.ProtectedComponents {
+		if protected == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultCertificateExpiryThreshold is how long before expiry certificates are reported as expiring by default.
+const DefaultCertificateExpiryThreshold = 30 * 24 * time.Hour
+
+// CertificateExpiryThresholdFor returns how long before expiry the certificates of the registry are reported as
+// expiring.
+func CertificateExpiryThresholdFor(quay *QuayRegistry) time.Duration {
+	if threshold := quay.Spec.CertificateExpiryThreshold; threshold != nil && threshold.Duration > 0 {
+		return threshold.Duration
+	}
+
+	return DefaultCertificateExpiryThreshold
+}
+
+// HealthOf returns the health of the registry, summarizing its conditions.
+func HealthOf(quay *QuayRegistry) RegistryHealth {
+	conditions := quay.Status.Conditions
+	if degraded := GetCondition(conditions, ConditionTypeDegraded); degraded != nil && degraded.Status == metav1.ConditionTrue {
+		return RegistryHealthDegraded
+	}
+	if violated := GetCondition(conditions, ConditionTypePolicyViolated); violated != nil && violated.Status == metav1.ConditionTrue {
+		return RegistryHealthDegraded
+	}
+	for _, service := range []ConditionType{ConditionTypeDatabaseHealthy, ConditionTypeRedisHealthy, ConditionTypeStorageHealthy, ConditionTypeAuthHealthy} {
+		if healthy := GetCondition(conditions, service); healthy != nil && healthy.Status == metav1.ConditionFalse {
+			return RegistryHealthDegraded
+		}
+	}
+
+	if quay.Spec.Suspend {
+		return RegistryHealthSuspended
+	}
+	if quay.Spec.DesiredVersion != "" && quay.Spec.DesiredVersion != quay.Status.CurrentVersion {
+		return RegistryHealthUpgrading
+	}
+	if available := GetCondition(conditions, ConditionTypeAvailable); available != nil && available.Status == metav1.ConditionTrue {
+		return RegistryHealthAvailable
+	}
+
+	return RegistryHealthProgressing
+}
+
+// StorageMigrationPhaseFor returns the current phase of the storage migration declared in the spec, or an empty
+// phase if there is none. A migration to a different target location starts over from the beginning.
+func StorageMigrationPhaseFor(quay *QuayRegistry) StorageMigrationPhase {
+	if quay.Spec.StorageMigration == nil {
+		return ""
+	}
+
+	status := quay.Status.StorageMigration
+	if status == nil || status.TargetLocation != quay.Spec.StorageMigration.TargetLocation {
+		return StorageMigrationPhaseReplicating
+	}
+
+	return status.Phase
+}
+
+// PostgresVersionFor returns the major version of the managed database declared in the spec.
+func PostgresVersionFor(quay *QuayRegistry) PostgresVersion {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Version == "" {
+		return DefaultPostgresVersion
+	}
+
+	return quay.Spec.Postgres.Version
+}
+
+// DesiredPostgresImageFor returns the image of the major version of the managed database declared in the spec.
+func DesiredPostgresImageFor(quay *QuayRegistry) string {
+	return postgresImages[PostgresVersionFor(quay)]
+}
+
+// PostgresVersionOf returns the major version of the given Postgres image from its tag, or an empty version if the
+// tag does not start with one, such as `latest`.
+func PostgresVersionOf(image string) PostgresVersion {
+	image = strings.SplitN(image, "@", 2)[0]
+	colon := strings.LastIndex(image, ":")
+	if colon < strings.LastIndex(image, "/") {
+		return ""
+	}
+
+	major := strings.SplitN(image[colon+1:], ".", 2)[0]
+	if _, err := strconv.Atoi(major); err != nil {
+		return ""
+	}
+
+	return PostgresVersion(major)
+}
+
+// PostgresVersionDeclared returns true if `spec.postgres.version` is set, rather than defaulted.
+func PostgresVersionDeclared(quay *QuayRegistry) bool {
+	return quay.Spec.Postgres != nil && quay.Spec.Postgres.Version != ""
+}
+
+// PostgresUpdatePhaseFor returns the current phase of updating the managed database to the desired image. A
+// database without a recorded image is new, and so is deployed with the desired image directly. A `PostgresCluster`
+// is updated by the Crunchy Postgres Operator.
+func PostgresUpdatePhaseFor(quay *QuayRegistry) PostgresUpdatePhase {
+	desired := DesiredPostgresImageFor(quay)
+	status := quay.Status.Postgres
+	if status == nil || status.Image == desired || UsesPostgresCluster(quay) {
+		return PostgresUpdatePhaseCurrent
+	}
+	if status.Phase == PostgresUpdatePhaseFailed && status.TargetImage == desired {
+		return PostgresUpdatePhaseFailed
+	}
+	if status.Phase == PostgresUpdatePhaseBlocked && status.TargetImage == desired && !PostgresVersionDeclared(quay) {
+		return PostgresUpdatePhaseBlocked
+	}
+	if (status.Phase == PostgresUpdatePhaseRestoring || status.Phase == PostgresUpdatePhaseRestoreFailed) && status.TargetImage == desired {
+		return status.Phase
+	}
+
+	return PostgresUpdatePhaseBackingUp
+}
+
+// PostgresMajorUpgradePending returns true if the managed database runs an older major version than the spec
+// declares, in which case Quay is stopped until the database has been restored with the new version.
+func PostgresMajorUpgradePending(quay *QuayRegistry) bool {
+	status := quay.Status.Postgres
+	if status == nil || status.Version == "" || UsesPostgresCluster(quay) || !ComponentIsManaged(quay.Spec.Components, "postgres") {
+		return false
+	}
+
+	return status.Version != PostgresVersionFor(quay)
+}
+
+// PostgresImageFor returns the image the managed database should run, which remains the recorded one until it has
+// been backed up. The backup of a major version upgrade is restored with the new image.
+func PostgresImageFor(quay *QuayRegistry) string {
+	switch PostgresUpdatePhaseFor(quay) {
+	case PostgresUpdatePhaseCurrent, PostgresUpdatePhaseRestoring, PostgresUpdatePhaseRestoreFailed:
+		return DesiredPostgresImageFor(quay)
+	}
+
+	return quay.Status.Postgres.Image
+}
+
+// PostgresDataDirectoryFor returns the directory of the volume of the managed database its data should be in. The
+// backup of a major version upgrade is restored into a directory named after the new version.
+func PostgresDataDirectoryFor(quay *QuayRegistry) string {
+	switch PostgresUpdatePhaseFor(quay) {
+	case PostgresUpdatePhaseRestoring, PostgresUpdatePhaseRestoreFailed:
+		return "pgdata-" + string(PostgresVersionFor(quay))
+	}
+	if status := quay.Status.Postgres; status != nil && status.DataDirectory != "" {
+		return status.DataDirectory
+	}
+
+	return "pgdata"
+}
+
+// GetCondition returns the condition of the given type, or nil if it is not present.
+func GetCondition(conditions []Condition, conditionType ConditionType) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type and returns `transitioned` if its status changed.
+// The `lastTransitionTime` is only updated when the status changes.
+func SetCondition(conditions []Condition, condition Condition) ([]Condition, bool) {
+	updated := make([]Condition, len(conditions))
+	copy(updated, conditions)
+
+	existing := GetCondition(updated, condition.Type)
+	if existing == nil {
+		condition.LastTransitionTime = metav1.Now()
+
+		return append(updated, condition), true
+	}
+
+	transitioned := existing.Status != condition.Status
+	if transitioned {
+		condition.LastTransitionTime = metav1.Now()
+	} else {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = condition
+
+	return updated, transitioned
+}
+
 func supportsRoutes(quay *QuayRegistry) bool {
 	annotations := quay.GetAnnotations()
 	if annotations == nil {
@@ -265,6 +2323,12 @@ func supportsObjectBucketClaims(quay *QuayRegistry) bool {
 	return ok
 }
 
+func supportsPostgresClusters(quay *QuayRegistry) bool {
+	_, ok := quay.GetAnnotations()[SupportsPostgresClustersAnnotation]
+
+	return ok
+}
+
 func init() {
 	SchemeBuilder.Register(&QuayRegistry{}, &QuayRegistryList{})
 }
@@ -14,6 +14,65 @@ var ensureDefaultComponentsTests = []struct {
 	expected    []Component
 	expectedErr error
 }{
+	{
+		"MirrorWorkersUnmanagedByDefault",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Mode: RegistryModeMirrorWorkers,
+				Components: []Component{
+					{Kind: "redis", Managed: true},
+				},
+			},
+		},
+		[]Component{
+			{Kind: "redis", Managed: true},
+			{Kind: "postgres", Managed: false},
+			{Kind: "clair", Managed: false},
+			{Kind: "horizontalpodautoscaler", Managed: false},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"InternalRouteUnmanagedByDefault",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SupportsRoutesAnnotation: "true"},
+			},
+			Spec: QuayRegistrySpec{
+				Exposure: ExposureInternal,
+			},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "route", Managed: false},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: true},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"InternalRouteManaged",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SupportsRoutesAnnotation: "true"},
+			},
+			Spec: QuayRegistrySpec{
+				Exposure: ExposureInternal,
+				Components: []Component{
+					{Kind: "route", Managed: true},
+				},
+			},
+		},
+		nil,
+		errors.New("cannot use `route` component with `exposure: Internal`"),
+	},
 	{
 		"AllComponentsProvided",
 		QuayRegistry{
@@ -36,6 +95,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "clair", Managed: true},
 			{Kind: "objectstorage", Managed: true},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
 		},
 		nil,
 	},
@@ -61,6 +123,27 @@ var ensureDefaultComponentsTests = []struct {
 		},
 		errors.New("cannot use `objectstorage` component when `ObjectBucketClaims` API not available"),
 	},
+	{
+		"S3StorageWithoutObjectBucketClaims",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ObjectStorage: &ObjectStorage{
+					S3: &S3Storage{Bucket: "quay", Region: "us-east-1"},
+				},
+			},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "objectstorage", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
 	{
 		"AllComponentsProvidedWithRoutes",
 		QuayRegistry{
@@ -88,6 +171,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "objectstorage", Managed: true},
 			{Kind: "route", Managed: true},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
 		},
 		nil,
 	},
@@ -105,6 +191,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "clair", Managed: true},
 			{Kind: "objectstorage", Managed: true},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
 		},
 		nil,
 	},
@@ -127,6 +216,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "objectstorage", Managed: true},
 			{Kind: "route", Managed: true},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
 		},
 		nil,
 	},
@@ -146,6 +238,9 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "clair", Managed: true},
 			{Kind: "objectstorage", Managed: false},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
 		},
 		nil,
 	},
@@ -173,8 +268,179 @@ var ensureDefaultComponentsTests = []struct {
 			{Kind: "objectstorage", Managed: false},
 			{Kind: "route", Managed: false},
 			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"MinIOWithoutObjectBucketClaims",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: true},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"MinIOUnmanagedOnceDeployed",
+		QuayRegistry{
+			Spec:   QuayRegistrySpec{},
+			Status: QuayRegistryStatus{CurrentVersion: QuayVersionVader},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: false},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"ObjectStorageAndMinIO",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SupportsObjectStorageAnnotation: "true"},
+			},
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+					{Kind: "minio", Managed: true},
+				},
+			},
+		},
+		nil,
+		errors.New("cannot use both `objectstorage` and `minio` components"),
+	},
+	{
+		"LocalStorageManaged",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{SupportsObjectStorageAnnotation: "true"},
+			},
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "localstorage", Managed: true},
+				},
+			},
+		},
+		[]Component{
+			{Kind: "localstorage", Managed: true},
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: false},
+			{Kind: "objectstorage", Managed: false},
+			{Kind: "minio", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"LocalStorageAndAutoscaling",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Components: []Component{
+					{Kind: "localstorage", Managed: true},
+					{Kind: "horizontalpodautoscaler", Managed: true},
+				},
+			},
+		},
+		nil,
+		errors.New("cannot use both `horizontalpodautoscaler` and `localstorage` components"),
+	},
+	{
+		"ExternalDatabaseUnmanagedByDefault",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ExternalDatabase: &ExternalDatabase{CredentialsSecret: "db"},
+			},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: false},
+			{Kind: "redis", Managed: true},
+			{Kind: "clair", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: true},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"ExternalDatabaseManagedPostgres",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ExternalDatabase: &ExternalDatabase{CredentialsSecret: "db"},
+				Components: []Component{
+					{Kind: "postgres", Managed: true},
+				},
+			},
+		},
+		nil,
+		errors.New("cannot use managed `postgres` component with `spec.externalDatabase`"),
+	},
+	{
+		"ExternalRedisUnmanagedByDefault",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ExternalRedis: &ExternalRedis{CredentialsSecret: "redis"},
+			},
+		},
+		[]Component{
+			{Kind: "postgres", Managed: true},
+			{Kind: "redis", Managed: false},
+			{Kind: "clair", Managed: true},
+			{Kind: "horizontalpodautoscaler", Managed: true},
+			{Kind: "minio", Managed: true},
+			{Kind: "localstorage", Managed: false},
+			{Kind: "pgbouncer", Managed: false},
+		},
+		nil,
+	},
+	{
+		"ExternalRedisManagedRedis",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ExternalRedis: &ExternalRedis{CredentialsSecret: "redis"},
+				Components: []Component{
+					{Kind: "redis", Managed: true},
+				},
+			},
+		},
+		nil,
+		errors.New("cannot use managed `redis` component with `spec.externalRedis`"),
+	},
+	{
+		"UnknownProtectedComponent",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				ProtectedComponents: []string{"postgres", "database"},
+			},
+		},
+		nil,
+		errors.New("unknown component `database` in `spec.protectedComponents`"),
+	},
+	{
+		"PostgresClusterAPINotAvailable",
+		QuayRegistry{
+			Spec: QuayRegistrySpec{
+				Postgres: &ManagedPostgres{Provider: PostgresProviderPostgresCluster},
+			},
 		},
 		nil,
+		errors.New("cannot use `spec.postgres.provider: PostgresCluster` when `PostgresCluster` API not available"),
 	},
 }
 
@@ -240,6 +506,42 @@ func TestEnsureDesiredVersion(t *testing.T) {
 	}
 }
 
+// upgradePathRanks is a ranking of hypothetical versions, so that upgrade paths longer than those of the versions the
+// Operator currently manages can be tested.
+var upgradePathRanks = map[QuayVersion]int{
+	QuayVersionDev: 0,
+	"first":        1,
+	"second":       2,
+	"third":        3,
+	"fourth":       4,
+}
+
+var upgradePathForTests = []struct {
+	name     string
+	current  QuayVersion
+	desired  QuayVersion
+	expected []QuayVersion
+}{
+	{"NewRegistry", "", "fourth", nil},
+	{"SameVersion", "second", "second", []QuayVersion{}},
+	{"NextVersion", "first", "second", []QuayVersion{}},
+	{"SkipsOneVersion", "first", "third", []QuayVersion{"second"}},
+	{"SkipsTwoVersions", "first", "fourth", []QuayVersion{"second", "third"}},
+	{"Downgrade", "fourth", "first", []QuayVersion{}},
+	{"FromDev", QuayVersionDev, "fourth", nil},
+	{"ToDev", "first", QuayVersionDev, nil},
+}
+
+func TestUpgradePathFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range upgradePathForTests {
+		assert.Equal(test.expected, upgradePathFor(upgradePathRanks, test.current, test.desired), test.name)
+	}
+
+	assert.Empty(UpgradePathFor(QuayVersionQuiGon, QuayVersionVader), "every managed version can be upgraded to from the one before it")
+}
+
 func TestEnsureDefaultComponents(t *testing.T) {
 	assert := assert.New(t)
 
@@ -371,6 +673,54 @@ var ensureRegistryEndpointTests = []struct {
 		"",
 		true,
 	},
+	{
+		"DetectedClusterHostname",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test",
+				Namespace:   "ns-1",
+				Annotations: map[string]string{SupportsRoutesAnnotation: "true"},
+			},
+			Status: QuayRegistryStatus{
+				ClusterHostname: "apps.detected.example.com",
+			},
+		},
+		"test-quay-ns-1.apps.detected.example.com",
+		false,
+	},
+	{
+		"Internal",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "ns-1",
+				Annotations: map[string]string{
+					SupportsRoutesAnnotation:  "true",
+					ClusterHostnameAnnotation: "apps.example.com",
+				},
+			},
+			Spec: QuayRegistrySpec{
+				Exposure: ExposureInternal,
+			},
+		},
+		"test-quay-app.ns-1.svc",
+		false,
+	},
+	{
+		"InternalCustomPort",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test",
+				Namespace: "ns-1",
+			},
+			Spec: QuayRegistrySpec{
+				Exposure: ExposureInternal,
+				Endpoint: &EndpointSettings{Port: 8443},
+			},
+		},
+		"test-quay-app.ns-1.svc:8443",
+		false,
+	},
 }
 
 func TestEnsureRegistryEndpoint(t *testing.T) {
@@ -383,3 +733,809 @@ func TestEnsureRegistryEndpoint(t *testing.T) {
 		assert.Equal(test.expected, quay.Status.RegistryEndpoint, test.name)
 	}
 }
+
+var clusterHostnameForTests = []struct {
+	name        string
+	annotations map[string]string
+	detected    string
+	expected    string
+}{
+	{"Unknown", nil, "", ""},
+	{"Detected", nil, "apps.detected.example.com", "apps.detected.example.com"},
+	{"Override", map[string]string{ClusterHostnameAnnotation: "apps.example.com"}, "apps.detected.example.com", "apps.example.com"},
+	{"OverrideOnly", map[string]string{ClusterHostnameAnnotation: "apps.example.com"}, "", "apps.example.com"},
+}
+
+var endpointHostForTests = []struct {
+	name              string
+	endpoint          *EndpointSettings
+	hostname          string
+	expectedHTTPSPort int32
+	expectedHTTPPort  int32
+	expected          string
+}{
+	{
+		"Default",
+		nil,
+		"quay.example.com",
+		443,
+		80,
+		"quay.example.com",
+	},
+	{
+		"QuayCustomPort",
+		&EndpointSettings{Port: 8443},
+		"quay.example.com",
+		8443,
+		80,
+		"quay.example.com:8443",
+	},
+	{
+		"ExternalDefaultPort",
+		&EndpointSettings{TLSTermination: TLSTerminationExternal},
+		"quay.example.com",
+		0,
+		80,
+		"quay.example.com",
+	},
+	{
+		"ExternalCustomPort",
+		&EndpointSettings{Port: 8080, TLSTermination: TLSTerminationExternal},
+		"quay.example.com",
+		0,
+		8080,
+		"quay.example.com",
+	},
+	{
+		"NoneCustomPort",
+		&EndpointSettings{Port: 8080, TLSTermination: TLSTerminationNone},
+		"quay.example.com",
+		0,
+		8080,
+		"quay.example.com:8080",
+	},
+	{
+		"HostnameWithPort",
+		&EndpointSettings{Port: 8443},
+		"quay.example.com:8443",
+		8443,
+		80,
+		"quay.example.com:8443",
+	},
+}
+
+func TestEndpointHostFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range endpointHostForTests {
+		quay := &QuayRegistry{Spec: QuayRegistrySpec{Endpoint: test.endpoint}}
+
+		httpsPort, httpPort := ServicePortsFor(quay)
+		assert.Equal(test.expectedHTTPSPort, httpsPort, test.name)
+		assert.Equal(test.expectedHTTPPort, httpPort, test.name)
+		assert.Equal(test.expected, EndpointHostFor(quay, test.hostname), test.name)
+	}
+}
+
+func TestClusterHostnameFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range clusterHostnameForTests {
+		quay := &QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations},
+			Status:     QuayRegistryStatus{ClusterHostname: test.detected},
+		}
+
+		assert.Equal(test.expected, ClusterHostnameFor(quay), test.name)
+	}
+}
+
+var setConditionTests = []struct {
+	name                 string
+	conditions           []Condition
+	condition            Condition
+	expectedTransitioned bool
+	expectedLength       int
+}{
+	{
+		"NewCondition",
+		[]Condition{},
+		Condition{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue},
+		true,
+		1,
+	},
+	{
+		"SameStatus",
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue, LastTransitionTime: metav1.Unix(0, 0)},
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionFalse},
+		},
+		Condition{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue, Reason: ConditionReasonUpgradeComplete},
+		false,
+		2,
+	},
+	{
+		"ChangedStatus",
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue, LastTransitionTime: metav1.Unix(0, 0)},
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionFalse},
+		},
+		Condition{Type: ConditionTypeDegraded, Status: metav1.ConditionTrue},
+		true,
+		2,
+	},
+}
+
+func TestSetCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range setConditionTests {
+		original := GetCondition(test.conditions, test.condition.Type)
+		conditions, transitioned := SetCondition(test.conditions, test.condition)
+
+		assert.Equal(test.expectedTransitioned, transitioned, test.name)
+		assert.Len(conditions, test.expectedLength, test.name)
+
+		updated := GetCondition(conditions, test.condition.Type)
+		assert.NotNil(updated, test.name)
+		assert.Equal(test.condition.Status, updated.Status, test.name)
+		assert.Equal(test.condition.Reason, updated.Reason, test.name)
+		if !transitioned {
+			assert.Equal(original.LastTransitionTime, updated.LastTransitionTime, test.name)
+		} else {
+			assert.False(updated.LastTransitionTime.IsZero(), test.name)
+		}
+	}
+}
+
+var referencedSecretsTests = []struct {
+	name     string
+	quay     QuayRegistry
+	expected []string
+}{
+	{
+		"NoConfigBundle",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		},
+		[]string{},
+	},
+	{
+		"ConfigBundle",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: false},
+				},
+			},
+		},
+		[]string{"test-config-bundle"},
+	},
+	{
+		"ManagedObjectStorageAndWebhooks",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				Notifications: []NotificationWebhook{
+					{URL: "https://example.com", SigningSecret: "webhook-key"},
+					{URL: "https://example.com/unsigned"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "test-quay-datastore", "webhook-key"},
+	},
+	{
+		"JWTPublicKey",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Authentication: &Authentication{
+					Type: AuthenticationTypeJWT,
+					JWT:  &JWTAuthentication{PublicKeySecret: "broker-public-key"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "broker-public-key"},
+	},
+	{
+		"SecretKeysBackupPassphrase",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				SecretKeysBackup:   &SecretKeysBackup{PassphraseSecret: "secret-keys-passphrase"},
+			},
+		},
+		[]string{"test-config-bundle", "secret-keys-passphrase"},
+	},
+	{
+		"BuildTriggerCredentials",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				BuildTriggers: &BuildTriggers{
+					GitHub:    &BuildTriggerProvider{CredentialsSecret: "github-oauth"},
+					Bitbucket: &BuildTriggerProvider{CredentialsSecret: "bitbucket-oauth"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "github-oauth", "bitbucket-oauth"},
+	},
+	{
+		"S3StorageCredentials",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				ObjectStorage: &ObjectStorage{
+					S3: &S3Storage{Bucket: "quay", Region: "us-east-1", CredentialsSecret: "s3-credentials"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "s3-credentials"},
+	},
+	{
+		"GCSStorageCredentials",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				ObjectStorage: &ObjectStorage{
+					GCS: &GCSStorage{Bucket: "quay", CredentialsSecret: "gcs-hmac-key"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "gcs-hmac-key"},
+	},
+	{
+		"StorageLocationCredentials",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				ObjectStorage: &ObjectStorage{
+					S3: &S3Storage{Bucket: "quay", Region: "us-east-1", CredentialsSecret: "s3-credentials"},
+					Locations: []StorageLocation{
+						{Name: "eu_west", GCS: &GCSStorage{Bucket: "quay-eu", CredentialsSecret: "gcs-hmac-key"}},
+						{Name: "ap_south", S3: &S3Storage{Bucket: "quay-ap", Region: "ap-south-1"}},
+					},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "s3-credentials", "gcs-hmac-key"},
+	},
+	{
+		"StorageCABundle",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				ObjectStorage: &ObjectStorage{
+					S3:       &S3Storage{Bucket: "quay", Endpoint: "rgw.example.com", CredentialsSecret: "s3-credentials"},
+					CABundle: &StorageCABundle{Secret: "storage-ca"},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "s3-credentials", "storage-ca"},
+	},
+	{
+		"CloudFrontSigningKey",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: true},
+				},
+				ObjectStorage: &ObjectStorage{
+					S3: &S3Storage{
+						Bucket:            "quay",
+						Region:            "us-east-1",
+						CredentialsSecret: "s3-credentials",
+						CloudFront:        &CloudFrontDistribution{Domain: "d111111abcdef8.cloudfront.net", KeyID: "K2JCJMDEHXQW5F", SigningKeySecret: "cloudfront-signing-key"},
+					},
+				},
+			},
+		},
+		[]string{"test-config-bundle", "s3-credentials", "cloudfront-signing-key"},
+	},
+	{
+		"ExternalRedis",
+		QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: QuayRegistrySpec{
+				ConfigBundleSecret: "test-config-bundle",
+				Components: []Component{
+					{Kind: "objectstorage", Managed: false},
+					{Kind: "redis", Managed: false},
+				},
+				ExternalRedis: &ExternalRedis{CredentialsSecret: "redis-credentials"},
+			},
+		},
+		[]string{"test-config-bundle", "redis-credentials"},
+	},
+}
+
+func TestReferencedSecrets(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range referencedSecretsTests {
+		assert.Equal(test.expected, ReferencedSecrets(&test.quay), test.name)
+	}
+}
+
+var storageLocationOrderTests = []struct {
+	name                     string
+	storage                  *ObjectStorage
+	expectedPreference       []string
+	expectedDefaultLocations []string
+}{
+	{
+		"SingleLocation",
+		&ObjectStorage{S3: &S3Storage{Bucket: "quay", Region: "us-east-1"}},
+		[]string{"local_us"},
+		[]string{"local_us"},
+	},
+	{
+		"DeclaredOrder",
+		&ObjectStorage{
+			S3:        &S3Storage{Bucket: "quay", Region: "us-east-1"},
+			Locations: []StorageLocation{{Name: "eu_west"}, {Name: "ap_south"}},
+		},
+		[]string{"local_us", "eu_west", "ap_south"},
+		[]string{"local_us", "eu_west", "ap_south"},
+	},
+	{
+		"ExplicitOrder",
+		&ObjectStorage{
+			S3:               &S3Storage{Bucket: "quay", Region: "us-east-1"},
+			Locations:        []StorageLocation{{Name: "eu_west"}, {Name: "ap_south"}},
+			Preference:       []string{"eu_west", "local_us", "ap_south"},
+			DefaultLocations: []string{"eu_west"},
+		},
+		[]string{"eu_west", "local_us", "ap_south"},
+		[]string{"eu_west"},
+	},
+	{
+		"DegradedLocation",
+		&ObjectStorage{
+			S3:                &S3Storage{Bucket: "quay", Region: "us-east-1"},
+			Locations:         []StorageLocation{{Name: "eu_west"}, {Name: "ap_south"}},
+			DegradedLocations: []string{"local_us"},
+		},
+		[]string{"eu_west", "ap_south", "local_us"},
+		[]string{"eu_west", "ap_south"},
+	},
+	{
+		"DegradedExplicitOrder",
+		&ObjectStorage{
+			S3:                &S3Storage{Bucket: "quay", Region: "us-east-1"},
+			Locations:         []StorageLocation{{Name: "eu_west"}, {Name: "ap_south"}},
+			Preference:        []string{"eu_west", "local_us", "ap_south"},
+			DefaultLocations:  []string{"eu_west", "local_us"},
+			DegradedLocations: []string{"eu_west"},
+		},
+		[]string{"local_us", "ap_south", "eu_west"},
+		[]string{"local_us"},
+	},
+}
+
+func TestStorageLocationOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range storageLocationOrderTests {
+		quay := &QuayRegistry{Spec: QuayRegistrySpec{ObjectStorage: test.storage}}
+
+		assert.Equal(test.expectedPreference, StoragePreferenceFor(quay), test.name)
+		assert.Equal(test.expectedDefaultLocations, StorageDefaultLocationsFor(quay), test.name)
+	}
+}
+
+var pausedComponentsTests = []struct {
+	name        string
+	annotations map[string]string
+	expected    []string
+}{
+	{
+		"NoAnnotation",
+		nil,
+		[]string{},
+	},
+	{
+		"SingleComponent",
+		map[string]string{PausedComponentsAnnotation: "route"},
+		[]string{"route"},
+	},
+	{
+		"MultipleComponentsWithWhitespace",
+		map[string]string{PausedComponentsAnnotation: "clair, redis"},
+		[]string{"clair", "redis"},
+	},
+	{
+		"UnknownComponentIgnored",
+		map[string]string{PausedComponentsAnnotation: "clair,not-a-component"},
+		[]string{"clair"},
+	},
+}
+
+func TestPausedComponents(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range pausedComponentsTests {
+		quay := &QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: test.annotations}}
+
+		assert.Equal(test.expected, PausedComponents(quay), test.name)
+		for _, kind := range allComponents {
+			assert.Equal(contains(test.expected, kind), ComponentIsPaused(quay, kind), test.name+"/"+kind)
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+var needsObjectBucketClaimFinalizerTests = []struct {
+	name     string
+	spec     QuayRegistrySpec
+	expected bool
+}{
+	{
+		"ManagedObjectBucketClaim",
+		QuayRegistrySpec{Components: []Component{{Kind: "objectstorage", Managed: true}}},
+		true,
+	},
+	{
+		"UnmanagedObjectStorage",
+		QuayRegistrySpec{Components: []Component{{Kind: "objectstorage", Managed: false}}},
+		false,
+	},
+	{
+		"ExistingBucket",
+		QuayRegistrySpec{
+			Components:    []Component{{Kind: "objectstorage", Managed: true}},
+			ObjectStorage: &ObjectStorage{S3: &S3Storage{Bucket: "quay", Region: "us-east-1"}},
+		},
+		false,
+	},
+	{
+		"ManagedMinIO",
+		QuayRegistrySpec{Components: []Component{{Kind: "objectstorage", Managed: false}, {Kind: "minio", Managed: true}}},
+		false,
+	},
+}
+
+func TestNeedsObjectBucketClaimFinalizer(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range needsObjectBucketClaimFinalizerTests {
+		quay := &QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: test.spec}
+
+		assert.Equal(test.expected, NeedsObjectBucketClaimFinalizer(quay), test.name)
+	}
+}
+
+func TestHasFinalizer(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Finalizers: []string{"example.com/other"}}}
+	assert.False(HasFinalizer(quay, ObjectBucketClaimFinalizer))
+
+	quay.Finalizers = append(quay.Finalizers, ObjectBucketClaimFinalizer)
+	assert.True(HasFinalizer(quay, ObjectBucketClaimFinalizer))
+}
+
+var storageMigrationPhaseForTests = []struct {
+	name      string
+	migration *StorageMigration
+	status    *StorageMigrationStatus
+	expected  StorageMigrationPhase
+}{
+	{
+		"NoMigration",
+		nil,
+		nil,
+		"",
+	},
+	{
+		"NotStarted",
+		&StorageMigration{TargetLocation: "s3_us"},
+		nil,
+		StorageMigrationPhaseReplicating,
+	},
+	{
+		"InProgress",
+		&StorageMigration{TargetLocation: "s3_us"},
+		&StorageMigrationStatus{TargetLocation: "s3_us", Phase: StorageMigrationPhaseVerifying},
+		StorageMigrationPhaseVerifying,
+	},
+	{
+		"NewTargetLocation",
+		&StorageMigration{TargetLocation: "gcs_us"},
+		&StorageMigrationStatus{TargetLocation: "s3_us", Phase: StorageMigrationPhaseComplete},
+		StorageMigrationPhaseReplicating,
+	},
+}
+
+func TestStorageMigrationPhaseFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range storageMigrationPhaseForTests {
+		quay := &QuayRegistry{
+			Spec:   QuayRegistrySpec{StorageMigration: test.migration},
+			Status: QuayRegistryStatus{StorageMigration: test.status},
+		}
+
+		assert.Equal(test.expected, StorageMigrationPhaseFor(quay), test.name)
+	}
+}
+
+var postgresVersionOfTests = []struct {
+	image    string
+	expected PostgresVersion
+}{
+	{"postgres:13.13", PostgresVersion13},
+	{"postgres:10", PostgresVersion10},
+	{"registry.example.com:5000/postgres:12.17-alpine", PostgresVersion12},
+	{"postgres:13.13@sha256:4b2b1dc7a8ab6b3c4e0bd3bd16c0c2f4e2ee0d0e3f1b5a7c2d6e8f9a0b1c2d3e", PostgresVersion13},
+	{"postgres:latest", ""},
+	{"postgres", ""},
+	{"registry.example.com:5000/postgres", ""},
+}
+
+func TestPostgresVersionOf(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range postgresVersionOfTests {
+		assert.Equal(test.expected, PostgresVersionOf(test.image), test.image)
+	}
+}
+
+var postgresUpdatePhaseForTests = []struct {
+	name          string
+	postgres      *ManagedPostgres
+	status        *PostgresStatus
+	expected      PostgresUpdatePhase
+	expectedImage string
+}{
+	{
+		"NewDatabase",
+		nil,
+		nil,
+		PostgresUpdatePhaseCurrent,
+		"postgres:13.13",
+	},
+	{
+		"NewDatabaseVersion",
+		&ManagedPostgres{Version: PostgresVersion10},
+		nil,
+		PostgresUpdatePhaseCurrent,
+		"postgres:10.23",
+	},
+	{
+		"Current",
+		nil,
+		&PostgresStatus{Version: PostgresVersion13, Image: "postgres:13.13", Phase: PostgresUpdatePhaseCurrent},
+		PostgresUpdatePhaseCurrent,
+		"postgres:13.13",
+	},
+	{
+		"MinorVersionUpdate",
+		nil,
+		&PostgresStatus{Version: PostgresVersion13, Image: "postgres:13.12", Phase: PostgresUpdatePhaseCurrent},
+		PostgresUpdatePhaseBackingUp,
+		"postgres:13.12",
+	},
+	{
+		"Failed",
+		nil,
+		&PostgresStatus{Version: PostgresVersion13, Image: "postgres:13.12", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseFailed},
+		PostgresUpdatePhaseFailed,
+		"postgres:13.12",
+	},
+	{
+		"FailedForPreviousTarget",
+		nil,
+		&PostgresStatus{Version: PostgresVersion13, Image: "postgres:13.11", TargetImage: "postgres:13.12", Phase: PostgresUpdatePhaseFailed},
+		PostgresUpdatePhaseBackingUp,
+		"postgres:13.11",
+	},
+	{
+		"UnknownVersion",
+		nil,
+		&PostgresStatus{Image: "postgres:latest", Phase: PostgresUpdatePhaseCurrent},
+		PostgresUpdatePhaseBackingUp,
+		"postgres:latest",
+	},
+	{
+		"UnknownVersionBlocked",
+		nil,
+		&PostgresStatus{Image: "postgres:latest", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseBlocked},
+		PostgresUpdatePhaseBlocked,
+		"postgres:latest",
+	},
+	{
+		"UnknownVersionDeclared",
+		&ManagedPostgres{Version: PostgresVersion13},
+		&PostgresStatus{Image: "postgres:latest", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseBlocked},
+		PostgresUpdatePhaseBackingUp,
+		"postgres:latest",
+	},
+	{
+		"Restoring",
+		nil,
+		&PostgresStatus{Version: PostgresVersion12, Image: "postgres:12.17", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseRestoring},
+		PostgresUpdatePhaseRestoring,
+		"postgres:13.13",
+	},
+	{
+		"RestoreFailed",
+		nil,
+		&PostgresStatus{Version: PostgresVersion12, Image: "postgres:12.17", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseRestoreFailed},
+		PostgresUpdatePhaseRestoreFailed,
+		"postgres:13.13",
+	},
+	{
+		"RestoringPreviousTarget",
+		&ManagedPostgres{Version: PostgresVersion12},
+		&PostgresStatus{Version: PostgresVersion10, Image: "postgres:10.23", TargetImage: "postgres:13.13", Phase: PostgresUpdatePhaseRestoring},
+		PostgresUpdatePhaseBackingUp,
+		"postgres:10.23",
+	},
+}
+
+func TestPostgresUpdatePhaseFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range postgresUpdatePhaseForTests {
+		quay := &QuayRegistry{
+			Spec:   QuayRegistrySpec{Postgres: test.postgres},
+			Status: QuayRegistryStatus{Postgres: test.status},
+		}
+
+		assert.Equal(test.expected, PostgresUpdatePhaseFor(quay), test.name)
+		assert.Equal(test.expectedImage, PostgresImageFor(quay), test.name)
+	}
+}
+
+var healthOfTests = []struct {
+	name           string
+	desiredVersion QuayVersion
+	currentVersion QuayVersion
+	conditions     []Condition
+	suspend        bool
+	expected       RegistryHealth
+}{
+	{
+		"NoConditions",
+		QuayVersionVader,
+		"",
+		[]Condition{},
+		false,
+		RegistryHealthUpgrading,
+	},
+	{
+		"NotYetAvailable",
+		"",
+		"",
+		[]Condition{},
+		false,
+		RegistryHealthProgressing,
+	},
+	{
+		"Available",
+		QuayVersionVader,
+		QuayVersionVader,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue},
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionFalse},
+			{Type: ConditionTypeDatabaseHealthy, Status: metav1.ConditionTrue},
+			{Type: ConditionTypeStorageHealthy, Status: metav1.ConditionUnknown},
+		},
+		false,
+		RegistryHealthAvailable,
+	},
+	{
+		"Upgrading",
+		QuayVersionVader,
+		QuayVersionQuiGon,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue},
+		},
+		false,
+		RegistryHealthUpgrading,
+	},
+	{
+		"Degraded",
+		QuayVersionVader,
+		QuayVersionVader,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionFalse},
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionTrue},
+		},
+		false,
+		RegistryHealthDegraded,
+	},
+	{
+		"PolicyViolated",
+		QuayVersionVader,
+		QuayVersionVader,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue},
+			{Type: ConditionTypePolicyViolated, Status: metav1.ConditionTrue},
+		},
+		false,
+		RegistryHealthDegraded,
+	},
+	{
+		"UnhealthyService",
+		QuayVersionVader,
+		QuayVersionQuiGon,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionTrue},
+			{Type: ConditionTypeRedisHealthy, Status: metav1.ConditionFalse},
+		},
+		false,
+		RegistryHealthDegraded,
+	},
+	{
+		"Suspended",
+		QuayVersionVader,
+		QuayVersionVader,
+		[]Condition{
+			{Type: ConditionTypeAvailable, Status: metav1.ConditionFalse, Reason: ConditionReasonRegistrySuspended},
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionFalse, Reason: ConditionReasonRegistrySuspended},
+		},
+		true,
+		RegistryHealthSuspended,
+	},
+	{
+		"SuspendedDegraded",
+		QuayVersionVader,
+		QuayVersionVader,
+		[]Condition{
+			{Type: ConditionTypeDegraded, Status: metav1.ConditionTrue},
+		},
+		true,
+		RegistryHealthDegraded,
+	},
+}
+
+func TestHealthOf(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range healthOfTests {
+		quay := &QuayRegistry{
+			Spec:   QuayRegistrySpec{DesiredVersion: test.desiredVersion, Suspend: test.suspend},
+			Status: QuayRegistryStatus{CurrentVersion: test.currentVersion, Conditions: test.conditions},
+		}
+
+		assert.Equal(test.expected, HealthOf(quay), test.name)
+	}
+}
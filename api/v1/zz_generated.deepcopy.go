@@ -21,9 +21,275 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppTokens) DeepCopyInto(out *AppTokens) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppTokens.
+func (in *AppTokens) DeepCopy() *AppTokens {
+	if in == nil {
+		return nil
+	}
+	out := new(AppTokens)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Authentication) DeepCopyInto(out *Authentication) {
+	*out = *in
+	if in.JWT != nil {
+		in, out := &in.JWT, &out.JWT
+		*out = new(JWTAuthentication)
+		**out = **in
+	}
+	if in.AppTokens != nil {
+		in, out := &in.AppTokens, &out.AppTokens
+		*out = new(AppTokens)
+		**out = **in
+	}
+	if in.Usernames != nil {
+		in, out := &in.Usernames, &out.Usernames
+		*out = new(FederatedUsernames)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RestrictedUsers != nil {
+		in, out := &in.RestrictedUsers, &out.RestrictedUsers
+		*out = new(RestrictedUsers)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Authentication.
+func (in *Authentication) DeepCopy() *Authentication {
+	if in == nil {
+		return nil
+	}
+	out := new(Authentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoPrunePolicy) DeepCopyInto(out *AutoPrunePolicy) {
+	*out = *in
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoPrunePolicy.
+func (in *AutoPrunePolicy) DeepCopy() *AutoPrunePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoPrunePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureStorage) DeepCopyInto(out *AzureStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureStorage.
+func (in *AzureStorage) DeepCopy() *AzureStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlobVerification) DeepCopyInto(out *BlobVerification) {
+	*out = *in
+	if in.SampleSize != nil {
+		in, out := &in.SampleSize, &out.SampleSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlobVerification.
+func (in *BlobVerification) DeepCopy() *BlobVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(BlobVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlobVerificationStatus) DeepCopyInto(out *BlobVerificationStatus) {
+	*out = *in
+	if in.LastCompletionTime != nil {
+		in, out := &in.LastCompletionTime, &out.LastCompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlobVerificationStatus.
+func (in *BlobVerificationStatus) DeepCopy() *BlobVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlobVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStatus.
+func (in *BuildStatus) DeepCopy() *BuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildTriggerProvider) DeepCopyInto(out *BuildTriggerProvider) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildTriggerProvider.
+func (in *BuildTriggerProvider) DeepCopy() *BuildTriggerProvider {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildTriggerProvider)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildTriggers) DeepCopyInto(out *BuildTriggers) {
+	*out = *in
+	if in.GitHub != nil {
+		in, out := &in.GitHub, &out.GitHub
+		*out = new(BuildTriggerProvider)
+		**out = **in
+	}
+	if in.GitLab != nil {
+		in, out := &in.GitLab, &out.GitLab
+		*out = new(BuildTriggerProvider)
+		**out = **in
+	}
+	if in.Bitbucket != nil {
+		in, out := &in.Bitbucket, &out.Bitbucket
+		*out = new(BuildTriggerProvider)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildTriggers.
+func (in *BuildTriggers) DeepCopy() *BuildTriggers {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildTriggers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClairMetrics) DeepCopyInto(out *ClairMetrics) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClairMetrics.
+func (in *ClairMetrics) DeepCopy() *ClairMetrics {
+	if in == nil {
+		return nil
+	}
+	out := new(ClairMetrics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClairSettings) DeepCopyInto(out *ClairSettings) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(ClairMetrics)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClairSettings.
+func (in *ClairSettings) DeepCopy() *ClairSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ClairSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClairUpdaters) DeepCopyInto(out *ClairUpdaters) {
+	*out = *in
+	if in.Sets != nil {
+		in, out := &in.Sets, &out.Sets
+		*out = make([]ClairUpdaterSet, len(*in))
+		copy(*out, *in)
+	}
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClairUpdaters.
+func (in *ClairUpdaters) DeepCopy() *ClairUpdaters {
+	if in == nil {
+		return nil
+	}
+	out := new(ClairUpdaters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudFrontDistribution) DeepCopyInto(out *CloudFrontDistribution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudFrontDistribution.
+func (in *CloudFrontDistribution) DeepCopy() *CloudFrontDistribution {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudFrontDistribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Component) DeepCopyInto(out *Component) {
 	*out = *in
@@ -40,95 +306,1470 @@ func (in *Component) DeepCopy() *Component {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QuayRegistry) DeepCopyInto(out *QuayRegistry) {
+func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistry.
-func (in *QuayRegistry) DeepCopy() *QuayRegistry {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Condition.
+func (in *Condition) DeepCopy() *Condition {
 	if in == nil {
 		return nil
 	}
-	out := new(QuayRegistry)
+	out := new(Condition)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *QuayRegistry) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseReadReplicas) DeepCopyInto(out *DatabaseReadReplicas) {
+	*out = *in
+	if in.CredentialsSecrets != nil {
+		in, out := &in.CredentialsSecrets, &out.CredentialsSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseReadReplicas.
+func (in *DatabaseReadReplicas) DeepCopy() *DatabaseReadReplicas {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseReadReplicas)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QuayRegistryList) DeepCopyInto(out *QuayRegistryList) {
+func (in *EndpointSettings) DeepCopyInto(out *EndpointSettings) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]QuayRegistry, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointSettings.
+func (in *EndpointSettings) DeepCopy() *EndpointSettings {
+	if in == nil {
+		return nil
 	}
+	out := new(EndpointSettings)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistryList.
-func (in *QuayRegistryList) DeepCopy() *QuayRegistryList {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EphemeralStorage) DeepCopyInto(out *EphemeralStorage) {
+	*out = *in
+	if in.Request != nil {
+		in, out := &in.Request, &out.Request
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Limit != nil {
+		in, out := &in.Limit, &out.Limit
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EphemeralStorage.
+func (in *EphemeralStorage) DeepCopy() *EphemeralStorage {
 	if in == nil {
 		return nil
 	}
-	out := new(QuayRegistryList)
+	out := new(EphemeralStorage)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *QuayRegistryList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalDNSSettings) DeepCopyInto(out *ExternalDNSSettings) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalDNSSettings.
+func (in *ExternalDNSSettings) DeepCopy() *ExternalDNSSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalDNSSettings)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QuayRegistrySpec) DeepCopyInto(out *QuayRegistrySpec) {
+func (in *ExternalDatabase) DeepCopyInto(out *ExternalDatabase) {
 	*out = *in
-	if in.Components != nil {
-		in, out := &in.Components, &out.Components
-		*out = make([]Component, len(*in))
-		copy(*out, *in)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalDatabase.
+func (in *ExternalDatabase) DeepCopy() *ExternalDatabase {
+	if in == nil {
+		return nil
 	}
+	out := new(ExternalDatabase)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistrySpec.
-func (in *QuayRegistrySpec) DeepCopy() *QuayRegistrySpec {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalRedis) DeepCopyInto(out *ExternalRedis) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalRedis.
+func (in *ExternalRedis) DeepCopy() *ExternalRedis {
 	if in == nil {
 		return nil
 	}
-	out := new(QuayRegistrySpec)
+	out := new(ExternalRedis)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QuayRegistryStatus) DeepCopyInto(out *QuayRegistryStatus) {
+func (in *FederatedUsernames) DeepCopyInto(out *FederatedUsernames) {
 	*out = *in
+	if in.Confirm != nil {
+		in, out := &in.Confirm, &out.Confirm
+		*out = new(bool)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistryStatus.
-func (in *QuayRegistryStatus) DeepCopy() *QuayRegistryStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederatedUsernames.
+func (in *FederatedUsernames) DeepCopy() *FederatedUsernames {
 	if in == nil {
 		return nil
 	}
-	out := new(QuayRegistryStatus)
+	out := new(FederatedUsernames)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCSStorage) DeepCopyInto(out *GCSStorage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCSStorage.
+func (in *GCSStorage) DeepCopy() *GCSStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(GCSStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IBMCOSStorage) DeepCopyInto(out *IBMCOSStorage) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IBMCOSStorage.
+func (in *IBMCOSStorage) DeepCopy() *IBMCOSStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(IBMCOSStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageOverrides) DeepCopyInto(out *ImageOverrides) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageOverrides.
+func (in *ImageOverrides) DeepCopy() *ImageOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JWTAuthentication) DeepCopyInto(out *JWTAuthentication) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JWTAuthentication.
+func (in *JWTAuthentication) DeepCopy() *JWTAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(JWTAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LastError) DeepCopyInto(out *LastError) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastError.
+func (in *LastError) DeepCopy() *LastError {
+	if in == nil {
+		return nil
+	}
+	out := new(LastError)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedPostgres) DeepCopyInto(out *ManagedPostgres) {
+	*out = *in
+	if in.Instances != nil {
+		in, out := &in.Instances, &out.Instances
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = new(PostgresBackups)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = new(PostgresMetrics)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedPostgres.
+func (in *ManagedPostgres) DeepCopy() *ManagedPostgres {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedPostgres)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationWebhook) DeepCopyInto(out *NotificationWebhook) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]NotificationEvent, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationWebhook.
+func (in *NotificationWebhook) DeepCopy() *NotificationWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStorage) DeepCopyInto(out *ObjectStorage) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Storage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(GCSStorage)
+		**out = **in
+	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(AzureStorage)
+		**out = **in
+	}
+	if in.Swift != nil {
+		in, out := &in.Swift, &out.Swift
+		*out = new(SwiftStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IBMCOS != nil {
+		in, out := &in.IBMCOS, &out.IBMCOS
+		*out = new(IBMCOSStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Locations != nil {
+		in, out := &in.Locations, &out.Locations
+		*out = make([]StorageLocation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Preference != nil {
+		in, out := &in.Preference, &out.Preference
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultLocations != nil {
+		in, out := &in.DefaultLocations, &out.DefaultLocations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DegradedLocations != nil {
+		in, out := &in.DegradedLocations, &out.DegradedLocations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = new(StorageCABundle)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStorage.
+func (in *ObjectStorage) DeepCopy() *ObjectStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PersistentVolumeRetention) DeepCopyInto(out *PersistentVolumeRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PersistentVolumeRetention.
+func (in *PersistentVolumeRetention) DeepCopy() *PersistentVolumeRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(PersistentVolumeRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PgBouncerSettings) DeepCopyInto(out *PgBouncerSettings) {
+	*out = *in
+	if in.DefaultPoolSize != nil {
+		in, out := &in.DefaultPoolSize, &out.DefaultPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PgBouncerSettings.
+func (in *PgBouncerSettings) DeepCopy() *PgBouncerSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(PgBouncerSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedChange) DeepCopyInto(out *PlannedChange) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedChange.
+func (in *PlannedChange) DeepCopy() *PlannedChange {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodOverride) DeepCopyInto(out *PodOverride) {
+	*out = *in
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(corev1.Lifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodOverride.
+func (in *PodOverride) DeepCopy() *PodOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(PodOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresBackupStatus) DeepCopyInto(out *PostgresBackupStatus) {
+	*out = *in
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresBackupStatus.
+func (in *PostgresBackupStatus) DeepCopy() *PostgresBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresBackups) DeepCopyInto(out *PostgresBackups) {
+	*out = *in
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresBackups.
+func (in *PostgresBackups) DeepCopy() *PostgresBackups {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresBackups)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresMetrics) DeepCopyInto(out *PostgresMetrics) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresMetrics.
+func (in *PostgresMetrics) DeepCopy() *PostgresMetrics {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresMetrics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostgresStatus) DeepCopyInto(out *PostgresStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostgresStatus.
+func (in *PostgresStatus) DeepCopy() *PostgresStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PostgresStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileOverrides) DeepCopyInto(out *ProfileOverrides) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.WorkerCounts != nil {
+		in, out := &in.WorkerCounts, &out.WorkerCounts
+		*out = new(WorkerCounts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileOverrides.
+func (in *ProfileOverrides) DeepCopy() *ProfileOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayOperatorConfig) DeepCopyInto(out *QuayOperatorConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayOperatorConfig.
+func (in *QuayOperatorConfig) DeepCopy() *QuayOperatorConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayOperatorConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuayOperatorConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayOperatorConfigList) DeepCopyInto(out *QuayOperatorConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]QuayOperatorConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayOperatorConfigList.
+func (in *QuayOperatorConfigList) DeepCopy() *QuayOperatorConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayOperatorConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuayOperatorConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayOperatorConfigSpec) DeepCopyInto(out *QuayOperatorConfigSpec) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = new(ImageOverrides)
+		**out = **in
+	}
+	if in.AllowedStorageBackends != nil {
+		in, out := &in.AllowedStorageBackends, &out.AllowedStorageBackends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OverridableConfigFields != nil {
+		in, out := &in.OverridableConfigFields, &out.OverridableConfigFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]SecurityPolicy, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayOperatorConfigSpec.
+func (in *QuayOperatorConfigSpec) DeepCopy() *QuayOperatorConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayOperatorConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayRegistry) DeepCopyInto(out *QuayRegistry) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistry.
+func (in *QuayRegistry) DeepCopy() *QuayRegistry {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayRegistry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuayRegistry) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayRegistryList) DeepCopyInto(out *QuayRegistryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]QuayRegistry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistryList.
+func (in *QuayRegistryList) DeepCopy() *QuayRegistryList {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayRegistryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *QuayRegistryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayRegistrySpec) DeepCopyInto(out *QuayRegistrySpec) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]Component, len(*in))
+		copy(*out, *in)
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]NotificationWebhook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = new(StorageMigration)
+		**out = **in
+	}
+	if in.BlobVerification != nil {
+		in, out := &in.BlobVerification, &out.BlobVerification
+		*out = new(BlobVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProfileOverrides != nil {
+		in, out := &in.ProfileOverrides, &out.ProfileOverrides
+		*out = new(ProfileOverrides)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodOverrides != nil {
+		in, out := &in.PodOverrides, &out.PodOverrides
+		*out = make([]PodOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(Authentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TagPolicy != nil {
+		in, out := &in.TagPolicy, &out.TagPolicy
+		*out = new(TagPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = new(RepositoryPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageAffinity != nil {
+		in, out := &in.StorageAffinity, &out.StorageAffinity
+		*out = new(StorageAffinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClairUpdaters != nil {
+		in, out := &in.ClairUpdaters, &out.ClairUpdaters
+		*out = new(ClairUpdaters)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Clair != nil {
+		in, out := &in.Clair, &out.Clair
+		*out = new(ClairSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Route != nil {
+		in, out := &in.Route, &out.Route
+		*out = new(RouteSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Endpoint != nil {
+		in, out := &in.Endpoint, &out.Endpoint
+		*out = new(EndpointSettings)
+		**out = **in
+	}
+	if in.ExternalDNS != nil {
+		in, out := &in.ExternalDNS, &out.ExternalDNS
+		*out = new(ExternalDNSSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CertificateExpiryThreshold != nil {
+		in, out := &in.CertificateExpiryThreshold, &out.CertificateExpiryThreshold
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.SecretKeysBackup != nil {
+		in, out := &in.SecretKeysBackup, &out.SecretKeysBackup
+		*out = new(SecretKeysBackup)
+		**out = **in
+	}
+	if in.BuildTriggers != nil {
+		in, out := &in.BuildTriggers, &out.BuildTriggers
+		*out = new(BuildTriggers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectStorage != nil {
+		in, out := &in.ObjectStorage, &out.ObjectStorage
+		*out = new(ObjectStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StorageUploads != nil {
+		in, out := &in.StorageUploads, &out.StorageUploads
+		*out = make([]StorageUploadSettings, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TempStorage != nil {
+		in, out := &in.TempStorage, &out.TempStorage
+		*out = make([]TempStorage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EphemeralStorage != nil {
+		in, out := &in.EphemeralStorage, &out.EphemeralStorage
+		*out = make([]EphemeralStorage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Postgres != nil {
+		in, out := &in.Postgres, &out.Postgres
+		*out = new(ManagedPostgres)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PersistentVolumeRetentionPolicy != nil {
+		in, out := &in.PersistentVolumeRetentionPolicy, &out.PersistentVolumeRetentionPolicy
+		*out = make([]PersistentVolumeRetention, len(*in))
+		copy(*out, *in)
+	}
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalDatabase != nil {
+		in, out := &in.ExternalDatabase, &out.ExternalDatabase
+		*out = new(ExternalDatabase)
+		**out = **in
+	}
+	if in.ExternalRedis != nil {
+		in, out := &in.ExternalRedis, &out.ExternalRedis
+		*out = new(ExternalRedis)
+		**out = **in
+	}
+	if in.DatabaseReadReplicas != nil {
+		in, out := &in.DatabaseReadReplicas, &out.DatabaseReadReplicas
+		*out = new(DatabaseReadReplicas)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProtectedComponents != nil {
+		in, out := &in.ProtectedComponents, &out.ProtectedComponents
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PgBouncer != nil {
+		in, out := &in.PgBouncer, &out.PgBouncer
+		*out = new(PgBouncerSettings)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistrySpec.
+func (in *QuayRegistrySpec) DeepCopy() *QuayRegistrySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayRegistrySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QuayRegistryStatus) DeepCopyInto(out *QuayRegistryStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StorageMigration != nil {
+		in, out := &in.StorageMigration, &out.StorageMigration
+		*out = new(StorageMigrationStatus)
+		**out = **in
+	}
+	if in.BlobVerification != nil {
+		in, out := &in.BlobVerification, &out.BlobVerification
+		*out = new(BlobVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Builds != nil {
+		in, out := &in.Builds, &out.Builds
+		*out = new(BuildStatus)
+		**out = **in
+	}
+	if in.PlannedChanges != nil {
+		in, out := &in.PlannedChanges, &out.PlannedChanges
+		*out = make([]PlannedChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastError != nil {
+		in, out := &in.LastError, &out.LastError
+		*out = new(LastError)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OperatorConfig != nil {
+		in, out := &in.OperatorConfig, &out.OperatorConfig
+		*out = new(QuayOperatorConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Postgres != nil {
+		in, out := &in.Postgres, &out.Postgres
+		*out = new(PostgresStatus)
+		**out = **in
+	}
+	if in.PostgresBackup != nil {
+		in, out := &in.PostgresBackup, &out.PostgresBackup
+		*out = new(PostgresBackupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetainedVolumes != nil {
+		in, out := &in.RetainedVolumes, &out.RetainedVolumes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuayRegistryStatus.
+func (in *QuayRegistryStatus) DeepCopy() *QuayRegistryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(QuayRegistryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSentinel) DeepCopyInto(out *RedisSentinel) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSentinel.
+func (in *RedisSentinel) DeepCopy() *RedisSentinel {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisSentinel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisSettings) DeepCopyInto(out *RedisSettings) {
+	*out = *in
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HealthCheckInterval != nil {
+		in, out := &in.HealthCheckInterval, &out.HealthCheckInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RetryOnTimeout != nil {
+		in, out := &in.RetryOnTimeout, &out.RetryOnTimeout
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Database != nil {
+		in, out := &in.Database, &out.Database
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RedisTLS)
+		**out = **in
+	}
+	if in.Sentinel != nil {
+		in, out := &in.Sentinel, &out.Sentinel
+		*out = new(RedisSentinel)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisSettings.
+func (in *RedisSettings) DeepCopy() *RedisSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisTLS) DeepCopyInto(out *RedisTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisTLS.
+func (in *RedisTLS) DeepCopy() *RedisTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryAPIRoute) DeepCopyInto(out *RegistryAPIRoute) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RouteRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryAPIRoute.
+func (in *RegistryAPIRoute) DeepCopy() *RegistryAPIRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryAPIRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicy) DeepCopyInto(out *RepositoryPolicy) {
+	*out = *in
+	if in.CreateNamespaceOnPush != nil {
+		in, out := &in.CreateNamespaceOnPush, &out.CreateNamespaceOnPush
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicy.
+func (in *RepositoryPolicy) DeepCopy() *RepositoryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestrictedUsers) DeepCopyInto(out *RestrictedUsers) {
+	*out = *in
+	if in.Exempt != nil {
+		in, out := &in.Exempt, &out.Exempt
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestrictedUsers.
+func (in *RestrictedUsers) DeepCopy() *RestrictedUsers {
+	if in == nil {
+		return nil
+	}
+	out := new(RestrictedUsers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteHSTS) DeepCopyInto(out *RouteHSTS) {
+	*out = *in
+	out.MaxAge = in.MaxAge
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteHSTS.
+func (in *RouteHSTS) DeepCopy() *RouteHSTS {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteHSTS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteRateLimit) DeepCopyInto(out *RouteRateLimit) {
+	*out = *in
+	if in.ConcurrentConnections != nil {
+		in, out := &in.ConcurrentConnections, &out.ConcurrentConnections
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConnectionRate != nil {
+		in, out := &in.ConnectionRate, &out.ConnectionRate
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteRateLimit.
+func (in *RouteRateLimit) DeepCopy() *RouteRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteSettings) DeepCopyInto(out *RouteSettings) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HSTS != nil {
+		in, out := &in.HSTS, &out.HSTS
+		*out = new(RouteHSTS)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RouteRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedSourceRanges != nil {
+		in, out := &in.AllowedSourceRanges, &out.AllowedSourceRanges
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistryAPI != nil {
+		in, out := &in.RegistryAPI, &out.RegistryAPI
+		*out = new(RegistryAPIRoute)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteSettings.
+func (in *RouteSettings) DeepCopy() *RouteSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Storage) DeepCopyInto(out *S3Storage) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerSideEncryption != nil {
+		in, out := &in.ServerSideEncryption, &out.ServerSideEncryption
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CloudFront != nil {
+		in, out := &in.CloudFront, &out.CloudFront
+		*out = new(CloudFrontDistribution)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Storage.
+func (in *S3Storage) DeepCopy() *S3Storage {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Storage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeysBackup) DeepCopyInto(out *SecretKeysBackup) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeysBackup.
+func (in *SecretKeysBackup) DeepCopy() *SecretKeysBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeysBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageAffinity) DeepCopyInto(out *StorageAffinity) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageAffinity.
+func (in *StorageAffinity) DeepCopy() *StorageAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageCABundle) DeepCopyInto(out *StorageCABundle) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageCABundle.
+func (in *StorageCABundle) DeepCopy() *StorageCABundle {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageCABundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageLocation) DeepCopyInto(out *StorageLocation) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Storage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(GCSStorage)
+		**out = **in
+	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(AzureStorage)
+		**out = **in
+	}
+	if in.Swift != nil {
+		in, out := &in.Swift, &out.Swift
+		*out = new(SwiftStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IBMCOS != nil {
+		in, out := &in.IBMCOS, &out.IBMCOS
+		*out = new(IBMCOSStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageLocation.
+func (in *StorageLocation) DeepCopy() *StorageLocation {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageLocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigration) DeepCopyInto(out *StorageMigration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigration.
+func (in *StorageMigration) DeepCopy() *StorageMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageMigrationStatus) DeepCopyInto(out *StorageMigrationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMigrationStatus.
+func (in *StorageMigrationStatus) DeepCopy() *StorageMigrationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageMigrationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageUploadSettings) DeepCopyInto(out *StorageUploadSettings) {
+	*out = *in
+	if in.MinimumChunkSizeMB != nil {
+		in, out := &in.MinimumChunkSizeMB, &out.MinimumChunkSizeMB
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaximumChunkSizeMB != nil {
+		in, out := &in.MaximumChunkSizeMB, &out.MaximumChunkSizeMB
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerSideAssembly != nil {
+		in, out := &in.ServerSideAssembly, &out.ServerSideAssembly
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageUploadSettings.
+func (in *StorageUploadSettings) DeepCopy() *StorageUploadSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageUploadSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SwiftStorage) DeepCopyInto(out *SwiftStorage) {
+	*out = *in
+	if in.AuthVersion != nil {
+		in, out := &in.AuthVersion, &out.AuthVersion
+		*out = new(int32)
+		**out = **in
+	}
+	if in.OSOptions != nil {
+		in, out := &in.OSOptions, &out.OSOptions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SwiftStorage.
+func (in *SwiftStorage) DeepCopy() *SwiftStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(SwiftStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagPolicy) DeepCopyInto(out *TagPolicy) {
+	*out = *in
+	if in.ExpirationOptions != nil {
+		in, out := &in.ExpirationOptions, &out.ExpirationOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImmutablePatterns != nil {
+		in, out := &in.ImmutablePatterns, &out.ImmutablePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AutoPrune != nil {
+		in, out := &in.AutoPrune, &out.AutoPrune
+		*out = new(AutoPrunePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagPolicy.
+func (in *TagPolicy) DeepCopy() *TagPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TagPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TempStorage) DeepCopyInto(out *TempStorage) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TempStorage.
+func (in *TempStorage) DeepCopy() *TempStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(TempStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkerCounts) DeepCopyInto(out *WorkerCounts) {
+	*out = *in
+	if in.Web != nil {
+		in, out := &in.Web, &out.Web
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Secscan != nil {
+		in, out := &in.Secscan, &out.Secscan
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkerCounts.
+func (in *WorkerCounts) DeepCopy() *WorkerCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkerCounts)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// approvalIDFor returns the ID which approves applying the given objects. It is derived from their rendered state, so
+// that an approval does not carry over to different changes.
+func approvalIDFor(objects []k8sruntime.Object) string {
+	hashes := []string{}
+	for _, obj := range objects {
+		hashes = append(hashes, objectKey(obj)+"="+objectHash(obj))
+	}
+	sort.Strings(hashes)
+	sum := sha256.Sum256([]byte(strings.Join(hashes, "\n")))
+
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// withoutUnapprovedChanges splits the changed objects into those which may be applied and those which would update an
+// existing object of a component in `spec.protectedComponents`, along with the changes the latter would make. Objects
+// which do not exist yet are created without approval. Nothing is held back once the `approved-changes` annotation
+// matches the approval ID of the pending objects.
+func (r *QuayRegistryReconciler) withoutUnapprovedChanges(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) ([]k8sruntime.Object, []k8sruntime.Object, []v1.PlannedChange, error) {
+	approved := []k8sruntime.Object{}
+	pending := []k8sruntime.Object{}
+	changes := []v1.PlannedChange{}
+	for _, obj := range objects {
+		if kind := kustomize.ComponentKindFor(obj); kind == "" || !v1.ComponentIsProtected(quay, kind) {
+			approved = append(approved, obj)
+			continue
+		}
+
+		planned, err := r.planChanges(ctx, []k8sruntime.Object{obj})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(planned) == 0 || planned[0].Action != v1.PlannedActionUpdate {
+			approved = append(approved, obj)
+			continue
+		}
+
+		pending = append(pending, obj)
+		changes = append(changes, planned[0])
+	}
+
+	if len(pending) > 0 && quay.GetAnnotations()[v1.ApprovedChangesAnnotation] == approvalIDFor(pending) {
+		return append(approved, pending...), nil, nil, nil
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Object < changes[j].Object })
+
+	return approved, pending, changes, nil
+}
+
+// pendingApprovalCondition returns the `ChangesPendingApproval` condition for the given pending objects, or nil if
+// the condition should be left as is.
+func pendingApprovalCondition(quay *v1.QuayRegistry, pending []k8sruntime.Object) *v1.Condition {
+	if len(pending) == 0 {
+		if existing := v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeChangesPendingApproval); existing == nil || existing.Status == metav1.ConditionFalse {
+			return nil
+		}
+
+		return &v1.Condition{
+			Type:   v1.ConditionTypeChangesPendingApproval,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonChangesApproved,
+		}
+	}
+
+	kinds := map[string]bool{}
+	for _, obj := range pending {
+		kinds[kustomize.ComponentKindFor(obj)] = true
+	}
+	components := []string{}
+	for kind := range kinds {
+		components = append(components, "`"+kind+"`")
+	}
+	sort.Strings(components)
+
+	return &v1.Condition{
+		Type:   v1.ConditionTypeChangesPendingApproval,
+		Status: metav1.ConditionTrue,
+		Reason: v1.ConditionReasonApprovalRequired,
+		Message: fmt.Sprintf("changes to %d objects of protected components %s are not applied until the `%s` annotation is set to `%s`, see `status.plannedChanges`",
+			len(pending), strings.Join(components, ", "), v1.ApprovedChangesAnnotation, approvalIDFor(pending)),
+	}
+}
+
+// reportPendingApproval reports the changes to protected components which await approval in the
+// `ChangesPendingApproval` condition, recording a `Warning` `Event` whenever they change.
+func (r *QuayRegistryReconciler) reportPendingApproval(ctx context.Context, quay *v1.QuayRegistry, pending []k8sruntime.Object) error {
+	condition := pendingApprovalCondition(quay, pending)
+	if condition == nil {
+		return nil
+	}
+
+	previousMessage := ""
+	if existing := v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeChangesPendingApproval); existing != nil {
+		previousMessage = existing.Message
+	}
+
+	if err := r.updateConditions(ctx, quay, *condition); err != nil {
+		return err
+	}
+
+	if condition.Status == metav1.ConditionTrue && condition.Message != previousMessage {
+		r.recordEvent(quay, corev1.EventTypeWarning, v1.ConditionReasonApprovalRequired, condition.Message)
+	}
+
+	return nil
+}
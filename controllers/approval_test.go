@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// imageObject returns a `Deployment` labeled with the given component which runs the given image.
+func imageObject(name, component, image string) k8sruntime.Object {
+	deployment := componentObject(name, component, 1).(*appsv1.Deployment)
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: name, Image: image}}
+
+	return deployment
+}
+
+var withoutUnapprovedChangesTests = []struct {
+	name             string
+	protected        []string
+	approved         bool
+	live             []k8sruntime.Object
+	objects          []k8sruntime.Object
+	expectedApproved []string
+	expectedPending  []string
+}{
+	{
+		"NothingProtected",
+		nil,
+		false,
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1")},
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:2")},
+		[]string{"test-quay-database"},
+		[]string{},
+	},
+	{
+		"ProtectedCreated",
+		[]string{"postgres"},
+		false,
+		[]k8sruntime.Object{},
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1")},
+		[]string{"test-quay-database"},
+		[]string{},
+	},
+	{
+		"ProtectedUnchanged",
+		[]string{"postgres"},
+		false,
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1")},
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1")},
+		[]string{"test-quay-database"},
+		[]string{},
+	},
+	{
+		"ProtectedUpdated",
+		[]string{"postgres"},
+		false,
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1"), imageObject("test-clair-app", "clair", "image:1")},
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:2"), imageObject("test-clair-app", "clair", "image:2")},
+		[]string{"test-clair-app"},
+		[]string{"test-quay-database"},
+	},
+	{
+		"ProtectedUpdateApproved",
+		[]string{"postgres"},
+		true,
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:1"), imageObject("test-clair-app", "clair", "image:1")},
+		[]k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:2"), imageObject("test-clair-app", "clair", "image:2")},
+		[]string{"test-clair-app", "test-quay-database"},
+		[]string{},
+	},
+}
+
+func TestWithoutUnapprovedChanges(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range withoutUnapprovedChangesTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec:       v1.QuayRegistrySpec{ProtectedComponents: test.protected},
+		}
+		if test.approved {
+			quay.SetAnnotations(map[string]string{v1.ApprovedChangesAnnotation: approvalIDFor(test.objects[:1])})
+		}
+		r, _ := stubReconciler(test.live...)
+
+		approved, pending, changes, err := r.withoutUnapprovedChanges(context.Background(), quay, test.objects)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expectedApproved, objectNames(approved), test.name)
+		assert.Equal(test.expectedPending, objectNames(pending), test.name)
+		assert.Equal(len(test.expectedPending), len(changes), test.name)
+	}
+}
+
+func TestPendingApprovalCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{}
+	assert.Nil(pendingApprovalCondition(quay, nil))
+
+	pending := []k8sruntime.Object{imageObject("test-quay-database", "postgres", "image:2")}
+	condition := pendingApprovalCondition(quay, pending)
+	assert.Equal(metav1.ConditionTrue, condition.Status)
+	assert.Equal(v1.ConditionReasonApprovalRequired, condition.Reason)
+	assert.Contains(condition.Message, "`postgres`")
+	assert.Contains(condition.Message, approvalIDFor(pending))
+
+	quay.Status.Conditions = []v1.Condition{*condition}
+	condition = pendingApprovalCondition(quay, nil)
+	assert.Equal(metav1.ConditionFalse, condition.Status)
+	assert.Equal(v1.ConditionReasonChangesApproved, condition.Reason)
+}
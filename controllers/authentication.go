@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// withAuthenticationFiles returns a copy of the config bundle including any files referenced by `spec.authentication`,
+// such as the public key of an external JWT issuer.
+func (r *QuayRegistryReconciler) withAuthenticationFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	auth := quay.Spec.Authentication
+	if auth == nil || auth.Type != v1.AuthenticationTypeJWT || auth.JWT == nil || auth.JWT.PublicKeySecret == "" {
+		return configBundle, nil
+	}
+
+	var publicKeySecret corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: auth.JWT.PublicKeySecret}, &publicKeySecret); err != nil {
+		return nil, err
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if publicKey, ok := publicKeySecret.Data[kustomize.JWTPublicKeyFile]; ok {
+		withFiles.Data[kustomize.JWTPublicKeyFile] = publicKey
+	}
+
+	return withFiles, nil
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+// autoPrunePolicyFor returns the auto-prune policy to create for existing organizations and its `status.autoPrunePolicy`,
+// or nil if `spec.tagPolicy.autoPrune` does not ask for it.
+func autoPrunePolicyFor(quayRegistry *v1.QuayRegistry) (*quay.AutoPrunePolicy, string) {
+	if quayRegistry.Spec.TagPolicy == nil {
+		return nil, ""
+	}
+	prune := quayRegistry.Spec.TagPolicy.AutoPrune
+	if prune == nil || !prune.ApplyToExistingOrganizations {
+		return nil, ""
+	}
+
+	policy := &quay.AutoPrunePolicy{Method: string(prune.Method), Value: prune.Value}
+	if prune.Method == v1.AutoPruneMethodNumberOfTags {
+		// NOTE: The value was validated when the config bundle was rendered.
+		count, _ := strconv.Atoi(prune.Value)
+		policy.Value = count
+	}
+
+	return policy, string(prune.Method) + ":" + prune.Value
+}
+
+// applyAutoPrunePolicy creates the auto-prune policy of `spec.tagPolicy.autoPrune` for every organization without
+// one through the Quay API, once per policy. Quay applies `DEFAULT_NAMESPACE_AUTOPRUNE_POLICY` to organizations
+// created afterwards. Does nothing until the registry has a Quay API token.
+func (r *QuayRegistryReconciler) applyAutoPrunePolicy(ctx context.Context, quayRegistry *v1.QuayRegistry) error {
+	if quayRegistry.Spec.DryRun || quayRegistry.Spec.Mode == v1.RegistryModeMirrorWorkers {
+		return nil
+	}
+
+	policy, applied := autoPrunePolicyFor(quayRegistry)
+	if applied == quayRegistry.Status.AutoPrunePolicy {
+		return nil
+	}
+
+	if policy != nil {
+		available := v1.GetCondition(quayRegistry.Status.Conditions, v1.ConditionTypeAvailable)
+		if available == nil || available.Status != metav1.ConditionTrue {
+			return nil
+		}
+
+		client, err := r.quayAPIClient(ctx, quayRegistry)
+		if err != nil || client == nil {
+			return err
+		}
+
+		orgs, err := createAutoPrunePolicies(ctx, client, *policy)
+		if err != nil {
+			return err
+		}
+		r.recordEvent(quayRegistry, corev1.EventTypeNormal, "AutoPrunePolicyApplied", "created auto-prune policy "+applied+" for "+strconv.Itoa(orgs)+" existing organizations")
+	}
+
+	quayRegistry.Status.AutoPrunePolicy = applied
+
+	return r.Client.Status().Update(ctx, quayRegistry)
+}
+
+// createAutoPrunePolicies creates the given auto-prune policy for every organization without one, leaving policies
+// set by organization administrators alone. Returns the number of organizations it was created for.
+func createAutoPrunePolicies(ctx context.Context, client *quay.Client, policy quay.AutoPrunePolicy) (int, error) {
+	orgs, err := client.ListOrganizations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, org := range orgs {
+		policies, err := client.ListAutoPrunePolicies(ctx, org)
+		if err != nil {
+			return created, err
+		}
+		if len(policies) > 0 {
+			continue
+		}
+
+		if err := client.CreateAutoPrunePolicy(ctx, org, policy); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+var autoPrunePolicyForTests = []struct {
+	name            string
+	tagPolicy       *v1.TagPolicy
+	expected        *quay.AutoPrunePolicy
+	expectedApplied string
+}{
+	{
+		"NoTagPolicy",
+		nil,
+		nil,
+		"",
+	},
+	{
+		"NewOrganizationsOnly",
+		&v1.TagPolicy{AutoPrune: &v1.AutoPrunePolicy{Method: v1.AutoPruneMethodCreationDate, Value: "30d"}},
+		nil,
+		"",
+	},
+	{
+		"CreationDate",
+		&v1.TagPolicy{AutoPrune: &v1.AutoPrunePolicy{Method: v1.AutoPruneMethodCreationDate, Value: "30d", ApplyToExistingOrganizations: true}},
+		&quay.AutoPrunePolicy{Method: "creation_date", Value: "30d"},
+		"creation_date:30d",
+	},
+	{
+		"NumberOfTags",
+		&v1.TagPolicy{AutoPrune: &v1.AutoPrunePolicy{Method: v1.AutoPruneMethodNumberOfTags, Value: "50", ApplyToExistingOrganizations: true}},
+		&quay.AutoPrunePolicy{Method: "number_of_tags", Value: 50},
+		"number_of_tags:50",
+	},
+}
+
+func TestAutoPrunePolicyFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range autoPrunePolicyForTests {
+		quayRegistry := &v1.QuayRegistry{Spec: v1.QuayRegistrySpec{TagPolicy: test.tagPolicy}}
+		policy, applied := autoPrunePolicyFor(quayRegistry)
+
+		assert.Equal(test.expected, policy, test.name)
+		assert.Equal(test.expectedApplied, applied, test.name)
+	}
+}
+
+func TestCreateAutoPrunePolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	created := map[string]quay.AutoPrunePolicy{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/superuser/organizations/":
+			_, _ = w.Write([]byte(`{"organizations": [{"name": "pruned"}, {"name": "unpruned"}]}`))
+		case "GET /api/v1/organization/pruned/autoprunepolicy/":
+			_, _ = w.Write([]byte(`{"policies": [{"method": "number_of_tags", "value": 5}]}`))
+		case "GET /api/v1/organization/unpruned/autoprunepolicy/":
+			_, _ = w.Write([]byte(`{"policies": []}`))
+		case "POST /api/v1/organization/unpruned/autoprunepolicy/":
+			var policy quay.AutoPrunePolicy
+			assert.Nil(json.NewDecoder(r.Body).Decode(&policy))
+			created["unpruned"] = policy
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := quay.NewClient(server.URL, "abc123", true)
+	count, err := createAutoPrunePolicies(context.Background(), client, quay.AutoPrunePolicy{Method: "creation_date", Value: "30d"})
+
+	assert.Nil(err)
+	assert.Equal(1, count)
+	assert.Equal(map[string]quay.AutoPrunePolicy{"unpruned": {Method: "creation_date", Value: "30d"}}, created, "policies of administrators are kept")
+}
+
+func TestApplyAutoPrunePolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	quayRegistry := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Status: v1.QuayRegistryStatus{
+			Conditions:      availableConditions(v1.ConditionReasonComponentsCreationSuccess),
+			AutoPrunePolicy: "creation_date:30d",
+		},
+	}
+
+	r, stub := stubReconciler()
+	assert.Nil(r.applyAutoPrunePolicy(context.Background(), quayRegistry))
+	assert.Equal("", quayRegistry.Status.AutoPrunePolicy, "policy no longer applied to existing organizations")
+	assert.Len(stub.statusUpdated, 1)
+
+	quayRegistry.Spec.TagPolicy = &v1.TagPolicy{AutoPrune: &v1.AutoPrunePolicy{Method: v1.AutoPruneMethodCreationDate, Value: "30d", ApplyToExistingOrganizations: true}}
+	assert.Nil(r.applyAutoPrunePolicy(context.Background(), quayRegistry))
+	assert.Equal("", quayRegistry.Status.AutoPrunePolicy, "no Quay API token")
+	assert.Len(stub.statusUpdated, 1)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+
+// blobVerificationBlobs is the number of blobs of each outcome of the last verification of each registry, so alerts
+// can be defined in Prometheus.
+var blobVerificationBlobs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "quay_operator_blob_verification_blobs",
+	Help: "Number of blobs sampled by the last blob verification of a QuayRegistry, by result.",
+}, []string{"namespace", "quayregistry", "result"})
+
+func init() {
+	metrics.Registry.MustRegister(blobVerificationBlobs)
+}
+
+// reportBlobVerificationMetrics sets the blob verification metrics of the given registry, or removes them if it has
+// no verification result.
+func reportBlobVerificationMetrics(name types.NamespacedName, status *v1.BlobVerificationStatus) {
+	results := map[string]int32{}
+	if status != nil && status.LastCompletionTime != nil {
+		results = map[string]int32{"verified": status.Verified, "corrupted": status.Corrupted, "missing": status.Missing}
+	}
+
+	for _, result := range []string{"verified", "corrupted", "missing"} {
+		count, ok := results[result]
+		if !ok {
+			blobVerificationBlobs.DeleteLabelValues(name.Namespace, name.Name, result)
+			continue
+		}
+		blobVerificationBlobs.WithLabelValues(name.Namespace, name.Name, result).Set(float64(count))
+	}
+}
+
+// forgetBlobVerificationMetrics removes the blob verification metrics of a deleted registry.
+func forgetBlobVerificationMetrics(name types.NamespacedName) {
+	reportBlobVerificationMetrics(name, nil)
+}
+
+// blobVerificationResultFor returns the result the verification pod of the given `Job` wrote as its termination
+// message, or nil if none of its pods completed.
+func blobVerificationResultFor(job *batchv1.Job, pods []corev1.Pod) *kustomize.BlobVerificationResult {
+	for _, pod := range pods {
+		if pod.GetLabels()["job-name"] != job.GetName() {
+			continue
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			terminated := status.State.Terminated
+			if status.Name != kustomize.BlobVerificationContainer || terminated == nil || terminated.ExitCode != 0 {
+				continue
+			}
+
+			var result kustomize.BlobVerificationResult
+			if err := json.Unmarshal([]byte(terminated.Message), &result); err == nil {
+				return &result
+			}
+		}
+	}
+
+	return nil
+}
+
+// nextBlobVerificationStatus returns the result of the scheduled verifications of blobs after inspecting the `Jobs`
+// created by their `CronJob` and the pods of those `Jobs`, or nil if no schedule is declared. The result of the last
+// verification which ran to completion is kept when a later one fails.
+func nextBlobVerificationStatus(quay *v1.QuayRegistry, jobs []batchv1.Job, pods []corev1.Pod) *v1.BlobVerificationStatus {
+	if quay.Spec.BlobVerification == nil {
+		return nil
+	}
+
+	status := &v1.BlobVerificationStatus{}
+	if existing := quay.Status.BlobVerification; existing != nil {
+		status = existing.DeepCopy()
+	}
+
+	var latest *batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		if _, failed := jobFailed(job); job.Status.Succeeded == 0 && !failed {
+			continue
+		}
+
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) {
+			latest = job
+		}
+	}
+
+	if latest == nil || latest.GetName() == status.LastJob {
+		return status
+	}
+
+	if message, failed := jobFailed(latest); failed {
+		status.LastJob = latest.GetName()
+		status.Failed = true
+		status.Message = latest.GetName() + " failed: " + message
+
+		return status
+	}
+
+	result := blobVerificationResultFor(latest, pods)
+	if result == nil {
+		// NOTE: The pod may not be listed yet, so the `Job` is inspected again on the next reconcile.
+		return status
+	}
+
+	status.LastJob = latest.GetName()
+	status.LastCompletionTime = latest.Status.CompletionTime.DeepCopy()
+	status.Verified = result.Verified
+	status.Corrupted = result.Corrupted
+	status.Missing = result.Missing
+	status.Failed = false
+	sampled := result.Verified + result.Corrupted + result.Missing
+	if result.Corrupted+result.Missing > 0 {
+		status.Message = fmt.Sprintf("%d of %d sampled blobs are corrupted and %d are missing, see the logs of %s", result.Corrupted, sampled, result.Missing, latest.GetName())
+	} else {
+		status.Message = fmt.Sprintf("verified %d sampled blobs", sampled)
+	}
+
+	return status
+}
+
+// reportBlobVerification reports the result of the last scheduled verification of blobs in `status.blobVerification`
+// and its metrics, and records an event when it fails or finds blobs which are corrupted or missing.
+func (r *QuayRegistryReconciler) reportBlobVerification(ctx context.Context, quay *v1.QuayRegistry) error {
+	var jobs batchv1.JobList
+	var pods corev1.PodList
+	if quay.Spec.BlobVerification != nil {
+		labels := client.MatchingLabels{"quay-component": kustomize.BlobVerificationComponent}
+		if err := r.Client.List(ctx, &jobs, client.InNamespace(quay.GetNamespace()), labels); err != nil {
+			return err
+		}
+		if err := r.Client.List(ctx, &pods, client.InNamespace(quay.GetNamespace()), labels); err != nil {
+			return err
+		}
+	}
+
+	existing := quay.Status.BlobVerification
+	status := nextBlobVerificationStatus(quay, jobs.Items, pods.Items)
+	reportBlobVerificationMetrics(types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName()}, status)
+	if reflect.DeepEqual(status, existing) {
+		return nil
+	}
+
+	quay.Status.BlobVerification = status
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return err
+	}
+
+	if status == nil || (existing != nil && existing.LastJob == status.LastJob) {
+		return nil
+	}
+	if status.Failed {
+		r.recordEvent(quay, corev1.EventTypeWarning, "BlobVerificationFailed", status.Message)
+	} else if status.Corrupted+status.Missing > 0 {
+		r.recordEvent(quay, corev1.EventTypeWarning, "CorruptedBlobs", status.Message)
+	}
+
+	return nil
+}
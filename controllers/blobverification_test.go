@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+var verificationEpoch = time.Date(2026, 1, 4, 4, 0, 0, 0, time.UTC)
+
+func blobVerificationJob(name string, created int, succeeded bool, failed bool) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns-1",
+			Labels:            map[string]string{"quay-component": kustomize.BlobVerificationComponent},
+			CreationTimestamp: metav1.NewTime(verificationEpoch.Add(time.Duration(created) * time.Hour)),
+		},
+	}
+	if succeeded {
+		job.Status.Succeeded = 1
+		job.Status.CompletionTime = verificationTime(created)
+	}
+	if failed {
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job was active longer than specified deadline"},
+		}
+	}
+
+	return job
+}
+
+func blobVerificationPod(job string, exitCode int32, message string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job + "-abcde",
+			Namespace: "ns-1",
+			Labels:    map[string]string{"quay-component": kustomize.BlobVerificationComponent, "job-name": job},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:  kustomize.BlobVerificationContainer,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Message: message}},
+				},
+			},
+		},
+	}
+}
+
+func verificationTime(created int) *metav1.Time {
+	completed := metav1.NewTime(verificationEpoch.Add(time.Duration(created)*time.Hour + time.Minute))
+
+	return &completed
+}
+
+var nextBlobVerificationStatusTests = []struct {
+	name     string
+	spec     *v1.BlobVerification
+	existing *v1.BlobVerificationStatus
+	jobs     []batchv1.Job
+	pods     []corev1.Pod
+	expected *v1.BlobVerificationStatus
+}{
+	{
+		"Disabled",
+		nil,
+		&v1.BlobVerificationStatus{LastJob: "verify-1"},
+		nil,
+		nil,
+		nil,
+	},
+	{
+		"NotYetRun",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		nil,
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, false, false)},
+		nil,
+		&v1.BlobVerificationStatus{},
+	},
+	{
+		"Verified",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		nil,
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, true, false), blobVerificationJob("verify-2", 168, true, false)},
+		[]corev1.Pod{
+			blobVerificationPod("verify-1", 0, `{"verified": 10, "corrupted": 1, "missing": 0}`),
+			blobVerificationPod("verify-2", 0, `{"verified": 12, "corrupted": 0, "missing": 0}`),
+		},
+		&v1.BlobVerificationStatus{LastCompletionTime: verificationTime(168), LastJob: "verify-2", Verified: 12, Message: "verified 12 sampled blobs"},
+	},
+	{
+		"Corrupted",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		nil,
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, true, false)},
+		[]corev1.Pod{blobVerificationPod("verify-1", 0, `{"verified": 7, "corrupted": 2, "missing": 1}`)},
+		&v1.BlobVerificationStatus{
+			LastCompletionTime: verificationTime(0),
+			LastJob:            "verify-1",
+			Verified:           7,
+			Corrupted:          2,
+			Missing:            1,
+			Message:            "2 of 10 sampled blobs are corrupted and 1 are missing, see the logs of verify-1",
+		},
+	},
+	{
+		"ResultNotYetListed",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		nil,
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, true, false)},
+		[]corev1.Pod{blobVerificationPod("verify-1", 1, "")},
+		&v1.BlobVerificationStatus{},
+	},
+	{
+		"FailedKeepsLastResult",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		&v1.BlobVerificationStatus{LastCompletionTime: verificationTime(0), LastJob: "verify-1", Verified: 12, Message: "verified 12 sampled blobs"},
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, true, false), blobVerificationJob("verify-2", 168, false, true)},
+		nil,
+		&v1.BlobVerificationStatus{
+			LastCompletionTime: verificationTime(0),
+			LastJob:            "verify-2",
+			Verified:           12,
+			Failed:             true,
+			Message:            "verify-2 failed: Job was active longer than specified deadline",
+		},
+	},
+	{
+		"AlreadyReported",
+		&v1.BlobVerification{Schedule: "0 4 * * 0"},
+		&v1.BlobVerificationStatus{LastCompletionTime: verificationTime(0), LastJob: "verify-1", Verified: 12, Message: "verified 12 sampled blobs"},
+		[]batchv1.Job{blobVerificationJob("verify-1", 0, true, false)},
+		nil,
+		&v1.BlobVerificationStatus{LastCompletionTime: verificationTime(0), LastJob: "verify-1", Verified: 12, Message: "verified 12 sampled blobs"},
+	},
+}
+
+func TestNextBlobVerificationStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range nextBlobVerificationStatusTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec:       v1.QuayRegistrySpec{BlobVerification: test.spec},
+			Status:     v1.QuayRegistryStatus{BlobVerification: test.existing},
+		}
+
+		assert.Equal(test.expected, nextBlobVerificationStatus(quay, test.jobs, test.pods), test.name)
+	}
+}
+
+func TestReportBlobVerification(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Spec:       v1.QuayRegistrySpec{BlobVerification: &v1.BlobVerification{Schedule: "0 4 * * 0"}},
+	}
+	job := blobVerificationJob("verify-1", 0, true, false)
+	pod := blobVerificationPod("verify-1", 0, `{"verified": 7, "corrupted": 2, "missing": 1}`)
+
+	r, stub := stubReconciler(&job, &pod)
+	assert.Nil(r.reportBlobVerification(context.Background(), quay))
+	assert.Len(stub.statusUpdated, 1)
+	assert.Equal(int32(2), quay.Status.BlobVerification.Corrupted)
+
+	assert.Nil(r.reportBlobVerification(context.Background(), quay))
+	assert.Len(stub.statusUpdated, 1, "unchanged status is not updated")
+}
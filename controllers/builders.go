@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"context"
+	goerrors "errors"
+	"net/http"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+// defaultBuilderNamespace is the namespace Quay starts builder `Jobs` in when the executor does not set
+// `BUILDER_NAMESPACE`.
+const defaultBuilderNamespace = "builder"
+
+// builderDeletionInterval is how often a deleted registry checks whether its builds are cancelled and its builder
+// `Jobs` are gone.
+const builderDeletionInterval = 5 * time.Second
+
+// buildCancellationTimeout is how long after its deletion a registry waits for its builds to be cancelled, before its
+// builder `Jobs` are deleted regardless.
+const buildCancellationTimeout = 2 * time.Minute
+
+// builderNamespacesFor returns the namespaces in which the Kubernetes executors of the `BUILD_MANAGER` in the config
+// bundle start builder `Jobs`. Other executors (such as `ec2`) run builders outside the cluster.
+func builderNamespacesFor(configBundle *corev1.Secret) []string {
+	var config struct {
+		BuildManager []interface{} `json:"BUILD_MANAGER"`
+	}
+	if err := yaml.Unmarshal(configBundle.Data["config.yaml"], &config); err != nil || len(config.BuildManager) < 2 {
+		return nil
+	}
+
+	managerConfig, err := yaml.Marshal(config.BuildManager[1])
+	if err != nil {
+		return nil
+	}
+	var manager struct {
+		Executors []struct {
+			Executor         string `json:"EXECUTOR"`
+			BuilderNamespace string `json:"BUILDER_NAMESPACE"`
+		} `json:"EXECUTORS"`
+	}
+	if err := yaml.Unmarshal(managerConfig, &manager); err != nil {
+		return nil
+	}
+
+	namespaces := []string{}
+	seen := map[string]bool{}
+	for _, executor := range manager.Executors {
+		if executor.Executor != "kubernetes" && executor.Executor != "kubernetesPodman" {
+			continue
+		}
+
+		namespace := executor.BuilderNamespace
+		if namespace == "" {
+			namespace = defaultBuilderNamespace
+		}
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	return namespaces
+}
+
+// startedBy returns true if the given builder `Job` was started by a Quay app pod of the `QuayRegistry`, which labels
+// its `Jobs` with its hostname.
+func startedBy(job *batchv1.Job, quayRegistry *v1.QuayRegistry) bool {
+	return strings.HasPrefix(job.GetLabels()["manager"], quayRegistry.GetName()+"-quay-app-")
+}
+
+// cancelBuilds cancels the unfinished builds of the registry through the given Quay API client, so that its build
+// manager stops their builders, including `ec2` instances. Returns the builds which were still unfinished, so that a
+// deleted registry can wait for them to finish.
+func (r *QuayRegistryReconciler) cancelBuilds(ctx context.Context, quayRegistry *v1.QuayRegistry, client *quay.Client) ([]quay.Build, error) {
+	builds, err := client.ListUnfinishedBuilds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notCancelled := []string{}
+	for _, build := range builds {
+		r.Log.Info("cancelling build", "repository", build.Repository.Namespace+"/"+build.Repository.Name, "build", build.ID)
+
+		err := client.CancelBuild(ctx, build)
+		var apiErr *quay.APIError
+		if quay.IsNotFound(err) {
+			continue
+		} else if goerrors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+			notCancelled = append(notCancelled, build.ID)
+		} else if err != nil {
+			return builds, err
+		}
+	}
+
+	if len(notCancelled) > 0 {
+		r.recordEvent(quayRegistry, corev1.EventTypeWarning, "BuildsNotCancelled", "Quay refused to cancel builds: "+strings.Join(notCancelled, ", "))
+	}
+
+	return builds, nil
+}
+
+// deleteBuilders deletes the builder `Jobs` started by the `QuayRegistry` in the given namespaces, along with their
+// pods. Returns the number of `Jobs` which still exist.
+func (r *QuayRegistryReconciler) deleteBuilders(ctx context.Context, quayRegistry *v1.QuayRegistry, namespaces []string) (int, error) {
+	remaining := 0
+	for _, namespace := range namespaces {
+		var jobs batchv1.JobList
+		if err := r.Client.List(ctx, &jobs, client.InNamespace(namespace), client.HasLabels{"build"}); errors.IsForbidden(err) {
+			// NOTE: Without access to the builder namespace the builders cannot be found, which must not block deletion.
+			r.Log.Info("not allowed to list builder `Jobs`, skipping", "namespace", namespace)
+			continue
+		} else if err != nil {
+			return remaining, err
+		}
+
+		for i := range jobs.Items {
+			job := &jobs.Items[i]
+			if !startedBy(job, quayRegistry) {
+				continue
+			}
+
+			remaining++
+			if !job.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			r.Log.Info("deleting builder `Job`", "job", namespace+"/"+job.GetName(), "build", job.GetLabels()["build"])
+			if err := r.Client.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+				return remaining, err
+			}
+		}
+	}
+
+	return remaining, nil
+}
+
+// ensureBuildersFinalizer adds the finalizer to a registry with builds enabled, or removes it once they are disabled.
+// Returns true if the `QuayRegistry` was updated.
+func (r *QuayRegistryReconciler) ensureBuildersFinalizer(ctx context.Context, quayRegistry *v1.QuayRegistry, configBundle *corev1.Secret) (bool, error) {
+	needed := buildsEnabled(configBundle) && quayRegistry.Spec.Mode != v1.RegistryModeMirrorWorkers
+	if needed == v1.HasFinalizer(quayRegistry, v1.BuildersFinalizer) || quayRegistry.Spec.DryRun {
+		return false, nil
+	}
+
+	updatedQuay := quayRegistry.DeepCopy()
+	if needed {
+		controllerutil.AddFinalizer(updatedQuay, v1.BuildersFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(updatedQuay, v1.BuildersFinalizer)
+	}
+
+	return true, r.Client.Update(ctx, updatedQuay)
+}
+
+// cancelDisabledBuilds cancels the builds of a registry whose builds are being disabled through the Quay API, before
+// the Quay app is reconfigured and no longer serves the build API.
+func (r *QuayRegistryReconciler) cancelDisabledBuilds(ctx context.Context, quayRegistry *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	if quayRegistry.Status.Builds == nil || quayRegistry.Spec.DryRun {
+		return nil
+	}
+	if buildsEnabled(configBundle) && quayRegistry.Spec.Mode != v1.RegistryModeMirrorWorkers {
+		return nil
+	}
+
+	client, err := r.quayAPIClient(ctx, quayRegistry)
+	if err != nil || client == nil {
+		return err
+	}
+
+	builds, err := r.cancelBuilds(ctx, quayRegistry, client)
+	if err == nil && len(builds) > 0 {
+		r.recordEvent(quayRegistry, corev1.EventTypeNormal, "BuildsCancelled", "cancelled builds of disabled builds")
+	}
+
+	return err
+}
+
+// cleanUpBuilders deletes the builder `Jobs` of a registry whose builds have been disabled, so that no build workers
+// outlive the build manager which started them.
+func (r *QuayRegistryReconciler) cleanUpBuilders(ctx context.Context, quayRegistry *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	if quayRegistry.Status.Builds == nil || quayRegistry.Spec.DryRun {
+		return nil
+	}
+	if buildsEnabled(configBundle) && quayRegistry.Spec.Mode != v1.RegistryModeMirrorWorkers {
+		return nil
+	}
+
+	remaining, err := r.deleteBuilders(ctx, quayRegistry, builderNamespacesFor(configBundle))
+	if err == nil && remaining > 0 {
+		r.recordEvent(quayRegistry, corev1.EventTypeNormal, "BuildersDeleted", "deleted builders of disabled builds")
+	}
+
+	return err
+}
+
+// finalizeBuilders cancels the builds of a deleted registry through the Quay API and waits for them to finish, up to
+// `buildCancellationTimeout`, then deletes its builder `Jobs`, and removes the finalizer once they are gone. Returns
+// true once the finalizer has been handled, so the remaining finalizers are left for the next reconcile.
+func (r *QuayRegistryReconciler) finalizeBuilders(ctx context.Context, quayRegistry *v1.QuayRegistry) (ctrl.Result, bool, error) {
+	if !v1.HasFinalizer(quayRegistry, v1.BuildersFinalizer) {
+		return ctrl.Result{}, false, nil
+	}
+
+	var configBundle corev1.Secret
+	configBundleName := types.NamespacedName{Namespace: quayRegistry.GetNamespace(), Name: quayRegistry.Spec.ConfigBundleSecret}
+	if err := r.Client.Get(ctx, configBundleName, &configBundle); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, true, err
+	}
+
+	if deleted := quayRegistry.GetDeletionTimestamp(); deleted != nil && time.Since(deleted.Time) < buildCancellationTimeout {
+		client, err := r.quayAPIClient(ctx, quayRegistry)
+		if err != nil {
+			return ctrl.Result{}, true, err
+		}
+
+		if client != nil {
+			if builds, err := r.cancelBuilds(ctx, quayRegistry, client); err != nil {
+				return ctrl.Result{}, true, err
+			} else if len(builds) > 0 {
+				return ctrl.Result{RequeueAfter: builderDeletionInterval}, true, nil
+			}
+		}
+	}
+
+	remaining, err := r.deleteBuilders(ctx, quayRegistry, builderNamespacesFor(&configBundle))
+	if err != nil {
+		return ctrl.Result{}, true, err
+	} else if remaining > 0 {
+		return ctrl.Result{RequeueAfter: builderDeletionInterval}, true, nil
+	}
+
+	r.Log.Info("builders deleted, removing finalizer")
+	updatedQuay := quayRegistry.DeepCopy()
+	controllerutil.RemoveFinalizer(updatedQuay, v1.BuildersFinalizer)
+
+	return ctrl.Result{}, true, r.Client.Update(ctx, updatedQuay)
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+var builderNamespacesForTests = []struct {
+	name     string
+	config   string
+	expected []string
+}{
+	{
+		"NoBuildManager",
+		"FEATURE_BUILD_SUPPORT: true\n",
+		nil,
+	},
+	{
+		"DefaultNamespace",
+		"BUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: kubernetes\n",
+		[]string{"builder"},
+	},
+	{
+		"ExecutorNamespaces",
+		"BUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: kubernetesPodman\n    BUILDER_NAMESPACE: builds\n  - EXECUTOR: kubernetes\n    BUILDER_NAMESPACE: builds\n  - EXECUTOR: kubernetes\n    BUILDER_NAMESPACE: more-builds\n",
+		[]string{"builds", "more-builds"},
+	},
+	{
+		"EC2Executor",
+		"BUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: ec2\n",
+		[]string{},
+	},
+}
+
+func TestBuilderNamespacesFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range builderNamespacesForTests {
+		configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte(test.config)}}
+
+		assert.Equal(test.expected, builderNamespacesFor(configBundle), test.name)
+	}
+}
+
+// builderJob returns a builder `Job` started by the given Quay app pod.
+func builderJob(name, namespace, manager string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{"build": name, "manager": manager},
+	}}
+}
+
+const kubernetesBuildManager = "FEATURE_BUILD_SUPPORT: true\nBUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: kubernetes\n    BUILDER_NAMESPACE: builds\n"
+
+var finalizeBuildersTests = []struct {
+	name              string
+	finalizers        []string
+	jobs              []k8sruntime.Object
+	expectedHandled   bool
+	expectedDeleted   []string
+	expectedFinalized bool
+}{
+	{
+		"NoFinalizer",
+		[]string{v1.ObjectBucketClaimFinalizer},
+		[]k8sruntime.Object{builderJob("build-1", "builds", "test-quay-app-abc-123")},
+		false,
+		nil,
+		false,
+	},
+	{
+		"RunningBuilders",
+		[]string{v1.BuildersFinalizer},
+		[]k8sruntime.Object{
+			builderJob("build-1", "builds", "test-quay-app-abc-123"),
+			builderJob("build-2", "builds", "other-quay-app-abc-123"),
+			builderJob("build-3", "ns-1", "test-quay-app-abc-123"),
+		},
+		true,
+		[]string{"build-1"},
+		false,
+	},
+	{
+		"NoBuilders",
+		[]string{v1.BuildersFinalizer},
+		[]k8sruntime.Object{builderJob("build-2", "builds", "other-quay-app-abc-123")},
+		true,
+		nil,
+		true,
+	},
+}
+
+func TestFinalizeBuilders(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range finalizeBuildersTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1", Finalizers: test.finalizers},
+			Spec:       v1.QuayRegistrySpec{ConfigBundleSecret: "test-config-bundle"},
+		}
+		configBundle := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-config-bundle", Namespace: "ns-1"},
+			Data:       map[string][]byte{"config.yaml": []byte(kubernetesBuildManager)},
+		}
+		r, stub := stubReconciler(append(test.jobs, configBundle)...)
+
+		result, handled, err := r.finalizeBuilders(context.Background(), quay)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expectedHandled, handled, test.name)
+
+		var deleted []string
+		for _, obj := range stub.deleted {
+			deleted = append(deleted, obj.(*batchv1.Job).GetName())
+		}
+		assert.Equal(test.expectedDeleted, deleted, test.name)
+		assert.Equal(len(test.expectedDeleted) > 0, result.RequeueAfter > 0, test.name)
+
+		if test.expectedFinalized {
+			assert.Len(stub.updated, 1, test.name)
+			assert.False(v1.HasFinalizer(stub.updated[0].(*v1.QuayRegistry), v1.BuildersFinalizer), test.name)
+		} else {
+			assert.Empty(stub.updated, test.name)
+		}
+	}
+}
+
+var cleanUpBuildersTests = []struct {
+	name            string
+	config          string
+	builds          *v1.BuildStatus
+	expectedDeleted int
+}{
+	{"BuildsEnabled", kubernetesBuildManager, &v1.BuildStatus{}, 0},
+	{"NeverEnabled", "FEATURE_BUILD_SUPPORT: false\nBUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: kubernetes\n    BUILDER_NAMESPACE: builds\n", nil, 0},
+	{"BuildsDisabled", "FEATURE_BUILD_SUPPORT: false\nBUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: kubernetes\n    BUILDER_NAMESPACE: builds\n", &v1.BuildStatus{}, 1},
+}
+
+func TestCleanUpBuilders(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range cleanUpBuildersTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Status:     v1.QuayRegistryStatus{Builds: test.builds},
+		}
+		configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte(test.config)}}
+		r, stub := stubReconciler(builderJob("build-1", "builds", "test-quay-app-abc-123"))
+
+		assert.Nil(r.cleanUpBuilders(context.Background(), quay, configBundle), test.name)
+		assert.Len(stub.deleted, test.expectedDeleted, test.name)
+	}
+}
+
+func TestCancelBuilds(t *testing.T) {
+	assert := assert.New(t)
+
+	cancelled := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/superuser/users/":
+			_, _ = w.Write([]byte(`{"users": []}`))
+		case "GET /api/v1/superuser/organizations/":
+			_, _ = w.Write([]byte(`{"organizations": [{"name": "org"}]}`))
+		case "GET /api/v1/repository":
+			_, _ = w.Write([]byte(`{"repositories": [{"namespace": "org", "name": "app"}]}`))
+		case "GET /api/v1/repository/org/app/build/":
+			_, _ = w.Write([]byte(`{"builds": [{"id": "queued", "phase": "waiting"}, {"id": "running", "phase": "building"}, {"id": "gone", "phase": "pushing"}, {"id": "done", "phase": "complete"}]}`))
+		case "DELETE /api/v1/repository/org/app/build/queued":
+			cancelled = append(cancelled, "queued")
+			w.WriteHeader(http.StatusNoContent)
+		case "DELETE /api/v1/repository/org/app/build/running":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message": "Request could not be completed"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	quayRegistry := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"}}
+	r, _ := stubReconciler()
+	builds, err := r.cancelBuilds(context.Background(), quayRegistry, quay.NewClient(server.URL, "abc123", true))
+
+	assert.Nil(err)
+	assert.Len(builds, 3, "unfinished builds are waited for")
+	assert.Equal([]string{"queued"}, cancelled)
+}
+
+var ensureBuildersFinalizerTests = []struct {
+	name     string
+	config   string
+	expected bool
+}{
+	{"BuildsDisabled", "FEATURE_BUILD_SUPPORT: false\n", false},
+	{"KubernetesExecutor", kubernetesBuildManager, true},
+	{"EC2Executor", "FEATURE_BUILD_SUPPORT: true\nBUILD_MANAGER:\n- ephemeral\n- EXECUTORS:\n  - EXECUTOR: ec2\n", true},
+}
+
+func TestEnsureBuildersFinalizer(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range ensureBuildersFinalizerTests {
+		quayRegistry := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"}}
+		configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte(test.config)}}
+		r, stub := stubReconciler()
+
+		updated, err := r.ensureBuildersFinalizer(context.Background(), quayRegistry, configBundle)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expected, updated, test.name)
+		if test.expected {
+			assert.True(v1.HasFinalizer(stub.updated[0].(*v1.QuayRegistry), v1.BuildersFinalizer), test.name)
+		}
+	}
+}
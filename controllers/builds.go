@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+// buildPollInterval is how often a `QuayRegistry` with builds enabled is requeued to refresh `status.builds`.
+const buildPollInterval = time.Minute
+
+// buildsEnabled returns true if `FEATURE_BUILD_SUPPORT` is enabled in the config bundle.
+func buildsEnabled(configBundle *corev1.Secret) bool {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configBundle.Data["config.yaml"], &config); err != nil {
+		return false
+	}
+	enabled, ok := config["FEATURE_BUILD_SUPPORT"].(bool)
+
+	return ok && enabled
+}
+
+// buildStatusFor returns the `status.builds` reported for the given build queue sizes.
+func buildStatusFor(metrics *quay.BuildQueueMetrics) *v1.BuildStatus {
+	return &v1.BuildStatus{
+		Queued:            metrics.Queued,
+		Active:            metrics.Active,
+		BuildersAvailable: metrics.Queued == 0 || metrics.Active > 0,
+	}
+}
+
+// fetchBuildQueue returns the sizes of the build queue from the builds listed by the Quay API, or from the metrics of
+// the Quay app if the registry has no Quay API token.
+func (r *QuayRegistryReconciler) fetchBuildQueue(ctx context.Context, quayRegistry *v1.QuayRegistry) (*quay.BuildQueueMetrics, error) {
+	client, err := r.quayAPIClient(ctx, quayRegistry)
+	if err != nil {
+		return nil, err
+	} else if client == nil {
+		return quay.FetchBuildQueueMetrics(ctx, quay.MetricsEndpointFor(quayRegistry))
+	}
+
+	builds, err := client.ListUnfinishedBuilds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return quay.BuildQueueFor(builds), nil
+}
+
+// reportBuilds updates `status.builds` from the Quay API or the metrics of the running Quay app. Returns true if builds are enabled,
+// so the registry should be polled again.
+func (r *QuayRegistryReconciler) reportBuilds(ctx context.Context, quayRegistry *v1.QuayRegistry, configBundle *corev1.Secret) (bool, error) {
+	var status *v1.BuildStatus
+	enabled := buildsEnabled(configBundle) && quayRegistry.Spec.Mode != v1.RegistryModeMirrorWorkers
+
+	if enabled {
+		available := v1.GetCondition(quayRegistry.Status.Conditions, v1.ConditionTypeAvailable)
+		if available == nil || available.Status != metav1.ConditionTrue {
+			return true, nil
+		}
+
+		metrics, err := r.fetchBuildQueue(ctx, quayRegistry)
+		if err != nil {
+			return true, err
+		}
+		status = buildStatusFor(metrics)
+	}
+
+	if reflect.DeepEqual(status, quayRegistry.Status.Builds) {
+		return enabled, nil
+	}
+
+	quayRegistry.Status.Builds = status
+	if err := r.Client.Status().Update(ctx, quayRegistry); err != nil {
+		return enabled, err
+	}
+
+	if status != nil && !status.BuildersAvailable {
+		r.recordEvent(quayRegistry, corev1.EventTypeWarning, "BuildersUnavailable", "builds are queued but no builder is running them")
+	}
+
+	return enabled, nil
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// withBuildTriggerFiles returns a copy of the config bundle including the OAuth credentials of the Git providers in
+// `spec.buildTriggers`.
+func (r *QuayRegistryReconciler) withBuildTriggerFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	triggers := quay.Spec.BuildTriggers
+	if triggers == nil {
+		return configBundle, nil
+	}
+
+	providers := map[string]*v1.BuildTriggerProvider{
+		"github":    triggers.GitHub,
+		"gitlab":    triggers.GitLab,
+		"bitbucket": triggers.Bitbucket,
+	}
+
+	credentials := map[string]kustomize.BuildTriggerCredentials{}
+	for name, provider := range providers {
+		if provider == nil || provider.CredentialsSecret == "" {
+			continue
+		}
+
+		var credentialsSecret corev1.Secret
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: provider.CredentialsSecret}, &credentialsSecret); err != nil {
+			return nil, err
+		}
+
+		credentials[name] = kustomize.BuildTriggerCredentials{
+			ClientID:     string(credentialsSecret.Data["clientId"]),
+			ClientSecret: string(credentialsSecret.Data["clientSecret"]),
+		}
+	}
+
+	credentialsFile, err := yaml.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	withFiles := configBundle.DeepCopy()
+	withFiles.Data[kustomize.BuildTriggerCredentialsFile] = credentialsFile
+
+	return withFiles, nil
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/certificates"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// certificateExpiry is the expiry of every certificate used by each registry, so alerts can be defined in Prometheus.
+var certificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "quay_operator_certificate_expiry_timestamp_seconds",
+	Help: "Unix time when a certificate used by a QuayRegistry expires.",
+}, []string{"namespace", "quayregistry", "certificate", "source"})
+
+// reportedCertificates are the label values reported for each registry, so they can be removed once the certificate
+// or registry is gone.
+var reportedCertificates = struct {
+	sync.Mutex
+	labels map[types.NamespacedName][]prometheus.Labels
+}{labels: map[types.NamespacedName][]prometheus.Labels{}}
+
+func init() {
+	metrics.Registry.MustRegister(certificateExpiry)
+}
+
+// reportCertificateExpiry sets the expiry metrics of the given certificates, replacing those previously reported for
+// the registry.
+func reportCertificateExpiry(name types.NamespacedName, certs []certificates.Certificate) {
+	reportedCertificates.Lock()
+	defer reportedCertificates.Unlock()
+
+	for _, labels := range reportedCertificates.labels[name] {
+		certificateExpiry.Delete(labels)
+	}
+
+	reported := []prometheus.Labels{}
+	for _, cert := range certs {
+		labels := prometheus.Labels{
+			"namespace":    name.Namespace,
+			"quayregistry": name.Name,
+			"certificate":  cert.Name,
+			"source":       string(cert.Source),
+		}
+		certificateExpiry.With(labels).Set(float64(cert.NotAfter.Unix()))
+		reported = append(reported, labels)
+	}
+
+	if len(reported) == 0 {
+		delete(reportedCertificates.labels, name)
+		return
+	}
+	reportedCertificates.labels[name] = reported
+}
+
+// forgetCertificateExpiry removes the expiry metrics of a deleted registry.
+func forgetCertificateExpiry(name types.NamespacedName) {
+	reportCertificateExpiry(name, nil)
+}
+
+// checkCertificates reports the expiry of the certificates in the rendered config bundle, returning a message
+// describing those within the expiry threshold of the registry, or an empty string if there are none.
+func (r *QuayRegistryReconciler) checkCertificates(quay *v1.QuayRegistry, objects []k8sruntime.Object, configBundle *corev1.Secret) string {
+	name := types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName()}
+
+	rendered := kustomize.ConfigSecretFor(objects)
+	if rendered == nil {
+		forgetCertificateExpiry(name)
+		return ""
+	}
+
+	certs, errs := certificates.Find(rendered.Data, configBundle.Data)
+	for _, err := range errs {
+		r.Log.Info("could not check certificate expiry", "quayregistry", name, "error", err.Error())
+	}
+	reportCertificateExpiry(name, certs)
+
+	now := time.Now()
+	expiring := certificates.Expiring(certs, now, v1.CertificateExpiryThresholdFor(quay))
+	if len(expiring) == 0 {
+		return ""
+	}
+
+	descriptions := []string{}
+	for _, cert := range expiring {
+		if cert.NotAfter.Before(now) {
+			descriptions = append(descriptions, "`"+cert.Name+"` expired at "+cert.NotAfter.UTC().Format(time.RFC3339))
+		} else {
+			descriptions = append(descriptions, "`"+cert.Name+"` expires at "+cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	sort.Strings(descriptions)
+
+	return "certificates expiring: " + strings.Join(descriptions, ", ")
+}
+
+// withCertificateExpiry marks the registry as `Degraded` in the given conditions if a certificate is expiring.
+func withCertificateExpiry(conditions []v1.Condition, expiring string) []v1.Condition {
+	if expiring == "" {
+		return conditions
+	}
+
+	for i := range conditions {
+		if conditions[i].Type == v1.ConditionTypeDegraded {
+			conditions[i] = v1.Condition{
+				Type:    v1.ConditionTypeDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  v1.ConditionReasonCertificateExpiring,
+				Message: expiring,
+			}
+		}
+	}
+
+	return conditions
+}
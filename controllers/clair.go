@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/webhookcert"
+)
+
+// withClairCertificate returns a copy of the config bundle including the CA of the certificate the managed Clair is
+// served with over HTTPS.
+func (r *QuayRegistryReconciler) withClairCertificate(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if !v1.ClairTLSEnabled(quay) {
+		return configBundle, nil
+	}
+
+	generated, err := r.serviceCertificate(ctx, quay, quay.GetName()+"-clair", v1.ClairTLSSecretFor(quay), "clair")
+	if err != nil {
+		return nil, err
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+	withFiles.Data[kustomize.ClairCABundleFile] = generated[webhookcert.CAKey]
+
+	return withFiles, nil
+}
+
+// serviceCertificate returns the certificate the given `Service` of a managed component is served with. The
+// certificate is generated the first time it is needed, and rotated before it expires, in a `Secret` owned by the
+// `QuayRegistry` but not rendered by `Inflate`.
+func (r *QuayRegistryReconciler) serviceCertificate(ctx context.Context, quay *v1.QuayRegistry, service, secretName, component string) (map[string][]byte, error) {
+	var tlsSecret corev1.Secret
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: secretName}, &tlsSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if !webhookcert.NeedsRotation(tlsSecret.Data, service, quay.GetNamespace(), time.Now()) {
+		return tlsSecret.Data, nil
+	}
+
+	generated, err := webhookcert.Generate(service, quay.GetNamespace(), tlsSecret.Data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !quay.Spec.DryRun {
+		r.Log.Info("generating certificate of managed component", "component", component, "secret", secretName)
+		if err := r.createOrUpdateObject(ctx, tlsSecretFor(quay, secretName, component, generated), *quay); err != nil {
+			return nil, err
+		}
+	}
+
+	return generated, nil
+}
+
+// tlsSecretFor returns the `Secret` storing the given certificate of a managed component.
+func tlsSecretFor(quay *v1.QuayRegistry, name, component string, data map[string][]byte) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": component},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "QuayRegistry",
+					Name:       quay.GetName(),
+					UID:        quay.GetUID(),
+				},
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: data,
+	}
+	secret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+
+	return secret
+}
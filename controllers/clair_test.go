@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/webhookcert"
+)
+
+func TestWithClairCertificate(t *testing.T) {
+	assert := assert.New(t)
+
+	existing, err := webhookcert.Generate("test-clair", "ns-1", nil, time.Now())
+	assert.Nil(err)
+
+	for _, test := range []struct {
+		name              string
+		clair             *v1.ClairSettings
+		objects           []k8sruntime.Object
+		expectedGenerated bool
+	}{
+		{"Disabled", nil, nil, false},
+		{"Generated", &v1.ClairSettings{TLS: true}, nil, true},
+		{
+			"Existing",
+			&v1.ClairSettings{TLS: true},
+			[]k8sruntime.Object{&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-clair-tls", Namespace: "ns-1"}, Data: existing}},
+			false,
+		},
+	} {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec: v1.QuayRegistrySpec{
+				Components: []v1.Component{{Kind: "clair", Managed: true}},
+				Clair:      test.clair,
+			},
+		}
+		r, stub := stubReconciler(test.objects...)
+
+		withFiles, err := r.withClairCertificate(context.Background(), quay, &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte("")}})
+		assert.Nil(err, test.name)
+
+		if test.clair == nil {
+			assert.NotContains(withFiles.Data, kustomize.ClairCABundleFile, test.name)
+			continue
+		}
+		if test.expectedGenerated {
+			assert.Len(stub.patched, 1, test.name)
+			assert.Equal(stub.patched[0].(*corev1.Secret).Data[webhookcert.CAKey], withFiles.Data[kustomize.ClairCABundleFile], test.name)
+		} else {
+			assert.Empty(stub.patched, test.name)
+			assert.Equal(existing[webhookcert.CAKey], withFiles.Data[kustomize.ClairCABundleFile], test.name)
+		}
+	}
+}
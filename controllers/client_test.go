@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	testlogr "github.com/go-logr/logr/testing"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubClient serves `Get` and `List` requests from the given objects and records the objects it is asked to `Create`,
+// `Patch`, `Update` or `Delete`, including status updates. Any other request panics, since the embedded `client.Client` is nil.
+type stubClient struct {
+	client.Client
+	objects       []k8sruntime.Object
+	created       []k8sruntime.Object
+	patched       []k8sruntime.Object
+	updated       []k8sruntime.Object
+	deleted       []k8sruntime.Object
+	statusUpdated []k8sruntime.Object
+}
+
+func (c *stubClient) Get(ctx context.Context, key client.ObjectKey, obj k8sruntime.Object) error {
+	for _, existing := range c.objects {
+		objectMeta, _ := meta.Accessor(existing)
+		if reflect.TypeOf(existing) == reflect.TypeOf(obj) && (types.NamespacedName{Namespace: objectMeta.GetNamespace(), Name: objectMeta.GetName()}) == key {
+			reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(existing.DeepCopyObject()).Elem())
+			return nil
+		}
+	}
+
+	return errors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func (c *stubClient) List(ctx context.Context, list k8sruntime.Object, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	itemType := reflect.PtrTo(reflect.ValueOf(list).Elem().FieldByName("Items").Type().Elem())
+	items := []k8sruntime.Object{}
+	for _, existing := range c.objects {
+		objectMeta, _ := meta.Accessor(existing)
+		if reflect.TypeOf(existing) != itemType || (listOpts.Namespace != "" && objectMeta.GetNamespace() != listOpts.Namespace) {
+			continue
+		}
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(objectMeta.GetLabels())) {
+			continue
+		}
+		items = append(items, existing.DeepCopyObject())
+	}
+
+	return meta.SetList(list, items)
+}
+
+func (c *stubClient) Create(ctx context.Context, obj k8sruntime.Object, opts ...client.CreateOption) error {
+	c.created = append(c.created, obj)
+
+	return nil
+}
+
+func (c *stubClient) Patch(ctx context.Context, obj k8sruntime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.patched = append(c.patched, obj)
+
+	return nil
+}
+
+func (c *stubClient) Update(ctx context.Context, obj k8sruntime.Object, opts ...client.UpdateOption) error {
+	c.updated = append(c.updated, obj)
+
+	return nil
+}
+
+func (c *stubClient) Delete(ctx context.Context, obj k8sruntime.Object, opts ...client.DeleteOption) error {
+	c.deleted = append(c.deleted, obj)
+
+	return nil
+}
+
+func (c *stubClient) Status() client.StatusWriter {
+	return &stubStatusWriter{client: c}
+}
+
+// stubStatusWriter records the objects whose status it is asked to `Update` or `Patch`.
+type stubStatusWriter struct {
+	client *stubClient
+}
+
+func (w *stubStatusWriter) Update(ctx context.Context, obj k8sruntime.Object, opts ...client.UpdateOption) error {
+	w.client.statusUpdated = append(w.client.statusUpdated, obj)
+
+	return nil
+}
+
+func (w *stubStatusWriter) Patch(ctx context.Context, obj k8sruntime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.statusUpdated = append(w.client.statusUpdated, obj)
+
+	return nil
+}
+
+// stubReconciler returns a `QuayRegistryReconciler` whose client serves the given objects.
+func stubReconciler(objects ...k8sruntime.Object) (*QuayRegistryReconciler, *stubClient) {
+	stub := &stubClient{objects: objects}
+
+	return &QuayRegistryReconciler{Client: stub, Log: testlogr.TestLogger{}}, stub
+}
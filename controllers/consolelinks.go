@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	consoleLinkSection = "Quay Registries"
+	// consoleLinkNamespaceLabel and consoleLinkNameLabel identify the `QuayRegistry` a cluster-scoped `ConsoleLink`
+	// belongs to, since it cannot have an owner reference to a namespaced object.
+	consoleLinkNamespaceLabel = "quay-operator/quayregistry-namespace"
+	consoleLinkNameLabel      = "quay-operator/quayregistry-name"
+)
+
+var consoleLinkGVK = schema.GroupVersionKind{Group: "console.openshift.io", Version: "v1", Kind: "ConsoleLink"}
+
+// +kubebuilder:rbac:groups=console.openshift.io,resources=consolelinks,verbs=get;list;watch;create;update;patch;delete;deletecollection
+
+// consoleLinksFor returns a `ConsoleLink` in the OpenShift console application menu for each of the registry's
+// endpoints which are known.
+func consoleLinksFor(quay *v1.QuayRegistry) []*unstructured.Unstructured {
+	endpoints := []struct {
+		suffix string
+		text   string
+		href   string
+	}{
+		{"registry", "Quay Registry", quay.Status.RegistryEndpoint},
+		{"config-editor", "Quay Config Editor", quay.Status.ConfigEditorEndpoint},
+	}
+
+	links := []*unstructured.Unstructured{}
+	for _, endpoint := range endpoints {
+		if endpoint.href == "" {
+			continue
+		}
+
+		href := endpoint.href
+		if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") {
+			href = "https://" + href
+		}
+
+		link := &unstructured.Unstructured{}
+		link.SetGroupVersionKind(consoleLinkGVK)
+		link.SetName(strings.Join([]string{"quay", quay.GetNamespace(), quay.GetName(), endpoint.suffix}, "-"))
+		link.SetLabels(map[string]string{
+			consoleLinkNamespaceLabel: quay.GetNamespace(),
+			consoleLinkNameLabel:      quay.GetName(),
+		})
+		link.Object["spec"] = map[string]interface{}{
+			"text":     endpoint.text + " (" + quay.GetNamespace() + "/" + quay.GetName() + ")",
+			"href":     href,
+			"location": "ApplicationMenu",
+			"applicationMenu": map[string]interface{}{
+				"section": consoleLinkSection,
+			},
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// reconcileConsoleLinks creates or updates the `ConsoleLinks` for the registry. Does nothing when not running
+// on OpenShift or when the console is not installed.
+func (r *QuayRegistryReconciler) reconcileConsoleLinks(ctx context.Context, quay *v1.QuayRegistry) error {
+	if _, ok := quay.GetAnnotations()[v1.SupportsRoutesAnnotation]; !ok {
+		return nil
+	}
+
+	for _, link := range consoleLinksFor(quay) {
+		err := r.Client.Patch(ctx, link, client.Apply, client.ForceOwnership, client.FieldOwner("quay-operator"))
+		if meta.IsNoMatchError(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteConsoleLinks removes the `ConsoleLinks` created for a deleted `QuayRegistry`.
+func (r *QuayRegistryReconciler) deleteConsoleLinks(ctx context.Context, quay types.NamespacedName) error {
+	link := &unstructured.Unstructured{}
+	link.SetGroupVersionKind(consoleLinkGVK)
+
+	err := r.Client.DeleteAllOf(ctx, link, client.MatchingLabels{
+		consoleLinkNamespaceLabel: quay.Namespace,
+		consoleLinkNameLabel:      quay.Name,
+	})
+	if meta.IsNoMatchError(err) {
+		return nil
+	}
+
+	return err
+}
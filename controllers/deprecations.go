@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// deprecatedConfigCondition returns the `ConfigDeprecated` condition for the fields of the config bundle which the
+// desired Quay version no longer supports, or nil if the condition should be left as is.
+func deprecatedConfigCondition(quay *v1.QuayRegistry, configBundle *corev1.Secret) *v1.Condition {
+	config, err := effectiveConfigFor(quay, configBundle)
+	if err != nil {
+		// NOTE: An invalid config bundle is reported when the registry is inflated.
+		return nil
+	}
+
+	warnings := kustomize.DeprecatedConfigFor(quay, config)
+	if len(warnings) == 0 {
+		if v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeConfigDeprecated) == nil {
+			return nil
+		}
+
+		return &v1.Condition{
+			Type:   v1.ConditionTypeConfigDeprecated,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonNoDeprecatedConfig,
+		}
+	}
+
+	return &v1.Condition{
+		Type:    v1.ConditionTypeConfigDeprecated,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ConditionReasonDeprecatedConfig,
+		Message: strings.Join(warnings, "; "),
+	}
+}
+
+// reportDeprecatedConfig reports the fields of the config bundle which the desired Quay version no longer supports in
+// the `ConfigDeprecated` condition, recording a `Warning` `Event` whenever they change.
+func (r *QuayRegistryReconciler) reportDeprecatedConfig(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	condition := deprecatedConfigCondition(quay, configBundle)
+	if condition == nil {
+		return nil
+	}
+
+	previousMessage := ""
+	if existing := v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeConfigDeprecated); existing != nil {
+		previousMessage = existing.Message
+	}
+
+	if err := r.updateConditions(ctx, quay, *condition); err != nil {
+		return err
+	}
+
+	if condition.Status == metav1.ConditionTrue && condition.Message != previousMessage {
+		r.recordEvent(quay, corev1.EventTypeWarning, v1.ConditionReasonDeprecatedConfig, condition.Message)
+	}
+
+	return nil
+}
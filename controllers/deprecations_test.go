@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var deprecatedConfigConditionTests = []struct {
+	name       string
+	config     string
+	conditions []v1.Condition
+	expected   *v1.Condition
+}{
+	{
+		"NoDeprecatedFields",
+		"SERVER_HOSTNAME: quay.io\n",
+		nil,
+		nil,
+	},
+	{
+		"DeprecatedFields",
+		"SERVER_HOSTNAME: quay.io\nSECURITY_SCANNER_ENDPOINT: http://clair\n",
+		nil,
+		&v1.Condition{
+			Type:    v1.ConditionTypeConfigDeprecated,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ConditionReasonDeprecatedConfig,
+			Message: "`SECURITY_SCANNER_ENDPOINT` is removed in Quay version `vader` and is ignored",
+		},
+	},
+	{
+		"DeprecatedFieldsRemoved",
+		"SERVER_HOSTNAME: quay.io\n",
+		[]v1.Condition{{Type: v1.ConditionTypeConfigDeprecated, Status: metav1.ConditionTrue, Reason: v1.ConditionReasonDeprecatedConfig}},
+		&v1.Condition{
+			Type:   v1.ConditionTypeConfigDeprecated,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonNoDeprecatedConfig,
+		},
+	},
+	{
+		"InvalidConfig",
+		"SERVER_HOSTNAME: [",
+		nil,
+		nil,
+	},
+}
+
+func TestDeprecatedConfigCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range deprecatedConfigConditionTests {
+		quay := &v1.QuayRegistry{
+			Spec:   v1.QuayRegistrySpec{DesiredVersion: v1.QuayVersionVader},
+			Status: v1.QuayRegistryStatus{Conditions: test.conditions},
+		}
+		configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte(test.config)}}
+
+		assert.Equal(test.expected, deprecatedConfigCondition(quay, configBundle), test.name)
+	}
+}
+
+func TestReportDeprecatedConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Spec:       v1.QuayRegistrySpec{DesiredVersion: v1.QuayVersionVader},
+	}
+	configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte("FEATURE_BITTORRENT: true\n")}}
+	r, stub := stubReconciler(quay)
+
+	assert.Nil(r.reportDeprecatedConfig(context.Background(), quay, configBundle))
+	assert.Len(stub.statusUpdated, 1)
+	assert.Equal(metav1.ConditionTrue, v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeConfigDeprecated).Status)
+
+	assert.Nil(r.reportDeprecatedConfig(context.Background(), quay, configBundle))
+	assert.Len(stub.statusUpdated, 1)
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/drift"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// driftCheckInterval is how often a `QuayRegistry` is requeued to compare live objects against the desired state.
+const driftCheckInterval = time.Minute * 5
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// detectDrift compares each object with its live counterpart, recording an `Event` for every object which
+// was modified or deleted outside of the Operator, and returns the drifted objects.
+func (r *QuayRegistryReconciler) detectDrift(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) []k8sruntime.Object {
+	log := r.Log.WithValues("quayregistry", quay.GetNamespace()+"/"+quay.GetName())
+
+	drifted := []k8sruntime.Object{}
+	for _, obj := range objects {
+		objectMeta, err := meta.Accessor(obj)
+		if err != nil {
+			continue
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		live, err := kustomize.ModelFor(gvk)
+		if err != nil {
+			log.Error(err, "unable to compare live object for drift detection", "object", gvk.Kind+"/"+objectMeta.GetName())
+			continue
+		}
+		description := gvk.Kind + "/" + objectMeta.GetName()
+
+		err = r.Client.Get(ctx, types.NamespacedName{Namespace: objectMeta.GetNamespace(), Name: objectMeta.GetName()}, live)
+		if errors.IsNotFound(err) {
+			r.recordEvent(quay, corev1.EventTypeWarning, v1.ConditionReasonManagedObjectsDrifted, description+" was deleted")
+			drifted = append(drifted, obj)
+			continue
+		} else if err != nil {
+			log.Error(err, "unable to retrieve live object for drift detection", "object", description)
+			continue
+		}
+
+		report, err := drift.Detect(obj, live)
+		if err != nil {
+			log.Error(err, "unable to compare live object for drift detection", "object", description)
+			continue
+		}
+
+		if report.Drifted() {
+			r.recordEvent(quay, corev1.EventTypeWarning, v1.ConditionReasonManagedObjectsDrifted, description+" "+report.String())
+			drifted = append(drifted, obj)
+		}
+	}
+
+	return drifted
+}
+
+// driftCondition returns the `Drifted` condition describing the result of drift detection.
+func driftCondition(quay *v1.QuayRegistry, drifted []k8sruntime.Object) v1.Condition {
+	if len(drifted) == 0 {
+		return v1.Condition{
+			Type:   v1.ConditionTypeDrifted,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonNoDrift,
+		}
+	}
+
+	if quay.Spec.DriftPolicy != v1.DriftPolicyDetectOnly {
+		return v1.Condition{
+			Type:    v1.ConditionTypeDrifted,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1.ConditionReasonDriftRemediated,
+			Message: "reverted changes to: " + describeObjects(drifted),
+		}
+	}
+
+	return v1.Condition{
+		Type:    v1.ConditionTypeDrifted,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ConditionReasonManagedObjectsDrifted,
+		Message: "managed objects modified outside of the Operator: " + describeObjects(drifted),
+	}
+}
+
+func describeObjects(objects []k8sruntime.Object) string {
+	descriptions := []string{}
+	for _, obj := range objects {
+		objectMeta, _ := meta.Accessor(obj)
+		descriptions = append(descriptions, obj.GetObjectKind().GroupVersionKind().Kind+"/"+objectMeta.GetName())
+	}
+
+	return strings.Join(descriptions, ", ")
+}
+
+// recordEvent records a Kubernetes `Event` on the `QuayRegistry` if an `EventRecorder` is configured.
+func (r *QuayRegistryReconciler) recordEvent(quay *v1.QuayRegistry, eventType, reason, message string) {
+	if r.EventRecorder == nil {
+		return
+	}
+
+	r.EventRecorder.Event(quay, eventType, reason, message)
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/drift"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// planChanges compares each object with its live counterpart and returns the changes applying them would make.
+func (r *QuayRegistryReconciler) planChanges(ctx context.Context, objects []k8sruntime.Object) ([]v1.PlannedChange, error) {
+	changes := []v1.PlannedChange{}
+	for _, obj := range objects {
+		objectMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		live, err := kustomize.ModelFor(gvk)
+		if err != nil {
+			return nil, err
+		}
+		description := gvk.Kind + "/" + objectMeta.GetName()
+
+		err = r.Client.Get(ctx, types.NamespacedName{Namespace: objectMeta.GetNamespace(), Name: objectMeta.GetName()}, live)
+		if errors.IsNotFound(err) {
+			changes = append(changes, v1.PlannedChange{Action: v1.PlannedActionCreate, Object: description})
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		report, err := drift.Detect(obj, live)
+		if err != nil {
+			return nil, err
+		}
+		if report.Drifted() {
+			changes = append(changes, v1.PlannedChange{Action: v1.PlannedActionUpdate, Object: description, Fields: report.Fields})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Object < changes[j].Object })
+
+	return changes, nil
+}
+
+// reportPlannedChanges stores the planned changes in `status.plannedChanges`, recording an `Event` when they change.
+func (r *QuayRegistryReconciler) reportPlannedChanges(ctx context.Context, quay *v1.QuayRegistry, changes []v1.PlannedChange) error {
+	if len(changes) == 0 {
+		changes = nil
+	}
+	if reflect.DeepEqual(changes, quay.Status.PlannedChanges) {
+		return nil
+	}
+
+	quay.Status.PlannedChanges = changes
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return err
+	}
+
+	if !quay.Spec.DryRun {
+		return nil
+	}
+
+	created, updated := 0, 0
+	for _, change := range changes {
+		if change.Action == v1.PlannedActionCreate {
+			created++
+		} else {
+			updated++
+		}
+	}
+	r.recordEvent(quay, corev1.EventTypeNormal, "DryRun", fmt.Sprintf("would create %d and update %d objects, see `status.plannedChanges`", created, updated))
+
+	return nil
+}
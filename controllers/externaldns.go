@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+const (
+	// dnsPollInterval is how often a `QuayRegistry` is requeued while waiting for its hostname to resolve.
+	dnsPollInterval = 30 * time.Second
+	// dnsLookupTimeout is how long to wait for the registry hostname to resolve.
+	dnsLookupTimeout = 5 * time.Second
+)
+
+// dnsPropagationFor returns the `DNSPropagated` condition of a registry whose hostname is published by external-dns,
+// or nil if the condition should be left as is.
+func dnsPropagationFor(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) *v1.Condition {
+	if quay.Spec.ExternalDNS == nil {
+		if v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeDNSPropagated) == nil {
+			return nil
+		}
+
+		return &v1.Condition{
+			Type:   v1.ConditionTypeDNSPropagated,
+			Status: metav1.ConditionUnknown,
+			Reason: v1.ConditionReasonExternalDNSDisabled,
+		}
+	}
+
+	hostname := kustomize.RegistryHostnameFor(objects)
+
+	lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupHost(lookupCtx, hostname)
+	if err != nil || len(addresses) == 0 {
+		message := "waiting for external-dns to publish `" + hostname + "`"
+		if err != nil {
+			message += ": " + err.Error()
+		}
+
+		return &v1.Condition{
+			Type:    v1.ConditionTypeDNSPropagated,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1.ConditionReasonDNSPropagationPending,
+			Message: message,
+		}
+	}
+
+	return &v1.Condition{
+		Type:    v1.ConditionTypeDNSPropagated,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ConditionReasonDNSPropagated,
+		Message: "`" + hostname + "` resolves",
+	}
+}
+
+// withDNSPropagation adds the given `DNSPropagated` condition, reporting the registry as not yet `Available` to
+// external clients while its hostname does not resolve.
+func withDNSPropagation(conditions []v1.Condition, dnsPropagated *v1.Condition) []v1.Condition {
+	if dnsPropagated == nil {
+		return conditions
+	}
+
+	if dnsPropagated.Status == metav1.ConditionFalse {
+		for i := range conditions {
+			if conditions[i].Type == v1.ConditionTypeAvailable {
+				conditions[i] = v1.Condition{
+					Type:    v1.ConditionTypeAvailable,
+					Status:  metav1.ConditionFalse,
+					Reason:  v1.ConditionReasonDNSPropagationPending,
+					Message: dnsPropagated.Message,
+				}
+			}
+		}
+	}
+
+	return append(conditions, *dnsPropagated)
+}
@@ -7,9 +7,14 @@ import (
 	objectbucket "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	routev1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
 )
 
 const (
@@ -19,6 +24,52 @@ const (
 	datastoreSecretKey  = "AWS_SECRET_ACCESS_KEY"
 )
 
+// ingressDomainSources are the OpenShift objects the ingress domain of the cluster is read from, in order of
+// preference.
+var ingressDomainSources = []struct {
+	gvk   schema.GroupVersionKind
+	key   types.NamespacedName
+	field []string
+}{
+	{
+		schema.GroupVersionKind{Group: "operator.openshift.io", Version: "v1", Kind: "IngressController"},
+		types.NamespacedName{Namespace: "openshift-ingress-operator", Name: "default"},
+		[]string{"status", "domain"},
+	},
+	{
+		schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Ingress"},
+		types.NamespacedName{Name: "cluster"},
+		[]string{"spec", "domain"},
+	},
+}
+
+// +kubebuilder:rbac:groups=operator.openshift.io,resources=ingresscontrollers,verbs=get
+// +kubebuilder:rbac:groups=config.openshift.io,resources=ingresses,verbs=get
+
+// detectClusterHostname returns the ingress domain of the cluster from the default `IngressController` or the
+// cluster ingress config, falling back to the canonical hostname of an existing `Route`.
+func (r *QuayRegistryReconciler) detectClusterHostname(ctx context.Context, routes []routev1.Route) string {
+	for _, source := range ingressDomainSources {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(source.gvk)
+		if err := r.Client.Get(ctx, source.key, obj); err != nil {
+			continue
+		}
+
+		if domain, found, err := unstructured.NestedString(obj.Object, source.field...); err == nil && found && domain != "" {
+			return domain
+		}
+	}
+
+	for _, route := range routes {
+		if len(route.Status.Ingress) > 0 && route.Status.Ingress[0].RouterCanonicalHostname != "" {
+			return route.Status.Ingress[0].RouterCanonicalHostname
+		}
+	}
+
+	return ""
+}
+
 func (r *QuayRegistryReconciler) checkRoutesAvailable(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
 	var routes routev1.RouteList
 	err := r.Client.List(context.Background(), &routes)
@@ -31,13 +82,10 @@ func (r *QuayRegistryReconciler) checkRoutesAvailable(quay *v1.QuayRegistry) (*v
 
 		existingAnnotations[v1.SupportsRoutesAnnotation] = "true"
 
-		if _, ok := existingAnnotations[v1.ClusterHostnameAnnotation]; !ok && len(routes.Items) > 0 {
-			for _, route := range routes.Items {
-				if len(route.Status.Ingress) > 0 {
-					existingAnnotations[v1.ClusterHostnameAnnotation] = route.Status.Ingress[0].RouterCanonicalHostname
-					r.Log.Info("detected router canonical hostname: " + route.Status.Ingress[0].RouterCanonicalHostname)
-					break
-				}
+		if _, ok := existingAnnotations[v1.ClusterHostnameAnnotation]; !ok {
+			if hostname := r.detectClusterHostname(context.Background(), routes.Items); hostname != "" {
+				quay.Status.ClusterHostname = hostname
+				r.Log.Info("detected cluster ingress domain: " + hostname)
 			}
 		}
 
@@ -47,10 +95,11 @@ func (r *QuayRegistryReconciler) checkRoutesAvailable(quay *v1.QuayRegistry) (*v
 	return quay, nil
 }
 
+// checkObjectBucketClaimsAvailable marks the registry as supporting managed object storage if the
+// `ObjectBucketClaims` API is available.
 func (r *QuayRegistryReconciler) checkObjectBucketClaimsAvailable(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
-	datastoreName := types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName() + "-quay-datastore"}
 	var objectBucketClaims objectbucket.ObjectBucketClaimList
-	if err := r.Client.List(context.Background(), &objectBucketClaims); err == nil {
+	if err := r.Client.List(context.Background(), &objectBucketClaims, client.InNamespace(quay.GetNamespace())); err == nil {
 		r.Log.Info("cluster supports `ObjectBucketClaims` API")
 
 		existingAnnotations := quay.GetAnnotations()
@@ -58,39 +107,188 @@ func (r *QuayRegistryReconciler) checkObjectBucketClaimsAvailable(quay *v1.QuayR
 			existingAnnotations = map[string]string{}
 		}
 		existingAnnotations[v1.SupportsObjectStorageAnnotation] = "true"
+		quay.SetAnnotations(existingAnnotations)
+	} else {
+		r.Log.Info("cluster does not support `ObjectBucketClaim` API")
+	}
 
-		for _, obc := range objectBucketClaims.Items {
-			if obc.GetNamespace()+"/"+obc.GetName() == datastoreName.String() {
-				var datastoreSecret corev1.Secret
-				if err = r.Client.Get(context.Background(), datastoreName, &datastoreSecret); err != nil {
-					r.Log.Error(err, "unable to retrieve Quay datastore `Secret`")
-					return nil, err
-				}
-
-				var datastoreConfig corev1.ConfigMap
-				if err = r.Client.Get(context.Background(), datastoreName, &datastoreConfig); err != nil {
-					r.Log.Error(err, "unable to retrieve Quay datastore `ConfigMap`")
-					return nil, err
-				}
-
-				r.Log.Info("found `ObjectBucketClaim` and credentials `Secret`, `ConfigMap`")
-
-				host := string(datastoreConfig.Data[datastoreBucketHost])
-				if strings.Contains(host, ".svc") && !strings.Contains(host, ".svc.cluster.local") {
-					r.Log.Info("`ObjectBucketClaim` is using in-cluster endpoint, ensuring we use the fully qualified domain name")
-					host = strings.ReplaceAll(host, ".svc", ".svc.cluster.local")
-				}
-
-				existingAnnotations[v1.StorageBucketNameAnnotation] = string(datastoreConfig.Data[datastoreBucketName])
-				existingAnnotations[v1.StorageHostnameAnnotation] = host
-				existingAnnotations[v1.StorageAccessKeyAnnotation] = string(datastoreSecret.Data[datastoreAccessKey])
-				existingAnnotations[v1.StorageSecretKeyAnnotation] = string(datastoreSecret.Data[datastoreSecretKey])
-			}
+	return quay, nil
+}
+
+// +kubebuilder:rbac:groups=postgres-operator.crunchydata.com,resources=postgresclusters,verbs=get;list;watch;create;update;patch;delete
+
+// checkPostgresClustersAvailable marks the registry as supporting a managed database deployed by the Crunchy Postgres
+// Operator if the `PostgresCluster` API is available.
+func (r *QuayRegistryReconciler) checkPostgresClustersAvailable(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	postgresClusters := &unstructured.UnstructuredList{}
+	postgresClusters.SetGroupVersionKind(kustomize.PostgresClusterGVK.GroupVersion().WithKind("PostgresClusterList"))
+	if err := r.Client.List(context.Background(), postgresClusters, client.InNamespace(quay.GetNamespace())); err == nil {
+		r.Log.Info("cluster supports `PostgresCluster` API")
+
+		existingAnnotations := quay.GetAnnotations()
+		if existingAnnotations == nil {
+			existingAnnotations = map[string]string{}
 		}
+		existingAnnotations[v1.SupportsPostgresClustersAnnotation] = "true"
 		quay.SetAnnotations(existingAnnotations)
-	} else if err != nil {
-		r.Log.Info("cluster does not support `ObjectBucketClaim` API")
+	} else {
+		r.Log.Info("cluster does not support `PostgresCluster` API")
+	}
+
+	return quay, nil
+}
+
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
+
+// checkServiceMonitorsAvailable marks the registry as supporting the scraping of metrics by the Prometheus Operator if
+// the `ServiceMonitor` API is available.
+func (r *QuayRegistryReconciler) checkServiceMonitorsAvailable(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	serviceMonitors := &unstructured.UnstructuredList{}
+	serviceMonitors.SetGroupVersionKind(kustomize.ServiceMonitorGVK.GroupVersion().WithKind("ServiceMonitorList"))
+	if err := r.Client.List(context.Background(), serviceMonitors, client.InNamespace(quay.GetNamespace())); err == nil {
+		r.Log.Info("cluster supports `ServiceMonitor` API")
+
+		existingAnnotations := quay.GetAnnotations()
+		if existingAnnotations == nil {
+			existingAnnotations = map[string]string{}
+		}
+		existingAnnotations[v1.SupportsServiceMonitorsAnnotation] = "true"
+		quay.SetAnnotations(existingAnnotations)
+	} else {
+		r.Log.Info("cluster does not support `ServiceMonitor` API")
+	}
+
+	return quay, nil
+}
+
+// checkObjectBucketClaimCredentials copies the bucket name, endpoint and credentials of the bucket claimed with the
+// `ObjectBucketClaim` of the registry into annotations. It must only be called once the `QuayRegistry` will no longer
+// be updated, so the credentials are never stored in it.
+func (r *QuayRegistryReconciler) checkObjectBucketClaimCredentials(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	if !supportsObjectBucketClaims(quay) {
+		return quay, nil
+	}
+
+	datastoreName := types.NamespacedName{Namespace: quay.GetNamespace(), Name: v1.ObjectBucketClaimNameFor(quay)}
+	var obc objectbucket.ObjectBucketClaim
+	if err := r.Client.Get(context.Background(), datastoreName, &obc); err != nil {
+		if errors.IsNotFound(err) {
+			return quay, nil
+		}
+
+		r.Log.Error(err, "unable to retrieve Quay datastore `ObjectBucketClaim`")
+		return nil, err
+	}
+
+	var datastoreSecret corev1.Secret
+	if err := r.Client.Get(context.Background(), datastoreName, &datastoreSecret); err != nil {
+		r.Log.Error(err, "unable to retrieve Quay datastore `Secret`")
+		return nil, err
+	}
+
+	var datastoreConfig corev1.ConfigMap
+	if err := r.Client.Get(context.Background(), datastoreName, &datastoreConfig); err != nil {
+		r.Log.Error(err, "unable to retrieve Quay datastore `ConfigMap`")
+		return nil, err
+	}
+
+	r.Log.Info("found `ObjectBucketClaim` and credentials `Secret`, `ConfigMap`")
+
+	host := string(datastoreConfig.Data[datastoreBucketHost])
+	if strings.Contains(host, ".svc") && !strings.Contains(host, ".svc.cluster.local") {
+		r.Log.Info("`ObjectBucketClaim` is using in-cluster endpoint, ensuring we use the fully qualified domain name")
+		host = strings.ReplaceAll(host, ".svc", ".svc.cluster.local")
+	}
+
+	existingAnnotations := quay.GetAnnotations()
+	if existingAnnotations == nil {
+		existingAnnotations = map[string]string{}
+	}
+	existingAnnotations[v1.StorageBucketNameAnnotation] = string(datastoreConfig.Data[datastoreBucketName])
+	existingAnnotations[v1.StorageHostnameAnnotation] = host
+	existingAnnotations[v1.StorageAccessKeyAnnotation] = string(datastoreSecret.Data[datastoreAccessKey])
+	existingAnnotations[v1.StorageSecretKeyAnnotation] = string(datastoreSecret.Data[datastoreSecretKey])
+	quay.SetAnnotations(existingAnnotations)
+
+	return quay, nil
+}
+
+// supportsObjectBucketClaims returns true if the `ObjectBucketClaims` API was found by
+// `checkObjectBucketClaimsAvailable`.
+func supportsObjectBucketClaims(quay *v1.QuayRegistry) bool {
+	_, ok := quay.GetAnnotations()[v1.SupportsObjectStorageAnnotation]
+
+	return ok
+}
+
+// storageCredentialAnnotations are the annotations which the credentials of storage locations are copied into.
+var storageCredentialAnnotations = []string{
+	v1.StorageAccessKeyAnnotation,
+	v1.StorageSecretKeyAnnotation,
+	v1.StorageSASTokenAnnotation,
+	v1.StorageTempURLKeyAnnotation,
+}
+
+// checkObjectStorageCredentials copies the credentials of the existing bucket in `spec.objectStorage` into the same
+// annotations as those of an `ObjectBucketClaim`, or removes them if the Quay pods use their default credentials. The
+// credentials of each location in `spec.objectStorage.locations` are copied into annotations suffixed with its name.
+// Like `checkObjectBucketClaimCredentials`, it must only be called once the `QuayRegistry` will no longer be updated.
+func (r *QuayRegistryReconciler) checkObjectStorageCredentials(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	existingAnnotations := quay.GetAnnotations()
+	if existingAnnotations == nil {
+		existingAnnotations = map[string]string{}
+	}
+	for annotation := range existingAnnotations {
+		for _, credential := range storageCredentialAnnotations {
+			if strings.HasPrefix(annotation, credential+".") {
+				delete(existingAnnotations, annotation)
+			}
+		}
+	}
+
+	locations := v1.StorageLocationsFor(quay)
+	if primary := v1.PrimaryStorageLocationFor(quay); primary != nil {
+		for _, credential := range storageCredentialAnnotations {
+			delete(existingAnnotations, credential)
+		}
+		locations = append([]v1.StorageLocation{*primary}, locations...)
+	}
+
+	for _, location := range locations {
+		credentialsSecretName := location.CredentialsSecret()
+		if credentialsSecretName == "" {
+			continue
+		}
+
+		var credentialsSecret corev1.Secret
+		credentialsName := types.NamespacedName{Namespace: quay.GetNamespace(), Name: credentialsSecretName}
+		if err := r.Client.Get(context.Background(), credentialsName, &credentialsSecret); err != nil {
+			r.Log.Error(err, "unable to retrieve object storage credentials `Secret`", "location", location.Name)
+			return nil, err
+		}
+
+		annotation := func(name string) string {
+			if location.Name == v1.PrimaryStorageLocation {
+				return name
+			}
+
+			return v1.StorageLocationAnnotation(name, location.Name)
+		}
+
+		switch {
+		case location.Azure != nil:
+			existingAnnotations[annotation(v1.StorageSecretKeyAnnotation)] = string(credentialsSecret.Data["accountKey"])
+			existingAnnotations[annotation(v1.StorageSASTokenAnnotation)] = string(credentialsSecret.Data["sasToken"])
+		case location.Swift != nil:
+			existingAnnotations[annotation(v1.StorageAccessKeyAnnotation)] = string(credentialsSecret.Data["user"])
+			existingAnnotations[annotation(v1.StorageSecretKeyAnnotation)] = string(credentialsSecret.Data["password"])
+			existingAnnotations[annotation(v1.StorageTempURLKeyAnnotation)] = string(credentialsSecret.Data["tempURLKey"])
+		default:
+			existingAnnotations[annotation(v1.StorageAccessKeyAnnotation)] = string(credentialsSecret.Data["accessKey"])
+			existingAnnotations[annotation(v1.StorageSecretKeyAnnotation)] = string(credentialsSecret.Data["secretKey"])
+		}
 	}
+	quay.SetAnnotations(existingAnnotations)
 
 	return quay, nil
 }
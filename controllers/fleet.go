@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// fleetHealth is the number of registries of each health, so the fleet can be monitored at a glance.
+var fleetHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "quay_operator_quayregistries",
+	Help: "Number of QuayRegistries managed by the Operator, by health.",
+}, []string{"health"})
+
+// registryHealth is 1 for the current health of each registry, so the registries behind the fleet summary can be
+// listed.
+var registryHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "quay_operator_quayregistry_health",
+	Help: "Health of a QuayRegistry, summarizing its conditions.",
+}, []string{"namespace", "quayregistry", "health"})
+
+// reportedHealth is the health last reported for each registry.
+var reportedHealth = struct {
+	sync.Mutex
+	health map[types.NamespacedName]v1.RegistryHealth
+}{health: map[types.NamespacedName]v1.RegistryHealth{}}
+
+func init() {
+	metrics.Registry.MustRegister(fleetHealth, registryHealth)
+
+	for _, health := range v1.RegistryHealths {
+		fleetHealth.WithLabelValues(string(health)).Set(0)
+	}
+}
+
+// reportFleetHealth sets the health metrics of the given registry and updates the fleet summary. Since every status
+// update triggers another reconcile, it is reported from the status the registry was reconciled with.
+func reportFleetHealth(quay *v1.QuayRegistry) {
+	name := types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName()}
+	setFleetHealth(name, v1.HealthOf(quay))
+}
+
+// forgetFleetHealth removes the health metrics of a deleted registry.
+func forgetFleetHealth(name types.NamespacedName) {
+	setFleetHealth(name, "")
+}
+
+func setFleetHealth(name types.NamespacedName, health v1.RegistryHealth) {
+	reportedHealth.Lock()
+	defer reportedHealth.Unlock()
+
+	if previous, ok := reportedHealth.health[name]; ok {
+		registryHealth.DeleteLabelValues(name.Namespace, name.Name, string(previous))
+	}
+
+	if health == "" {
+		delete(reportedHealth.health, name)
+	} else {
+		reportedHealth.health[name] = health
+		registryHealth.WithLabelValues(name.Namespace, name.Name, string(health)).Set(1)
+	}
+
+	counts := map[v1.RegistryHealth]int{}
+	for _, h := range reportedHealth.health {
+		counts[h]++
+	}
+	for _, h := range v1.RegistryHealths {
+		fleetHealth.WithLabelValues(string(h)).Set(float64(counts[h]))
+	}
+}
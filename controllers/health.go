@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+// healthPollInterval is how often a running `QuayRegistry` is requeued to refresh its health conditions.
+const healthPollInterval = time.Minute
+
+// healthServices maps each health condition to the service reported by the Quay health checks.
+var healthServices = []struct {
+	conditionType v1.ConditionType
+	service       string
+}{
+	{v1.ConditionTypeDatabaseHealthy, "database"},
+	{v1.ConditionTypeRedisHealthy, "redis"},
+	{v1.ConditionTypeStorageHealthy, "storage"},
+	{v1.ConditionTypeAuthHealthy, "auth"},
+}
+
+// healthConditionsFor returns a condition for each service from the given health check reports. A service is
+// healthy only if every report which includes it says so, and unknown if no report includes it.
+func healthConditionsFor(reports ...*quay.HealthReport) []v1.Condition {
+	conditions := []v1.Condition{}
+
+	for _, health := range healthServices {
+		checked, healthy := false, true
+		for _, report := range reports {
+			if report == nil {
+				continue
+			}
+			if ok, found := report.Services[health.service]; found {
+				checked = true
+				healthy = healthy && ok
+			}
+		}
+
+		condition := v1.Condition{Type: health.conditionType}
+		switch {
+		case !checked:
+			condition.Status = metav1.ConditionUnknown
+			condition.Reason = v1.ConditionReasonHealthCheckUnavailable
+			condition.Message = "Quay did not report the health of " + health.service
+		case healthy:
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = v1.ConditionReasonHealthCheckPassed
+		default:
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = v1.ConditionReasonHealthCheckFailed
+			condition.Message = "Quay reported " + health.service + " as unhealthy"
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions
+}
+
+// reportHealth polls the Quay health check endpoints and reflects the health of each service in the `QuayRegistry`
+// status conditions. Returns true if the registry is running, so it should be polled again.
+func (r *QuayRegistryReconciler) reportHealth(ctx context.Context, quayRegistry *v1.QuayRegistry) (bool, error) {
+	if quayRegistry.Spec.Mode == v1.RegistryModeMirrorWorkers {
+		return false, nil
+	}
+
+	available := v1.GetCondition(quayRegistry.Status.Conditions, v1.ConditionTypeAvailable)
+	if available == nil || available.Status != metav1.ConditionTrue {
+		return false, nil
+	}
+
+	reports := []*quay.HealthReport{}
+	failures := []string{}
+	for _, check := range []string{quay.HealthCheckInstance, quay.HealthCheckEndToEnd} {
+		report, err := quay.FetchHealth(ctx, quay.HealthEndpointFor(quayRegistry, check))
+		if err != nil {
+			failures = append(failures, check+": "+err.Error())
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if err := r.updateConditions(ctx, quayRegistry, healthConditionsFor(reports...)...); err != nil {
+		return true, err
+	}
+
+	if len(reports) == 0 {
+		return true, fmt.Errorf("could not check Quay health: %s", strings.Join(failures, ", "))
+	}
+
+	return true, nil
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+var healthConditionsForTests = []struct {
+	name     string
+	reports  []*quay.HealthReport
+	expected map[v1.ConditionType]metav1.ConditionStatus
+}{
+	{
+		"NoReports",
+		[]*quay.HealthReport{},
+		map[v1.ConditionType]metav1.ConditionStatus{
+			v1.ConditionTypeDatabaseHealthy: metav1.ConditionUnknown,
+			v1.ConditionTypeRedisHealthy:    metav1.ConditionUnknown,
+			v1.ConditionTypeStorageHealthy:  metav1.ConditionUnknown,
+			v1.ConditionTypeAuthHealthy:     metav1.ConditionUnknown,
+		},
+	},
+	{
+		"Healthy",
+		[]*quay.HealthReport{{Services: map[string]bool{"database": true, "redis": true, "storage": true, "auth": true}}},
+		map[v1.ConditionType]metav1.ConditionStatus{
+			v1.ConditionTypeDatabaseHealthy: metav1.ConditionTrue,
+			v1.ConditionTypeRedisHealthy:    metav1.ConditionTrue,
+			v1.ConditionTypeStorageHealthy:  metav1.ConditionTrue,
+			v1.ConditionTypeAuthHealthy:     metav1.ConditionTrue,
+		},
+	},
+	{
+		"UnhealthyInOneReport",
+		[]*quay.HealthReport{
+			{Services: map[string]bool{"database": true, "redis": true}},
+			nil,
+			{Services: map[string]bool{"database": false, "storage": true}},
+		},
+		map[v1.ConditionType]metav1.ConditionStatus{
+			v1.ConditionTypeDatabaseHealthy: metav1.ConditionFalse,
+			v1.ConditionTypeRedisHealthy:    metav1.ConditionTrue,
+			v1.ConditionTypeStorageHealthy:  metav1.ConditionTrue,
+			v1.ConditionTypeAuthHealthy:     metav1.ConditionUnknown,
+		},
+	},
+}
+
+func TestHealthConditionsFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range healthConditionsForTests {
+		conditions := healthConditionsFor(test.reports...)
+
+		assert.Len(conditions, len(test.expected), test.name)
+		for _, condition := range conditions {
+			assert.Equal(test.expected[condition.Type], condition.Status, "%s: %s", test.name, condition.Type)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/preflight"
+)
+
+// lastErrorFor returns the `status.lastError` for the given error, keeping the transition time of an identical
+// existing error. Errors without a reason are reported with the fallback reason.
+func lastErrorFor(existing *v1.LastError, err error, fallback v1.ErrorReason) *v1.LastError {
+	if err == nil {
+		return nil
+	}
+
+	lastError := &v1.LastError{
+		Reason:             v1.ErrorReasonFor(err, fallback),
+		Message:            err.Error(),
+		LastTransitionTime: metav1.Now(),
+	}
+	if existing != nil && existing.Reason == lastError.Reason && existing.Message == lastError.Message {
+		lastError.LastTransitionTime = existing.LastTransitionTime
+	}
+
+	return lastError
+}
+
+// reportLastError stores the given error in `status.lastError`, or clears it if the error is nil.
+func (r *QuayRegistryReconciler) reportLastError(ctx context.Context, quay *v1.QuayRegistry, err error, fallback v1.ErrorReason) error {
+	lastError := lastErrorFor(quay.Status.LastError, err, fallback)
+	if lastError == nil && quay.Status.LastError == nil {
+		return nil
+	}
+	if lastError != nil && quay.Status.LastError != nil && lastError.LastTransitionTime.Equal(&quay.Status.LastError.LastTransitionTime) {
+		return nil
+	}
+
+	quay.Status.LastError = lastError
+	if updateErr := r.Client.Status().Update(ctx, quay); updateErr != nil {
+		return updateErr
+	}
+
+	if lastError != nil {
+		r.recordEvent(quay, corev1.EventTypeWarning, string(lastError.Reason), lastError.Message)
+	}
+
+	return nil
+}
+
+// effectiveConfigFor returns the Quay config of the config bundle with the fields from the `QuayOperatorConfig` set,
+// substitution variables resolved and the connections of `spec.externalDatabase` and `spec.externalRedis` added.
+func effectiveConfigFor(quay *v1.QuayRegistry, configBundle *corev1.Secret) (map[string]interface{}, error) {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configBundle.Data["config.yaml"], &config); err != nil {
+		return nil, err
+	}
+	config, _, _, err := kustomize.WithOperatorConfig(quay, config)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err = kustomize.ResolveConfigVariables(quay, config)
+	if err != nil {
+		return nil, err
+	}
+
+	externalDatabaseConfig, err := kustomize.ExternalDatabaseConfigFor(quay, config, configBundle.Data)
+	if err != nil {
+		return nil, err
+	}
+	for field, value := range externalDatabaseConfig {
+		config[field] = value
+	}
+
+	externalRedisConfig, err := kustomize.ExternalRedisConfigFor(quay, configBundle.Data)
+	if err != nil {
+		return nil, err
+	}
+	for field, value := range externalRedisConfig {
+		config[field] = value
+	}
+
+	return config, nil
+}
+
+// preflightCheck verifies the unmanaged backing services in the config bundle can be reached.
+func preflightCheck(quay *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	config, err := effectiveConfigFor(quay, configBundle)
+	if err != nil {
+		return v1.NewReasonedError(v1.ErrorReasonConfigConflict, err)
+	}
+
+	return preflight.Check(quay, config, configBundle.Data)
+}
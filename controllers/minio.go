@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// checkMinIOCredentials copies the credentials of the managed `minio` component into annotations, generating them
+// the first time the component is managed, or removes them if the component is unmanaged.
+func (r *QuayRegistryReconciler) checkMinIOCredentials(quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	existingAnnotations := quay.GetAnnotations()
+	if existingAnnotations == nil {
+		existingAnnotations = map[string]string{}
+	}
+	delete(existingAnnotations, v1.MinIOAccessKeyAnnotation)
+	delete(existingAnnotations, v1.MinIOSecretKeyAnnotation)
+
+	if !v1.ComponentIsManaged(quay.Spec.Components, "minio") {
+		quay.SetAnnotations(existingAnnotations)
+		return quay, nil
+	}
+
+	var credentialsSecret corev1.Secret
+	credentialsName := types.NamespacedName{Namespace: quay.GetNamespace(), Name: v1.MinIOCredentialsSecretFor(quay)}
+	if err := r.Client.Get(context.Background(), credentialsName, &credentialsSecret); err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "unable to retrieve MinIO credentials `Secret`")
+			return nil, err
+		}
+
+		generated, err := kustomize.MinIOCredentialsFor(quay)
+		if err != nil {
+			return nil, err
+		}
+		if !quay.Spec.DryRun {
+			r.Log.Info("generating MinIO credentials `Secret`")
+			if err := r.Client.Create(context.Background(), generated); err != nil {
+				return nil, err
+			}
+		}
+		credentialsSecret = *generated
+	}
+
+	existingAnnotations[v1.MinIOAccessKeyAnnotation] = string(credentialsSecret.Data[kustomize.MinIOAccessKey])
+	existingAnnotations[v1.MinIOSecretKeyAnnotation] = string(credentialsSecret.Data[kustomize.MinIOSecretKey])
+	quay.SetAnnotations(existingAnnotations)
+
+	return quay, nil
+}
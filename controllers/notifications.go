@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/notify"
+)
+
+// maxNotificationsInFlight is the number of webhook requests sent at once, after which notifications are dropped.
+const maxNotificationsInFlight = 10
+
+// notifications sends webhook requests in the background for every `QuayRegistry`.
+var notifications = notify.NewDispatcher(maxNotificationsInFlight)
+
+// eventForCondition returns the notification event sent when the given condition becomes true, if any.
+func eventForCondition(conditionType v1.ConditionType) (v1.NotificationEvent, bool) {
+	switch conditionType {
+	case v1.ConditionTypeAvailable:
+		return v1.NotificationEventAvailable, true
+	case v1.ConditionTypeDegraded:
+		return v1.NotificationEventDegraded, true
+	default:
+		return "", false
+	}
+}
+
+// updateConditions sets the given conditions on the `QuayRegistry` status, persisting them if anything changed
+// and notifying configured webhooks of any conditions which transitioned to true.
+func (r *QuayRegistryReconciler) updateConditions(ctx context.Context, quay *v1.QuayRegistry, conditions ...v1.Condition) error {
+	transitioned := []v1.Condition{}
+	before := quay.Status.DeepCopy()
+	for _, condition := range conditions {
+		var changed bool
+		quay.Status.Conditions, changed = v1.SetCondition(quay.Status.Conditions, condition)
+		if changed {
+			transitioned = append(transitioned, condition)
+		}
+	}
+
+	if conditionsEqual(before.Conditions, quay.Status.Conditions) {
+		return nil
+	}
+
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return err
+	}
+
+	for _, condition := range transitioned {
+		if event, ok := eventForCondition(condition.Type); ok && condition.Status == metav1.ConditionTrue {
+			r.notify(ctx, quay, event, condition.Message)
+		}
+	}
+
+	return nil
+}
+
+// conditionsEqual returns true if both sets of conditions have the same type, status, reason and message.
+func conditionsEqual(first, second []v1.Condition) bool {
+	if len(first) != len(second) {
+		return false
+	}
+
+	for _, a := range first {
+		b := v1.GetCondition(second, a.Type)
+		if b == nil || a.Status != b.Status || a.Reason != b.Reason || a.Message != b.Message {
+			return false
+		}
+	}
+
+	return true
+}
+
+// notify sends the event to every webhook subscribed to it in the background. Failures are logged but never block
+// reconciliation.
+func (r *QuayRegistryReconciler) notify(ctx context.Context, quay *v1.QuayRegistry, event v1.NotificationEvent, message string) {
+	log := r.Log.WithValues("quayregistry", quay.GetNamespace()+"/"+quay.GetName(), "event", event)
+	payload := notify.PayloadFor(quay, event, message)
+
+	for _, webhook := range quay.Spec.Notifications {
+		if !notify.Subscribed(webhook, event) {
+			continue
+		}
+
+		var key []byte
+		if webhook.SigningSecret != "" {
+			var signingSecret corev1.Secret
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: webhook.SigningSecret}, &signingSecret); err != nil {
+				log.Error(err, "unable to retrieve webhook `signingSecret`, skipping notification", "url", webhook.URL)
+				continue
+			}
+			key = signingSecret.Data[notify.SigningKey]
+		}
+
+		url := webhook.URL
+		sent := notifications.Dispatch(webhook, key, payload, func(err error) {
+			if err != nil {
+				log.Error(err, "failed to send notification", "url", url)
+				return
+			}
+
+			log.Info("sent notification", "url", url)
+		})
+		if !sent {
+			log.Info("too many notifications in flight, dropping notification", "url", url)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var conditionsEqualTests = []struct {
+	name     string
+	first    []v1.Condition
+	second   []v1.Condition
+	expected bool
+}{
+	{
+		"Empty",
+		[]v1.Condition{},
+		[]v1.Condition{},
+		true,
+	},
+	{
+		"Equal",
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue, Reason: "HealthChecksPassing"}},
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue, Reason: "HealthChecksPassing", LastTransitionTime: metav1.Now()}},
+		true,
+	},
+	{
+		"StatusChanged",
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue}},
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionFalse}},
+		false,
+	},
+	{
+		"MessageChanged",
+		[]v1.Condition{{Type: v1.ConditionTypeDegraded, Status: metav1.ConditionTrue, Message: "first"}},
+		[]v1.Condition{{Type: v1.ConditionTypeDegraded, Status: metav1.ConditionTrue, Message: "second"}},
+		false,
+	},
+	{
+		"ConditionAdded",
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue}},
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue}, {Type: v1.ConditionTypeDegraded, Status: metav1.ConditionFalse}},
+		false,
+	},
+	{
+		"DifferentTypes",
+		[]v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue}},
+		[]v1.Condition{{Type: v1.ConditionTypeDegraded, Status: metav1.ConditionTrue}},
+		false,
+	},
+}
+
+func TestConditionsEqual(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range conditionsEqualTests {
+		assert.Equal(test.expected, conditionsEqual(test.first, test.second), test.name)
+	}
+}
+
+func TestEventForCondition(t *testing.T) {
+	assert := assert.New(t)
+
+	event, ok := eventForCondition(v1.ConditionTypeAvailable)
+	assert.True(ok)
+	assert.Equal(v1.NotificationEventAvailable, event)
+
+	event, ok = eventForCondition(v1.ConditionTypeDegraded)
+	assert.True(ok)
+	assert.Equal(v1.NotificationEventDegraded, event)
+
+	_, ok = eventForCondition(v1.ConditionTypeDrifted)
+	assert.False(ok)
+}
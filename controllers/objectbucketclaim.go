@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	objectbucket "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// +kubebuilder:rbac:groups=objectbucket.io,resources=objectbucketclaims,verbs=get;list;watch;create;update;patch;delete
+
+// objectBucketClaimDeletionInterval is how often a deleted registry checks whether its `ObjectBucketClaim` is gone.
+const objectBucketClaimDeletionInterval = 5 * time.Second
+
+// ensureObjectBucketClaimFinalizer adds the finalizer to a registry which claims a bucket, or removes it once the
+// registry no longer does. Returns true if the `QuayRegistry` was updated.
+func (r *QuayRegistryReconciler) ensureObjectBucketClaimFinalizer(ctx context.Context, quay *v1.QuayRegistry, components []v1.Component) (bool, error) {
+	withComponents := quay.DeepCopy()
+	withComponents.Spec.Components = components
+
+	needed := v1.NeedsObjectBucketClaimFinalizer(withComponents)
+	if needed == v1.HasFinalizer(quay, v1.ObjectBucketClaimFinalizer) || quay.Spec.DryRun {
+		return false, nil
+	}
+
+	updatedQuay := quay.DeepCopy()
+	if needed {
+		controllerutil.AddFinalizer(updatedQuay, v1.ObjectBucketClaimFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(updatedQuay, v1.ObjectBucketClaimFinalizer)
+	}
+
+	return true, r.Client.Update(ctx, updatedQuay)
+}
+
+// finalizeObjectBucketClaim deletes the `ObjectBucketClaim` of a deleted registry, and removes the finalizer once its
+// provisioner has released the bucket.
+func (r *QuayRegistryReconciler) finalizeObjectBucketClaim(ctx context.Context, quay *v1.QuayRegistry) (ctrl.Result, error) {
+	if !v1.HasFinalizer(quay, v1.ObjectBucketClaimFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	var obc objectbucket.ObjectBucketClaim
+	obcName := types.NamespacedName{Namespace: quay.GetNamespace(), Name: v1.ObjectBucketClaimNameFor(quay)}
+	if err := r.Client.Get(ctx, obcName, &obc); err == nil {
+		if obc.GetDeletionTimestamp().IsZero() {
+			r.Log.Info("deleting `ObjectBucketClaim` of deleted QuayRegistry", "objectBucketClaim", obcName.String())
+			if err := r.Client.Delete(ctx, &obc); client.IgnoreNotFound(err) != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{RequeueAfter: objectBucketClaimDeletionInterval}, nil
+	} else if !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	r.Log.Info("`ObjectBucketClaim` released, removing finalizer", "objectBucketClaim", obcName.String())
+	updatedQuay := quay.DeepCopy()
+	controllerutil.RemoveFinalizer(updatedQuay, v1.ObjectBucketClaimFinalizer)
+
+	return ctrl.Result{}, r.Client.Update(ctx, updatedQuay)
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	objectbucket "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/secretkeys"
+)
+
+// withObjectStorageFiles returns a copy of the config bundle including any files referenced by `spec.objectStorage`,
+// such as the private key CloudFront URLs are signed with and the CA bundle of the storage endpoints.
+func (r *QuayRegistryReconciler) withObjectStorageFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if cloudFrontSigningKeySecret(quay) == "" && v1.StorageCABundleFor(quay) == nil {
+		return configBundle, nil
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+
+	if name := cloudFrontSigningKeySecret(quay); name != "" {
+		var signingKeySecret corev1.Secret
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: name}, &signingKeySecret); err != nil {
+			return nil, err
+		}
+
+		if signingKey, ok := signingKeySecret.Data[kustomize.CloudFrontSigningKeyFile]; ok {
+			withFiles.Data[kustomize.CloudFrontSigningKeyFile] = signingKey
+		}
+	}
+
+	if caBundle := v1.StorageCABundleFor(quay); caBundle != nil {
+		key := caBundle.KeyOrDefault()
+		switch {
+		case caBundle.Secret != "":
+			var caBundleSecret corev1.Secret
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: caBundle.Secret}, &caBundleSecret); err != nil {
+				return nil, err
+			}
+
+			if bundle, ok := caBundleSecret.Data[key]; ok {
+				withFiles.Data[kustomize.StorageCABundleFile] = bundle
+			}
+		case caBundle.ConfigMap != "":
+			var caBundleConfigMap corev1.ConfigMap
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: caBundle.ConfigMap}, &caBundleConfigMap); err != nil {
+				return nil, err
+			}
+
+			if bundle, ok := caBundleConfigMap.Data[key]; ok {
+				withFiles.Data[kustomize.StorageCABundleFile] = []byte(bundle)
+			}
+		}
+	}
+
+	return withFiles, nil
+}
+
+// cloudFrontSigningKeySecret returns the name of the `Secret` with the private key CloudFront URLs are signed with, if
+// the managed bucket is served through CloudFront.
+func cloudFrontSigningKeySecret(quay *v1.QuayRegistry) string {
+	s3 := v1.S3StorageFor(quay)
+	if !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") || s3 == nil || s3.CloudFront == nil {
+		return ""
+	}
+
+	return s3.CloudFront.SigningKeySecret
+}
+
+// storageAnnotations are the annotations which the endpoint and credentials of the managed bucket are copied into
+// while rendering the registry. They are never stored in the `QuayRegistry`.
+var storageAnnotations = append([]string{v1.StorageHostnameAnnotation, v1.StorageBucketNameAnnotation}, storageCredentialAnnotations...)
+
+// withoutStorageAnnotations returns a copy of the given annotations without the storage endpoint and credentials, and
+// whether any were removed.
+func withoutStorageAnnotations(annotations map[string]string) (map[string]string, bool) {
+	remaining := map[string]string{}
+	removed := false
+	for annotation, value := range annotations {
+		stored := false
+		for _, storageAnnotation := range storageAnnotations {
+			if annotation == storageAnnotation || strings.HasPrefix(annotation, storageAnnotation+".") {
+				stored = true
+			}
+		}
+		if stored {
+			removed = true
+		} else {
+			remaining[annotation] = value
+		}
+	}
+
+	return remaining, removed
+}
+
+// storageAnnotationsDeprecationMessage tells users to stop setting the `storage-*` annotations, which manifests applied
+// by hand or by GitOps tools would otherwise set again after they are migrated or removed.
+const storageAnnotationsDeprecationMessage = "the `storage-hostname`, `storage-bucketname`, `storage-access-key` and `storage-secret-key` annotations are deprecated, remove them from the manifest of the QuayRegistry"
+
+// storageAnnotationsSetByHand returns true if the storage annotations of the given `QuayRegistry` cannot have been
+// copied from its `ObjectBucketClaim`, since it stores images in the bucket of `spec.objectStorage` instead.
+func storageAnnotationsSetByHand(quay *v1.QuayRegistry) bool {
+	_, stored := withoutStorageAnnotations(quay.GetAnnotations())
+
+	return stored && quay.Spec.ObjectStorage != nil
+}
+
+// storageAnnotationsCopiedFromClaim returns true if the storage annotations of the given `QuayRegistry` were copied
+// from its `ObjectBucketClaim`, which is the case if the `objectstorage` component is managed and claims the bucket of
+// an existing claim. Any value set by hand would be replaced by those of the claim anyway.
+func (r *QuayRegistryReconciler) storageAnnotationsCopiedFromClaim(ctx context.Context, quay *v1.QuayRegistry) (bool, error) {
+	if !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") || !v1.ClaimsObjectBucket(quay) {
+		return false, nil
+	}
+
+	var obc objectbucket.ObjectBucketClaim
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: v1.ObjectBucketClaimNameFor(quay)}, &obc)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// removeStoredStorageAnnotations removes the storage endpoint and credentials which previous versions of the Operator
+// copied from the `ObjectBucketClaim` into the annotations of a `QuayRegistry` and could persist. Annotations set by
+// hand are left to `migrateStorageAnnotations`, or kept with a deprecation warning if they are ignored in favor of
+// `spec.objectStorage`. Returns true if the `QuayRegistry` was updated.
+func (r *QuayRegistryReconciler) removeStoredStorageAnnotations(ctx context.Context, quay *v1.QuayRegistry) (bool, error) {
+	annotations, removed := withoutStorageAnnotations(quay.GetAnnotations())
+	if !removed || quay.Spec.DryRun {
+		return false, nil
+	}
+
+	if storageAnnotationsSetByHand(quay) {
+		r.recordEvent(quay, corev1.EventTypeWarning, "StorageAnnotationsDeprecated", "object storage annotations are ignored in favor of `spec.objectStorage`: "+storageAnnotationsDeprecationMessage)
+
+		return false, nil
+	}
+
+	copied, err := r.storageAnnotationsCopiedFromClaim(ctx, quay)
+	if err != nil || !copied {
+		return false, err
+	}
+
+	updatedQuay := quay.DeepCopy()
+	updatedQuay.SetAnnotations(annotations)
+	if err := r.Client.Update(ctx, updatedQuay); err != nil {
+		return false, err
+	}
+	r.recordEvent(quay, corev1.EventTypeNormal, "StorageAnnotationsRemoved", "removed object storage endpoint and credentials copied from the `ObjectBucketClaim`")
+
+	return true, nil
+}
+
+// objectStorageFromAnnotations returns the `spec.objectStorage` for the bucket configured by hand with the `storage-*`
+// annotations of the given `QuayRegistry`, together with the data of its credentials `Secret`. Returns nil if the
+// annotations do not describe a bucket.
+func objectStorageFromAnnotations(quay *v1.QuayRegistry) (*v1.ObjectStorage, map[string][]byte) {
+	annotations := quay.GetAnnotations()
+	hostname, bucketName := annotations[v1.StorageHostnameAnnotation], annotations[v1.StorageBucketNameAnnotation]
+	accessKey, secretKey := annotations[v1.StorageAccessKeyAnnotation], annotations[v1.StorageSecretKeyAnnotation]
+	if hostname == "" || bucketName == "" || accessKey == "" || secretKey == "" {
+		return nil, nil
+	}
+
+	// NOTE: Disabling server-side encryption renders the same `RadosGWStorage` location as the annotations did.
+	serverSideEncryption := false
+	objectStorage := &v1.ObjectStorage{
+		S3: &v1.S3Storage{
+			Bucket:               bucketName,
+			Endpoint:             hostname,
+			CredentialsSecret:    v1.MigratedStorageCredentialsSecretFor(quay),
+			ServerSideEncryption: &serverSideEncryption,
+		},
+	}
+
+	return objectStorage, map[string][]byte{"accessKey": []byte(accessKey), "secretKey": []byte(secretKey)}
+}
+
+// migrateStorageAnnotations moves a bucket configured by hand with the `storage-*` annotations into
+// `spec.objectStorage`, with its credentials in a `Secret`, and removes the annotations with a deprecation warning.
+// Annotations which were copied from an `ObjectBucketClaim` are left to `removeStoredStorageAnnotations`. Returns true
+// if the `QuayRegistry` was updated.
+func (r *QuayRegistryReconciler) migrateStorageAnnotations(ctx context.Context, quay *v1.QuayRegistry) (bool, error) {
+	if quay.Spec.DryRun || quay.Spec.ObjectStorage != nil || !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		return false, nil
+	}
+
+	objectStorage, credentials := objectStorageFromAnnotations(quay)
+	if objectStorage == nil {
+		return false, nil
+	}
+
+	var obc objectbucket.ObjectBucketClaim
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: v1.ObjectBucketClaimNameFor(quay)}, &obc)
+	if err == nil {
+		return false, nil
+	} else if !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return false, err
+	}
+
+	// NOTE: The `Secret` is not owned by the `QuayRegistry`, like every other `Secret` referenced by its spec.
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: v1.MigratedStorageCredentialsSecretFor(quay), Namespace: quay.GetNamespace()},
+		Data:       credentials,
+	}
+	credentialsSecret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	if err := r.createOrUpdateObject(ctx, credentialsSecret, *quay); err != nil {
+		return false, err
+	}
+
+	updatedQuay := quay.DeepCopy()
+	annotations, _ := withoutStorageAnnotations(quay.GetAnnotations())
+	updatedQuay.SetAnnotations(annotations)
+	updatedQuay.Spec.ObjectStorage = objectStorage
+	if err := r.Client.Update(ctx, updatedQuay); err != nil {
+		return false, err
+	}
+	r.recordEvent(quay, corev1.EventTypeWarning, "StorageAnnotationsMigrated", "moved object storage annotations to `spec.objectStorage`, with the credentials in `Secret` "+credentialsSecret.GetName()+": "+storageAnnotationsDeprecationMessage)
+
+	return true, nil
+}
+
+// storageCredentialsFingerprint returns a fingerprint of the object storage credentials copied into the annotations
+// of the given `QuayRegistry`, or an empty string if it has none.
+func storageCredentialsFingerprint(quay *v1.QuayRegistry) string {
+	annotations := quay.GetAnnotations()
+	names := []string{}
+	for annotation := range annotations {
+		for _, credential := range storageCredentialAnnotations {
+			if annotation == credential || strings.HasPrefix(annotation, credential+".") {
+				names = append(names, annotation)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	credentials := ""
+	for _, name := range names {
+		credentials += name + "=" + annotations[name] + "\n"
+	}
+
+	return secretkeys.Fingerprint(credentials)
+}
+
+// reportStorageCredentials records the given fingerprint of the object storage credentials in
+// `status.storageCredentialsFingerprint`, and an event if they were rotated. Rotated credentials are rendered into a
+// new config bundle, which rolls out the Quay pods. The fingerprint must be taken before the status of the
+// `QuayRegistry` is updated, since the credentials are only copied into the annotations in memory.
+func (r *QuayRegistryReconciler) reportStorageCredentials(ctx context.Context, quay *v1.QuayRegistry, fingerprint string) error {
+	if fingerprint == quay.Status.StorageCredentialsFingerprint {
+		return nil
+	}
+
+	rotated := fingerprint != "" && quay.Status.StorageCredentialsFingerprint != ""
+	quay.Status.StorageCredentialsFingerprint = fingerprint
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return err
+	}
+	if rotated {
+		r.recordEvent(quay, corev1.EventTypeNormal, "StorageCredentialsRotated", "object storage credentials changed, rolling out the Quay pods with the new credentials")
+	}
+
+	return nil
+}
+
+// withLocalStorage marks the registry as `Degraded` in the given conditions if it stores images with the
+// `localstorage` component, which is only meant for evaluating Quay.
+func withLocalStorage(conditions []v1.Condition, quay *v1.QuayRegistry) []v1.Condition {
+	if !v1.ComponentIsManaged(quay.Spec.Components, "localstorage") {
+		return conditions
+	}
+
+	for i := range conditions {
+		if conditions[i].Type == v1.ConditionTypeDegraded {
+			conditions[i] = v1.Condition{
+				Type:   v1.ConditionTypeDegraded,
+				Status: metav1.ConditionTrue,
+				Reason: v1.ConditionReasonLocalStorage,
+				Message: "images are stored on the `" + quay.GetName() + "-quay-datastorage` `PersistentVolumeClaim` of the " +
+					"`localstorage` component, which is not highly available or backed up: do not use it in production",
+			}
+		}
+	}
+
+	return conditions
+}
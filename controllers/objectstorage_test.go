@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	objectbucket "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var withoutStorageAnnotationsTests = []struct {
+	name            string
+	annotations     map[string]string
+	expected        map[string]string
+	expectedRemoved bool
+}{
+	{
+		"NoAnnotations",
+		nil,
+		map[string]string{},
+		false,
+	},
+	{
+		"OtherAnnotations",
+		map[string]string{v1.SupportsObjectStorageAnnotation: "true", v1.PausedComponentsAnnotation: "clair"},
+		map[string]string{v1.SupportsObjectStorageAnnotation: "true", v1.PausedComponentsAnnotation: "clair"},
+		false,
+	},
+	{
+		"ObjectBucketClaim",
+		map[string]string{
+			v1.SupportsObjectStorageAnnotation: "true",
+			v1.StorageHostnameAnnotation:       "s3.openshift-storage.svc.cluster.local",
+			v1.StorageBucketNameAnnotation:     "quay-datastore",
+			v1.StorageAccessKeyAnnotation:      "abc123",
+			v1.StorageSecretKeyAnnotation:      "super-secret",
+		},
+		map[string]string{v1.SupportsObjectStorageAnnotation: "true"},
+		true,
+	},
+	{
+		"AdditionalLocations",
+		map[string]string{
+			v1.StorageLocationAnnotation(v1.StorageSASTokenAnnotation, "eu_west"):    "sv=2022-11-02",
+			v1.StorageLocationAnnotation(v1.StorageTempURLKeyAnnotation, "ap_south"): "temp-url-key",
+		},
+		map[string]string{},
+		true,
+	},
+}
+
+func TestWithoutStorageAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range withoutStorageAnnotationsTests {
+		annotations, removed := withoutStorageAnnotations(test.annotations)
+
+		assert.Equal(test.expectedRemoved, removed, test.name)
+		assert.Equal(test.expected, annotations, test.name)
+	}
+}
+
+// annotatedStorageQuayRegistry returns a `QuayRegistry` with a bucket configured by hand with the given annotations.
+func annotatedStorageQuayRegistry(annotations map[string]string, components []v1.Component) *v1.QuayRegistry {
+	return &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "ns-1", Annotations: annotations},
+		Spec:       v1.QuayRegistrySpec{Components: components},
+	}
+}
+
+var handConfiguredStorageAnnotations = map[string]string{
+	v1.StorageHostnameAnnotation:   "rgw.example.com",
+	v1.StorageBucketNameAnnotation: "quay",
+	v1.StorageAccessKeyAnnotation:  "access",
+	v1.StorageSecretKeyAnnotation:  "secret",
+	v1.PausedComponentsAnnotation:  "clair",
+}
+
+var managedObjectStorage = []v1.Component{{Kind: "objectstorage", Managed: true}}
+
+var migrateStorageAnnotationsTests = []struct {
+	name     string
+	quay     *v1.QuayRegistry
+	objects  []k8sruntime.Object
+	expected bool
+}{
+	{
+		"Migrated",
+		annotatedStorageQuayRegistry(handConfiguredStorageAnnotations, managedObjectStorage),
+		[]k8sruntime.Object{},
+		true,
+	},
+	{
+		"ClaimedBucket",
+		annotatedStorageQuayRegistry(handConfiguredStorageAnnotations, managedObjectStorage),
+		[]k8sruntime.Object{&objectbucket.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "skynet-quay-datastore", Namespace: "ns-1"}}},
+		false,
+	},
+	{
+		"Unmanaged",
+		annotatedStorageQuayRegistry(handConfiguredStorageAnnotations, []v1.Component{{Kind: "objectstorage", Managed: false}}),
+		[]k8sruntime.Object{},
+		false,
+	},
+	{
+		"MissingCredentials",
+		annotatedStorageQuayRegistry(map[string]string{v1.StorageHostnameAnnotation: "rgw.example.com", v1.StorageBucketNameAnnotation: "quay"}, managedObjectStorage),
+		[]k8sruntime.Object{},
+		false,
+	},
+	{
+		"NoAnnotations",
+		annotatedStorageQuayRegistry(nil, managedObjectStorage),
+		[]k8sruntime.Object{},
+		false,
+	},
+}
+
+func TestMigrateStorageAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range migrateStorageAnnotationsTests {
+		r, stub := stubReconciler(test.objects...)
+
+		migrated, err := r.migrateStorageAnnotations(context.Background(), test.quay)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expected, migrated, test.name)
+		if !test.expected {
+			assert.Empty(stub.patched, test.name)
+			assert.Empty(stub.updated, test.name)
+			continue
+		}
+
+		assert.Len(stub.patched, 1, test.name)
+		credentialsSecret := stub.patched[0].(*corev1.Secret)
+		assert.Equal("skynet-quay-storage-credentials", credentialsSecret.GetName(), test.name)
+		assert.Equal(map[string][]byte{"accessKey": []byte("access"), "secretKey": []byte("secret")}, credentialsSecret.Data, test.name)
+		assert.Empty(credentialsSecret.GetOwnerReferences(), test.name)
+
+		assert.Len(stub.updated, 1, test.name)
+		updated := stub.updated[0].(*v1.QuayRegistry)
+		assert.Equal(map[string]string{v1.PausedComponentsAnnotation: "clair"}, updated.GetAnnotations(), test.name)
+		disabled := false
+		assert.Equal(&v1.ObjectStorage{S3: &v1.S3Storage{
+			Bucket:               "quay",
+			Endpoint:             "rgw.example.com",
+			CredentialsSecret:    "skynet-quay-storage-credentials",
+			ServerSideEncryption: &disabled,
+		}}, updated.Spec.ObjectStorage, test.name)
+		assert.Nil(test.quay.Spec.ObjectStorage, "%s: given QuayRegistry is not modified", test.name)
+	}
+}
+
+var storageAnnotationsSetByHandTests = []struct {
+	name          string
+	annotations   map[string]string
+	objectStorage *v1.ObjectStorage
+	expected      bool
+}{
+	{
+		"ReappliedAfterMigration",
+		handConfiguredStorageAnnotations,
+		&v1.ObjectStorage{S3: &v1.S3Storage{Bucket: "quay", CredentialsSecret: "skynet-quay-storage-credentials"}},
+		true,
+	},
+	{
+		"CopiedFromObjectBucketClaim",
+		handConfiguredStorageAnnotations,
+		nil,
+		false,
+	},
+	{
+		"NoAnnotations",
+		map[string]string{v1.PausedComponentsAnnotation: "clair"},
+		&v1.ObjectStorage{S3: &v1.S3Storage{Bucket: "quay", CredentialsSecret: "skynet-quay-storage-credentials"}},
+		false,
+	},
+}
+
+func TestStorageAnnotationsSetByHand(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range storageAnnotationsSetByHandTests {
+		quay := annotatedStorageQuayRegistry(test.annotations, managedObjectStorage)
+		quay.Spec.ObjectStorage = test.objectStorage
+
+		assert.Equal(test.expected, storageAnnotationsSetByHand(quay), test.name)
+	}
+}
+
+var removeStoredStorageAnnotationsTests = []struct {
+	name          string
+	components    []v1.Component
+	objectStorage *v1.ObjectStorage
+	objects       []k8sruntime.Object
+	expected      bool
+}{
+	{
+		"CopiedFromObjectBucketClaim",
+		managedObjectStorage,
+		nil,
+		[]k8sruntime.Object{&objectbucket.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "skynet-quay-datastore", Namespace: "ns-1"}}},
+		true,
+	},
+	{
+		"NoObjectBucketClaim",
+		managedObjectStorage,
+		nil,
+		[]k8sruntime.Object{},
+		false,
+	},
+	{
+		"Unmanaged",
+		[]v1.Component{{Kind: "objectstorage", Managed: false}},
+		nil,
+		[]k8sruntime.Object{&objectbucket.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "skynet-quay-datastore", Namespace: "ns-1"}}},
+		false,
+	},
+	{
+		"ReappliedAfterMigration",
+		managedObjectStorage,
+		&v1.ObjectStorage{S3: &v1.S3Storage{Bucket: "quay", CredentialsSecret: "skynet-quay-storage-credentials"}},
+		[]k8sruntime.Object{&objectbucket.ObjectBucketClaim{ObjectMeta: metav1.ObjectMeta{Name: "skynet-quay-datastore", Namespace: "ns-1"}}},
+		false,
+	},
+}
+
+func TestRemoveStoredStorageAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range removeStoredStorageAnnotationsTests {
+		quay := annotatedStorageQuayRegistry(handConfiguredStorageAnnotations, test.components)
+		quay.Spec.ObjectStorage = test.objectStorage
+		r, stub := stubReconciler(test.objects...)
+
+		removed, err := r.removeStoredStorageAnnotations(context.Background(), quay)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expected, removed, test.name)
+		if !test.expected {
+			assert.Empty(stub.updated, test.name)
+			continue
+		}
+		assert.Len(stub.updated, 1, test.name)
+		assert.Equal(map[string]string{v1.PausedComponentsAnnotation: "clair"}, stub.updated[0].(*v1.QuayRegistry).GetAnnotations(), test.name)
+	}
+}
+
+// fingerprintOf returns the fingerprint of the storage credentials in the given annotations.
+func fingerprintOf(annotations map[string]string) string {
+	return storageCredentialsFingerprint(&v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+}
+
+var reportStorageCredentialsTests = []struct {
+	name                string
+	recorded            string
+	annotations         map[string]string
+	expectedUpdated     bool
+	expectedFingerprint string
+}{
+	{
+		"NoCredentials",
+		"",
+		nil,
+		false,
+		"",
+	},
+	{
+		"FirstCredentials",
+		"",
+		map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret"},
+		true,
+		fingerprintOf(map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret"}),
+	},
+	{
+		"Unchanged",
+		fingerprintOf(map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret"}),
+		map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret", v1.PausedComponentsAnnotation: "clair"},
+		false,
+		fingerprintOf(map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret"}),
+	},
+	{
+		"RotatedLocation",
+		fingerprintOf(map[string]string{v1.StorageLocationAnnotation(v1.StorageSecretKeyAnnotation, "eu_west"): "old-secret"}),
+		map[string]string{v1.StorageLocationAnnotation(v1.StorageSecretKeyAnnotation, "eu_west"): "new-secret"},
+		true,
+		fingerprintOf(map[string]string{v1.StorageLocationAnnotation(v1.StorageSecretKeyAnnotation, "eu_west"): "new-secret"}),
+	},
+}
+
+func TestReportStorageCredentials(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range reportStorageCredentialsTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1", Annotations: test.annotations},
+			Status:     v1.QuayRegistryStatus{StorageCredentialsFingerprint: test.recorded},
+		}
+		r, stub := stubReconciler()
+
+		assert.Nil(r.reportStorageCredentials(context.Background(), quay, storageCredentialsFingerprint(quay)), test.name)
+		assert.Equal(test.expectedUpdated, len(stub.statusUpdated) > 0, test.name)
+		assert.Equal(test.expectedFingerprint, quay.Status.StorageCredentialsFingerprint, test.name)
+	}
+}
+
+func TestReportStorageCredentialsAfterStatusUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	annotations := map[string]string{v1.StorageAccessKeyAnnotation: "abc123", v1.StorageSecretKeyAnnotation: "super-secret"}
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1", Annotations: annotations},
+	}
+	r, stub := stubReconciler()
+
+	fingerprint := storageCredentialsFingerprint(quay)
+	assert.Nil(r.updateConditions(context.Background(), quay, v1.Condition{
+		Type:   v1.ConditionTypeAvailable,
+		Status: metav1.ConditionTrue,
+		Reason: v1.ConditionReasonComponentsCreationSuccess,
+	}))
+	// NOTE: The API server responds to the status update with the stored `QuayRegistry`, without the copied credentials.
+	quay.SetAnnotations(nil)
+
+	assert.Nil(r.reportStorageCredentials(context.Background(), quay, fingerprint))
+	assert.Len(stub.statusUpdated, 2)
+	assert.Equal(fingerprintOf(annotations), quay.Status.StorageCredentialsFingerprint)
+	assert.NotEqual(storageCredentialsFingerprint(quay), quay.Status.StorageCredentialsFingerprint)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// operatorConditionNameEnv is injected by OLM into the Operator `Deployment`.
+	operatorConditionNameEnv = "OPERATOR_CONDITION_NAME"
+	operatorNamespaceEnv     = "MY_POD_NAMESPACE"
+
+	upgradeableCondition        = "Upgradeable"
+	upgradeableReasonReady      = "Ready"
+	upgradeableReasonInProgress = "QuayRegistryUpgradeInProgress"
+)
+
+var operatorConditionGVK = schema.GroupVersionKind{Group: "operators.coreos.com", Version: "v1", Kind: "OperatorCondition"}
+
+// +kubebuilder:rbac:groups=operators.coreos.com,resources=operatorconditions,verbs=get;update;patch
+
+// upgradeBlockedBy returns the names of the `QuayRegistries` which are in the middle of an operation
+// that would be unsafe to interrupt by replacing the Operator, together with the operation.
+func upgradeBlockedBy(quays []v1.QuayRegistry) []string {
+	blocking := []string{}
+	for i := range quays {
+		quay := &quays[i]
+		name := quay.GetNamespace() + "/" + quay.GetName()
+
+		if quay.Spec.DesiredVersion != "" && quay.Spec.DesiredVersion != quay.Status.CurrentVersion {
+			blocking = append(blocking, name+" (upgrade)")
+		}
+		switch v1.StorageMigrationPhaseFor(quay) {
+		case v1.StorageMigrationPhaseReplicating, v1.StorageMigrationPhaseVerifying:
+			blocking = append(blocking, name+" (storage migration)")
+		}
+		if v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+			switch v1.PostgresUpdatePhaseFor(quay) {
+			case v1.PostgresUpdatePhaseBackingUp:
+				blocking = append(blocking, name+" (database backup)")
+			case v1.PostgresUpdatePhaseRestoring:
+				blocking = append(blocking, name+" (database upgrade)")
+			}
+		}
+	}
+
+	return blocking
+}
+
+// reportUpgradeable sets the `Upgradeable` condition on the Operator's `OperatorCondition` so that OLM does not
+// replace the Operator while any `QuayRegistry` is being upgraded, migrating its storage or backing up or upgrading
+// its database.
+// Does nothing when not installed by OLM.
+func (r *QuayRegistryReconciler) reportUpgradeable(ctx context.Context) error {
+	name := os.Getenv(operatorConditionNameEnv)
+	if name == "" {
+		return nil
+	}
+
+	var quays v1.QuayRegistryList
+	if err := r.Client.List(ctx, &quays); err != nil {
+		return err
+	}
+
+	status, reason, message := metav1.ConditionTrue, upgradeableReasonReady, "no QuayRegistry upgrades, storage migrations or database backups in progress"
+	if blocking := upgradeBlockedBy(quays.Items); len(blocking) > 0 {
+		status = metav1.ConditionFalse
+		reason = upgradeableReasonInProgress
+		message = "QuayRegistry operations in progress: " + strings.Join(blocking, ", ")
+	}
+
+	operatorCondition := &unstructured.Unstructured{}
+	operatorCondition.SetGroupVersionKind(operatorConditionGVK)
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: os.Getenv(operatorNamespaceEnv), Name: name}, operatorCondition); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	existing, _, err := unstructured.NestedSlice(operatorCondition.Object, "spec", "conditions")
+	if err != nil {
+		return err
+	}
+
+	conditions := []interface{}{}
+	for _, c := range existing {
+		if cond, ok := c.(map[string]interface{}); ok && cond["type"] == upgradeableCondition {
+			if cond["status"] == string(status) && cond["message"] == message {
+				return nil
+			}
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	conditions = append(conditions, map[string]interface{}{
+		"type":               upgradeableCondition,
+		"status":             string(status),
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": metav1.Now().UTC().Format(time.RFC3339),
+	})
+
+	if err := unstructured.SetNestedSlice(operatorCondition.Object, conditions, "spec", "conditions"); err != nil {
+		return err
+	}
+
+	r.Log.Info("updating `OperatorCondition`", "Upgradeable", status, "message", message)
+
+	return r.Client.Update(ctx, operatorCondition)
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// quayRegistryWith returns a `QuayRegistry` running the current version with the given spec and status.
+func quayRegistryWith(spec v1.QuayRegistrySpec, status v1.QuayRegistryStatus) v1.QuayRegistry {
+	if spec.DesiredVersion == "" {
+		spec.DesiredVersion = v1.QuayVersionVader
+	}
+	if status.CurrentVersion == "" {
+		status.CurrentVersion = v1.QuayVersionVader
+	}
+
+	return v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "ns-1"}, Spec: spec, Status: status}
+}
+
+var managedPostgres = []v1.Component{{Kind: "postgres", Managed: true}}
+
+var upgradeBlockedByTests = []struct {
+	name     string
+	quay     v1.QuayRegistry
+	expected []string
+}{
+	{
+		"Idle",
+		quayRegistryWith(v1.QuayRegistrySpec{Components: managedPostgres}, v1.QuayRegistryStatus{}),
+		[]string{},
+	},
+	{
+		"Upgrading",
+		quayRegistryWith(v1.QuayRegistrySpec{}, v1.QuayRegistryStatus{CurrentVersion: v1.QuayVersionQuiGon}),
+		[]string{"ns-1/skynet (upgrade)"},
+	},
+	{
+		"StorageMigrationStarting",
+		quayRegistryWith(v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}}, v1.QuayRegistryStatus{}),
+		[]string{"ns-1/skynet (storage migration)"},
+	},
+	{
+		"StorageMigrationReplicating",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}},
+			v1.QuayRegistryStatus{StorageMigration: &v1.StorageMigrationStatus{TargetLocation: "us-east", Phase: v1.StorageMigrationPhaseReplicating}}),
+		[]string{"ns-1/skynet (storage migration)"},
+	},
+	{
+		"StorageMigrationVerifying",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}},
+			v1.QuayRegistryStatus{StorageMigration: &v1.StorageMigrationStatus{TargetLocation: "us-east", Phase: v1.StorageMigrationPhaseVerifying}}),
+		[]string{"ns-1/skynet (storage migration)"},
+	},
+	{
+		"StorageMigrationComplete",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}},
+			v1.QuayRegistryStatus{StorageMigration: &v1.StorageMigrationStatus{TargetLocation: "us-east", Phase: v1.StorageMigrationPhaseComplete}}),
+		[]string{},
+	},
+	{
+		"StorageMigrationFailed",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}},
+			v1.QuayRegistryStatus{StorageMigration: &v1.StorageMigrationStatus{TargetLocation: "us-east", Phase: v1.StorageMigrationPhaseFailed}}),
+		[]string{},
+	},
+	{
+		"PostgresBackingUp",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{Components: managedPostgres},
+			v1.QuayRegistryStatus{Postgres: &v1.PostgresStatus{Version: v1.PostgresVersion10, Image: "postgres:10.23", TargetImage: "postgres:13.13", Phase: v1.PostgresUpdatePhaseBackingUp}}),
+		[]string{"ns-1/skynet (database backup)"},
+	},
+	{
+		"PostgresRestoring",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{Components: managedPostgres},
+			v1.QuayRegistryStatus{Postgres: &v1.PostgresStatus{Version: v1.PostgresVersion10, Image: "postgres:10.23", TargetImage: "postgres:13.13", Phase: v1.PostgresUpdatePhaseRestoring}}),
+		[]string{"ns-1/skynet (database upgrade)"},
+	},
+	{
+		"PostgresBackupFailed",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{Components: managedPostgres},
+			v1.QuayRegistryStatus{Postgres: &v1.PostgresStatus{Version: v1.PostgresVersion10, Image: "postgres:10.23", TargetImage: "postgres:13.13", Phase: v1.PostgresUpdatePhaseFailed}}),
+		[]string{},
+	},
+	{
+		"PostgresUnmanaged",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{Components: []v1.Component{{Kind: "postgres", Managed: false}}},
+			v1.QuayRegistryStatus{Postgres: &v1.PostgresStatus{Version: v1.PostgresVersion10, Image: "postgres:10.23", TargetImage: "postgres:13.13", Phase: v1.PostgresUpdatePhaseBackingUp}}),
+		[]string{},
+	},
+	{
+		"UpgradingWhileMigratingStorage",
+		quayRegistryWith(
+			v1.QuayRegistrySpec{StorageMigration: &v1.StorageMigration{TargetLocation: "us-east"}},
+			v1.QuayRegistryStatus{CurrentVersion: v1.QuayVersionQuiGon, StorageMigration: &v1.StorageMigrationStatus{TargetLocation: "us-east", Phase: v1.StorageMigrationPhaseVerifying}}),
+		[]string{"ns-1/skynet (upgrade)", "ns-1/skynet (storage migration)"},
+	},
+}
+
+func TestUpgradeBlockedBy(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range upgradeBlockedByTests {
+		assert.Equal(test.expected, upgradeBlockedBy([]v1.QuayRegistry{test.quay}), test.name)
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// +kubebuilder:rbac:groups=quay.redhat.com,resources=quayoperatorconfigs,verbs=get;list;watch
+
+// operatorConfigFor returns the fleet-wide defaults from the `QuayOperatorConfig`, or nil if there is none.
+func operatorConfigFor(ctx context.Context, c client.Client) (*v1.QuayOperatorConfigSpec, error) {
+	var operatorConfig v1.QuayOperatorConfig
+	if err := c.Get(ctx, types.NamespacedName{Name: v1.QuayOperatorConfigName}, &operatorConfig); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return operatorConfig.Spec.DeepCopy(), nil
+}
+
+// checkOperatorConfig sets `status.operatorConfig` to the fleet-wide defaults from the `QuayOperatorConfig`, or
+// clears it if there is none.
+func (r *QuayRegistryReconciler) checkOperatorConfig(ctx context.Context, quay *v1.QuayRegistry) (*v1.QuayRegistry, error) {
+	operatorConfig, err := operatorConfigFor(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+	quay.Status.OperatorConfig = operatorConfig
+
+	return quay, nil
+}
+
+// operatorConfigToRegistries maps a `QuayOperatorConfig` event to every `QuayRegistry`, since they all inherit it.
+func (r *QuayRegistryReconciler) operatorConfigToRegistries(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetName() != v1.QuayOperatorConfigName {
+		return nil
+	}
+
+	var quays v1.QuayRegistryList
+	if err := r.Client.List(context.Background(), &quays); err != nil {
+		r.Log.Error(err, "unable to list QuayRegistries for `QuayOperatorConfig`")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, quay := range quays.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: quay.GetNamespace(),
+			Name:      quay.GetName(),
+		}})
+	}
+
+	return requests
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/policy"
+)
+
+// ValidatingWebhookPath is the path the `QuayRegistryValidator` is served on by the webhook server.
+const ValidatingWebhookPath = "/validate-quay-redhat-com-v1-quayregistry"
+
+// +kubebuilder:webhook:path=/validate-quay-redhat-com-v1-quayregistry,mutating=false,failurePolicy=fail,groups=quay.redhat.com,resources=quayregistries,verbs=create;update,versions=v1,name=vquayregistry.quay.redhat.com
+
+// checkPolicies returns the violations of the security policies enforced by the `QuayOperatorConfig`, or nil if
+// there are none.
+func checkPolicies(quay *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	policies := quay.Status.OperatorConfig.EnforcedPolicies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	config, err := effectiveConfigFor(quay, configBundle)
+	if err != nil {
+		return v1.NewReasonedError(v1.ErrorReasonConfigConflict, err)
+	}
+
+	if violations := policy.Check(policies, quay, config); len(violations) > 0 {
+		return v1.NewReasonedError(v1.ErrorReasonPolicyViolation, utilerrors.NewAggregate(violations))
+	}
+
+	return nil
+}
+
+// enforcePolicies reports the result of checking the enforced policies in the status of the `QuayRegistry`, returning
+// the violations which must block the rollout.
+func (r *QuayRegistryReconciler) enforcePolicies(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	violations := checkPolicies(quay, configBundle)
+	if violations == nil && v1.GetCondition(quay.Status.Conditions, v1.ConditionTypePolicyViolated) == nil {
+		return nil
+	}
+
+	if err := r.updateConditions(ctx, quay, policyConditions(violations)...); err != nil {
+		r.Log.Error(err, "could not update QuayRegistry `status.conditions`")
+	}
+
+	return violations
+}
+
+// policyConditions returns the conditions describing the result of checking the enforced policies.
+func policyConditions(violations error) []v1.Condition {
+	if violations == nil {
+		return []v1.Condition{{
+			Type:   v1.ConditionTypePolicyViolated,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonPoliciesSatisfied,
+		}}
+	}
+
+	return []v1.Condition{
+		{
+			Type:    v1.ConditionTypePolicyViolated,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ConditionReasonPolicyViolation,
+			Message: violations.Error(),
+		},
+		{
+			Type:    v1.ConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ConditionReasonPolicyViolation,
+			Message: violations.Error(),
+		},
+	}
+}
+
+// QuayRegistryValidator rejects a `QuayRegistry` which violates the security policies enforced by the
+// `QuayOperatorConfig`, so that the violation is reported when it is applied rather than only in its status.
+type QuayRegistryValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder is called by the webhook server to set the decoder of admission requests.
+func (v *QuayRegistryValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+
+	return nil
+}
+
+// Handle checks the `QuayRegistry` being created or updated against the enforced policies.
+func (v *QuayRegistryValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	var quay v1.QuayRegistry
+	if err := v.decoder.Decode(req, &quay); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	operatorConfig, err := operatorConfigFor(ctx, v.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(operatorConfig.EnforcedPolicies()) == 0 {
+		return admission.Allowed("")
+	}
+
+	// Components which are not declared are added by the Operator, so they are checked as they will be deployed.
+	updatedQuay, err := v1.EnsureDefaultComponents(&quay)
+	if err != nil {
+		// Components which cannot be used are reported by the Operator once it has checked the available APIs.
+		updatedQuay = quay.DeepCopy()
+	}
+	updatedQuay.Status.OperatorConfig = operatorConfig
+
+	configBundle := corev1.Secret{Data: map[string][]byte{"config.yaml": encode(kustomize.BaseConfig())}}
+	if name := updatedQuay.Spec.ConfigBundleSecret; name != "" {
+		if err := v.Client.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: name}, &configBundle); err != nil {
+			// The config bundle may be created after the `QuayRegistry`, in which case it is checked when reconciled.
+			if errors.IsNotFound(err) {
+				return admission.Allowed("config bundle not found, policies are checked once it exists")
+			}
+
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
+	if err := checkPolicies(updatedQuay, &configBundle); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
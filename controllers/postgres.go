@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/webhookcert"
+)
+
+// postgresUpdatePollInterval is how often a `QuayRegistry` is requeued to check the backup of its managed database.
+const postgresUpdatePollInterval = time.Minute
+
+// recordPostgresImage records the image the `Deployment` of a managed database deployed before its image was tracked
+// runs, so that it is backed up before being updated to the image of its version. An image without a version in its
+// tag, such as `postgres:latest`, is recorded without one, which blocks the update until the version is declared.
+func (r *QuayRegistryReconciler) recordPostgresImage(ctx context.Context, quay *v1.QuayRegistry) error {
+	if quay.Status.Postgres != nil || !v1.ComponentIsManaged(quay.Spec.Components, "postgres") || quay.Spec.DryRun {
+		return nil
+	}
+
+	var deployment appsv1.Deployment
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName() + "-quay-postgres"}, &deployment); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+
+	image := deployment.Spec.Template.Spec.Containers[0].Image
+	r.Log.Info("recording image of managed database", "quayregistry", quay.GetNamespace()+"/"+quay.GetName(), "image", image)
+
+	quay.Status.Postgres = &v1.PostgresStatus{
+		Version: v1.PostgresVersionOf(image),
+		Image:   image,
+		Phase:   v1.PostgresUpdatePhaseCurrent,
+	}
+
+	return r.Client.Status().Update(ctx, quay)
+}
+
+// recordClairPostgresImage records the image of the `Deployment` of the database of the managed `clair` component
+// once it exists, which marks that the database has been initialized with a password.
+func (r *QuayRegistryReconciler) recordClairPostgresImage(ctx context.Context, quay *v1.QuayRegistry) error {
+	if quay.Status.ClairPostgresImage != "" || !v1.ComponentIsManaged(quay.Spec.Components, "clair") || quay.Spec.DryRun {
+		return nil
+	}
+
+	var deployment appsv1.Deployment
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName() + "-clair-postgres"}, &deployment); errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+
+	quay.Status.ClairPostgresImage = deployment.Spec.Template.Spec.Containers[0].Image
+
+	return r.Client.Status().Update(ctx, quay)
+}
+
+// withDatabasePassword copies the stored password of the managed database into the annotations, so the enforced
+// policies can check it before the registry is rendered.
+func withDatabasePassword(quay *v1.QuayRegistry, secretKeysBundle *corev1.Secret) *v1.QuayRegistry {
+	annotations := quay.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	delete(annotations, v1.DatabasePasswordAnnotation)
+
+	if password := kustomize.DatabasePasswordFor(quay, secretKeysBundle); password != "" && v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		annotations[v1.DatabasePasswordAnnotation] = password
+	}
+	quay.SetAnnotations(annotations)
+
+	return quay
+}
+
+// postgresJob returns the rendered `Job` backing up the managed database, or restoring its backup, if any.
+func postgresJob(objects []k8sruntime.Object) *batchv1.Job {
+	for _, obj := range objects {
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			continue
+		}
+
+		switch job.GetLabels()["quay-component"] {
+		case kustomize.PostgresBackupComponent, kustomize.PostgresRestoreComponent:
+			return job
+		}
+	}
+
+	return nil
+}
+
+// nextPostgresStatus returns the status of the managed database after inspecting the `Job` backing it up, or
+// restoring its backup once a major version upgrade has been backed up.
+func nextPostgresStatus(quay *v1.QuayRegistry, job *batchv1.Job) *v1.PostgresStatus {
+	if !v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		return nil
+	}
+
+	desired := v1.DesiredPostgresImageFor(quay)
+	existing := quay.Status.Postgres
+	phase := v1.PostgresUpdatePhaseFor(quay)
+	if phase == v1.PostgresUpdatePhaseCurrent {
+		status := &v1.PostgresStatus{Version: v1.PostgresVersionFor(quay), Image: desired, Phase: phase}
+		if existing != nil {
+			status.Message = existing.Message
+			status.DataDirectory = existing.DataDirectory
+		}
+
+		return status
+	}
+
+	status := &v1.PostgresStatus{
+		Version:       existing.Version,
+		Image:         existing.Image,
+		TargetImage:   desired,
+		Phase:         phase,
+		DataDirectory: existing.DataDirectory,
+	}
+	if existing.TargetImage == desired {
+		status.Message = existing.Message
+	} else {
+		status.Message = "backing up database before updating it from " + existing.Image + " to " + desired
+	}
+
+	if job == nil {
+		return status
+	}
+
+	if phase == v1.PostgresUpdatePhaseRestoring || phase == v1.PostgresUpdatePhaseRestoreFailed {
+		return nextPostgresRestoreStatus(quay, status, job)
+	}
+
+	if message, failed := jobFailed(job); failed {
+		status.Phase = v1.PostgresUpdatePhaseFailed
+		status.Message = job.GetName() + " failed: " + message
+
+		return status
+	}
+
+	if job.Status.Succeeded > 0 && existing.Version == "" && !v1.PostgresVersionDeclared(quay) {
+		status.Phase = v1.PostgresUpdatePhaseBlocked
+		status.Message = "backed up database, but not updating it from " + existing.Image + " to " + desired + " since its major version is unknown, set `spec.postgres.version` to the major version of its data"
+
+		return status
+	}
+
+	if job.Status.Succeeded > 0 && v1.PostgresMajorUpgradePending(quay) {
+		status.Phase = v1.PostgresUpdatePhaseRestoring
+		status.Message = "backed up database, restoring it with " + desired + " to upgrade it from " + existing.Image
+
+		return status
+	}
+
+	if job.Status.Succeeded > 0 {
+		status.Version = v1.PostgresVersionFor(quay)
+		status.Image = desired
+		status.TargetImage = ""
+		status.Phase = v1.PostgresUpdatePhaseCurrent
+		status.Message = "backed up database and updated it from " + existing.Image + " to " + desired
+	}
+
+	return status
+}
+
+// nextPostgresRestoreStatus returns the status of the managed database after inspecting the `Job` restoring its backup
+// into the data directory of its new major version. The previous data directory is kept, so the upgrade is rolled
+// back by setting `spec.postgres.version` back to the recorded version.
+func nextPostgresRestoreStatus(quay *v1.QuayRegistry, status *v1.PostgresStatus, job *batchv1.Job) *v1.PostgresStatus {
+	previous := status.Image
+	if message, failed := jobFailed(job); failed {
+		status.Phase = v1.PostgresUpdatePhaseRestoreFailed
+		status.Message = job.GetName() + " failed: " + message + ", delete it to retry, or set `spec.postgres.version` back to " +
+			string(status.Version) + " to keep running " + previous
+
+		return status
+	}
+
+	if job.Status.Succeeded > 0 {
+		status.Version = v1.PostgresVersionFor(quay)
+		status.Image = status.TargetImage
+		status.TargetImage = ""
+		status.Phase = v1.PostgresUpdatePhaseCurrent
+		status.DataDirectory = v1.PostgresDataDirectoryFor(quay)
+		status.Message = "restored backup of database and upgraded it from " + previous + " to " + status.Image
+
+		return status
+	}
+
+	if status.Phase == v1.PostgresUpdatePhaseRestoreFailed {
+		status.Phase = v1.PostgresUpdatePhaseRestoring
+		status.Message = "retrying restore of backup of database with " + status.TargetImage
+	}
+
+	return status
+}
+
+// progressPostgresUpdate advances `status.postgres` once the database has been backed up, or its backup restored,
+// which rolls out the new image on the next reconcile. Returns true if the update is still in progress.
+func (r *QuayRegistryReconciler) progressPostgresUpdate(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) (bool, error) {
+	var live *batchv1.Job
+	if job := postgresJob(objects); job != nil {
+		live = &batchv1.Job{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: job.GetNamespace(), Name: job.GetName()}, live); err != nil {
+			return true, err
+		}
+	}
+
+	existing := quay.Status.Postgres
+	status := nextPostgresStatus(quay, live)
+	if reflect.DeepEqual(status, existing) {
+		return status != nil && (status.Phase == v1.PostgresUpdatePhaseBackingUp || status.Phase == v1.PostgresUpdatePhaseRestoring), r.reportDatabaseUpgrade(ctx, quay)
+	}
+
+	r.Log.Info("updating managed database status", "quayregistry", quay.GetNamespace()+"/"+quay.GetName(), "status", status)
+
+	quay.Status.Postgres = status
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return true, err
+	}
+	if err := r.reportDatabaseUpgrade(ctx, quay); err != nil {
+		return true, err
+	}
+
+	if status == nil || existing == nil || (status.Phase == existing.Phase && status.Image == existing.Image) {
+		return false, nil
+	}
+
+	switch status.Phase {
+	case v1.PostgresUpdatePhaseFailed:
+		r.recordEvent(quay, corev1.EventTypeWarning, "PostgresBackupFailed", status.Message)
+	case v1.PostgresUpdatePhaseBlocked:
+		r.recordEvent(quay, corev1.EventTypeWarning, "PostgresUpdateBlocked", status.Message)
+	case v1.PostgresUpdatePhaseBackingUp:
+		r.recordEvent(quay, corev1.EventTypeNormal, "PostgresBackingUp", status.Message)
+	case v1.PostgresUpdatePhaseRestoring:
+		r.recordEvent(quay, corev1.EventTypeNormal, "PostgresRestoring", status.Message)
+	case v1.PostgresUpdatePhaseRestoreFailed:
+		r.recordEvent(quay, corev1.EventTypeWarning, "PostgresRestoreFailed", status.Message)
+	case v1.PostgresUpdatePhaseCurrent:
+		r.recordEvent(quay, corev1.EventTypeNormal, "PostgresUpdated", status.Message)
+	}
+
+	// NOTE: The update is in progress until the new image is rendered, which happens on the next reconcile.
+	return true, nil
+}
+
+// databaseUpgradeCondition returns the `DatabaseUpgrading` condition for the progress of a major version upgrade of
+// the managed database, or nil if the condition should be left as is.
+func databaseUpgradeCondition(quay *v1.QuayRegistry) *v1.Condition {
+	if !v1.PostgresMajorUpgradePending(quay) {
+		if existing := v1.GetCondition(quay.Status.Conditions, v1.ConditionTypeDatabaseUpgrading); existing == nil || existing.Status == metav1.ConditionFalse {
+			return nil
+		}
+
+		condition := &v1.Condition{
+			Type:   v1.ConditionTypeDatabaseUpgrading,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonDatabaseUpgraded,
+		}
+		if quay.Status.Postgres != nil {
+			condition.Message = "database runs " + quay.Status.Postgres.Image
+		}
+
+		return condition
+	}
+
+	condition := &v1.Condition{
+		Type:    v1.ConditionTypeDatabaseUpgrading,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ConditionReasonDatabaseBackingUp,
+		Message: quay.Status.Postgres.Message,
+	}
+	switch v1.PostgresUpdatePhaseFor(quay) {
+	case v1.PostgresUpdatePhaseRestoring:
+		condition.Reason = v1.ConditionReasonDatabaseRestoring
+	case v1.PostgresUpdatePhaseFailed, v1.PostgresUpdatePhaseRestoreFailed:
+		condition.Reason = v1.ConditionReasonDatabaseUpgradeFailed
+	}
+
+	return condition
+}
+
+// reportDatabaseUpgrade reports the progress of a major version upgrade of the managed database, during which Quay
+// is stopped, in the `DatabaseUpgrading` condition.
+func (r *QuayRegistryReconciler) reportDatabaseUpgrade(ctx context.Context, quay *v1.QuayRegistry) error {
+	condition := databaseUpgradeCondition(quay)
+	if condition == nil {
+		return nil
+	}
+
+	return r.updateConditions(ctx, quay, *condition)
+}
+
+// withExternalDatabaseFiles returns a copy of the config bundle including the `DB_URI` and CA of the database in
+// `spec.externalDatabase`.
+func (r *QuayRegistryReconciler) withExternalDatabaseFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if quay.Spec.ExternalDatabase == nil {
+		return configBundle, nil
+	}
+
+	var credentialsSecret corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.Spec.ExternalDatabase.CredentialsSecret}, &credentialsSecret); err != nil {
+		return nil, err
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+
+	withFiles.Data[kustomize.ExternalDatabaseURIFile] = credentialsSecret.Data["DB_URI"]
+	if caCert, ok := credentialsSecret.Data["ca.crt"]; ok {
+		withFiles.Data[kustomize.DatabaseCAFile] = caCert
+	}
+
+	return withFiles, nil
+}
+
+// withDatabaseReadReplicaFiles returns a copy of the config bundle including the `DB_URI` of each read replica in
+// `spec.databaseReadReplicas`.
+func (r *QuayRegistryReconciler) withDatabaseReadReplicaFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if quay.Spec.DatabaseReadReplicas == nil || len(quay.Spec.DatabaseReadReplicas.CredentialsSecrets) == 0 {
+		return configBundle, nil
+	}
+
+	var uris []string
+	for _, name := range quay.Spec.DatabaseReadReplicas.CredentialsSecrets {
+		var credentialsSecret corev1.Secret
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: name}, &credentialsSecret); err != nil {
+			return nil, err
+		}
+		uris = append(uris, strings.TrimSpace(string(credentialsSecret.Data["DB_URI"])))
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+	withFiles.Data[kustomize.DatabaseReadReplicaURIsFile] = []byte(strings.Join(uris, "\n"))
+
+	return withFiles, nil
+}
+
+// withDatabaseCertificates returns a copy of the config bundle including the CA of the certificate of each managed
+// database which is served over TLS, which Quay and Clair verify the databases with.
+func (r *QuayRegistryReconciler) withDatabaseCertificates(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	caFiles := kustomize.ManagedDatabaseCAFiles(quay)
+	if len(caFiles) == 0 {
+		return configBundle, nil
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+
+	for database, caFile := range caFiles {
+		generated, err := r.serviceCertificate(ctx, quay, quay.GetName()+"-"+database, v1.DatabaseTLSSecretFor(quay, database), database)
+		if err != nil {
+			return nil, err
+		}
+		withFiles.Data[caFile] = generated[webhookcert.CAKey]
+	}
+
+	return withFiles, nil
+}
+
+// withPostgresClusterFiles returns a copy of the config bundle including the `DB_URI` of the user of Quay and the CA of
+// the `PostgresCluster` of the managed database, once the Crunchy Postgres Operator has created them.
+func (r *QuayRegistryReconciler) withPostgresClusterFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if !v1.UsesPostgresCluster(quay) {
+		return configBundle, nil
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+
+	files := []struct {
+		secret string
+		key    string
+		file   string
+	}{
+		{kustomize.PostgresClusterUserSecretFor(quay), "uri", kustomize.PostgresClusterURIFile},
+		{kustomize.PostgresClusterCertSecretFor(quay), "ca.crt", kustomize.DatabaseCAFile},
+	}
+	for _, file := range files {
+		var secret corev1.Secret
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: file.secret}, &secret); errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if contents, ok := secret.Data[file.key]; ok {
+			withFiles.Data[file.file] = contents
+		}
+	}
+
+	return withFiles, nil
+}
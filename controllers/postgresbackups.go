@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+
+// nextPostgresBackupStatus returns the result of the scheduled backups of the managed database after inspecting the
+// `Jobs` created by its `CronJob`, or nil if no schedule is declared. The time of the last successful backup is kept
+// after its `Job` is deleted by the history limit of the `CronJob`.
+func nextPostgresBackupStatus(quay *v1.QuayRegistry, jobs []batchv1.Job) *v1.PostgresBackupStatus {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Backups == nil {
+		return nil
+	}
+
+	status := &v1.PostgresBackupStatus{}
+	if existing := quay.Status.PostgresBackup; existing != nil {
+		status = existing.DeepCopy()
+	}
+
+	var latest *batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		_, failed := jobFailed(job)
+		if job.Status.Succeeded == 0 && !failed {
+			continue
+		}
+
+		if job.Status.Succeeded > 0 && job.Status.CompletionTime != nil &&
+			(status.LastSuccessfulTime == nil || status.LastSuccessfulTime.Before(job.Status.CompletionTime)) {
+			completed := *job.Status.CompletionTime
+			status.LastSuccessfulTime = &completed
+		}
+
+		if latest == nil || latest.CreationTimestamp.Before(&job.CreationTimestamp) {
+			latest = job
+		}
+	}
+
+	if latest == nil {
+		return status
+	}
+
+	status.LastJob = latest.GetName()
+	if message, failed := jobFailed(latest); failed {
+		status.Failed = true
+		status.Message = latest.GetName() + " failed: " + message
+	} else {
+		status.Failed = false
+		status.Message = "backed up database to " + kustomize.PostgresBackupPrefixFor(quay)
+	}
+
+	return status
+}
+
+// reportPostgresBackups reports the result of the last scheduled backup of the managed database in
+// `status.postgresBackup`, and records an event when a backup fails.
+func (r *QuayRegistryReconciler) reportPostgresBackups(ctx context.Context, quay *v1.QuayRegistry) error {
+	var jobs batchv1.JobList
+	if quay.Spec.Postgres != nil && quay.Spec.Postgres.Backups != nil {
+		if err := r.Client.List(ctx, &jobs, client.InNamespace(quay.GetNamespace()), client.MatchingLabels{
+			"quay-component": kustomize.PostgresScheduledBackupComponent,
+		}); err != nil {
+			return err
+		}
+	}
+
+	existing := quay.Status.PostgresBackup
+	status := nextPostgresBackupStatus(quay, jobs.Items)
+	if reflect.DeepEqual(status, existing) {
+		return nil
+	}
+
+	quay.Status.PostgresBackup = status
+	if err := r.Client.Status().Update(ctx, quay); err != nil {
+		return err
+	}
+
+	if status != nil && status.Failed && (existing == nil || !existing.Failed || existing.LastJob != status.LastJob) {
+		r.recordEvent(quay, corev1.EventTypeWarning, "ScheduledBackupFailed", status.Message)
+	}
+
+	return nil
+}
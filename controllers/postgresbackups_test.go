@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+var backupsEpoch = time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+
+func scheduledBackupJob(name string, created int, succeeded bool, failed bool) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "ns-1",
+			Labels:            map[string]string{"quay-component": kustomize.PostgresScheduledBackupComponent},
+			CreationTimestamp: metav1.NewTime(backupsEpoch.Add(time.Duration(created) * time.Hour)),
+		},
+	}
+	if succeeded {
+		completed := metav1.NewTime(backupsEpoch.Add(time.Duration(created)*time.Hour + time.Minute))
+		job.Status.Succeeded = 1
+		job.Status.CompletionTime = &completed
+	}
+	if failed {
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "Job has reached the specified backoff limit"},
+		}
+	}
+
+	return job
+}
+
+func backupTime(created int) *metav1.Time {
+	completed := metav1.NewTime(backupsEpoch.Add(time.Duration(created)*time.Hour + time.Minute))
+
+	return &completed
+}
+
+var nextPostgresBackupStatusTests = []struct {
+	name     string
+	backups  *v1.PostgresBackups
+	existing *v1.PostgresBackupStatus
+	jobs     []batchv1.Job
+	expected *v1.PostgresBackupStatus
+}{
+	{
+		"Disabled",
+		nil,
+		&v1.PostgresBackupStatus{LastJob: "backup-1"},
+		nil,
+		nil,
+	},
+	{
+		"NoBackupYet",
+		&v1.PostgresBackups{Schedule: "0 3 * * *"},
+		nil,
+		[]batchv1.Job{scheduledBackupJob("backup-1", 0, false, false)},
+		&v1.PostgresBackupStatus{},
+	},
+	{
+		"Succeeded",
+		&v1.PostgresBackups{Schedule: "0 3 * * *"},
+		nil,
+		[]batchv1.Job{scheduledBackupJob("backup-1", 0, true, false), scheduledBackupJob("backup-2", 24, true, false)},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(24), LastJob: "backup-2", Message: "backed up database to quay-postgres-backups/ns-1/test/"},
+	},
+	{
+		"Failed",
+		&v1.PostgresBackups{Schedule: "0 3 * * *"},
+		nil,
+		[]batchv1.Job{scheduledBackupJob("backup-2", 24, false, true), scheduledBackupJob("backup-1", 0, true, false)},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(0), LastJob: "backup-2", Failed: true, Message: "backup-2 failed: Job has reached the specified backoff limit"},
+	},
+	{
+		"SucceededJobDeleted",
+		&v1.PostgresBackups{Schedule: "0 3 * * *"},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(48), LastJob: "backup-3", Message: "backed up database to quay-postgres-backups/ns-1/test/"},
+		[]batchv1.Job{scheduledBackupJob("backup-2", 24, true, false), scheduledBackupJob("backup-4", 72, false, false)},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(48), LastJob: "backup-2", Message: "backed up database to quay-postgres-backups/ns-1/test/"},
+	},
+	{
+		"RecoveredAfterFailure",
+		&v1.PostgresBackups{Schedule: "0 3 * * *"},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(0), LastJob: "backup-2", Failed: true, Message: "backup-2 failed: Job has reached the specified backoff limit"},
+		[]batchv1.Job{scheduledBackupJob("backup-2", 24, false, true), scheduledBackupJob("backup-3", 48, true, false)},
+		&v1.PostgresBackupStatus{LastSuccessfulTime: backupTime(48), LastJob: "backup-3", Message: "backed up database to quay-postgres-backups/ns-1/test/"},
+	},
+}
+
+func TestNextPostgresBackupStatus(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range nextPostgresBackupStatusTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec:       v1.QuayRegistrySpec{Postgres: &v1.ManagedPostgres{Backups: test.backups}},
+			Status:     v1.QuayRegistryStatus{PostgresBackup: test.existing},
+		}
+
+		assert.Equal(test.expected, nextPostgresBackupStatus(quay, test.jobs), test.name)
+	}
+}
+
+func TestReportPostgresBackups(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Spec:       v1.QuayRegistrySpec{Postgres: &v1.ManagedPostgres{Backups: &v1.PostgresBackups{Schedule: "0 3 * * *"}}},
+	}
+	succeeded := scheduledBackupJob("backup-1", 0, true, false)
+	other := scheduledBackupJob("backup-1", 24, false, true)
+	other.Namespace = "ns-2"
+
+	r, stub := stubReconciler(&succeeded, &other)
+	assert.Nil(r.reportPostgresBackups(context.Background(), quay))
+	assert.Len(stub.statusUpdated, 1)
+	assert.Equal(&v1.PostgresBackupStatus{
+		LastSuccessfulTime: backupTime(0),
+		LastJob:            "backup-1",
+		Message:            "backed up database to quay-postgres-backups/ns-1/test/",
+	}, quay.Status.PostgresBackup)
+
+	assert.Nil(r.reportPostgresBackups(context.Background(), quay))
+	assert.Len(stub.statusUpdated, 1, "unchanged status is not updated")
+}
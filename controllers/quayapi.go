@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+// bootstrapUserFor returns the superuser the Operator creates to obtain a Quay API token, which is the first of
+// `SUPER_USERS` in the config bundle, or an empty string if `FEATURE_USER_INITIALIZE` does not allow it.
+func bootstrapUserFor(configBundle *corev1.Secret) string {
+	var config struct {
+		UserInitialize bool     `json:"FEATURE_USER_INITIALIZE"`
+		SuperUsers     []string `json:"SUPER_USERS"`
+	}
+	if err := yaml.Unmarshal(configBundle.Data["config.yaml"], &config); err != nil {
+		return ""
+	}
+	if !config.UserInitialize || len(config.SuperUsers) == 0 {
+		return ""
+	}
+
+	return config.SuperUsers[0]
+}
+
+// quayAPIClient returns a client of the Quay API of the registry, authenticated with the token stored in its token
+// `Secret`, or nil if it has no token.
+func (r *QuayRegistryReconciler) quayAPIClient(ctx context.Context, quayRegistry *v1.QuayRegistry) (*quay.Client, error) {
+	var tokenSecret corev1.Secret
+	tokenSecretName := types.NamespacedName{Namespace: quayRegistry.GetNamespace(), Name: quay.TokenSecretName(quayRegistry)}
+	if err := r.Client.Get(ctx, tokenSecretName, &tokenSecret); kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	client := quay.NewClientFor(quayRegistry, &tokenSecret)
+	if !client.HasToken() {
+		return nil, nil
+	}
+
+	return client, nil
+}
+
+// ensureQuayAPIToken bootstraps the superuser of the config bundle once the registry is available, and stores its
+// credentials and Quay API token in the token `Secret`. Quay only creates the first user of an empty database, so the
+// `Secret` is also created if the user was rejected, without a token, so bootstrapping is not attempted again.
+func (r *QuayRegistryReconciler) ensureQuayAPIToken(ctx context.Context, quayRegistry *v1.QuayRegistry, configBundle *corev1.Secret) error {
+	if quayRegistry.Spec.DryRun || quayRegistry.Spec.Mode == v1.RegistryModeMirrorWorkers {
+		return nil
+	}
+
+	available := v1.GetCondition(quayRegistry.Status.Conditions, v1.ConditionTypeAvailable)
+	if available == nil || available.Status != metav1.ConditionTrue {
+		return nil
+	}
+
+	username := bootstrapUserFor(configBundle)
+	if username == "" {
+		return nil
+	}
+
+	var tokenSecret corev1.Secret
+	tokenSecretName := types.NamespacedName{Namespace: quayRegistry.GetNamespace(), Name: quay.TokenSecretName(quayRegistry)}
+	if err := r.Client.Get(ctx, tokenSecretName, &tokenSecret); err == nil || !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	secret, err := r.bootstrapQuayAPIToken(ctx, quayRegistry, quay.NewClientFor(quayRegistry, nil), username)
+	if err != nil {
+		return err
+	}
+
+	return r.Client.Create(ctx, secret)
+}
+
+// bootstrapQuayAPIToken creates the given superuser with a generated password through the given client, and returns
+// the token `Secret` storing its credentials and token.
+func (r *QuayRegistryReconciler) bootstrapQuayAPIToken(ctx context.Context, quayRegistry *v1.QuayRegistry, client *quay.Client, username string) (*corev1.Secret, error) {
+	password, err := quay.GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := client.Bootstrap(ctx, username, password, "")
+	var apiErr *quay.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+		// NOTE: The database already has users, so a token of an existing superuser must be added by hand.
+		r.recordEvent(quayRegistry, corev1.EventTypeWarning, "QuayAPITokenNotBootstrapped", "could not bootstrap "+username+": "+apiErr.Message)
+
+		return quay.TokenSecretFor(quayRegistry, username, "", ""), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	r.recordEvent(quayRegistry, corev1.EventTypeNormal, "QuayAPITokenBootstrapped", "bootstrapped "+username+" and stored its Quay API token in "+quay.TokenSecretName(quayRegistry))
+
+	return quay.TokenSecretFor(quayRegistry, username, password, token), nil
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/quay"
+)
+
+var bootstrapUserForTests = []struct {
+	name     string
+	config   string
+	expected string
+}{
+	{
+		"NotAllowed",
+		"SUPER_USERS:\n- quayadmin\n",
+		"",
+	},
+	{
+		"NoSuperUsers",
+		"FEATURE_USER_INITIALIZE: true\n",
+		"",
+	},
+	{
+		"FirstSuperUser",
+		"FEATURE_USER_INITIALIZE: true\nSUPER_USERS:\n- quayadmin\n- other\n",
+		"quayadmin",
+	},
+}
+
+func TestBootstrapUserFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range bootstrapUserForTests {
+		configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte(test.config)}}
+
+		assert.Equal(test.expected, bootstrapUserFor(configBundle), test.name)
+	}
+}
+
+var bootstrapQuayAPITokenTests = []struct {
+	name          string
+	statusCode    int
+	body          string
+	expectedToken string
+	expectedErr   bool
+}{
+	{"Bootstrapped", http.StatusOK, `{"access_token": "abc123"}`, "abc123", false},
+	{"DatabaseNotEmpty", http.StatusBadRequest, `{"message": "Cannot initialize user in a non-empty database"}`, "", false},
+	{"Unavailable", http.StatusServiceUnavailable, `{}`, "", true},
+}
+
+func TestBootstrapQuayAPIToken(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range bootstrapQuayAPITokenTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.statusCode)
+			_, _ = w.Write([]byte(test.body))
+		}))
+
+		// NOTE: Requests which fail because Quay is unavailable are retried until the deadline.
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		quayRegistry := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"}}
+		r, _ := stubReconciler()
+		secret, err := r.bootstrapQuayAPIToken(ctx, quayRegistry, quay.NewClient(server.URL, "", true), "quayadmin")
+		server.Close()
+		cancel()
+
+		if test.expectedErr {
+			assert.NotNil(err, test.name)
+			continue
+		}
+		assert.Nil(err, test.name)
+		assert.Equal("test-quay-registry-api-token", secret.GetName(), test.name)
+		assert.Equal("quayadmin", string(secret.Data["username"]), test.name)
+		assert.Equal(test.expectedToken, quay.TokenFromSecret(secret), test.name)
+		assert.Equal(test.expectedToken != "", len(secret.Data["password"]) > 0, test.name)
+	}
+}
+
+func TestQuayAPIClient(t *testing.T) {
+	assert := assert.New(t)
+
+	quayRegistry := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"}}
+
+	r, _ := stubReconciler()
+	client, err := r.quayAPIClient(context.Background(), quayRegistry)
+	assert.Nil(err)
+	assert.Nil(client, "no token `Secret`")
+
+	r, _ = stubReconciler(quay.TokenSecretFor(quayRegistry, "quayadmin", "", ""))
+	client, err = r.quayAPIClient(context.Background(), quayRegistry)
+	assert.Nil(err)
+	assert.Nil(client, "no token")
+
+	r, _ = stubReconciler(quay.TokenSecretFor(quayRegistry, "quayadmin", "password", "abc123"))
+	client, err = r.quayAPIClient(context.Background(), quayRegistry)
+	assert.Nil(err)
+	assert.True(client.HasToken())
+}
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -34,12 +35,14 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	quayredhatcomv1 "github.com/quay/quay-operator/api/v1"
 	v1 "github.com/quay/quay-operator/api/v1"
 	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/secretkeys"
 )
 
 const upgradePollInterval = time.Second * 10
@@ -48,13 +51,16 @@ const upgradePollTimeout = time.Second * 120
 // QuayRegistryReconciler reconciles a QuayRegistry object
 type QuayRegistryReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
-	Config *rest.Config
+	Log           logr.Logger
+	Scheme        *runtime.Scheme
+	Config        *rest.Config
+	EventRecorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=quay.redhat.com.quay.redhat.com,resources=quayregistries,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=quay.redhat.com.quay.redhat.com,resources=quayregistries/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 // TODO(alecmerdler): Define needed RBAC permissions for all consumed API resources...
 
 func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -65,13 +71,68 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 
 	var quay v1.QuayRegistry
 	if err := r.Client.Get(ctx, req.NamespacedName, &quay); err != nil {
+		if errors.IsNotFound(err) {
+			kustomize.ForgetRendered(req.NamespacedName)
+			forgetApplied(req.NamespacedName)
+			forgetCertificateExpiry(req.NamespacedName)
+			forgetFleetHealth(req.NamespacedName)
+			forgetBlobVerificationMetrics(req.NamespacedName)
+
+			if err := r.deleteConsoleLinks(ctx, req.NamespacedName); err != nil {
+				log.Error(err, "unable to delete `ConsoleLinks` for deleted QuayRegistry")
+			}
+		}
+
 		log.Error(err, "unable to retrieve QuayRegistry")
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	reportFleetHealth(&quay)
+
+	if !quay.GetDeletionTimestamp().IsZero() {
+		// NOTE: Builders are deleted before anything else, while their build manager is still running.
+		if result, handled, err := r.finalizeBuilders(ctx, &quay); err != nil {
+			log.Error(err, "unable to delete builders of deleted QuayRegistry")
+			return ctrl.Result{RequeueAfter: builderDeletionInterval}, nil
+		} else if handled {
+			return result, nil
+		}
+
+		result, err := r.finalizeObjectBucketClaim(ctx, &quay)
+		if err != nil {
+			log.Error(err, "unable to release `ObjectBucketClaim` of deleted QuayRegistry")
+			return ctrl.Result{RequeueAfter: objectBucketClaimDeletionInterval}, nil
+		}
+		return result, nil
+	}
+
+	if migrated, err := r.migrateStorageAnnotations(ctx, &quay); err != nil {
+		log.Error(err, "failed to migrate object storage annotations of QuayRegistry to `spec.objectStorage`")
+		return ctrl.Result{}, nil
+	} else if migrated {
+		log.Info("migrated object storage annotations of QuayRegistry to `spec.objectStorage`")
+		return ctrl.Result{}, nil
+	}
+
+	if removed, err := r.removeStoredStorageAnnotations(ctx, &quay); err != nil {
+		log.Error(err, "failed to remove object storage annotations from QuayRegistry")
+		return ctrl.Result{}, nil
+	} else if removed {
+		log.Info("removed object storage annotations from QuayRegistry")
+		return ctrl.Result{}, nil
+	}
+
+	if requested, err := r.handleReloadRequest(ctx, &quay); err != nil {
+		log.Error(err, "failed to remove `"+v1.ReloadConfigAnnotation+"` annotation from QuayRegistry")
+		return ctrl.Result{}, nil
+	} else if requested {
+		log.Info("reloading config bundle and re-applying every managed object, as requested by annotation")
+		return ctrl.Result{}, nil
+	}
+
 	updatedQuay := quay.DeepCopy()
 
-	if quay.Spec.ConfigBundleSecret == "" {
+	if quay.Spec.ConfigBundleSecret == "" && !quay.Spec.DryRun {
 		log.Info("`spec.configBundleSecret` is unset. Creating base `Secret`")
 
 		baseConfigBundle := corev1.Secret{
@@ -101,7 +162,10 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 	}
 
 	var configBundle corev1.Secret
-	if err := r.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.Spec.ConfigBundleSecret}, &configBundle); err != nil {
+	if quay.Spec.ConfigBundleSecret == "" {
+		// A dry run previews the registry using the base config bundle which would be created.
+		configBundle.Data = map[string][]byte{"config.yaml": encode(kustomize.BaseConfig())}
+	} else if err := r.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.Spec.ConfigBundleSecret}, &configBundle); err != nil {
 		log.Error(err, "unable to retrieve referenced `configBundleSecret`", "configBundleSecret", quay.Spec.ConfigBundleSecret)
 		return ctrl.Result{}, nil
 	}
@@ -114,15 +178,39 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		}
 	}
 
+	if !quay.Spec.DryRun {
+		restored, err := r.restoreSecretKeys(ctx, &quay, &secretKeysBundle)
+		if err != nil {
+			// NOTE: Generating new secret keys instead would make the existing database unreadable.
+			log.Error(err, "unable to restore secret keys from `spec.secretKeysBackup`")
+
+			if reportErr := r.reportLastError(ctx, &quay, err, v1.ErrorReasonSecretKeysRestoreFailed); reportErr != nil {
+				log.Error(reportErr, "could not update QuayRegistry `status.lastError`")
+			}
+
+			return ctrl.Result{}, nil
+		}
+		if restored {
+			r.recordEvent(&quay, corev1.EventTypeNormal, "SecretKeysRestored", "restored secret keys from `spec.secretKeysBackup`")
+		}
+	}
+
 	log.Info("successfully retrieved referenced `configBundleSecret`", "configBundleSecret", configBundle.GetName(), "resourceVersion", configBundle.GetResourceVersion())
 
 	updatedQuay, err := v1.EnsureDesiredVersion(&quay)
 	if err != nil {
 		log.Error(err, "could not ensure `spec.desiredVersion`")
+
+		if blocked := unsupportedUpgradeCondition(&quay); blocked != nil {
+			if err = r.updateConditions(ctx, &quay, *blocked); err != nil {
+				log.Error(err, "could not update QuayRegistry `status.conditions`")
+			}
+		}
+
 		return ctrl.Result{}, nil
 	}
 
-	if quay.Spec.DesiredVersion != updatedQuay.Spec.DesiredVersion {
+	if quay.Spec.DesiredVersion != updatedQuay.Spec.DesiredVersion && !quay.Spec.DryRun {
 		log.Info("updating QuayRegistry `spec.desiredVersion`")
 		if err = r.Client.Update(ctx, updatedQuay); err != nil {
 			log.Error(err, "failed to update `spec.desiredVersion`")
@@ -143,13 +231,25 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
 	}
 
+	updatedQuay, err = r.checkPostgresClustersAvailable(updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "could not check for `PostgresCluster` API")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
+	updatedQuay, err = r.checkServiceMonitorsAvailable(updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "could not check for `ServiceMonitor` API")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
 	updatedQuay, err = v1.EnsureDefaultComponents(updatedQuay.DeepCopy())
 	if err != nil {
 		log.Error(err, "could not ensure default `spec.components`")
 		return ctrl.Result{}, nil
 	}
 
-	if !v1.ComponentsMatch(quay.Spec.Components, updatedQuay.Spec.Components) {
+	if !v1.ComponentsMatch(quay.Spec.Components, updatedQuay.Spec.Components) && !quay.Spec.DryRun {
 		log.Info("updating QuayRegistry `spec.components` to include defaults")
 		if err = r.Client.Update(ctx, updatedQuay); err != nil {
 			log.Error(err, "failed to update `spec.components` to include defaults")
@@ -158,21 +258,275 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		return ctrl.Result{}, nil
 	}
 
+	if updated, err := r.ensureObjectBucketClaimFinalizer(ctx, &quay, updatedQuay.Spec.Components); err != nil {
+		log.Error(err, "failed to update QuayRegistry `ObjectBucketClaim` finalizer")
+		return ctrl.Result{}, nil
+	} else if updated {
+		return ctrl.Result{}, nil
+	}
+
+	if updated, err := r.ensureBuildersFinalizer(ctx, &quay, &configBundle); err != nil {
+		log.Error(err, "failed to update QuayRegistry builders finalizer")
+		return ctrl.Result{}, nil
+	} else if updated {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.cancelDisabledBuilds(ctx, &quay, &configBundle); err != nil {
+		log.Error(err, "could not cancel builds of disabled builds")
+	}
+
+	// NOTE: The storage credentials are only copied into the annotations once the `QuayRegistry` is no longer updated.
+	updatedQuay, err = r.checkObjectBucketClaimCredentials(updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "could not retrieve `ObjectBucketClaim` credentials")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
+	updatedQuay, err = r.checkObjectStorageCredentials(updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "could not retrieve object storage credentials")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
+	updatedQuay, err = r.checkMinIOCredentials(updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "could not ensure MinIO credentials")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+	// NOTE: Status updates replace the `QuayRegistry` with the stored one, which has none of the copied credentials.
+	storageFingerprint := storageCredentialsFingerprint(updatedQuay)
+
+	updatedQuay, err = r.checkOperatorConfig(ctx, updatedQuay.DeepCopy())
+	if err != nil {
+		log.Error(err, "unable to retrieve `QuayOperatorConfig`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err := r.withAuthenticationFiles(ctx, updatedQuay, &configBundle)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secret` referenced by `spec.authentication`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withBuildTriggerFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secret` referenced by `spec.buildTriggers`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withExternalDatabaseFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secret` referenced by `spec.externalDatabase`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withExternalRedisFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secret` referenced by `spec.externalRedis`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withPostgresClusterFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secrets` of the `PostgresCluster` of the managed database")
+		return ctrl.Result{}, nil
+	}
+	// NOTE: Quay cannot connect to a `PostgresCluster` until the Crunchy Postgres Operator has created its user.
+	_, hasDatabaseURI := configBundleWithFiles.Data[kustomize.PostgresClusterURIFile]
+	waitingForDatabase := v1.UsesPostgresCluster(updatedQuay) && !hasDatabaseURI
+
+	configBundleWithFiles, err = r.withDatabaseReadReplicaFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secrets` referenced by `spec.databaseReadReplicas`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withObjectStorageFiles(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "unable to retrieve `Secret` or `ConfigMap` referenced by `spec.objectStorage`")
+		return ctrl.Result{}, nil
+	}
+
+	configBundleWithFiles, err = r.withClairCertificate(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "could not ensure certificate of managed Clair")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
+	configBundleWithFiles, err = r.withDatabaseCertificates(ctx, updatedQuay, configBundleWithFiles)
+	if err != nil {
+		log.Error(err, "could not ensure certificates of managed databases")
+		return ctrl.Result{RequeueAfter: time.Millisecond * 1000}, nil
+	}
+
+	updatedQuay = withDatabasePassword(updatedQuay.DeepCopy(), &secretKeysBundle)
+
+	if violations := r.enforcePolicies(ctx, updatedQuay, configBundleWithFiles); violations != nil {
+		log.Info("not rolling out QuayRegistry which violates enforced policies", "violations", violations.Error())
+
+		if reportErr := r.reportLastError(ctx, updatedQuay, violations, v1.ErrorReasonPolicyViolation); reportErr != nil {
+			log.Error(reportErr, "could not update QuayRegistry `status.lastError`")
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if err = r.reportDeprecatedConfig(ctx, updatedQuay, configBundleWithFiles); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.conditions` with deprecated config fields")
+	}
+
+	if err = r.recordPostgresImage(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not record image of managed database in QuayRegistry `status.postgres`")
+		return ctrl.Result{}, nil
+	}
+	if err = r.recordClairPostgresImage(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not record image of managed Clair database in QuayRegistry `status.clairPostgresImage`")
+		return ctrl.Result{}, nil
+	}
+
 	log.Info("inflating QuayRegistry into Kubernetes objects using Kustomize")
-	deploymentObjects, err := kustomize.Inflate(updatedQuay, &configBundle, &secretKeysBundle, log)
+	deploymentObjects, err := kustomize.InflateCached(updatedQuay, configBundleWithFiles, &secretKeysBundle, log)
 	if err != nil {
 		log.Error(err, "could not inflate QuayRegistry into Kubernetes objects")
+
+		if reportErr := r.reportLastError(ctx, updatedQuay, err, v1.ErrorReasonConfigConflict); reportErr != nil {
+			log.Error(reportErr, "could not update QuayRegistry `status.lastError`")
+		}
+
+		invalid := v1.Condition{
+			Type:    v1.ConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ConditionReasonInvalidConfiguration,
+			Message: err.Error(),
+		}
+		if err = r.updateConditions(ctx, updatedQuay, invalid); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.conditions`")
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	deploymentObjects, paused := withoutPausedComponents(updatedQuay, deploymentObjects)
+	if len(paused) > 0 {
+		log.Info("skipping objects belonging to paused components", "pausedComponents", v1.PausedComponents(updatedQuay), "skipped", len(paused))
+		// Ensure any changes made while paused are reverted once the component is resumed.
+		forgetAppliedObjects(req.NamespacedName, paused)
+	}
+
+	deploymentObjects = r.withPreservedReplicas(ctx, updatedQuay, deploymentObjects)
+	deploymentObjects, restarted := r.withConfigRestart(ctx, updatedQuay, deploymentObjects)
+	if restarted {
+		log.Info("only restart-only config fields changed, updating config bundle in place and restarting Quay pods")
+	}
+
+	if updatedQuay.Spec.DryRun {
+		log.Info("dry run, planning changes without applying them")
+
+		changes, err := r.planChanges(ctx, deploymentObjects)
+		if err != nil {
+			log.Error(err, "could not plan changes to managed objects")
+			return ctrl.Result{}, err
+		}
+		if err = r.reportPlannedChanges(ctx, updatedQuay, changes); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.plannedChanges`")
+		}
+
+		return ctrl.Result{RequeueAfter: driftCheckInterval}, nil
+	}
+
+	databaseSecretKey := kustomize.DatabaseSecretKeyFor(deploymentObjects)
+	if err = checkDatabaseSecretKey(updatedQuay, databaseSecretKey); err != nil {
+		log.Error(err, "refusing to roll out changed `DATABASE_SECRET_KEY`")
+
+		if reportErr := r.reportLastError(ctx, updatedQuay, err, v1.ErrorReasonConfigConflict); reportErr != nil {
+			log.Error(reportErr, "could not update QuayRegistry `status.lastError`")
+		}
+
+		invalid := v1.Condition{
+			Type:    v1.ConditionTypeDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  v1.ConditionReasonInvalidConfiguration,
+			Message: err.Error(),
+		}
+		if err = r.updateConditions(ctx, updatedQuay, invalid); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.conditions`")
+		}
+
 		return ctrl.Result{}, nil
 	}
 
-	for _, obj := range deploymentObjects {
+	changed, unchanged, components := changedObjects(req.NamespacedName, deploymentObjects)
+	changed, pending, pendingChanges, err := r.withoutUnapprovedChanges(ctx, updatedQuay, changed)
+	if err != nil {
+		log.Error(err, "could not plan changes to protected components")
+		return ctrl.Result{}, err
+	}
+	if len(pending) > 0 {
+		log.Info("holding back changes to protected components until they are approved", "pending", len(pending))
+	}
+	if err = r.reportPendingApproval(ctx, updatedQuay, pending); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.conditions`")
+	}
+	drifted := r.detectDrift(ctx, updatedQuay, unchanged)
+	if len(drifted) > 0 && updatedQuay.Spec.DriftPolicy != v1.DriftPolicyDetectOnly {
+		log.Info("reverting managed objects modified outside of the Operator", "drifted", len(drifted))
+		changed = append(changed, drifted...)
+	}
+	log.Info("applying changed objects", "changed", len(changed), "total", len(deploymentObjects), "components", components)
+
+	for _, obj := range changed {
+		key, hash := objectKey(obj), objectHash(obj)
+
 		err = r.createOrUpdateObject(ctx, obj, quay)
 		if err != nil {
 			log.Error(err, "all Kubernetes objects not created/updated successfully")
+
+			if reportErr := r.reportLastError(ctx, updatedQuay, err, v1.ErrorReasonUnknown); reportErr != nil {
+				log.Error(reportErr, "could not update QuayRegistry `status.lastError`")
+			}
+
+			degraded := v1.Condition{
+				Type:    v1.ConditionTypeDegraded,
+				Status:  metav1.ConditionTrue,
+				Reason:  v1.ConditionReasonComponentCreationFailed,
+				Message: err.Error(),
+			}
+			if err = r.updateConditions(ctx, updatedQuay, degraded); err != nil {
+				log.Error(err, "could not update QuayRegistry `status.conditions`")
+			}
+
 			return ctrl.Result{Requeue: true}, nil
 		}
+
+		recordApplied(req.NamespacedName, key, hash)
 	}
 	log.Info("all objects created/updated successfully")
+	if restarted {
+		r.recordEvent(updatedQuay, corev1.EventTypeNormal, "ConfigRestarted", "updated config bundle in place and restarted Quay pods, since only restart-only fields changed")
+	}
+
+	if err = r.backupSecretKeys(ctx, updatedQuay, secretKeysFor(updatedQuay, deploymentObjects, &secretKeysBundle)); err != nil {
+		log.Error(err, "could not back up secret keys to `spec.secretKeysBackup`")
+	}
+
+	if err = r.reconcileVolumeRetention(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not apply `spec.persistentVolumeRetentionPolicy`")
+	}
+
+	// NOTE: Outside of a dry run, only changes awaiting approval are reported.
+	if err = r.reportPlannedChanges(ctx, updatedQuay, pendingChanges); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.plannedChanges`")
+	}
+
+	// NOTE: Errors from the preflight check are reported, but do not prevent the registry from being deployed.
+	preflightErr := preflightCheck(updatedQuay, configBundleWithFiles)
+	if preflightErr != nil {
+		log.Info("preflight check of unmanaged components failed", "error", preflightErr.Error())
+	}
+	if err = r.reportLastError(ctx, updatedQuay, preflightErr, v1.ErrorReasonUnknown); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.lastError`")
+	}
 
 	if quay.Status.LastUpdate == "" {
 		updatedQuay.Status.LastUpdate = time.Now().UTC().String()
@@ -183,9 +537,69 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		}
 	}
 
-	if updatedQuay.Spec.DesiredVersion != updatedQuay.Status.CurrentVersion {
+	if fingerprint := secretkeys.Fingerprint(databaseSecretKey); fingerprint != "" && fingerprint != updatedQuay.Status.DatabaseSecretKeyFingerprint {
+		updatedQuay.Status.DatabaseSecretKeyFingerprint = fingerprint
+
+		if err = r.Client.Status().Update(ctx, updatedQuay); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.databaseSecretKeyFingerprint`")
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if err = r.reportStorageCredentials(ctx, updatedQuay, storageFingerprint); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.storageCredentialsFingerprint`")
+		return ctrl.Result{}, nil
+	}
+
+	if err = r.updateConditions(ctx, updatedQuay, driftCondition(updatedQuay, drifted)); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.conditions`")
+		return ctrl.Result{}, nil
+	}
+
+	expiring := r.checkCertificates(updatedQuay, deploymentObjects, &configBundle)
+	if expiring != "" {
+		log.Info("certificates used by QuayRegistry are expiring", "expiring", expiring)
+	}
+
+	dnsPropagated := dnsPropagationFor(ctx, updatedQuay, deploymentObjects)
+	dnsPending := dnsPropagated != nil && dnsPropagated.Status == metav1.ConditionFalse
+	if dnsPending {
+		log.Info("waiting for registry hostname to resolve", "message", dnsPropagated.Message)
+	}
+
+	wasSuspended := false
+	if available := v1.GetCondition(updatedQuay.Status.Conditions, v1.ConditionTypeAvailable); available != nil {
+		wasSuspended = available.Reason == v1.ConditionReasonRegistrySuspended
+	}
+
+	if updatedQuay.Spec.Suspend {
+		// NOTE: A pending upgrade is completed once the registry is resumed.
+		if err = r.updateConditions(ctx, updatedQuay, suspendedConditions()...); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.conditions`")
+			return ctrl.Result{}, nil
+		}
+		if !wasSuspended {
+			r.recordEvent(updatedQuay, corev1.EventTypeNormal, "RegistrySuspended", "scaled every component to zero")
+		}
+	} else if updatedQuay.Spec.DesiredVersion == updatedQuay.Status.CurrentVersion {
+		if err = r.updateConditions(ctx, updatedQuay, withDNSPropagation(withCertificateExpiry(withLocalStorage(availableConditions(v1.ConditionReasonComponentsCreationSuccess), updatedQuay), expiring), dnsPropagated)...); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.conditions`")
+			return ctrl.Result{}, nil
+		}
+	} else if updatedQuay.Spec.Mode == v1.RegistryModeMirrorWorkers {
+		// Mirror workers have no upgrade to wait for, since the existing registry migrates the database.
+		updatedQuay.Status.CurrentVersion = updatedQuay.Spec.DesiredVersion
+		if err = r.Client.Status().Update(ctx, updatedQuay); err != nil {
+			log.Error(err, "could not update QuayRegistry status with current version")
+			return ctrl.Result{}, nil
+		}
+		if err = r.updateConditions(ctx, updatedQuay, withDNSPropagation(withCertificateExpiry(withLocalStorage(availableConditions(v1.ConditionReasonComponentsCreationSuccess), updatedQuay), expiring), dnsPropagated)...); err != nil {
+			log.Error(err, "could not update QuayRegistry `status.conditions`")
+			return ctrl.Result{}, nil
+		}
+	} else {
 		go func(quayRegistry *v1.QuayRegistry) {
-			err = wait.Poll(upgradePollInterval, upgradePollTimeout, func() (bool, error) {
+			pollErr := wait.Poll(upgradePollInterval, upgradePollTimeout, func() (bool, error) {
 				log.Info("checking Quay upgrade deployment readiness")
 
 				var upgradeDeployment appsv1.Deployment
@@ -211,14 +625,145 @@ func (r *QuayRegistryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 						log.Error(err, "could not update QuayRegistry status with current version")
 						return true, err
 					}
+
+					r.notify(ctx, updatedQuay, v1.NotificationEventUpgradeComplete, "upgraded to "+string(updatedQuay.Status.CurrentVersion))
+					if err = r.updateConditions(ctx, updatedQuay, availableConditions(v1.ConditionReasonUpgradeComplete)...); err != nil {
+						log.Error(err, "could not update QuayRegistry `status.conditions`")
+						return true, err
+					}
+					if err = r.reportUpgradeable(ctx); err != nil {
+						log.Error(err, "could not report `Upgradeable` condition to OLM")
+					}
 				}
 
 				return upgradeDeployment.Status.ReadyReplicas > 0, nil
 			})
+
+			if pollErr == wait.ErrWaitTimeout {
+				var latest v1.QuayRegistry
+				if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quayRegistry.GetNamespace(), Name: quayRegistry.GetName()}, &latest); err != nil {
+					log.Error(err, "could not retrieve QuayRegistry to report pending migration")
+					return
+				}
+
+				pending := v1.NewReasonedError(v1.ErrorReasonMigrationPending, fmt.Errorf("database migration to %s has not completed", latest.Spec.DesiredVersion))
+				if err := r.reportLastError(ctx, &latest, pending, v1.ErrorReasonMigrationPending); err != nil {
+					log.Error(err, "could not update QuayRegistry `status.lastError`")
+				}
+			}
 		}(updatedQuay.DeepCopy())
 	}
 
-	return ctrl.Result{}, nil
+	if wasSuspended && !updatedQuay.Spec.Suspend {
+		r.recordEvent(updatedQuay, corev1.EventTypeNormal, "RegistryResumed", "scaled components back up")
+	}
+
+	if err = r.reportUpgradeable(ctx); err != nil {
+		log.Error(err, "could not report `Upgradeable` condition to OLM")
+	}
+
+	if err = r.reconcileConsoleLinks(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not create/update `ConsoleLinks`")
+	}
+
+	migrating, err := r.progressStorageMigration(ctx, updatedQuay, deploymentObjects)
+	if err != nil {
+		log.Error(err, "could not update QuayRegistry `status.storageMigration`")
+	}
+	updatingPostgres, err := r.progressPostgresUpdate(ctx, updatedQuay, deploymentObjects)
+	if err != nil {
+		log.Error(err, "could not update QuayRegistry `status.postgres`")
+	}
+	if err := r.reportPostgresBackups(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.postgresBackup`")
+	}
+	if err := r.reportBlobVerification(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not update QuayRegistry `status.blobVerification`")
+	}
+	if err := r.ensureQuayAPIToken(ctx, updatedQuay, &configBundle); err != nil {
+		log.Error(err, "could not bootstrap Quay API token")
+	}
+	if err := r.applyAutoPrunePolicy(ctx, updatedQuay); err != nil {
+		log.Error(err, "could not apply auto-prune policy to existing organizations")
+	}
+	if err := r.cleanUpBuilders(ctx, updatedQuay, &configBundle); err != nil {
+		log.Error(err, "could not delete builders of disabled builds")
+	}
+	polling, err := r.reportBuilds(ctx, updatedQuay, &configBundle)
+	if err != nil {
+		log.Error(err, "could not update QuayRegistry `status.builds`")
+	}
+	running, err := r.reportHealth(ctx, updatedQuay)
+	if err != nil {
+		log.Error(err, "could not report Quay health in QuayRegistry `status.conditions`")
+	}
+
+	if dnsPending {
+		return ctrl.Result{RequeueAfter: dnsPollInterval}, nil
+	}
+	if migrating {
+		return ctrl.Result{RequeueAfter: storageMigrationPollInterval}, nil
+	}
+	if updatingPostgres || waitingForDatabase {
+		return ctrl.Result{RequeueAfter: postgresUpdatePollInterval}, nil
+	}
+	if polling {
+		return ctrl.Result{RequeueAfter: buildPollInterval}, nil
+	}
+	if running {
+		return ctrl.Result{RequeueAfter: healthPollInterval}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: driftCheckInterval}, nil
+}
+
+// availableConditions returns the conditions describing a fully deployed registry.
+func availableConditions(reason string) []v1.Condition {
+	return []v1.Condition{
+		{
+			Type:   v1.ConditionTypeAvailable,
+			Status: metav1.ConditionTrue,
+			Reason: reason,
+		},
+		{
+			Type:   v1.ConditionTypeDegraded,
+			Status: metav1.ConditionFalse,
+			Reason: reason,
+		},
+	}
+}
+
+// suspendedConditions returns the conditions describing a registry scaled to zero by `spec.suspend`.
+func suspendedConditions() []v1.Condition {
+	return []v1.Condition{
+		{
+			Type:    v1.ConditionTypeAvailable,
+			Status:  metav1.ConditionFalse,
+			Reason:  v1.ConditionReasonRegistrySuspended,
+			Message: "every component is scaled to zero until `spec.suspend` is unset",
+		},
+		{
+			Type:   v1.ConditionTypeDegraded,
+			Status: metav1.ConditionFalse,
+			Reason: v1.ConditionReasonRegistrySuspended,
+		},
+	}
+}
+
+// unsupportedUpgradeCondition returns the `Degraded` condition reporting that `spec.desiredVersion` cannot be upgraded
+// to directly, or nil if it can. The registry keeps running its current version.
+func unsupportedUpgradeCondition(quay *v1.QuayRegistry) *v1.Condition {
+	err := v1.ValidateUpgradePath(quay)
+	if err == nil {
+		return nil
+	}
+
+	return &v1.Condition{
+		Type:    v1.ConditionTypeDegraded,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.ConditionReasonUnsupportedUpgradePath,
+		Message: err.Error(),
+	}
 }
 
 func encode(value interface{}) []byte {
@@ -268,8 +813,9 @@ func (r *QuayRegistryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&quayredhatcomv1.QuayRegistry{}).
-		// TODO(alecmerdler): Add `.Owns()` for every resource type we manage...
-		Complete(r)
+	// TODO(alecmerdler): Add `.Owns()` for every resource type we manage...
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&quayredhatcomv1.QuayRegistry{})
+
+	return r.watchReferencedObjects(builder).Complete(r)
 }
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// withExternalRedisFiles returns a copy of the config bundle including the `host`, `port` and `password` of the Redis
+// in `spec.externalRedis`.
+func (r *QuayRegistryReconciler) withExternalRedisFiles(ctx context.Context, quay *v1.QuayRegistry, configBundle *corev1.Secret) (*corev1.Secret, error) {
+	if quay.Spec.ExternalRedis == nil {
+		return configBundle, nil
+	}
+
+	var credentialsSecret corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.Spec.ExternalRedis.CredentialsSecret}, &credentialsSecret); err != nil {
+		return nil, err
+	}
+
+	credentials := map[string]string{}
+	for _, key := range []string{"host", "port", "password"} {
+		if value, ok := credentialsSecret.Data[key]; ok {
+			credentials[key] = string(value)
+		}
+	}
+	credentialsFile, err := yaml.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	withFiles := configBundle.DeepCopy()
+	if withFiles.Data == nil {
+		withFiles.Data = map[string][]byte{}
+	}
+	withFiles.Data[kustomize.ExternalRedisFile] = credentialsFile
+
+	return withFiles, nil
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+func TestWithExternalRedisFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Spec:       v1.QuayRegistrySpec{ExternalRedis: &v1.ExternalRedis{CredentialsSecret: "redis"}},
+	}
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "ns-1"},
+		Data: map[string][]byte{
+			"host":     []byte("redis.example.com"),
+			"password": []byte("s3cr3t"),
+		},
+	}
+	configBundle := &corev1.Secret{Data: map[string][]byte{"config.yaml": []byte("SERVER_HOSTNAME: quay.io\n")}}
+	r, _ := stubReconciler(credentialsSecret)
+
+	withFiles, err := r.withExternalRedisFiles(context.Background(), quay, configBundle)
+
+	assert.Nil(err)
+	assert.Equal([]byte("host: redis.example.com\npassword: s3cr3t\n"), withFiles.Data[kustomize.ExternalRedisFile])
+	assert.NotContains(configBundle.Data, kustomize.ExternalRedisFile)
+
+	config, err := kustomize.ExternalRedisConfigFor(quay, withFiles.Data)
+	assert.Nil(err)
+	assert.Equal("s3cr3t", config["BUILDLOGS_REDIS"].(map[string]interface{})["password"])
+
+	quay.Spec.ExternalRedis.CredentialsSecret = "missing"
+	_, err = r.withExternalRedisFiles(context.Background(), quay, configBundle)
+	assert.NotNil(err)
+}
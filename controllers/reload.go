@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// withConfigRestart returns the objects to apply so that a change to only restart-only config fields updates the
+// config bundle mounted by the running Quay pods in place and restarts them, rather than rolling out a new config
+// bundle to every `Deployment`. Returns true if the mounted config bundle is changed.
+func (r *QuayRegistryReconciler) withConfigRestart(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) ([]k8sruntime.Object, bool) {
+	rendered := kustomize.ConfigSecretFor(objects)
+	if rendered == nil {
+		return objects, false
+	}
+
+	var deployment appsv1.Deployment
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName() + "-quay-app"}, &deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "could not retrieve Quay `Deployment` to check for restart-only config")
+		}
+		return objects, false
+	}
+
+	deployedName := kustomize.ConfigSecretNameFor(&deployment)
+	if deployedName == "" || deployedName == rendered.GetName() {
+		return objects, false
+	}
+
+	var deployed corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: deployedName}, &deployed); err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "could not retrieve deployed config bundle to check for restart-only config")
+		}
+		return objects, false
+	}
+
+	if !kustomize.ConfigRestartOnly(deployed.Data, rendered.Data) {
+		return objects, false
+	}
+
+	// The config bundle keeps the name it was updated in place under until a change requires a rollout.
+	return kustomize.WithConfigChecksum(kustomize.WithConfigSecretName(objects, deployedName)), !reflect.DeepEqual(deployed.Data, rendered.Data)
+}
+
+// handleReloadRequest forgets the rendered and applied objects of a `QuayRegistry` with the `reload-config`
+// annotation, so that the config bundle is rendered again and every managed object applied on the next reconcile,
+// and removes the annotation, which triggers that reconcile. Returns true if the `QuayRegistry` was updated.
+func (r *QuayRegistryReconciler) handleReloadRequest(ctx context.Context, quay *v1.QuayRegistry) (bool, error) {
+	requested, ok := quay.GetAnnotations()[v1.ReloadConfigAnnotation]
+	if !ok || quay.Spec.DryRun {
+		return false, nil
+	}
+
+	name := types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName()}
+	kustomize.ForgetRendered(name)
+	forgetApplied(name)
+
+	updatedQuay := quay.DeepCopy()
+	annotations := map[string]string{}
+	for key, value := range quay.GetAnnotations() {
+		if key != v1.ReloadConfigAnnotation {
+			annotations[key] = value
+		}
+	}
+	updatedQuay.SetAnnotations(annotations)
+	if err := r.Client.Update(ctx, updatedQuay); err != nil {
+		return false, err
+	}
+	r.recordEvent(quay, corev1.EventTypeNormal, "ConfigReloadRequested", "re-reading config bundle and re-applying every managed object, as requested by `"+v1.ReloadConfigAnnotation+"="+requested+"`")
+
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var handleReloadRequestTests = []struct {
+	name        string
+	annotations map[string]string
+	dryRun      bool
+	expected    bool
+}{
+	{
+		"NotRequested",
+		map[string]string{v1.PausedComponentsAnnotation: "clair"},
+		false,
+		false,
+	},
+	{
+		"Requested",
+		map[string]string{v1.PausedComponentsAnnotation: "clair", v1.ReloadConfigAnnotation: "now"},
+		false,
+		true,
+	},
+	{
+		"RequestedDuringDryRun",
+		map[string]string{v1.ReloadConfigAnnotation: "now"},
+		true,
+		false,
+	},
+}
+
+func TestHandleReloadRequest(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range handleReloadRequestTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "ns-1", Annotations: test.annotations},
+			Spec:       v1.QuayRegistrySpec{DryRun: test.dryRun},
+		}
+		name := types.NamespacedName{Namespace: "ns-1", Name: "skynet"}
+		deployment := componentObject("skynet-quay-app", "quay-app", 1)
+		recordApplied(name, objectKey(deployment), objectHash(deployment))
+
+		r, stub := stubReconciler(quay)
+		requested, err := r.handleReloadRequest(context.Background(), quay)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expected, requested, test.name)
+
+		changed, _, _ := changedObjects(name, []k8sruntime.Object{deployment})
+		forgetApplied(name)
+		if !test.expected {
+			assert.Empty(stub.updated, test.name)
+			assert.Empty(changed, test.name)
+			continue
+		}
+
+		assert.Len(changed, 1, test.name+": applied objects are forgotten")
+		assert.Len(stub.updated, 1, test.name)
+		assert.Equal(map[string]string{v1.PausedComponentsAnnotation: "clair"}, stub.updated[0].(*v1.QuayRegistry).GetAnnotations(), test.name)
+		assert.Equal("now", quay.GetAnnotations()[v1.ReloadConfigAnnotation], "%s: given QuayRegistry is not modified", test.name)
+	}
+}
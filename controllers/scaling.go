@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// withPreservedReplicas returns the objects with the replica count of the live Quay app `Deployment` kept if it is
+// scaled by a `HorizontalPodAutoscaler` or by hand, so that applying the `Deployment` does not reset it. Quay remains
+// stopped while its database is upgraded to a new major version, and while the registry is suspended.
+func (r *QuayRegistryReconciler) withPreservedReplicas(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) []k8sruntime.Object {
+	autoscaled := v1.ComponentIsManaged(quay.Spec.Components, "horizontalpodautoscaler") && !v1.PostgresMajorUpgradePending(quay) && !quay.Spec.Suspend
+
+	preserved := []k8sruntime.Object{}
+	for _, obj := range objects {
+		deployment, ok := obj.(*appsv1.Deployment)
+		if !ok || deployment.GetName() != quay.GetName()+"-quay-app" {
+			preserved = append(preserved, obj)
+			continue
+		}
+
+		var live appsv1.Deployment
+		err := r.Client.Get(ctx, types.NamespacedName{Namespace: deployment.GetNamespace(), Name: deployment.GetName()}, &live)
+		if errors.IsNotFound(err) {
+			preserved = append(preserved, kustomize.WithPreservedReplicas(deployment, nil, autoscaled))
+			continue
+		} else if err != nil {
+			r.Log.Error(err, "could not retrieve Quay `Deployment` to preserve its replicas")
+			preserved = append(preserved, obj)
+			continue
+		}
+
+		preserved = append(preserved, kustomize.WithPreservedReplicas(deployment, &live, autoscaled))
+	}
+
+	return preserved
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// quayAppDeployment returns a Quay app `Deployment` with the given replicas and applied replicas annotation.
+func quayAppDeployment(name string, replicas int32, appliedReplicas string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-1"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	if appliedReplicas != "" {
+		deployment.SetAnnotations(map[string]string{"quay-applied-replicas": appliedReplicas})
+	}
+
+	return deployment
+}
+
+var withPreservedReplicasTests = []struct {
+	name       string
+	components []v1.Component
+	live       []k8sruntime.Object
+	desired    *appsv1.Deployment
+	expected   int32
+}{
+	{
+		"Created",
+		[]v1.Component{},
+		[]k8sruntime.Object{},
+		quayAppDeployment("test-quay-app", 2, ""),
+		2,
+	},
+	{
+		"ScaledManually",
+		[]v1.Component{},
+		[]k8sruntime.Object{quayAppDeployment("test-quay-app", 5, "2")},
+		quayAppDeployment("test-quay-app", 2, ""),
+		5,
+	},
+	{
+		"ReplicasChanged",
+		[]v1.Component{},
+		[]k8sruntime.Object{quayAppDeployment("test-quay-app", 5, "2")},
+		quayAppDeployment("test-quay-app", 3, ""),
+		3,
+	},
+	{
+		"Autoscaled",
+		[]v1.Component{{Kind: "horizontalpodautoscaler", Managed: true}},
+		[]k8sruntime.Object{quayAppDeployment("test-quay-app", 7, "3")},
+		quayAppDeployment("test-quay-app", 3, ""),
+		7,
+	},
+	{
+		"OtherDeployment",
+		[]v1.Component{{Kind: "horizontalpodautoscaler", Managed: true}},
+		[]k8sruntime.Object{quayAppDeployment("test-clair-app", 7, "3")},
+		quayAppDeployment("test-clair-app", 3, ""),
+		3,
+	},
+}
+
+func TestWithPreservedReplicas(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range withPreservedReplicasTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec:       v1.QuayRegistrySpec{Components: test.components},
+		}
+		r, _ := stubReconciler(test.live...)
+
+		preserved := r.withPreservedReplicas(context.Background(), quay, []k8sruntime.Object{test.desired})
+
+		assert.Len(preserved, 1, test.name)
+		assert.Equal(test.expected, *preserved[0].(*appsv1.Deployment).Spec.Replicas, test.name)
+		assert.NotContains(test.desired.GetAnnotations(), "quay-applied-replicas", "%s: given objects are not modified", test.name)
+	}
+}
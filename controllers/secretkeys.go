@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+	"github.com/quay/quay-operator/pkg/secretkeys"
+)
+
+const (
+	// secretKeysBackupKey is the key of the encrypted backup in the backup `Secret`.
+	secretKeysBackupKey = "secret-keys.enc"
+	// secretKeysPassphraseKey is the key of the passphrase in the `Secret` referenced by `spec.secretKeysBackup`.
+	secretKeysPassphraseKey = "passphrase"
+	// acceptDatabaseSecretKeyAnnotation accepts a change to the `DATABASE_SECRET_KEY` with the given fingerprint, once
+	// the database has been re-encrypted with it.
+	acceptDatabaseSecretKeyAnnotation = "quay-operator/accept-database-secret-key"
+)
+
+// secretKeysPassphraseFor returns the passphrase used to encrypt the backup of the secret keys.
+func (r *QuayRegistryReconciler) secretKeysPassphraseFor(ctx context.Context, quay *v1.QuayRegistry) ([]byte, error) {
+	name := quay.Spec.SecretKeysBackup.PassphraseSecret
+
+	var passphraseSecret corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: name}, &passphraseSecret); err != nil {
+		return nil, fmt.Errorf("could not retrieve `spec.secretKeysBackup.passphraseSecret` %s: %w", name, err)
+	}
+
+	passphrase := passphraseSecret.Data[secretKeysPassphraseKey]
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("`spec.secretKeysBackup.passphraseSecret` %s has no `%s` key", name, secretKeysPassphraseKey)
+	}
+
+	return passphrase, nil
+}
+
+// restoreSecretKeys recreates the managed secret keys `Secret` from its backup if it is missing, so that a registry
+// restored into a new cluster can still decrypt its database. The given secret keys bundle is updated with the
+// restored keys. Returns true if the keys were restored.
+func (r *QuayRegistryReconciler) restoreSecretKeys(ctx context.Context, quay *v1.QuayRegistry, secretKeysBundle *corev1.Secret) (bool, error) {
+	if quay.Spec.SecretKeysBackup == nil || len(secretKeysBundle.Data) > 0 {
+		return false, nil
+	}
+
+	var backup corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: kustomize.SecretKeysBackupName(quay)}, &backup); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	passphrase, err := r.secretKeysPassphraseFor(ctx, quay)
+	if err != nil {
+		return false, err
+	}
+
+	keys, err := secretkeys.Open(backup.Data[secretKeysBackupKey], passphrase)
+	if err != nil {
+		return false, err
+	}
+
+	restored := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kustomize.SecretKeySecretName(quay),
+			Namespace: quay.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "QuayRegistry",
+					Name:       quay.GetName(),
+					UID:        quay.GetUID(),
+				},
+			},
+		},
+		Data: keys,
+	}
+	restored.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+
+	if err := r.createOrUpdateObject(ctx, restored, *quay); err != nil {
+		return false, err
+	}
+
+	secretKeysBundle.Data = keys
+
+	return true, nil
+}
+
+// secretKeysFor returns the secret keys of the registry, preferring those generated by the latest render.
+func secretKeysFor(quay *v1.QuayRegistry, objects []k8sruntime.Object, secretKeysBundle *corev1.Secret) map[string][]byte {
+	for _, obj := range objects {
+		if secret, ok := obj.(*corev1.Secret); ok && secret.GetName() == kustomize.SecretKeySecretName(quay) {
+			return secret.Data
+		}
+	}
+
+	return secretKeysBundle.Data
+}
+
+// backupSecretKeys writes the secret keys, encrypted with the passphrase from `spec.secretKeysBackup`, to the backup
+// `Secret`. The backup is not owned by the `QuayRegistry`, so it outlives it. An up to date backup is not rewritten.
+func (r *QuayRegistryReconciler) backupSecretKeys(ctx context.Context, quay *v1.QuayRegistry, keys map[string][]byte) error {
+	if quay.Spec.SecretKeysBackup == nil || len(keys) == 0 {
+		return nil
+	}
+
+	passphrase, err := r.secretKeysPassphraseFor(ctx, quay)
+	if err != nil {
+		return err
+	}
+
+	var existing corev1.Secret
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: kustomize.SecretKeysBackupName(quay)}, &existing); err == nil {
+		if backedUp, err := secretkeys.Open(existing.Data[secretKeysBackupKey], passphrase); err == nil && reflect.DeepEqual(backedUp, keys) {
+			return nil
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+
+	sealed, err := secretkeys.Seal(keys, passphrase)
+	if err != nil {
+		return err
+	}
+
+	backup := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kustomize.SecretKeysBackupName(quay),
+			Namespace: quay.GetNamespace(),
+		},
+		Data: map[string][]byte{secretKeysBackupKey: sealed},
+	}
+	backup.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+
+	return r.createOrUpdateObject(ctx, backup, *quay)
+}
+
+// checkDatabaseSecretKey returns an error if the given `DATABASE_SECRET_KEY` is not the one the database of the
+// registry is encrypted with, and the change has not been accepted with an annotation.
+func checkDatabaseSecretKey(quay *v1.QuayRegistry, key string) error {
+	err := secretkeys.CheckDatabaseSecretKey(quay.Status.DatabaseSecretKeyFingerprint, key, quay.GetAnnotations()[acceptDatabaseSecretKeyAnnotation])
+	if err != nil {
+		return fmt.Errorf("%w; restore the previous key, or annotate the `QuayRegistry` with `%s: %s` if the database was re-encrypted", err, acceptDatabaseSecretKeyAnnotation, secretkeys.Fingerprint(key))
+	}
+
+	return nil
+}
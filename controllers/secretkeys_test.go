@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/secretkeys"
+)
+
+var checkDatabaseSecretKeyTests = []struct {
+	name        string
+	recorded    string
+	accepted    string
+	key         string
+	expectedErr bool
+}{
+	{"NotRecorded", "", "", "new-key", false},
+	{"Unchanged", secretkeys.Fingerprint("key"), "", "key", false},
+	{"Changed", secretkeys.Fingerprint("key"), "", "new-key", true},
+	{"ChangeAccepted", secretkeys.Fingerprint("key"), secretkeys.Fingerprint("new-key"), "new-key", false},
+	{"OtherChangeAccepted", secretkeys.Fingerprint("key"), secretkeys.Fingerprint("other-key"), "new-key", true},
+	{"Removed", secretkeys.Fingerprint("key"), "", "", false},
+}
+
+func TestCheckDatabaseSecretKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range checkDatabaseSecretKeyTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Status:     v1.QuayRegistryStatus{DatabaseSecretKeyFingerprint: test.recorded},
+		}
+		if test.accepted != "" {
+			quay.SetAnnotations(map[string]string{acceptDatabaseSecretKeyAnnotation: test.accepted})
+		}
+
+		err := checkDatabaseSecretKey(quay, test.key)
+
+		if test.expectedErr {
+			assert.NotNil(err, test.name)
+			assert.Contains(err.Error(), acceptDatabaseSecretKeyAnnotation+": "+secretkeys.Fingerprint(test.key), test.name)
+		} else {
+			assert.Nil(err, test.name)
+		}
+	}
+}
+
+// secretKeysBackupWith returns the backup `Secret` of the given keys and the `Secret` of the passphrase used to seal
+// them.
+func secretKeysBackupWith(keys map[string][]byte) []k8sruntime.Object {
+	passphrase := []byte("correct horse battery staple")
+	sealed, err := secretkeys.Seal(keys, passphrase)
+	if err != nil {
+		panic(err)
+	}
+
+	return []k8sruntime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-quay-registry-secret-keys-backup", Namespace: "ns-1"},
+			Data:       map[string][]byte{secretKeysBackupKey: sealed},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "passphrase", Namespace: "ns-1"},
+			Data:       map[string][]byte{secretKeysPassphraseKey: passphrase},
+		},
+	}
+}
+
+var restoreSecretKeysTests = []struct {
+	name             string
+	secretKeysBackup *v1.SecretKeysBackup
+	existing         map[string][]byte
+	objects          []k8sruntime.Object
+	expected         bool
+	expectedKeys     map[string][]byte
+	expectedErr      bool
+}{
+	{
+		"NoBackupConfigured",
+		nil,
+		map[string][]byte{},
+		secretKeysBackupWith(map[string][]byte{"DATABASE_SECRET_KEY": []byte("backed-up")}),
+		false,
+		map[string][]byte{},
+		false,
+	},
+	{
+		"KeysExist",
+		&v1.SecretKeysBackup{PassphraseSecret: "passphrase"},
+		map[string][]byte{"DATABASE_SECRET_KEY": []byte("existing")},
+		secretKeysBackupWith(map[string][]byte{"DATABASE_SECRET_KEY": []byte("backed-up")}),
+		false,
+		map[string][]byte{"DATABASE_SECRET_KEY": []byte("existing")},
+		false,
+	},
+	{
+		"NoBackup",
+		&v1.SecretKeysBackup{PassphraseSecret: "passphrase"},
+		map[string][]byte{},
+		[]k8sruntime.Object{},
+		false,
+		map[string][]byte{},
+		false,
+	},
+	{
+		"Restored",
+		&v1.SecretKeysBackup{PassphraseSecret: "passphrase"},
+		map[string][]byte{},
+		secretKeysBackupWith(map[string][]byte{"DATABASE_SECRET_KEY": []byte("backed-up")}),
+		true,
+		map[string][]byte{"DATABASE_SECRET_KEY": []byte("backed-up")},
+		false,
+	},
+	{
+		"MissingPassphrase",
+		&v1.SecretKeysBackup{PassphraseSecret: "passphrase"},
+		map[string][]byte{},
+		secretKeysBackupWith(map[string][]byte{"DATABASE_SECRET_KEY": []byte("backed-up")})[:1],
+		false,
+		map[string][]byte{},
+		true,
+	},
+}
+
+func TestRestoreSecretKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range restoreSecretKeysTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+			Spec:       v1.QuayRegistrySpec{SecretKeysBackup: test.secretKeysBackup},
+		}
+		secretKeysBundle := &corev1.Secret{Data: test.existing}
+		r, stub := stubReconciler(test.objects...)
+
+		restored, err := r.restoreSecretKeys(context.Background(), quay, secretKeysBundle)
+
+		assert.Equal(test.expectedErr, err != nil, test.name)
+		assert.Equal(test.expected, restored, test.name)
+		assert.Equal(test.expectedKeys, secretKeysBundle.Data, test.name)
+		if test.expected {
+			assert.Len(stub.patched, 1, test.name)
+			assert.Equal("test-quay-registry-managed-secret-keys", stub.patched[0].(*corev1.Secret).GetName(), test.name)
+		} else {
+			assert.Empty(stub.patched, test.name)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+const (
+	componentLabel = "quay-component"
+	// baseComponent is used for objects which are not labeled with a specific component.
+	baseComponent = "quay"
+)
+
+// appliedObjects holds a hash of every object last successfully applied for each `QuayRegistry`, so that rendered
+// objects which are unchanged are not applied again. The whole registry is still rendered on every change.
+var appliedObjects = struct {
+	sync.Mutex
+	hashes map[types.NamespacedName]map[string]string
+}{hashes: map[types.NamespacedName]map[string]string{}}
+
+func objectKey(obj k8sruntime.Object) string {
+	objectMeta, _ := meta.Accessor(obj)
+
+	return obj.GetObjectKind().GroupVersionKind().String() + "/" + objectMeta.GetName()
+}
+
+func objectHash(obj k8sruntime.Object) string {
+	marshalled, _ := json.Marshal(obj)
+	sum := sha256.Sum256(marshalled)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// componentFor returns the name of the component which the given object belongs to.
+func componentFor(obj k8sruntime.Object) string {
+	objectMeta, _ := meta.Accessor(obj)
+	if component, ok := objectMeta.GetLabels()[componentLabel]; ok {
+		return component
+	}
+
+	return baseComponent
+}
+
+// changedObjects splits the objects into those whose desired state differs from what was last applied and those
+// which are unchanged, along with the names of the components the changed objects belong to.
+func changedObjects(quay types.NamespacedName, objects []k8sruntime.Object) ([]k8sruntime.Object, []k8sruntime.Object, []string) {
+	appliedObjects.Lock()
+	defer appliedObjects.Unlock()
+
+	applied := appliedObjects.hashes[quay]
+	changed := []k8sruntime.Object{}
+	unchanged := []k8sruntime.Object{}
+	components := map[string]bool{}
+	for _, obj := range objects {
+		if hash, ok := applied[objectKey(obj)]; ok && hash == objectHash(obj) {
+			unchanged = append(unchanged, obj)
+			continue
+		}
+
+		changed = append(changed, obj)
+		components[componentFor(obj)] = true
+	}
+
+	names := []string{}
+	for component := range components {
+		names = append(names, component)
+	}
+	sort.Strings(names)
+
+	return changed, unchanged, names
+}
+
+// withoutPausedComponents splits the objects into those which should be reconciled and those belonging to a
+// component listed in the `paused-components` annotation, which are left untouched.
+func withoutPausedComponents(quay *v1.QuayRegistry, objects []k8sruntime.Object) ([]k8sruntime.Object, []k8sruntime.Object) {
+	reconciled := []k8sruntime.Object{}
+	paused := []k8sruntime.Object{}
+	for _, obj := range objects {
+		if kind := kustomize.ComponentKindFor(obj); kind != "" && v1.ComponentIsPaused(quay, kind) {
+			paused = append(paused, obj)
+			continue
+		}
+
+		reconciled = append(reconciled, obj)
+	}
+
+	return reconciled, paused
+}
+
+// recordApplied stores the hash of an object which was successfully applied. Must be called with the object
+// as it was rendered, before it is mutated by the API server response.
+func recordApplied(quay types.NamespacedName, key, hash string) {
+	appliedObjects.Lock()
+	defer appliedObjects.Unlock()
+
+	if _, ok := appliedObjects.hashes[quay]; !ok {
+		appliedObjects.hashes[quay] = map[string]string{}
+	}
+	appliedObjects.hashes[quay][key] = hash
+}
+
+// forgetAppliedObjects removes the applied hashes of the given objects, so they are re-applied on the next reconcile.
+func forgetAppliedObjects(quay types.NamespacedName, objects []k8sruntime.Object) {
+	appliedObjects.Lock()
+	defer appliedObjects.Unlock()
+
+	for _, obj := range objects {
+		delete(appliedObjects.hashes[quay], objectKey(obj))
+	}
+}
+
+// forgetApplied removes all applied object hashes for the given `QuayRegistry`, forcing a full apply.
+func forgetApplied(quay types.NamespacedName) {
+	appliedObjects.Lock()
+	defer appliedObjects.Unlock()
+
+	delete(appliedObjects.hashes, quay)
+}
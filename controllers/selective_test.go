@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// componentObject returns a `Deployment` labeled with the given component, or unlabeled if it is empty.
+func componentObject(name, component string, replicas int32) k8sruntime.Object {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-1"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	deployment.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	if component != "" {
+		deployment.SetLabels(map[string]string{componentLabel: component})
+	}
+
+	return deployment
+}
+
+var changedObjectsTests = []struct {
+	name               string
+	applied            []k8sruntime.Object
+	objects            []k8sruntime.Object
+	expectedChanged    []string
+	expectedUnchanged  []string
+	expectedComponents []string
+}{
+	{
+		"NothingApplied",
+		[]k8sruntime.Object{},
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 2), componentObject("test-clair-app", "clair", 1)},
+		[]string{"test-quay-app", "test-clair-app"},
+		[]string{},
+		[]string{"clair", "quay-app"},
+	},
+	{
+		"AllUnchanged",
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 2), componentObject("test-clair-app", "clair", 1)},
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 2), componentObject("test-clair-app", "clair", 1)},
+		[]string{},
+		[]string{"test-quay-app", "test-clair-app"},
+		[]string{},
+	},
+	{
+		"OneChanged",
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 2), componentObject("test-clair-app", "clair", 1)},
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 3), componentObject("test-clair-app", "clair", 1)},
+		[]string{"test-quay-app"},
+		[]string{"test-clair-app"},
+		[]string{"quay-app"},
+	},
+	{
+		"Unlabeled",
+		[]k8sruntime.Object{},
+		[]k8sruntime.Object{componentObject("test-quay-config-editor", "", 1)},
+		[]string{"test-quay-config-editor"},
+		[]string{},
+		[]string{baseComponent},
+	},
+	{
+		"SameNameDifferentKind",
+		[]k8sruntime.Object{componentObject("test-quay-app", "quay-app", 2)},
+		[]k8sruntime.Object{&corev1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: "test-quay-app", Namespace: "ns-1", Labels: map[string]string{componentLabel: "quay-app"}},
+		}},
+		[]string{"test-quay-app"},
+		[]string{},
+		[]string{"quay-app"},
+	},
+}
+
+func objectNames(objects []k8sruntime.Object) []string {
+	names := []string{}
+	for _, obj := range objects {
+		objectMeta, _ := meta.Accessor(obj)
+		names = append(names, objectMeta.GetName())
+	}
+
+	return names
+}
+
+func TestChangedObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range changedObjectsTests {
+		quay := types.NamespacedName{Namespace: "ns-1", Name: test.name}
+		for _, obj := range test.applied {
+			recordApplied(quay, objectKey(obj), objectHash(obj))
+		}
+
+		changed, unchanged, components := changedObjects(quay, test.objects)
+		forgetApplied(quay)
+
+		assert.Equal(test.expectedChanged, objectNames(changed), test.name)
+		assert.Equal(test.expectedUnchanged, objectNames(unchanged), test.name)
+		assert.Equal(test.expectedComponents, components, test.name)
+	}
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// storageMigrationPollInterval is how often a `QuayRegistry` is requeued to check the progress of a storage migration.
+const storageMigrationPollInterval = time.Minute
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// storageMigrationJob returns the rendered `Job` running the current storage migration step, if any.
+func storageMigrationJob(objects []k8sruntime.Object) *batchv1.Job {
+	for _, obj := range objects {
+		if job, ok := obj.(*batchv1.Job); ok && job.GetLabels()["quay-component"] == kustomize.StorageMigrationComponent {
+			return job
+		}
+	}
+
+	return nil
+}
+
+// jobFailed returns the message of the `Failed` condition of the `Job`, if it has failed.
+func jobFailed(job *batchv1.Job) (string, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.Message, true
+		}
+	}
+
+	return "", false
+}
+
+// nextStorageMigrationStatus returns the migration status after inspecting the `Job` for the current step.
+func nextStorageMigrationStatus(quay *v1.QuayRegistry, job *batchv1.Job) *v1.StorageMigrationStatus {
+	phase := v1.StorageMigrationPhaseFor(quay)
+	if phase == "" {
+		return nil
+	}
+
+	status := &v1.StorageMigrationStatus{TargetLocation: quay.Spec.StorageMigration.TargetLocation, Phase: phase}
+	if existing := quay.Status.StorageMigration; existing != nil && existing.TargetLocation == status.TargetLocation {
+		status.Message = existing.Message
+	} else {
+		status.Message = "copying existing blobs to " + status.TargetLocation
+	}
+
+	if job == nil {
+		return status
+	}
+
+	if message, failed := jobFailed(job); failed {
+		status.Phase = v1.StorageMigrationPhaseFailed
+		status.Message = job.GetName() + " failed: " + message
+
+		return status
+	}
+
+	if job.Status.Succeeded > 0 {
+		switch phase {
+		case v1.StorageMigrationPhaseReplicating:
+			status.Phase = v1.StorageMigrationPhaseVerifying
+			status.Message = "verifying every blob exists in " + status.TargetLocation
+		case v1.StorageMigrationPhaseVerifying:
+			status.Phase = v1.StorageMigrationPhaseComplete
+			status.Message = "all blobs copied, " + status.TargetLocation + " is now the preferred storage location"
+		}
+	}
+
+	return status
+}
+
+// progressStorageMigration advances `status.storageMigration` once the `Job` for the current step has finished.
+// Returns true if the migration is still in progress.
+func (r *QuayRegistryReconciler) progressStorageMigration(ctx context.Context, quay *v1.QuayRegistry, objects []k8sruntime.Object) (bool, error) {
+	var live *batchv1.Job
+	if job := storageMigrationJob(objects); job != nil {
+		live = &batchv1.Job{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: job.GetNamespace(), Name: job.GetName()}, live); err != nil {
+			return true, err
+		}
+	}
+
+	status := nextStorageMigrationStatus(quay, live)
+	if !reflect.DeepEqual(status, quay.Status.StorageMigration) {
+		r.Log.Info("updating storage migration", "quayregistry", quay.GetNamespace()+"/"+quay.GetName(), "status", status)
+
+		quay.Status.StorageMigration = status
+		if err := r.Client.Status().Update(ctx, quay); err != nil {
+			return true, err
+		}
+
+		if status != nil {
+			eventType := corev1.EventTypeNormal
+			if status.Phase == v1.StorageMigrationPhaseFailed {
+				eventType = corev1.EventTypeWarning
+			}
+			r.recordEvent(quay, eventType, "StorageMigration"+string(status.Phase), status.Message)
+		}
+	}
+
+	return status != nil && (status.Phase == v1.StorageMigrationPhaseReplicating || status.Phase == v1.StorageMigrationPhaseVerifying), nil
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// ownedBy returns true if the given claim is owned by the `QuayRegistry`.
+func ownedBy(claim *corev1.PersistentVolumeClaim, quay *v1.QuayRegistry) bool {
+	for _, owner := range claim.GetOwnerReferences() {
+		if owner.UID == quay.GetUID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reconcileVolumeRetention applies `spec.persistentVolumeRetentionPolicy` to the volumes of databases which are no
+// longer managed, deleting those with the `Delete` policy, and reports the volumes which are kept in
+// `status.retainedVolumes`. Volumes of managed databases are retained by not being owned by the `QuayRegistry`.
+func (r *QuayRegistryReconciler) reconcileVolumeRetention(ctx context.Context, quay *v1.QuayRegistry) error {
+	if quay.Spec.DryRun {
+		return nil
+	}
+
+	var retained []string
+	for _, component := range kustomize.DatabaseComponents() {
+		policy := v1.PersistentVolumeRetentionPolicyFor(quay, component)
+		managed := v1.ComponentIsManaged(quay.Spec.Components, component)
+		if managed && policy != v1.PersistentVolumeRetentionPolicyRetain {
+			continue
+		}
+
+		for _, name := range kustomize.DatabaseVolumeClaimsFor(quay, component) {
+			var claim corev1.PersistentVolumeClaim
+			if err := r.Client.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: name}, &claim); errors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if !claim.GetDeletionTimestamp().IsZero() {
+				continue
+			}
+
+			if !managed && policy == v1.PersistentVolumeRetentionPolicyDelete && ownedBy(&claim, quay) {
+				if err := r.Client.Delete(ctx, &claim); client.IgnoreNotFound(err) != nil {
+					return err
+				}
+				r.recordEvent(quay, corev1.EventTypeNormal, "VolumeDeleted", "deleted `PersistentVolumeClaim` "+name+" of unmanaged component "+component)
+
+				continue
+			}
+
+			retained = append(retained, name)
+		}
+	}
+
+	if reflect.DeepEqual(retained, quay.Status.RetainedVolumes) {
+		return nil
+	}
+	quay.Status.RetainedVolumes = retained
+
+	return r.Client.Status().Update(ctx, quay)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// databaseClaim returns a `PersistentVolumeClaim` of the test registry, owned by it if `owned` is set.
+func databaseClaim(name string, owned bool) *corev1.PersistentVolumeClaim {
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns-1"}}
+	if owned {
+		claim.SetOwnerReferences([]metav1.OwnerReference{{Kind: "QuayRegistry", Name: "test", UID: "test-uid"}})
+	}
+
+	return claim
+}
+
+var reconcileVolumeRetentionTests = []struct {
+	name             string
+	components       []v1.Component
+	retention        []v1.PersistentVolumeRetention
+	claims           []k8sruntime.Object
+	expectedDeleted  []string
+	expectedRetained []string
+}{
+	{
+		"ManagedDefault",
+		[]v1.Component{{Kind: "postgres", Managed: true}, {Kind: "clair", Managed: true}},
+		nil,
+		[]k8sruntime.Object{databaseClaim("test-quay-postgres", true), databaseClaim("test-clair-postgres", true)},
+		nil,
+		nil,
+	},
+	{
+		"ManagedRetain",
+		[]v1.Component{{Kind: "postgres", Managed: true}, {Kind: "clair", Managed: true}},
+		[]v1.PersistentVolumeRetention{{Component: "postgres", Policy: v1.PersistentVolumeRetentionPolicyRetain}},
+		[]k8sruntime.Object{databaseClaim("test-quay-postgres", false), databaseClaim("test-quay-postgres-backup", false), databaseClaim("test-clair-postgres", true)},
+		nil,
+		[]string{"test-quay-postgres", "test-quay-postgres-backup"},
+	},
+	{
+		"UnmanagedDefault",
+		[]v1.Component{{Kind: "postgres", Managed: false}, {Kind: "clair", Managed: true}},
+		nil,
+		[]k8sruntime.Object{databaseClaim("test-quay-postgres", true)},
+		nil,
+		[]string{"test-quay-postgres"},
+	},
+	{
+		"UnmanagedDelete",
+		[]v1.Component{{Kind: "postgres", Managed: true}, {Kind: "clair", Managed: false}},
+		[]v1.PersistentVolumeRetention{{Component: "clair", Policy: v1.PersistentVolumeRetentionPolicyDelete}},
+		[]k8sruntime.Object{databaseClaim("test-clair-postgres", true)},
+		[]string{"test-clair-postgres"},
+		nil,
+	},
+}
+
+func TestReconcileVolumeRetention(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range reconcileVolumeRetentionTests {
+		quay := &v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1", UID: "test-uid"},
+			Spec: v1.QuayRegistrySpec{
+				Components:                      test.components,
+				PersistentVolumeRetentionPolicy: test.retention,
+			},
+		}
+		r, stub := stubReconciler(test.claims...)
+
+		assert.Nil(r.reconcileVolumeRetention(context.Background(), quay), test.name)
+
+		var deleted []string
+		for _, obj := range stub.deleted {
+			deleted = append(deleted, obj.(*corev1.PersistentVolumeClaim).GetName())
+		}
+		assert.Equal(test.expectedDeleted, deleted, test.name)
+		assert.Equal(test.expectedRetained, quay.Status.RetainedVolumes, test.name)
+		assert.Equal(len(test.expectedRetained) > 0, len(stub.statusUpdated) > 0, test.name)
+	}
+}
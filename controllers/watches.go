@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets;configmaps,verbs=get;list;watch
+
+// referencingRegistries returns reconcile requests for every `QuayRegistry` in the object's namespace
+// which references it by name, using the given function to list the referenced names.
+func (r *QuayRegistryReconciler) referencingRegistries(obj handler.MapObject, referenced func(*v1.QuayRegistry) []string) []reconcile.Request {
+	var quays v1.QuayRegistryList
+	if err := r.Client.List(context.Background(), &quays, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list QuayRegistries for watched object", "name", obj.Meta.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range quays.Items {
+		for _, name := range referenced(&quays.Items[i]) {
+			if name == obj.Meta.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: quays.Items[i].GetNamespace(),
+					Name:      quays.Items[i].GetName(),
+				}})
+				break
+			}
+		}
+	}
+
+	return requests
+}
+
+// secretToRegistries maps a `Secret` event to the `QuayRegistries` which reference it.
+func (r *QuayRegistryReconciler) secretToRegistries(obj handler.MapObject) []reconcile.Request {
+	// Service account tokens change frequently and are never referenced by a `QuayRegistry`.
+	if secret, ok := obj.Object.(*corev1.Secret); ok && secret.Type == corev1.SecretTypeServiceAccountToken {
+		return nil
+	}
+
+	return r.referencingRegistries(obj, v1.ReferencedSecrets)
+}
+
+// configMapToRegistries maps a `ConfigMap` event to the `QuayRegistries` which reference it.
+func (r *QuayRegistryReconciler) configMapToRegistries(obj handler.MapObject) []reconcile.Request {
+	return r.referencingRegistries(obj, v1.ReferencedConfigMaps)
+}
+
+// watchReferencedObjects triggers reconciliation when a `Secret` or `ConfigMap` referenced by a `QuayRegistry` changes,
+// or the `QuayOperatorConfig` they all inherit, rather than waiting for the next periodic resync.
+func (r *QuayRegistryReconciler) watchReferencedObjects(builder *ctrl.Builder) *ctrl.Builder {
+	return builder.
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.secretToRegistries),
+		}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.configMapToRegistries),
+		}).
+		Watches(&source.Kind{Type: &v1.QuayOperatorConfig{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.operatorConfigToRegistries),
+		})
+}
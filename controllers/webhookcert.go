@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	admissionregistration "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/quay/quay-operator/pkg/webhookcert"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=create;update
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update
+
+// webhookCertCheckInterval is how often the webhook serving certificate is checked for rotation.
+const webhookCertCheckInterval = time.Hour
+
+// WebhookCertManager keeps the serving certificate of the webhook server, and the CA bundle the API server uses to
+// trust it, up to date without manual steps. The certificates are shared by every replica through a `Secret`, and
+// rotated before they expire.
+type WebhookCertManager struct {
+	Client client.Client
+	Log    logr.Logger
+	// Namespace is the namespace of the Operator, which the `Service` and `Secret` are in.
+	Namespace string
+	// ServiceName is the `Service` in front of the webhook server.
+	ServiceName string
+	// SecretName is the `Secret` the certificates are stored in.
+	SecretName string
+	// WebhookConfigurationName is the `ValidatingWebhookConfiguration` the CA bundle is injected into.
+	WebhookConfigurationName string
+	// CertDir is the directory the webhook server reads its certificate from.
+	CertDir string
+}
+
+// Ensure generates or rotates the certificates if needed, writes them to the `CertDir` and injects the CA bundle.
+func (m *WebhookCertManager) Ensure(ctx context.Context) error {
+	data, err := m.ensureSecret(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.writeCertDir(data); err != nil {
+		return err
+	}
+
+	return m.injectCABundle(ctx, data[webhookcert.CAKey])
+}
+
+// ensureSecret returns the certificates from the `Secret`, creating or rotating them if needed. If another replica
+// changed the `Secret` at the same time, its certificates are used instead.
+func (m *WebhookCertManager) ensureSecret(ctx context.Context) (map[string][]byte, error) {
+	name := types.NamespacedName{Namespace: m.Namespace, Name: m.SecretName}
+
+	var secret corev1.Secret
+	err := m.Client.Get(ctx, name, &secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil && !webhookcert.NeedsRotation(secret.Data, m.ServiceName, m.Namespace, time.Now()) {
+		return secret.Data, nil
+	}
+
+	generated, genErr := webhookcert.Generate(m.ServiceName, m.Namespace, secret.Data, time.Now())
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	if errors.IsNotFound(err) {
+		m.Log.Info("generating webhook serving certificate", "secret", name.String())
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: m.SecretName, Namespace: m.Namespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       generated,
+		}
+		err = m.Client.Create(ctx, &secret)
+	} else {
+		m.Log.Info("rotating webhook serving certificate", "secret", name.String())
+		secret.Data = generated
+		err = m.Client.Update(ctx, &secret)
+	}
+
+	if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
+		if err := m.Client.Get(ctx, name, &secret); err != nil {
+			return nil, err
+		}
+		return secret.Data, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return generated, nil
+}
+
+// writeCertDir writes the serving certificate for the webhook server, which reloads it when it changes.
+func (m *WebhookCertManager) writeCertDir(data map[string][]byte) error {
+	if err := os.MkdirAll(m.CertDir, 0700); err != nil {
+		return err
+	}
+
+	for _, key := range []string{webhookcert.PrivateKeyKey, webhookcert.CertKey} {
+		path := filepath.Join(m.CertDir, key)
+		if existing, err := ioutil.ReadFile(path); err == nil && bytes.Equal(existing, data[key]) {
+			continue
+		}
+		if err := ioutil.WriteFile(path, data[key], 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// injectCABundle sets the CA bundle of every webhook served by the Operator.
+func (m *WebhookCertManager) injectCABundle(ctx context.Context, caBundle []byte) error {
+	var config admissionregistration.ValidatingWebhookConfiguration
+	if err := m.Client.Get(ctx, types.NamespacedName{Name: m.WebhookConfigurationName}, &config); errors.IsNotFound(err) {
+		m.Log.Info("`ValidatingWebhookConfiguration` not found, not injecting webhook CA bundle", "validatingWebhookConfiguration", m.WebhookConfigurationName)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range config.Webhooks {
+		if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, caBundle) {
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	m.Log.Info("injecting webhook CA bundle", "validatingWebhookConfiguration", m.WebhookConfigurationName)
+
+	return m.Client.Update(ctx, &config)
+}
+
+// Start checks the certificates periodically until the stop channel is closed.
+func (m *WebhookCertManager) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(webhookCertCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			if err := m.Ensure(context.Background()); err != nil {
+				m.Log.Error(err, "unable to ensure webhook serving certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false, since every replica serves webhooks and must load rotated certificates.
+func (m *WebhookCertManager) NeedLeaderElection() bool {
+	return false
+}
@@ -17,21 +17,31 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	quayredhatcomv1 "github.com/quay/quay-operator/api/v1"
 	"github.com/quay/quay-operator/controllers"
 	"github.com/quay/quay-operator/pkg/configure"
+	"github.com/quay/quay-operator/pkg/statusapi"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -53,41 +63,145 @@ func init() {
 
 func main() {
 	var metricsAddr string
+	var healthProbeAddr string
 	var enableLeaderElection bool
+	var leaseDuration time.Duration
+	var renewDeadline time.Duration
+	var retryPeriod time.Duration
 	var namespace string
+	var webhookCertMode string
+	var webhookCertDir string
+	var webhookServiceName string
+	var webhookCertSecret string
+	var webhookConfigurationName string
+	var statusAPITokenFile string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthProbeAddr, "health-probe-addr", ":8081", "The address the liveness and readiness probe endpoints bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"The duration that non-leader replicas will wait before attempting to acquire leadership. "+
+			"Lower values mean faster failover when the leader is lost.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"The duration that the leader will retry refreshing leadership before giving up. Must be less than the lease duration.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"The duration replicas wait between attempts to acquire or renew leadership.")
 	flag.StringVar(&namespace, "namespace", "", "The Kubernetes namespace that the controller will watch.")
+	flag.StringVar(&webhookCertMode, "webhook-cert-mode", "auto",
+		"Who manages the webhook serving certificate: \"operator\" generates, rotates and injects it, \"external\" uses "+
+			"the certificate mounted by OLM, cert-manager or the OpenShift service CA, and \"auto\" picks \"external\" "+
+			"if the certificate directory is read-only.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory the webhook server reads its serving certificate from.")
+	flag.StringVar(&webhookServiceName, "webhook-service-name", "quay-operator-webhook-service",
+		"The Service in front of the webhook server, which the operator-managed certificate is issued for.")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "quay-operator-webhook-server-cert",
+		"The Secret the operator-managed webhook certificate is stored in.")
+	flag.StringVar(&webhookConfigurationName, "webhook-configuration-name", "quay-operator-validating-webhook-configuration",
+		"The ValidatingWebhookConfiguration the operator-managed CA bundle is injected into.")
+	flag.StringVar(&statusAPITokenFile, "status-api-token-file", "",
+		"The file holding the bearer token the read-only status API is authenticated with. The status API is disabled if unset.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	if enableLeaderElection && (renewDeadline >= leaseDuration || retryPeriod >= renewDeadline) {
+		setupLog.Error(errors.New("invalid leader election timings"), "must satisfy retry period < renew deadline < lease duration",
+			"leaseDuration", leaseDuration, "renewDeadline", renewDeadline, "retryPeriod", retryPeriod)
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		Port:               9443,
-		LeaderElection:     enableLeaderElection,
-		LeaderElectionID:   "7daa4ab6.quay.redhat.com",
-		Namespace:          namespace,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: healthProbeAddr,
+		Port:                   9443,
+		CertDir:                webhookCertDir,
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "7daa4ab6.quay.redhat.com",
+		LeaseDuration:          &leaseDuration,
+		RenewDeadline:          &renewDeadline,
+		RetryPeriod:            &retryPeriod,
+		Namespace:              namespace,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if err := mgr.AddHealthzCheck("ping", func(_ *http.Request) error { return nil }); err != nil {
+		setupLog.Error(err, "unable to add liveness check")
+		os.Exit(1)
+	}
+	// Standby replicas are only ready once their caches are warm, so that failover doesn't start from a cold cache.
+	if err := mgr.AddReadyzCheck("cache-sync", cacheSyncedCheck(mgr)); err != nil {
+		setupLog.Error(err, "unable to add readiness check")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.QuayRegistryReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("QuayRegistry"),
-		Scheme: mgr.GetScheme(),
-		Config: mgr.GetConfig(),
+		Client:        mgr.GetClient(),
+		Log:           ctrl.Log.WithName("controllers").WithName("QuayRegistry"),
+		Scheme:        mgr.GetScheme(),
+		Config:        mgr.GetConfig(),
+		EventRecorder: mgr.GetEventRecorderFor("quay-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "QuayRegistry")
 		os.Exit(1)
 	}
+	// The webhook server requires a serving certificate, so the webhook is only served when it is deployed.
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		operatorNamespace := os.Getenv("MY_POD_NAMESPACE")
+		if managesWebhookCerts(webhookCertMode, operatorNamespace, webhookCertDir) {
+			// The manager's client cannot be used before it starts, but the certificate must exist before the webhook
+			// server does.
+			directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+			if err != nil {
+				setupLog.Error(err, "unable to create client")
+				os.Exit(1)
+			}
+
+			certManager := &controllers.WebhookCertManager{
+				Client:                   directClient,
+				Log:                      ctrl.Log.WithName("webhook-certs"),
+				Namespace:                operatorNamespace,
+				ServiceName:              webhookServiceName,
+				SecretName:               webhookCertSecret,
+				WebhookConfigurationName: webhookConfigurationName,
+				CertDir:                  webhookCertDir,
+			}
+			if err := certManager.Ensure(context.Background()); err != nil {
+				setupLog.Error(err, "unable to ensure webhook serving certificate")
+				os.Exit(1)
+			}
+			if err := mgr.Add(certManager); err != nil {
+				setupLog.Error(err, "unable to add webhook certificate manager")
+				os.Exit(1)
+			}
+		}
+
+		mgr.GetWebhookServer().Register(controllers.ValidatingWebhookPath, &webhook.Admission{
+			Handler: &controllers.QuayRegistryValidator{Client: mgr.GetClient()},
+		})
+	}
 	// +kubebuilder:scaffold:builder
 
+	if statusAPITokenFile != "" {
+		token, err := ioutil.ReadFile(statusAPITokenFile)
+		if err == nil && len(bytes.TrimSpace(token)) == 0 {
+			err = errors.New("token file is empty")
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to read status API token", "file", statusAPITokenFile)
+			os.Exit(1)
+		}
+
+		handler := statusapi.Handler(mgr.GetClient(), string(bytes.TrimSpace(token)))
+		http.Handle(statusapi.RegistriesPath, handler)
+		http.Handle(statusapi.RegistriesPath+"/", handler)
+	}
+
 	setupLog.Info("starting server on port 7071")
 	go func() {
 		http.HandleFunc("/reconfigure", configure.ReconfigureHandler(mgr.GetClient()))
@@ -100,3 +214,41 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// managesWebhookCerts returns true if the Operator manages its own webhook serving certificate in the given mode.
+// In "auto" mode it does unless the certificate directory is read-only, since OLM, cert-manager and the OpenShift
+// service CA all mount their certificate from a `Secret`.
+func managesWebhookCerts(mode, namespace, certDir string) bool {
+	switch mode {
+	case "operator":
+		return true
+	case "external":
+		return false
+	}
+
+	if namespace == "" || os.MkdirAll(certDir, 0700) != nil {
+		return false
+	}
+	probe, err := ioutil.TempFile(certDir, ".probe")
+	if err != nil {
+		return false
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return true
+}
+
+// cacheSyncedCheck returns a readiness check which fails until the manager's informer caches have synced.
+func cacheSyncedCheck(mgr ctrl.Manager) healthz.Checker {
+	synced := make(chan struct{})
+	close(synced)
+
+	return func(_ *http.Request) error {
+		if !mgr.GetCache().WaitForCacheSync(synced) {
+			return errors.New("informer caches not synced")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,133 @@
+// Package certificates finds the certificates in a Quay config bundle and reports which are about to expire.
+package certificates
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source is where a certificate used by a registry comes from.
+type Source string
+
+const (
+	// SourceGenerated is the Quay TLS certificate generated by the Operator.
+	SourceGenerated Source = "Generated"
+	// SourceUserProvided is a certificate from the config bundle.
+	SourceUserProvided Source = "UserProvided"
+	// SourceDatabaseCA is the CA certificate used to verify the database.
+	SourceDatabaseCA Source = "DatabaseCA"
+	// SourceExtraCA is an additional CA certificate trusted by Quay.
+	SourceExtraCA Source = "ExtraCA"
+)
+
+const (
+	quayCertificateFile   = "ssl.cert"
+	databaseCAFile        = "database.pem"
+	extraCACertFilePrefix = "extra_ca_cert_"
+)
+
+// Certificate is the expiry of a certificate file used by a registry.
+type Certificate struct {
+	// Name is the name of the file in the config bundle.
+	Name   string
+	Source Source
+	// NotAfter is when the first certificate in the file to expire does so.
+	NotAfter time.Time
+}
+
+// isCertificateFile returns true if the given config bundle file is expected to contain certificates.
+func isCertificateFile(name string) bool {
+	if name == quayCertificateFile || strings.HasPrefix(name, extraCACertFilePrefix) {
+		return true
+	}
+
+	for _, extension := range []string{".crt", ".cert", ".pem"} {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sourceFor returns the source of the given certificate file. The Quay TLS certificate is generated unless it was
+// provided in the config bundle.
+func sourceFor(name string, provided map[string][]byte) Source {
+	switch {
+	case name == quayCertificateFile:
+		if _, ok := provided[name]; !ok {
+			return SourceGenerated
+		}
+		return SourceUserProvided
+	case name == databaseCAFile:
+		return SourceDatabaseCA
+	case strings.HasPrefix(name, extraCACertFilePrefix):
+		return SourceExtraCA
+	default:
+		return SourceUserProvided
+	}
+}
+
+// notAfter returns the earliest expiry of the PEM encoded certificates in the given file, or the zero time if it
+// contains none, such as for a private key.
+func notAfter(contents []byte) (time.Time, error) {
+	var earliest time.Time
+	for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+
+	return earliest, nil
+}
+
+// Find returns the certificates in the rendered config files, sorted by name. The config bundle provided by the user
+// is used to tell which were generated. Files which cannot be parsed are returned as errors.
+func Find(rendered, provided map[string][]byte) ([]Certificate, []error) {
+	certs := []Certificate{}
+	errs := []error{}
+	for name, contents := range rendered {
+		if !isCertificateFile(name) {
+			continue
+		}
+
+		expiry, err := notAfter(contents)
+		if err != nil {
+			errs = append(errs, errors.New("could not parse `"+name+"`: "+err.Error()))
+			continue
+		}
+		if expiry.IsZero() {
+			continue
+		}
+
+		certs = append(certs, Certificate{Name: name, Source: sourceFor(name, provided), NotAfter: expiry})
+	}
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+
+	return certs, errs
+}
+
+// Expiring returns the certificates which expire within the threshold of the given time, including those which
+// have already expired.
+func Expiring(certs []Certificate, now time.Time, threshold time.Duration) []Certificate {
+	expiring := []Certificate{}
+	for _, cert := range certs {
+		if cert.NotAfter.Before(now.Add(threshold)) {
+			expiring = append(expiring, cert)
+		}
+	}
+
+	return expiring
+}
@@ -0,0 +1,157 @@
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// fieldManager is the name the Operator uses when applying objects.
+const fieldManager = "quay-operator"
+
+// ignoredFields are set by other controllers (or the API server) and are never considered drift.
+var ignoredFields = map[string]bool{
+	"status":                     true,
+	"stringData":                 true,
+	"spec.replicas":              true,
+	"metadata.creationTimestamp": true,
+	"metadata.managedFields":     true,
+	"metadata.ownerReferences":   true,
+	"metadata.resourceVersion":   true,
+	"metadata.uid":               true,
+	"metadata.generation":        true,
+	"metadata.selfLink":          true,
+}
+
+// Report describes how a live object differs from its desired state.
+type Report struct {
+	// Fields are the paths of desired fields whose live value differs.
+	Fields []string
+	// Managers are the field managers other than the Operator which have modified the live object.
+	Managers []string
+}
+
+// Drifted returns true if any fields differ from the desired state.
+func (r Report) Drifted() bool {
+	return len(r.Fields) > 0
+}
+
+func (r Report) String() string {
+	if len(r.Managers) == 0 {
+		return "fields changed: " + strings.Join(r.Fields, ", ")
+	}
+
+	return fmt.Sprintf("fields changed: %s (modified by: %s)", strings.Join(r.Fields, ", "), strings.Join(r.Managers, ", "))
+}
+
+// Detect compares a desired object to its live counterpart. Only fields present in the desired object are
+// compared, so values defaulted by the API server are not reported as drift.
+func Detect(desired, live k8sruntime.Object) (Report, error) {
+	desiredFields, err := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return Report{}, err
+	}
+	liveFields, err := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		return Report{}, err
+	}
+
+	fields := diff("", desiredFields, liveFields)
+	sort.Strings(fields)
+
+	return Report{Fields: fields, Managers: modifiedBy(live)}, nil
+}
+
+// diff returns the paths of every value in `desired` which is missing or different in `live`.
+func diff(path string, desired, live interface{}) []string {
+	if ignoredFields[path] {
+		return nil
+	}
+
+	switch desiredValue := desired.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok && live != nil {
+			return []string{path}
+		}
+
+		fields := []string{}
+		for key, value := range desiredValue {
+			fields = append(fields, diff(join(path, key), value, liveValue[key])...)
+		}
+		return fields
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) != len(desiredValue) {
+			return []string{path}
+		}
+
+		fields := []string{}
+		for i := range desiredValue {
+			fields = append(fields, diff(fmt.Sprintf("%s[%d]", path, i), desiredValue[i], liveValue[i])...)
+		}
+		return fields
+	default:
+		if !reflect.DeepEqual(desired, live) {
+			return []string{path}
+		}
+		return nil
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// modifiedBy returns the field managers of the live object other than the Operator itself.
+func modifiedBy(live k8sruntime.Object) []string {
+	objectMeta, err := meta.Accessor(live)
+	if err != nil {
+		return nil
+	}
+
+	managers := []string{}
+	seen := map[string]bool{}
+	for _, entry := range objectMeta.GetManagedFields() {
+		if entry.Manager == fieldManager || seen[entry.Manager] || statusOnly(entry) {
+			continue
+		}
+		seen[entry.Manager] = true
+		managers = append(managers, entry.Manager)
+	}
+	sort.Strings(managers)
+
+	return managers
+}
+
+// statusOnly returns true if the managed fields entry only covers the `status` of the object.
+func statusOnly(entry metav1.ManagedFieldsEntry) bool {
+	if entry.FieldsV1 == nil {
+		return false
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+		return false
+	}
+
+	for field := range fields {
+		if field != "f:status" {
+			return false
+		}
+	}
+
+	return len(fields) > 0
+}
@@ -0,0 +1,91 @@
+package drift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func service(port int32, managers ...metav1.ManagedFieldsEntry) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "test-quay-app",
+			Labels:        map[string]string{"quay-component": "quay-app"},
+			ManagedFields: managers,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "https", Port: port, TargetPort: intstr.FromInt(8443)},
+			},
+		},
+	}
+}
+
+var detectTests = []struct {
+	name             string
+	desired          k8sruntime.Object
+	live             k8sruntime.Object
+	expectedFields   []string
+	expectedManagers []string
+}{
+	{
+		"NoDrift",
+		service(443),
+		service(443, metav1.ManagedFieldsEntry{Manager: "quay-operator"}),
+		[]string{},
+		[]string{},
+	},
+	{
+		"DefaultedFieldsIgnored",
+		service(443),
+		func() k8sruntime.Object {
+			live := service(443)
+			live.Spec.ClusterIP = "172.30.0.1"
+			live.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+			live.Spec.Ports[0].NodePort = 31000
+			return live
+		}(),
+		[]string{},
+		[]string{},
+	},
+	{
+		"ChangedField",
+		service(443),
+		service(8443,
+			metav1.ManagedFieldsEntry{Manager: "quay-operator"},
+			metav1.ManagedFieldsEntry{Manager: "kubectl"},
+			metav1.ManagedFieldsEntry{Manager: "kube-controller-manager", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:status":{}}`)}},
+		),
+		[]string{"spec.ports[0].port"},
+		[]string{"kubectl"},
+	},
+	{
+		"RemovedLabel",
+		service(443),
+		func() k8sruntime.Object {
+			live := service(443, metav1.ManagedFieldsEntry{Manager: "oc"})
+			live.SetLabels(map[string]string{})
+			return live
+		}(),
+		[]string{"metadata.labels.quay-component"},
+		[]string{"oc"},
+	},
+}
+
+func TestDetect(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range detectTests {
+		report, err := Detect(test.desired, test.live)
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expectedFields, report.Fields, test.name)
+		assert.Equal(test.expectedManagers, report.Managers, test.name)
+		assert.Equal(len(test.expectedFields) > 0, report.Drifted(), test.name)
+	}
+}
@@ -0,0 +1,117 @@
+package kustomize
+
+import (
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// antiAffinityDeployments are the `Deployments` (and the component which renders them) whose replicas are spread
+// across nodes. The manifests prefer this by default.
+var antiAffinityDeployments = []struct {
+	component string
+	name      string
+}{
+	{"", "quay-app"},
+	{"clair", "clair"},
+}
+
+// antiAffinityPatchesFor returns the Kustomize patches which require replicas to be scheduled on different nodes,
+// if `spec.podAntiAffinity` is `Required`.
+func antiAffinityPatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	if quay.Spec.PodAntiAffinity != v1.PodAntiAffinityRequired {
+		return patches
+	}
+
+	for _, deployment := range antiAffinityDeployments {
+		if deployment.component != "" && !v1.ComponentIsManaged(quay.Spec.Components, deployment.component) {
+			continue
+		}
+
+		patches = append(patches, types.Patch{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": deployment.name},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"affinity": map[string]interface{}{
+								"podAntiAffinity": map[string]interface{}{
+									"preferredDuringSchedulingIgnoredDuringExecution": nil,
+									"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{
+										map[string]interface{}{
+											"topologyKey": "kubernetes.io/hostname",
+											"labelSelector": map[string]interface{}{
+												"matchExpressions": []interface{}{
+													map[string]interface{}{
+														"key":      "quay-component",
+														"operator": "In",
+														"values":   []string{deployment.name},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			})),
+		})
+	}
+
+	return patches
+}
+
+// storageAffinityPreferredWeight is the weight of scheduling Quay next to the storage gateway, which is lower than that
+// of spreading its replicas so that a single node running the gateway does not attract every replica.
+const storageAffinityPreferredWeight = 50
+
+// storageAffinityPatchesFor returns the Kustomize patches which schedule the Quay app pods on the nodes running the
+// storage gateway selected by `spec.storageAffinity`.
+func storageAffinityPatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	storageAffinity := quay.Spec.StorageAffinity
+	if storageAffinity == nil {
+		return patches
+	}
+
+	namespaces := storageAffinity.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{quay.GetNamespace()}
+	}
+	term := map[string]interface{}{
+		"topologyKey":   "kubernetes.io/hostname",
+		"namespaces":    namespaces,
+		"labelSelector": map[string]interface{}{"matchLabels": storageAffinity.MatchLabels},
+	}
+
+	podAffinity := map[string]interface{}{
+		"preferredDuringSchedulingIgnoredDuringExecution": []interface{}{
+			map[string]interface{}{"weight": storageAffinityPreferredWeight, "podAffinityTerm": term},
+		},
+	}
+	if storageAffinity.Mode == v1.StorageAffinityRequired {
+		podAffinity = map[string]interface{}{
+			"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{term},
+		}
+	}
+
+	return append(patches, types.Patch{
+		Patch: string(encode(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "quay-app"},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"affinity": map[string]interface{}{"podAffinity": podAffinity},
+					},
+				},
+			},
+		})),
+	})
+}
@@ -0,0 +1,174 @@
+package kustomize
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/hostsettings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// JWTPublicKeyFile is the file in the config bundle which Quay reads the external JWT issuer's public key from.
+const JWTPublicKeyFile = "jwt-authn.cert"
+
+// expirationPattern matches the duration format Quay uses for expiration settings, such as `90d`.
+var expirationPattern = regexp.MustCompile(`^[0-9]+(w|d|h|m|s)$`)
+
+// serverHostnameFor returns the hostname Quay will be configured with, or an empty string if it is unknown.
+func serverHostnameFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) string {
+	if hostname, ok := userConfig["SERVER_HOSTNAME"].(string); ok {
+		return hostname
+	}
+
+	if quay.Spec.Exposure == v1.ExposureInternal {
+		return v1.InternalHostnameFor(quay, "quay-app")
+	}
+
+	if v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		if fieldGroup, err := FieldGroupFor("route", quay); err == nil {
+			return fieldGroup.(*hostsettings.HostSettingsFieldGroup).ServerHostname
+		}
+	}
+
+	return ""
+}
+
+// oidcProvidersIn returns the fields of the OIDC providers in the given Quay config, which are the `*_LOGIN_CONFIG`
+// fields with an `OIDC_SERVER`.
+func oidcProvidersIn(userConfig map[string]interface{}) []string {
+	providers := []string{}
+	for field, value := range userConfig {
+		provider, ok := value.(map[string]interface{})
+		if !ok || !strings.HasSuffix(field, "_LOGIN_CONFIG") {
+			continue
+		}
+		if _, ok := provider["OIDC_SERVER"]; ok {
+			providers = append(providers, field)
+		}
+	}
+	sort.Strings(providers)
+
+	return providers
+}
+
+// federatedUsernamesConfigFor returns the Quay config fields for `spec.authentication.usernames`. The OIDC providers
+// of the config bundle are copied with the username claim set, so they replace those of the config bundle.
+func federatedUsernamesConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	usernames := quay.Spec.Authentication.Usernames
+	config := map[string]interface{}{}
+
+	if usernames.Confirm != nil {
+		config["FEATURE_USERNAME_CONFIRMATION"] = *usernames.Confirm
+	}
+
+	if usernames.LDAPAttribute != "" {
+		if quay.Spec.Authentication.Type != "" || userConfig["AUTHENTICATION_TYPE"] != "LDAP" {
+			return nil, errors.New("`spec.authentication.usernames.ldapAttribute` requires `AUTHENTICATION_TYPE: LDAP` in the config bundle")
+		}
+		config["LDAP_UID_ATTR"] = usernames.LDAPAttribute
+	}
+
+	if usernames.OIDCClaim != "" {
+		providers := oidcProvidersIn(userConfig)
+		if len(providers) == 0 {
+			return nil, errors.New("`spec.authentication.usernames.oidcClaim` requires an OIDC provider in the config bundle")
+		}
+
+		for _, field := range providers {
+			provider := map[string]interface{}{}
+			for key, value := range userConfig[field].(map[string]interface{}) {
+				provider[key] = value
+			}
+			provider["PREFERRED_USERNAME_CLAIM_NAME"] = usernames.OIDCClaim
+			config[field] = provider
+		}
+	}
+
+	return config, nil
+}
+
+// authenticationConfigFor returns the Quay config fields for `spec.authentication`, or nil if it is not set.
+func authenticationConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	auth := quay.Spec.Authentication
+	if auth == nil {
+		return nil, nil
+	}
+
+	config := map[string]interface{}{}
+
+	if tokens := auth.AppTokens; tokens != nil {
+		if tokens.RequiredForCLI && !tokens.Enabled {
+			return nil, errors.New("`spec.authentication.appTokens.requiredForCLI` requires `enabled`")
+		}
+
+		config["FEATURE_APP_SPECIFIC_TOKENS"] = tokens.Enabled
+		config["FEATURE_REQUIRE_ENCRYPTED_BASIC_AUTH"] = tokens.RequiredForCLI
+		if tokens.Expiration != "" {
+			if !expirationPattern.MatchString(tokens.Expiration) {
+				return nil, errors.New("invalid `spec.authentication.appTokens.expiration`: " + tokens.Expiration)
+			}
+			config["APP_SPECIFIC_TOKEN_EXPIRATION"] = tokens.Expiration
+		}
+	}
+
+	if auth.Usernames != nil {
+		usernamesConfig, err := federatedUsernamesConfigFor(quay, userConfig)
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range usernamesConfig {
+			config[field] = value
+		}
+	}
+
+	if restricted := auth.RestrictedUsers; restricted != nil {
+		if len(restricted.Exempt) > 0 && !restricted.Enabled {
+			return nil, errors.New("`spec.authentication.restrictedUsers.exempt` requires `enabled`")
+		}
+
+		config["FEATURE_RESTRICTED_USERS"] = restricted.Enabled
+		if len(restricted.Exempt) > 0 {
+			config["RESTRICTED_USERS_WHITELIST"] = restricted.Exempt
+		}
+	}
+
+	switch auth.Type {
+	case "":
+		return config, nil
+	case v1.AuthenticationTypeDatabase:
+		config["AUTHENTICATION_TYPE"] = string(auth.Type)
+
+		return config, nil
+	case v1.AuthenticationTypeJWT:
+		jwt := auth.JWT
+		if jwt == nil {
+			return nil, errors.New("`spec.authentication.jwt` is required when `type` is `JWT`")
+		}
+		if jwt.Issuer == "" || jwt.VerifyEndpoint == "" {
+			return nil, errors.New("`spec.authentication.jwt` requires `issuer` and `verifyEndpoint`")
+		}
+		if _, ok := configFiles[JWTPublicKeyFile]; !ok {
+			return nil, errors.New("`" + JWTPublicKeyFile + "` not found in `spec.authentication.jwt.publicKeySecret`")
+		}
+		if hostname := serverHostnameFor(quay, userConfig); jwt.Audience != "" && hostname != "" && jwt.Audience != hostname {
+			return nil, errors.New("`spec.authentication.jwt.audience` must match `SERVER_HOSTNAME` (" + hostname + ")")
+		}
+
+		config["AUTHENTICATION_TYPE"] = string(auth.Type)
+		config["JWT_AUTH_ISSUER"] = jwt.Issuer
+		config["JWT_VERIFY_ENDPOINT"] = jwt.VerifyEndpoint
+		if jwt.GetUserEndpoint != "" {
+			config["JWT_GETUSER_ENDPOINT"] = jwt.GetUserEndpoint
+		}
+		if jwt.QueryEndpoint != "" {
+			config["JWT_QUERY_ENDPOINT"] = jwt.QueryEndpoint
+		}
+
+		return config, nil
+	default:
+		return nil, errors.New("unsupported `spec.authentication.type`: " + string(auth.Type))
+	}
+}
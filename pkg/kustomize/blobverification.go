@@ -0,0 +1,153 @@
+package kustomize
+
+import (
+	"errors"
+	"strconv"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// BlobVerificationComponent is the `quay-component` label value of the `CronJob` which verifies blobs on the schedule
+// of `spec.blobVerification`, and of the `Jobs` and pods it creates.
+const BlobVerificationComponent = "quay-blob-verification"
+
+// BlobVerificationContainer is the container of the verification pods, whose termination message is the JSON encoded
+// `BlobVerificationResult`.
+const BlobVerificationContainer = "verify"
+
+// blobVerificationHistory is how many succeeded and failed `Jobs` of the scheduled verifications are kept.
+const blobVerificationHistory = int32(3)
+
+// blobVerificationJobDeadline is how long a verification may run before it is stopped.
+const blobVerificationJobDeadline = int64(6 * 60 * 60)
+
+// BlobVerificationResult is the number of blobs of each outcome of a verification, as reported by its pod.
+type BlobVerificationResult struct {
+	Verified  int32 `json:"verified"`
+	Corrupted int32 `json:"corrupted"`
+	Missing   int32 `json:"missing"`
+}
+
+// blobVerificationScript verifies a random sample of blobs with the storage engines of Quay, reading each blob from the
+// locations it is placed in and comparing its SHA-256 digest with the one recorded in the database. The result is
+// written as the termination message of the container, and each corrupted or missing blob is logged.
+const blobVerificationScript = `
+import hashlib
+import json
+import os
+
+from peewee import fn
+
+from app import storage
+from data.database import ImageStorage, ImageStorageLocation, ImageStoragePlacement
+from data.model.storage import get_layer_path
+
+sample_size = int(os.environ["SAMPLE_SIZE"])
+result = {"verified": 0, "corrupted": 0, "missing": 0}
+
+blobs = (ImageStorage.select()
+         .where(ImageStorage.uploading == False, ImageStorage.content_checksum.startswith("sha256:"))
+         .order_by(fn.Random())
+         .limit(sample_size))
+for blob in blobs:
+    locations = {placement.location.name for placement in (ImageStoragePlacement.select(ImageStoragePlacement, ImageStorageLocation)
+                                                           .join(ImageStorageLocation)
+                                                           .where(ImageStoragePlacement.storage == blob))}
+    path = get_layer_path(blob)
+    if not locations or not storage.exists(locations, path):
+        result["missing"] += 1
+        print("missing blob %s at %s" % (blob.content_checksum, path))
+        continue
+
+    digest = hashlib.sha256()
+    for chunk in storage.stream_read(locations, path):
+        digest.update(chunk)
+    if "sha256:" + digest.hexdigest() == blob.content_checksum:
+        result["verified"] += 1
+    else:
+        result["corrupted"] += 1
+        print("corrupted blob %s at %s has digest sha256:%s" % (blob.content_checksum, path, digest.hexdigest()))
+
+print("verified %(verified)d blobs, %(corrupted)d corrupted, %(missing)d missing" % result)
+with open("/dev/termination-log", "w") as f:
+    json.dump(result, f)
+`
+
+// blobVerificationFor returns the `CronJob` which verifies blobs on the schedule of `spec.blobVerification` with the
+// image and config of the rendered Quay app `Deployment`. Like the other clients of the database, it is suspended while
+// the managed database is upgraded to a new major version. Returns nil if no schedule is declared.
+func blobVerificationFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) (*batchv1beta1.CronJob, error) {
+	if quay.Spec.BlobVerification == nil {
+		return nil, nil
+	}
+
+	var quayApp *apps.Deployment
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-app" {
+			quayApp = deployment
+		}
+	}
+	if quayApp == nil || len(quayApp.Spec.Template.Spec.Containers) == 0 {
+		return nil, errors.New("`spec.blobVerification` requires the Quay app `Deployment`")
+	}
+
+	sampleSize := v1.DefaultBlobVerificationSampleSize
+	if quay.Spec.BlobVerification.SampleSize != nil {
+		sampleSize = *quay.Spec.BlobVerification.SampleSize
+	}
+
+	template := quayApp.Spec.Template.DeepCopy()
+	template.ObjectMeta.Labels = map[string]string{"quay-component": BlobVerificationComponent}
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	verify := template.Spec.Containers[0]
+	verify.Name = BlobVerificationContainer
+	verify.Command = []string{"python", "-c", blobVerificationScript}
+	verify.Env = append(verify.Env, corev1.EnvVar{Name: "SAMPLE_SIZE", Value: strconv.Itoa(int(sampleSize))})
+	verify.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	verify.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+	verify.Ports = nil
+	verify.ReadinessProbe = nil
+	verify.LivenessProbe = nil
+	template.Spec.Containers = []corev1.Container{verify}
+
+	suspend := v1.PostgresMajorUpgradePending(quay)
+	deadline := blobVerificationJobDeadline
+	backoffLimit := int32(1)
+	history := blobVerificationHistory
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quay.GetName() + "-" + BlobVerificationComponent,
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": BlobVerificationComponent},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   quay.Spec.BlobVerification.Schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			Suspend:                    &suspend,
+			SuccessfulJobsHistoryLimit: &history,
+			FailedJobsHistoryLimit:     &history,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"quay-component": BlobVerificationComponent},
+				},
+				Spec: batch.JobSpec{
+					ActiveDeadlineSeconds: &deadline,
+					BackoffLimit:          &backoffLimit,
+					Template:              *template,
+				},
+			},
+		},
+	}
+	cronJob.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"})
+
+	return cronJob, nil
+}
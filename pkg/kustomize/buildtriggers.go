@@ -0,0 +1,147 @@
+package kustomize
+
+import (
+	"errors"
+	"strings"
+
+	route "github.com/openshift/api/route/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// BuildTriggerCredentialsFile is the file in the config bundle with the OAuth credentials of every provider in
+// `spec.buildTriggers`, keyed by provider. It is not included in the rendered config bundle.
+const BuildTriggerCredentialsFile = "build-trigger-credentials.yaml"
+
+// BuildTriggerCredentials are the OAuth client credentials of a Git provider, as stored in its credentials `Secret`.
+type BuildTriggerCredentials struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// webhooksPath is the path under which Quay receives the webhooks of build triggers.
+const webhooksPath = "/webhooks"
+
+// buildTriggerProvidersFor returns the providers configured in `spec.buildTriggers`, keyed by name.
+func buildTriggerProvidersFor(triggers *v1.BuildTriggers) map[string]*v1.BuildTriggerProvider {
+	providers := map[string]*v1.BuildTriggerProvider{}
+	if triggers.GitHub != nil {
+		providers["github"] = triggers.GitHub
+	}
+	if triggers.GitLab != nil {
+		providers["gitlab"] = triggers.GitLab
+	}
+	if triggers.Bitbucket != nil {
+		providers["bitbucket"] = triggers.Bitbucket
+	}
+
+	return providers
+}
+
+// buildTriggersConfigFor returns the Quay config fields for `spec.buildTriggers`, or nil if it is not set. The
+// credentials are read from `BuildTriggerCredentialsFile` in the given config files.
+func buildTriggersConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	triggers := quay.Spec.BuildTriggers
+	if triggers == nil {
+		return nil, nil
+	}
+
+	if enabled, _ := userConfig["FEATURE_BUILD_SUPPORT"].(bool); !enabled {
+		return nil, errors.New("`spec.buildTriggers` requires `FEATURE_BUILD_SUPPORT` to be enabled")
+	}
+	if quay.Spec.Exposure == v1.ExposureInternal {
+		return nil, errors.New("`spec.buildTriggers` cannot be used with `exposure: Internal`, since webhooks could not reach Quay")
+	}
+	if triggers.WebhookHostname != "" && !v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		return nil, errors.New("`spec.buildTriggers.webhookHostname` requires the `route` component to be managed")
+	}
+
+	var credentials map[string]BuildTriggerCredentials
+	if err := yaml.Unmarshal(configFiles[BuildTriggerCredentialsFile], &credentials); err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	for name, provider := range buildTriggerProvidersFor(triggers) {
+		creds := credentials[name]
+		if creds.ClientID == "" || creds.ClientSecret == "" {
+			return nil, errors.New("`spec.buildTriggers." + name + ".credentialsSecret` requires `clientId` and `clientSecret`")
+		}
+
+		switch name {
+		case "github":
+			endpoint, apiEndpoint := "https://github.com/", "https://api.github.com/"
+			if provider.Endpoint != "" {
+				endpoint = strings.TrimSuffix(provider.Endpoint, "/") + "/"
+				apiEndpoint = endpoint + "api/v3/"
+			}
+			config["FEATURE_GITHUB_BUILD"] = true
+			config["GITHUB_TRIGGER_CONFIG"] = map[string]interface{}{
+				"CLIENT_ID":       creds.ClientID,
+				"CLIENT_SECRET":   creds.ClientSecret,
+				"GITHUB_ENDPOINT": endpoint,
+				"API_ENDPOINT":    apiEndpoint,
+			}
+		case "gitlab":
+			endpoint := "https://gitlab.com"
+			if provider.Endpoint != "" {
+				endpoint = strings.TrimSuffix(provider.Endpoint, "/")
+			}
+			config["FEATURE_GITLAB_BUILD"] = true
+			config["GITLAB_TRIGGER_CONFIG"] = map[string]interface{}{
+				"CLIENT_ID":       creds.ClientID,
+				"CLIENT_SECRET":   creds.ClientSecret,
+				"GITLAB_ENDPOINT": endpoint,
+			}
+		case "bitbucket":
+			if provider.Endpoint != "" {
+				return nil, errors.New("`spec.buildTriggers.bitbucket.endpoint` is not supported")
+			}
+			config["FEATURE_BITBUCKET_BUILD"] = true
+			config["BITBUCKET_TRIGGER_CONFIG"] = map[string]interface{}{
+				"CONSUMER_KEY":    creds.ClientID,
+				"CONSUMER_SECRET": creds.ClientSecret,
+			}
+		}
+	}
+
+	if triggers.WebhookHostname != "" {
+		config["WEBHOOK_HOSTNAME_OVERRIDE"] = triggers.WebhookHostname
+	}
+
+	return config, nil
+}
+
+// withBuildTriggerRoute adds a `Route` for `spec.buildTriggers.webhookHostname` which only exposes the webhooks
+// path, copied from the managed Quay `Route`. Since path-based routing requires the router to terminate TLS, it
+// re-encrypts to Quay, trusting the given certificate.
+func withBuildTriggerRoute(quay *v1.QuayRegistry, objects []k8sruntime.Object, quayCert []byte) []k8sruntime.Object {
+	if quay.Spec.BuildTriggers == nil || quay.Spec.BuildTriggers.WebhookHostname == "" {
+		return objects
+	}
+
+	for _, obj := range objects {
+		quayRoute, ok := obj.(*route.Route)
+		if !ok || quayRoute.GetName() != quay.GetName()+"-quay" {
+			continue
+		}
+
+		webhooksRoute := quayRoute.DeepCopy()
+		webhooksRoute.SetName(quay.GetName() + "-quay-build-triggers")
+		webhooksRoute.Spec.Host = quay.Spec.BuildTriggers.WebhookHostname
+		webhooksRoute.Spec.Path = webhooksPath
+		webhooksRoute.Spec.Port = &route.RoutePort{TargetPort: intstr.FromString("https")}
+		webhooksRoute.Spec.TLS = &route.TLSConfig{
+			Termination:                   route.TLSTerminationReencrypt,
+			DestinationCACertificate:      string(quayCert),
+			InsecureEdgeTerminationPolicy: route.InsecureEdgeTerminationPolicyRedirect,
+		}
+
+		return append(objects, webhooksRoute)
+	}
+
+	return objects
+}
@@ -0,0 +1,123 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// Version is the version of the Operator, which determines the manifests rendered for a given `QuayRegistry`.
+// Overridden at build time using `-ldflags "-X github.com/quay/quay-operator/pkg/kustomize.Version=..."`.
+var Version = "dev"
+
+type renderedEntry struct {
+	key     string
+	objects []k8sruntime.Object
+}
+
+// renderCache holds the most recently rendered objects for each `QuayRegistry`, so that unchanged registries
+// are not re-inflated using Kustomize on every reconcile.
+var renderCache = struct {
+	sync.Mutex
+	entries map[types.NamespacedName]renderedEntry
+}{entries: map[types.NamespacedName]renderedEntry{}}
+
+// renderKey is every input which affects the output of `Inflate`.
+type renderKey struct {
+	Version         string
+	UID             types.UID
+	Annotations     map[string]string
+	Spec            v1.QuayRegistrySpec
+	CurrentVersion  v1.QuayVersion
+	ClusterHostname string
+	MigrationPhase  v1.StorageMigrationPhase
+	Postgres        *v1.PostgresStatus
+	ClairPostgres   string
+	OperatorConfig  *v1.QuayOperatorConfigSpec
+	ConfigBundle    map[string][]byte
+	SecretKeys      map[string][]byte
+}
+
+// cacheKeyFor returns a hash of the spec, config bundle and Operator version used to render a `QuayRegistry`.
+func cacheKeyFor(quay *v1.QuayRegistry, configBundle, secretKeysSecret *corev1.Secret) (string, error) {
+	key := renderKey{
+		Version:         Version,
+		UID:             quay.GetUID(),
+		Annotations:     quay.GetAnnotations(),
+		Spec:            quay.Spec,
+		CurrentVersion:  quay.Status.CurrentVersion,
+		ClusterHostname: quay.Status.ClusterHostname,
+		MigrationPhase:  v1.StorageMigrationPhaseFor(quay),
+		Postgres:        quay.Status.Postgres,
+		ClairPostgres:   quay.Status.ClairPostgresImage,
+		OperatorConfig:  quay.Status.OperatorConfig,
+		ConfigBundle:    configBundle.Data,
+	}
+	if secretKeysSecret != nil {
+		key.SecretKeys = secretKeysSecret.Data
+	}
+
+	// NOTE: `encoding/json` sorts map keys, so the output is stable.
+	marshalled, err := json.Marshal(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(marshalled)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func copyObjects(objects []k8sruntime.Object) []k8sruntime.Object {
+	copied := make([]k8sruntime.Object, len(objects))
+	for i, obj := range objects {
+		copied[i] = obj.DeepCopyObject()
+	}
+
+	return copied
+}
+
+// InflateCached behaves like `Inflate`, but returns the previously rendered objects if none of the inputs have changed.
+func InflateCached(quay *v1.QuayRegistry, configBundle *corev1.Secret, secretKeysSecret *corev1.Secret, log logr.Logger) ([]k8sruntime.Object, error) {
+	key, err := cacheKeyFor(quay, configBundle, secretKeysSecret)
+	if err != nil {
+		return nil, err
+	}
+	name := types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName()}
+
+	renderCache.Lock()
+	entry, ok := renderCache.entries[name]
+	renderCache.Unlock()
+
+	if ok && entry.key == key {
+		log.Info("inputs unchanged since last render, using cached objects")
+
+		return copyObjects(entry.objects), nil
+	}
+
+	objects, err := Inflate(quay, configBundle, secretKeysSecret, log)
+	if err != nil {
+		return nil, err
+	}
+
+	renderCache.Lock()
+	renderCache.entries[name] = renderedEntry{key: key, objects: copyObjects(objects)}
+	renderCache.Unlock()
+
+	return objects, nil
+}
+
+// ForgetRendered removes any cached objects for the given `QuayRegistry`.
+func ForgetRendered(name types.NamespacedName) {
+	renderCache.Lock()
+	defer renderCache.Unlock()
+
+	delete(renderCache.entries, name)
+}
@@ -0,0 +1,202 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/quay/clair/v4/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// ClairCABundleFile is the file of the Quay config bundle with the CA of the generated certificate of the managed
+	// Clair, which Quay trusts as one of its `extra_ca_cert_` files.
+	ClairCABundleFile = "extra_ca_cert_clair.crt"
+	// ClairCertificateChecksumAnnotation is the pod template annotation of the managed Clair with a checksum of its CA,
+	// so that rotating the certificate restarts Clair, which only reads it on startup.
+	ClairCertificateChecksumAnnotation = "quay-operator/clair-certificate-checksum"
+
+	// clairTLSDir is the directory the generated certificate of the managed Clair is mounted in.
+	clairTLSDir = "/var/run/clair-tls"
+	// clairMatcherConnections is how many connections to the managed Clair database the matchers of all replicas of
+	// the managed Clair share.
+	clairMatcherConnections = 100
+)
+
+// clairReplicasFor returns the number of pods of the managed Clair.
+func clairReplicasFor(quay *v1.QuayRegistry) int32 {
+	if quay.Spec.Clair == nil || quay.Spec.Clair.Replicas == nil {
+		return 1
+	}
+
+	return *quay.Spec.Clair.Replicas
+}
+
+// clairMatcherConnectionsFor returns the connection pool size of the matcher of each replica of the managed Clair, so
+// that adding replicas does not exhaust the connections of its database.
+func clairMatcherConnectionsFor(quay *v1.QuayRegistry) int {
+	connections := clairMatcherConnections / int(clairReplicasFor(quay))
+	if connections < 1 {
+		return 1
+	}
+
+	return connections
+}
+
+// clairMetricsFor returns the metrics config of the managed Clair for `spec.clair.metrics`, which reports to
+// Prometheus by default.
+func clairMetricsFor(quay *v1.QuayRegistry) config.Metrics {
+	metrics := config.Metrics{Name: string(v1.ClairMetricsPrometheus)}
+	if quay.Spec.Clair == nil || quay.Spec.Clair.Metrics == nil {
+		return metrics
+	}
+
+	switch quay.Spec.Clair.Metrics.Name {
+	case v1.ClairMetricsDogstatsd:
+		metrics.Name = string(v1.ClairMetricsDogstatsd)
+		metrics.Dogstatsd.URL = quay.Spec.Clair.Metrics.Endpoint
+	default:
+		if endpoint := quay.Spec.Clair.Metrics.Endpoint; endpoint != "" {
+			metrics.Prometheus.Endpoint = &endpoint
+		}
+	}
+
+	return metrics
+}
+
+// validateClairMetrics returns an error if `spec.clair.metrics` does not declare an endpoint its backend can use.
+func validateClairMetrics(quay *v1.QuayRegistry) error {
+	if quay.Spec.Clair == nil || quay.Spec.Clair.Metrics == nil {
+		return nil
+	}
+
+	endpoint := quay.Spec.Clair.Metrics.Endpoint
+	if quay.Spec.Clair.Metrics.Name == v1.ClairMetricsDogstatsd {
+		if endpoint == "" {
+			return errors.New("`spec.clair.metrics.endpoint` must be set to the URL of the DogStatsD agent with the `dogstatsd` backend")
+		}
+		if uri, err := url.Parse(endpoint); err != nil || uri.Host == "" {
+			return errors.New("`spec.clair.metrics.endpoint` must be a URL such as `udp://datadog-agent:8125` with the `dogstatsd` backend")
+		}
+
+		return nil
+	}
+
+	if endpoint != "" && !strings.HasPrefix(endpoint, "/") {
+		return errors.New("`spec.clair.metrics.endpoint` must be a path such as `/metrics` with the `prometheus` backend")
+	}
+
+	return nil
+}
+
+// clairSchemeFor returns the scheme the API of the managed Clair is served with.
+func clairSchemeFor(quay *v1.QuayRegistry) string {
+	if v1.ClairTLSEnabled(quay) {
+		return "https"
+	}
+
+	return "http"
+}
+
+// clairEndpointFor returns the URL Quay reaches the managed Clair at.
+func clairEndpointFor(quay *v1.QuayRegistry) string {
+	if v1.ClairTLSEnabled(quay) {
+		return "https://" + quay.GetName() + "-clair"
+	}
+
+	return "http://" + quay.GetName() + "-clair:80"
+}
+
+// withClairTLSConfig adds the generated certificate to the given Clair config. The config of the vendored Clair
+// release predates its `tls` field, so it is added to the marshalled config.
+func withClairTLSConfig(marshalled []byte) []byte {
+	var config map[string]interface{}
+	check(yaml.Unmarshal(marshalled, &config))
+
+	config["tls"] = map[string]interface{}{
+		"cert": clairTLSDir + "/tls.crt",
+		"key":  clairTLSDir + "/tls.key",
+	}
+
+	withTLS, err := yaml.Marshal(config)
+	check(err)
+
+	return withTLS
+}
+
+// withClairTLS mounts the generated certificate into the managed Clair, and serves its API on the HTTPS port of its
+// `Service`.
+func withClairTLS(quay *v1.QuayRegistry, resources []k8sruntime.Object, caBundle []byte) ([]k8sruntime.Object, error) {
+	if !v1.ClairTLSEnabled(quay) {
+		return resources, nil
+	}
+	if len(caBundle) == 0 {
+		return nil, errors.New("certificate for `clair` component has not been generated")
+	}
+
+	for _, resource := range resources {
+		switch obj := resource.(type) {
+		case *appsv1.Deployment:
+			if obj.GetName() != quay.GetName()+"-clair" {
+				continue
+			}
+
+			template := &obj.Spec.Template
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[ClairCertificateChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(caBundle))
+
+			template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+				Name: "clair-tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: v1.ClairTLSSecretFor(quay)},
+				},
+			})
+			for i := range template.Spec.Containers {
+				if template.Spec.Containers[i].Name != "clair" {
+					continue
+				}
+				template.Spec.Containers[i].VolumeMounts = append(template.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+					Name:      "clair-tls",
+					MountPath: clairTLSDir,
+					ReadOnly:  true,
+				})
+			}
+		case *corev1.Service:
+			if obj.GetName() != quay.GetName()+"-clair" {
+				continue
+			}
+
+			obj.Spec.Ports = []corev1.ServicePort{{
+				Name:       "clair-https",
+				Port:       443,
+				Protocol:   corev1.ProtocolTCP,
+				TargetPort: intstr.FromInt(8080),
+			}}
+		}
+	}
+
+	return resources, nil
+}
+
+// withClairReplicas scales the managed Clair to `spec.clair.replicas`.
+func withClairReplicas(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	replicas := clairReplicasFor(quay)
+	for _, resource := range resources {
+		if deployment, ok := resource.(*appsv1.Deployment); ok && deployment.GetName() == quay.GetName()+"-clair" {
+			deployment.Spec.Replicas = &replicas
+		}
+	}
+
+	return resources
+}
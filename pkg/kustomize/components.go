@@ -0,0 +1,436 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/database"
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/distributedstorage"
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/hostsettings"
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/securityscanner"
+	"github.com/quay/config-tool/pkg/lib/shared"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// ComponentProvider renders a component which can be declared in `spec.components`. Every component has a provider
+// registered using `RegisterComponent`, so a new component is added by implementing this interface in one place.
+type ComponentProvider interface {
+	// Name is the `kind` of the component in `spec.components`.
+	Name() string
+	// FieldGroup returns the name and values of the Quay config field group the component provides when managed,
+	// or an empty name and nil if it does not provide one.
+	FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error)
+	// ConfigFiles returns the files added to the Quay config bundle when the component is managed.
+	ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error)
+	// Resources returns the Kustomize component rendered when the component is managed.
+	Resources(quay *v1.QuayRegistry) (*ComponentResources, error)
+	// Validate returns an error if the component cannot be managed for the given `QuayRegistry`.
+	Validate(quay *v1.QuayRegistry) error
+}
+
+// ComponentResources are the objects rendered for a managed component.
+type ComponentResources struct {
+	// Path is the directory of the Kustomize component, relative to the `kustomize` directory, or empty if the
+	// component renders no objects.
+	Path string
+	// SecretFiles are merged into the `<kind>-config-secret` generated by the Kustomize component.
+	SecretFiles map[string][]byte
+}
+
+var componentProviders = struct {
+	sync.RWMutex
+	providers map[string]ComponentProvider
+}{providers: map[string]ComponentProvider{}}
+
+// RegisterComponent adds a component provider, replacing any existing provider with the same name.
+func RegisterComponent(provider ComponentProvider) {
+	componentProviders.Lock()
+	defer componentProviders.Unlock()
+
+	componentProviders.providers[provider.Name()] = provider
+}
+
+// ComponentProviderFor returns the registered provider of the given component.
+func ComponentProviderFor(kind string) (ComponentProvider, error) {
+	componentProviders.RLock()
+	defer componentProviders.RUnlock()
+
+	provider, ok := componentProviders.providers[kind]
+	if !ok {
+		return nil, errors.New("unknown component: " + kind)
+	}
+
+	return provider, nil
+}
+
+func init() {
+	for _, provider := range []ComponentProvider{
+		clairComponent{baseComponent{"clair"}},
+		postgresComponent{baseComponent{"postgres"}},
+		redisComponent{baseComponent{"redis"}},
+		objectStorageComponent{baseComponent{"objectstorage"}},
+		minioComponent{baseComponent{"minio"}},
+		localStorageComponent{baseComponent{"localstorage"}},
+		pgBouncerComponent{baseComponent{"pgbouncer"}},
+		routeComponent{baseComponent{"route"}},
+		baseComponent{"horizontalpodautoscaler"},
+	} {
+		RegisterComponent(provider)
+	}
+}
+
+// baseComponent implements a component which provides no field group, is rendered from the Kustomize component in
+// `components/<kind>` and has nothing to validate. Other components embed it and override what they need.
+type baseComponent struct {
+	name string
+}
+
+func (c baseComponent) Name() string {
+	return c.name
+}
+
+func (c baseComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	return "", nil, nil
+}
+
+func (c baseComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+func (c baseComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	return &ComponentResources{Path: filepath.Join("components", c.name)}, nil
+}
+
+func (c baseComponent) Validate(quay *v1.QuayRegistry) error {
+	return nil
+}
+
+// fieldGroupConfigFiles returns the field group of the given component as its config file.
+func fieldGroupConfigFiles(provider ComponentProvider, quay *v1.QuayRegistry) (map[string][]byte, error) {
+	_, fieldGroup, err := provider.FieldGroup(quay)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{provider.Name() + ".config.yaml": encode(fieldGroup)}, nil
+}
+
+type clairComponent struct {
+	baseComponent
+}
+
+func (c clairComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	fieldGroup, err := securityscanner.NewSecurityScannerFieldGroup(map[string]interface{}{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	fieldGroup.FeatureSecurityScanner = true
+	fieldGroup.SecurityScannerV4Endpoint = clairEndpointFor(quay)
+	fieldGroup.SecurityScannerV4NamespaceWhitelist = []string{"admin"}
+
+	return "SecurityScanner", fieldGroup, nil
+}
+
+func (c clairComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+// Resources passes the generated password of its database to both Clair and the database, which sets it when it is
+// initialized.
+func (c clairComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	return &ComponentResources{
+		Path: filepath.Join("components", c.Name()),
+		SecretFiles: map[string][]byte{
+			"config.yaml":       clairConfigFor(quay),
+			"POSTGRES_PASSWORD": []byte(quay.GetAnnotations()[v1.ClairDatabasePasswordAnnotation]),
+		},
+	}, nil
+}
+
+func (c clairComponent) Validate(quay *v1.QuayRegistry) error {
+	if updaters := quay.Spec.ClairUpdaters; updaters != nil && updaters.Period != nil && updaters.Period.Duration < time.Minute {
+		return errors.New("`spec.clairUpdaters.period` must be at least 1m")
+	}
+
+	return validateClairMetrics(quay)
+}
+
+type redisComponent struct {
+	baseComponent
+}
+
+func (c redisComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	return "Redis", managedRedisFieldGroupFor(quay), nil
+}
+
+func (c redisComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	return &ComponentResources{
+		Path: filepath.Join("components", c.Name()),
+		SecretFiles: map[string][]byte{
+			"REDIS_PASSWORD": []byte(quay.GetAnnotations()[v1.RedisPasswordAnnotation]),
+		},
+	}, nil
+}
+
+func (c redisComponent) Validate(quay *v1.QuayRegistry) error {
+	return validateRedisSettings(quay)
+}
+
+func (c redisComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+type postgresComponent struct {
+	baseComponent
+}
+
+func (c postgresComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	fieldGroup, err := database.NewDatabaseFieldGroup(map[string]interface{}{})
+	if err != nil {
+		return "", nil, err
+	}
+	user := "postgres"
+	password := quay.GetAnnotations()[v1.DatabasePasswordAnnotation]
+	host := strings.Join([]string{quay.GetName(), "quay-postgres"}, "-")
+	port := "5432"
+	name := "quay"
+	fieldGroup.DbUri = fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", user, password, host, port, name)
+
+	return "Database", fieldGroup, nil
+}
+
+// Resources passes the generated password to the database, which sets it when it is initialized.
+func (c postgresComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	return &ComponentResources{
+		Path: filepath.Join("components", c.Name()),
+		SecretFiles: map[string][]byte{
+			"POSTGRES_PASSWORD": []byte(quay.GetAnnotations()[v1.DatabasePasswordAnnotation]),
+		},
+	}, nil
+}
+
+func (c postgresComponent) Validate(quay *v1.QuayRegistry) error {
+	return validatePostgresVersion(quay)
+}
+
+// ConfigFiles verifies the certificate of the database when it is served over TLS.
+func (c postgresComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	if !v1.PostgresTLSEnabled(quay) {
+		return fieldGroupConfigFiles(c, quay)
+	}
+
+	_, fieldGroup, err := c.FieldGroup(quay)
+	if err != nil {
+		return nil, err
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(encode(fieldGroup), &config); err != nil {
+		return nil, err
+	}
+	args, _ := config["DB_CONNECTION_ARGS"].(map[string]interface{})
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	args["sslmode"] = string(v1.DatabaseSSLModeVerifyFull)
+	args["sslrootcert"] = "/conf/stack/" + DatabaseCAFile
+	config["DB_CONNECTION_ARGS"] = args
+
+	return map[string][]byte{c.Name() + ".config.yaml": encode(config)}, nil
+}
+
+type objectStorageComponent struct {
+	baseComponent
+}
+
+func (c objectStorageComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	if primary := v1.PrimaryStorageLocationFor(quay); primary != nil {
+		return "DistributedStorage", withStorageLocations(quay, existingBucketFieldGroupFor(existingBucketLocationFor(quay, *primary))), nil
+	}
+
+	// NOTE: Storage is proxied through Quay, since the bucket claimed from the cluster may not be reachable by clients.
+	if len(v1.StorageLocationsFor(quay)) > 0 {
+		fieldGroup := existingBucketFieldGroupFor(claimedBucketLocationFor(quay))
+		fieldGroup.FeatureProxyStorage = true
+
+		return "DistributedStorage", withStorageLocations(quay, fieldGroup), nil
+	}
+
+	hostname := quay.GetAnnotations()[v1.StorageHostnameAnnotation]
+	bucketName := quay.GetAnnotations()[v1.StorageBucketNameAnnotation]
+	accessKey := quay.GetAnnotations()[v1.StorageAccessKeyAnnotation]
+	secretKey := quay.GetAnnotations()[v1.StorageSecretKeyAnnotation]
+
+	fieldGroup := &distributedstorage.DistributedStorageFieldGroup{
+		FeatureProxyStorage:                true,
+		DistributedStoragePreference:       []string{v1.PrimaryStorageLocation},
+		DistributedStorageDefaultLocations: []string{v1.PrimaryStorageLocation},
+		DistributedStorageConfig: map[string]*distributedstorage.DistributedStorageDefinition{
+			v1.PrimaryStorageLocation: {
+				Name: "RadosGWStorage",
+				Args: &shared.DistributedStorageArgs{
+					Hostname:    hostname,
+					IsSecure:    true,
+					Port:        443,
+					StoragePath: defaultStoragePath,
+					BucketName:  bucketName,
+					AccessKey:   accessKey,
+					SecretKey:   secretKey,
+				},
+			},
+		},
+	}
+
+	return "DistributedStorage", fieldGroup, nil
+}
+
+func (c objectStorageComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+// Resources renders no objects for an existing bucket in `spec.objectStorage`.
+func (c objectStorageComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	if !v1.ClaimsObjectBucket(quay) {
+		return &ComponentResources{}, nil
+	}
+
+	return c.baseComponent.Resources(quay)
+}
+
+func (c objectStorageComponent) Validate(quay *v1.QuayRegistry) error {
+	if quay.Spec.ObjectStorage != nil {
+		if err := validateStorageCABundle(quay.Spec.ObjectStorage.CABundle); err != nil {
+			return err
+		}
+	}
+	if primary := v1.PrimaryStorageLocationFor(quay); primary != nil {
+		if err := validateStorageLocation(quay, "spec.objectStorage", *primary); err != nil {
+			return err
+		}
+	}
+
+	return validateStorageLocations(quay)
+}
+
+type minioComponent struct {
+	baseComponent
+}
+
+// FieldGroup proxies storage through Quay, since the MinIO `Service` is only reachable from inside the cluster.
+func (c minioComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	fieldGroup := existingBucketFieldGroupFor([]interface{}{"RadosGWStorage", map[string]interface{}{
+		"hostname":     strings.Join([]string{quay.GetName(), "quay-minio"}, "-"),
+		"is_secure":    false,
+		"port":         9000,
+		"access_key":   quay.GetAnnotations()[v1.MinIOAccessKeyAnnotation],
+		"secret_key":   quay.GetAnnotations()[v1.MinIOSecretKeyAnnotation],
+		"bucket_name":  minioBucketName,
+		"storage_path": defaultStoragePath,
+	}})
+	fieldGroup.FeatureProxyStorage = true
+
+	return "DistributedStorage", fieldGroup, nil
+}
+
+func (c minioComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+// Resources passes the generated credentials to MinIO as its root user.
+func (c minioComponent) Resources(quay *v1.QuayRegistry) (*ComponentResources, error) {
+	return &ComponentResources{
+		Path: filepath.Join("components", c.Name()),
+		SecretFiles: map[string][]byte{
+			"MINIO_ROOT_USER":     []byte(quay.GetAnnotations()[v1.MinIOAccessKeyAnnotation]),
+			"MINIO_ROOT_PASSWORD": []byte(quay.GetAnnotations()[v1.MinIOSecretKeyAnnotation]),
+		},
+	}, nil
+}
+
+func (c minioComponent) Validate(quay *v1.QuayRegistry) error {
+	if quay.GetAnnotations()[v1.MinIOAccessKeyAnnotation] == "" || quay.GetAnnotations()[v1.MinIOSecretKeyAnnotation] == "" {
+		return errors.New("credentials for `minio` component have not been generated")
+	}
+
+	return nil
+}
+
+type localStorageComponent struct {
+	baseComponent
+}
+
+// FieldGroup stores images under the mount path of the volume, which is proxied through Quay like any other storage
+// clients cannot reach directly.
+func (c localStorageComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	fieldGroup := existingBucketFieldGroupFor([]interface{}{"LocalStorage", map[string]interface{}{
+		"storage_path": defaultStoragePath,
+	}})
+	fieldGroup.FeatureProxyStorage = true
+
+	return "DistributedStorage", fieldGroup, nil
+}
+
+func (c localStorageComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return fieldGroupConfigFiles(c, quay)
+}
+
+// Validate rejects more than one Quay app pod, since only one can mount the `ReadWriteOnce` volume.
+func (c localStorageComponent) Validate(quay *v1.QuayRegistry) error {
+	if overrides := quay.Spec.ProfileOverrides; overrides != nil && overrides.Replicas != nil && *overrides.Replicas > 1 {
+		return errors.New("`spec.profileOverrides.replicas` cannot be more than 1 with the `localstorage` component")
+	}
+
+	return nil
+}
+
+type routeComponent struct {
+	baseComponent
+}
+
+func (c routeComponent) FieldGroup(quay *v1.QuayRegistry) (string, shared.FieldGroup, error) {
+	clusterHostname := v1.ClusterHostnameFor(quay)
+
+	fieldGroup := &hostsettings.HostSettingsFieldGroup{
+		ExternalTlsTermination: false,
+		PreferredUrlScheme:     "https",
+		ServerHostname: strings.Join([]string{
+			strings.Join([]string{quay.GetName(), "quay", quay.GetNamespace()}, "-"),
+			clusterHostname},
+			"."),
+	}
+
+	return "HostSettings", fieldGroup, nil
+}
+
+// ConfigFiles uses the `SERVER_HOSTNAME` from the config bundle if it is set, which is also passed to the `Route`.
+func (c routeComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	configFiles := map[string][]byte{}
+	_, fieldGroup, err := c.FieldGroup(quay)
+	if err != nil {
+		return nil, err
+	}
+
+	hostSettings := fieldGroup.(*hostsettings.HostSettingsFieldGroup)
+	if hostname, ok := userConfig["SERVER_HOSTNAME"].(string); ok {
+		configFiles[registryHostnameKey] = []byte(hostname)
+		hostSettings.ServerHostname = hostname
+	}
+	configFiles[c.Name()+".config.yaml"] = encode(hostSettings)
+
+	return configFiles, nil
+}
+
+func (c routeComponent) Validate(quay *v1.QuayRegistry) error {
+	if quay.Spec.Exposure == v1.ExposureInternal {
+		return errors.New("cannot use `route` component with `exposure: Internal`")
+	}
+
+	return nil
+}
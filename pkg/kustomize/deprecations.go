@@ -0,0 +1,99 @@
+package kustomize
+
+import (
+	"sort"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// configDeprecation is a config bundle field which a Quay version no longer supports.
+type configDeprecation struct {
+	// Field is the deprecated field.
+	Field string
+	// Removed is true if the Quay version ignores the field, rather than only warning about it.
+	Removed bool
+	// ReplacedBy is the field a renamed field was renamed to. The value of a renamed field is migrated to it in the
+	// rendered config bundle.
+	ReplacedBy string
+}
+
+// legacyFields configure the Clair v2 API, which was replaced by `SECURITY_SCANNER_V4_ENDPOINT`, and the conversion of
+// images to ACI and their signing, which was dropped along with rkt. Both are removed in `vader`.
+var legacyFields = []string{
+	"SECURITY_SCANNER_ENDPOINT",
+	"SECURITY_SCANNER_NOTIFICATIONS",
+	"SECURITY_SCANNER_API_VERSION",
+	"FEATURE_ACI_CONVERSION",
+	"GPG2_PRIVATE_KEY_NAME",
+	"GPG2_PRIVATE_KEY_FILENAME",
+	"GPG2_PUBLIC_KEY_FILENAME",
+}
+
+// deprecationsOf returns the deprecations of the given fields, followed by the other deprecations.
+func deprecationsOf(fields []string, removed bool, others ...configDeprecation) []configDeprecation {
+	deprecations := []configDeprecation{}
+	for _, field := range fields {
+		deprecations = append(deprecations, configDeprecation{Field: field, Removed: removed})
+	}
+
+	return append(deprecations, others...)
+}
+
+// configDeprecations are the config bundle fields which each Quay version the Operator can deploy no longer supports.
+var configDeprecations = map[v1.QuayVersion][]configDeprecation{
+	v1.QuayVersionQuiGon: deprecationsOf(legacyFields, false,
+		configDeprecation{Field: "FEATURE_BITTORRENT", Removed: true},
+	),
+	v1.QuayVersionVader: deprecationsOf(legacyFields, true,
+		configDeprecation{Field: "FEATURE_BITTORRENT", Removed: true},
+	),
+	v1.QuayVersionDev: deprecationsOf(legacyFields, true,
+		configDeprecation{Field: "FEATURE_BITTORRENT", Removed: true},
+		configDeprecation{Field: "FEATURE_EXPERIMENTAL_HELM_OCI_SUPPORT", ReplacedBy: "FEATURE_HELM_OCI_SUPPORT"},
+	),
+}
+
+// DeprecatedConfigFor returns a warning for every field of the given Quay config which the desired Quay version no
+// longer supports, sorted by field.
+func DeprecatedConfigFor(quay *v1.QuayRegistry, config map[string]interface{}) []string {
+	version := quay.Spec.DesiredVersion
+
+	warnings := []string{}
+	for _, deprecation := range configDeprecations[version] {
+		if _, ok := config[deprecation.Field]; !ok {
+			continue
+		}
+
+		warning := "`" + deprecation.Field + "` is deprecated in Quay version `" + string(version) + "`"
+		if deprecation.Removed {
+			warning = "`" + deprecation.Field + "` is removed in Quay version `" + string(version) + "` and is ignored"
+		}
+		if deprecation.ReplacedBy != "" {
+			warning += ", and is migrated to `" + deprecation.ReplacedBy + "`"
+		}
+		warnings = append(warnings, warning)
+	}
+	sort.Strings(warnings)
+
+	return warnings
+}
+
+// migrateRenamedConfig moves the values of the renamed fields of the given Quay config to the fields which replace
+// them, unless those are already set. Returns true if the config was changed.
+func migrateRenamedConfig(quay *v1.QuayRegistry, config map[string]interface{}) bool {
+	migrated := false
+	for _, deprecation := range configDeprecations[quay.Spec.DesiredVersion] {
+		value, ok := config[deprecation.Field]
+		if !ok || deprecation.ReplacedBy == "" {
+			continue
+		}
+
+		if _, ok := config[deprecation.ReplacedBy]; !ok {
+			config[deprecation.ReplacedBy] = value
+		}
+		delete(config, deprecation.Field)
+		migrated = true
+	}
+
+	return migrated
+}
@@ -0,0 +1,84 @@
+package kustomize
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// validateEphemeralStorage returns an error if `spec.ephemeralStorage` cannot be rendered, or would evict a pod
+// before its `EmptyDir` volume from `spec.tempStorage` is full.
+func validateEphemeralStorage(quay *v1.QuayRegistry) error {
+	seen := map[string]bool{}
+	for _, storage := range quay.Spec.EphemeralStorage {
+		deployment, ok := tempStorageDeployments[storage.Component]
+		if !ok {
+			return fmt.Errorf("`spec.ephemeralStorage` component `%s` must be `quay` or `clair`", storage.Component)
+		}
+		if seen[storage.Component] {
+			return fmt.Errorf("`spec.ephemeralStorage` lists component `%s` more than once", storage.Component)
+		}
+		seen[storage.Component] = true
+
+		if deployment.component != "" && !v1.ComponentIsManaged(quay.Spec.Components, deployment.component) {
+			return fmt.Errorf("`spec.ephemeralStorage` component `%s` requires it to be managed", storage.Component)
+		}
+		if storage.Limit == nil {
+			continue
+		}
+		if storage.Request != nil && storage.Request.Cmp(*storage.Limit) > 0 {
+			return fmt.Errorf("`spec.ephemeralStorage` request of `%s` cannot be larger than its limit", storage.Component)
+		}
+
+		for _, temp := range quay.Spec.TempStorage {
+			if temp.Component == storage.Component && temp.Backing != v1.TempStorageBackingPersistentVolumeClaim &&
+				temp.Size != nil && temp.Size.Cmp(*storage.Limit) > 0 {
+				return fmt.Errorf("`spec.ephemeralStorage` limit of `%s` cannot be smaller than its `spec.tempStorage` size", storage.Component)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ephemeralStoragePatchesFor returns the Kustomize patches which set the ephemeral storage resources of each
+// component in `spec.ephemeralStorage`. They are applied after those of `spec.tempStorage`, so an explicit request
+// replaces the one of an `EmptyDir` volume.
+func ephemeralStoragePatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	for _, storage := range quay.Spec.EphemeralStorage {
+		deployment, ok := tempStorageDeployments[storage.Component]
+		if !ok || (storage.Request == nil && storage.Limit == nil) {
+			continue
+		}
+
+		resources := map[string]interface{}{}
+		if storage.Request != nil {
+			resources["requests"] = map[string]interface{}{"ephemeral-storage": storage.Request.String()}
+		}
+		if storage.Limit != nil {
+			resources["limits"] = map[string]interface{}{"ephemeral-storage": storage.Limit.String()}
+		}
+
+		patches = append(patches, types.Patch{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": deployment.name},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{"name": deployment.container, "resources": resources},
+							},
+						},
+					},
+				},
+			})),
+		})
+	}
+
+	return patches
+}
@@ -0,0 +1,156 @@
+package kustomize
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/kustomize/api/resid"
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// exposedServices are the `Services` whose type is set by `spec.serviceType`.
+var exposedServices = []string{"quay-app", "quay-config-editor"}
+
+// serviceTypeFor returns the type of the exposed `Services`, or an empty string to keep the type from the manifests.
+func serviceTypeFor(quay *v1.QuayRegistry) corev1.ServiceType {
+	if quay.Spec.ServiceType == "" && quay.Spec.Exposure == v1.ExposureInternal {
+		return corev1.ServiceTypeClusterIP
+	}
+
+	return quay.Spec.ServiceType
+}
+
+// exposureConfigFor returns the Quay config fields for an internal-only registry, or nil if it is exposed externally.
+func exposureConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	if quay.Spec.Exposure != v1.ExposureInternal {
+		return nil, nil
+	}
+
+	if serviceType := serviceTypeFor(quay); serviceType != corev1.ServiceTypeClusterIP {
+		return nil, errors.New("`spec.serviceType` must be `ClusterIP` with `exposure: Internal`, got " + string(serviceType))
+	}
+
+	// NOTE: A hostname in the config bundle is kept, such as when an external load balancer forwards to the `Service`.
+	if _, ok := userConfig["SERVER_HOSTNAME"]; ok {
+		return nil, nil
+	}
+
+	return map[string]interface{}{"SERVER_HOSTNAME": v1.InternalHostnameFor(quay, "quay-app")}, nil
+}
+
+// servicePatchesFor returns the Kustomize patches which set the type of the exposed `Services`.
+func servicePatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+
+	serviceType := serviceTypeFor(quay)
+	if serviceType == "" {
+		return patches
+	}
+
+	for _, service := range exposedServices {
+		patches = append(patches, types.Patch{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": service},
+				"spec":       map[string]interface{}{"type": serviceType},
+			})),
+		})
+	}
+
+	return patches
+}
+
+// reservedServicePorts are the ports of the Quay `Service` which cannot be used by `spec.endpoint`.
+var reservedServicePorts = map[int32]string{
+	8081: "jwtproxy",
+	9091: "metrics",
+}
+
+// endpointConfigFor returns the Quay config fields for the port and TLS termination from `spec.endpoint`, or nil if
+// it is unset. The given hostname is the `SERVER_HOSTNAME` the registry would otherwise use.
+func endpointConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, hostname string) (map[string]interface{}, error) {
+	if quay.Spec.Endpoint == nil {
+		return nil, nil
+	}
+
+	if v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		return nil, errors.New("`spec.endpoint` requires the `route` component to be unmanaged, since the `Route` always serves HTTPS on port 443")
+	}
+
+	termination := v1.TLSTerminationFor(quay)
+	httpsPort, httpPort := v1.ServicePortsFor(quay)
+	if termination == v1.TLSTerminationQuay && httpsPort == httpPort {
+		return nil, errors.New("`spec.endpoint.port` 80 serves plain HTTP, so `spec.endpoint.tlsTermination` must be `External` or `None`")
+	}
+	for _, port := range []int32{httpsPort, httpPort} {
+		if name, ok := reservedServicePorts[port]; ok {
+			return nil, errors.New("`spec.endpoint.port` " + strconv.Itoa(int(port)) + " is used by the " + name + " port of the Quay `Service`")
+		}
+	}
+
+	scheme := "https"
+	if termination == v1.TLSTerminationNone {
+		scheme = "http"
+	}
+	externalTLSTermination := termination == v1.TLSTerminationExternal
+
+	if value, ok := userConfig["PREFERRED_URL_SCHEME"]; ok && value != scheme {
+		return nil, errors.New("`PREFERRED_URL_SCHEME` must be `" + scheme + "` with `spec.endpoint.tlsTermination: " + string(termination) + "`")
+	}
+	if value, ok := userConfig["EXTERNAL_TLS_TERMINATION"]; ok && value != externalTLSTermination {
+		return nil, errors.New("`EXTERNAL_TLS_TERMINATION` must be `" + strconv.FormatBool(externalTLSTermination) + "` with `spec.endpoint.tlsTermination: " + string(termination) + "`")
+	}
+
+	endpointConfig := map[string]interface{}{
+		"PREFERRED_URL_SCHEME":     scheme,
+		"EXTERNAL_TLS_TERMINATION": externalTLSTermination,
+	}
+	if hostname == "" {
+		return endpointConfig, nil
+	}
+
+	servicePort := httpsPort
+	if servicePort == 0 {
+		servicePort = httpPort
+	}
+	// Clients only connect to the port of the `Service` if Quay is not behind an external TLS terminator.
+	if _, port, err := net.SplitHostPort(hostname); err == nil && termination != v1.TLSTerminationExternal && port != strconv.Itoa(int(servicePort)) {
+		return nil, errors.New("`SERVER_HOSTNAME` port " + port + " does not match `spec.endpoint.port` " + strconv.Itoa(int(servicePort)))
+	}
+	endpointConfig["SERVER_HOSTNAME"] = v1.EndpointHostFor(quay, hostname)
+
+	return endpointConfig, nil
+}
+
+// endpointPatchesFor returns the Kustomize patches which replace the ports of the Quay `Service` with those from
+// `spec.endpoint`.
+func endpointPatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	if quay.Spec.Endpoint == nil {
+		return patches
+	}
+
+	ports := []corev1.ServicePort{}
+	httpsPort, httpPort := v1.ServicePortsFor(quay)
+	if httpsPort != 0 {
+		ports = append(ports, corev1.ServicePort{Name: "https", Protocol: corev1.ProtocolTCP, Port: httpsPort, TargetPort: intstr.FromInt(8443)})
+	}
+	ports = append(ports,
+		corev1.ServicePort{Name: "http", Protocol: corev1.ProtocolTCP, Port: httpPort, TargetPort: intstr.FromInt(8080)},
+		corev1.ServicePort{Name: "jwtproxy", Protocol: corev1.ProtocolTCP, Port: 8081, TargetPort: intstr.FromInt(8081)},
+		corev1.ServicePort{Name: "metrics", Protocol: corev1.ProtocolTCP, Port: 9091, TargetPort: intstr.FromInt(9091)},
+	)
+
+	return append(patches, types.Patch{
+		Patch: string(encode([]map[string]interface{}{
+			{"op": "replace", "path": "/spec/ports", "value": ports},
+		})),
+		Target: &types.Selector{Gvk: resid.Gvk{Version: "v1", Kind: "Service"}, Name: "quay-app"},
+	})
+}
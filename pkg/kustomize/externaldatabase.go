@@ -0,0 +1,52 @@
+package kustomize
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// ExternalDatabaseURIFile is the file in the config bundle with the `DB_URI` from `spec.externalDatabase`. It is not
+// included in the rendered config bundle.
+const ExternalDatabaseURIFile = "external-database-uri"
+
+// DatabaseCAFile is the file in the config bundle with the CA which the certificate of the external or managed
+// database is verified with.
+const DatabaseCAFile = "database.pem"
+
+// externalDatabaseSSLModeFor returns the `sslmode` Quay verifies the certificate of the external database with.
+func externalDatabaseSSLModeFor(quay *v1.QuayRegistry) v1.DatabaseSSLMode {
+	if mode := quay.Spec.ExternalDatabase.SSLMode; mode != "" {
+		return mode
+	}
+
+	return v1.DatabaseSSLModeVerifyFull
+}
+
+// ExternalDatabaseConfigFor returns the Quay config fields connecting to the database of `spec.externalDatabase`, or
+// nil if it is not set. The `DB_URI` is read from `ExternalDatabaseURIFile` in the given config files. Other
+// `DB_CONNECTION_ARGS` of the config bundle or profile are kept.
+func ExternalDatabaseConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	if quay.Spec.ExternalDatabase == nil {
+		return nil, nil
+	}
+
+	dbURI := strings.TrimSpace(string(configFiles[ExternalDatabaseURIFile]))
+	if dbURI == "" {
+		return nil, errors.New("`spec.externalDatabase.credentialsSecret` requires `DB_URI`")
+	}
+	if uri, err := url.Parse(dbURI); err != nil || uri.Host == "" {
+		return nil, errors.New("`DB_URI` of `spec.externalDatabase.credentialsSecret` is invalid")
+	}
+
+	config := map[string]interface{}{"DB_URI": dbURI}
+	if _, ok := configFiles[DatabaseCAFile]; !ok {
+		return config, nil
+	}
+
+	config["DB_CONNECTION_ARGS"] = withDatabaseCA(quay, userConfig, externalDatabaseSSLModeFor(quay))
+
+	return config, nil
+}
@@ -0,0 +1,105 @@
+package kustomize
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	route "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	externalDNSTTLAnnotation      = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+// RegistryHostnameFor returns the hostname of `SERVER_HOSTNAME` in the rendered config bundle, without the port, or an
+// empty string if it is not set.
+func RegistryHostnameFor(objects []k8sruntime.Object) string {
+	configSecret := ConfigSecretFor(objects)
+	if configSecret == nil {
+		return ""
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configSecret.Data["config.yaml"], &config); err != nil {
+		return ""
+	}
+
+	hostname, _ := config["SERVER_HOSTNAME"].(string)
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		return host
+	}
+
+	return hostname
+}
+
+// externalDNSAnnotationsFor returns the annotations which make external-dns publish the given hostname.
+func externalDNSAnnotationsFor(settings *v1.ExternalDNSSettings, hostname string) map[string]string {
+	annotations := map[string]string{externalDNSHostnameAnnotation: hostname}
+	if settings.TTL != nil && settings.TTL.Duration > 0 {
+		annotations[externalDNSTTLAnnotation] = strconv.FormatInt(int64(settings.TTL.Seconds()), 10)
+	}
+
+	return annotations
+}
+
+// withExternalDNS annotates the managed Quay `Route`, or the Quay `Service` if there is none, with `spec.externalDNS`.
+// A registry API `Route` with its own hostname is annotated as well.
+func withExternalDNS(quay *v1.QuayRegistry, objects []k8sruntime.Object) ([]k8sruntime.Object, error) {
+	if quay.Spec.ExternalDNS == nil {
+		return objects, nil
+	}
+
+	if quay.Spec.Exposure == v1.ExposureInternal {
+		return nil, errors.New("`spec.externalDNS` cannot be used with `exposure: Internal`")
+	}
+
+	hostname := RegistryHostnameFor(objects)
+	if hostname == "" {
+		return nil, errors.New("`spec.externalDNS` requires `SERVER_HOSTNAME` to be set")
+	}
+
+	// The `Service` is not annotated if there is a `Route`, so the hostname is only published once.
+	targets := map[string]string{quay.GetName() + "-quay-app": hostname}
+	if v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		targets = map[string]string{quay.GetName() + "-quay": hostname}
+		if apiHostname := registryAPIHostnameFor(quay); apiHostname != "" {
+			targets[quay.GetName()+"-quay-registry-api"] = apiHostname
+		}
+	}
+
+	for _, obj := range objects {
+		switch obj.(type) {
+		case *route.Route, *corev1.Service:
+		default:
+			continue
+		}
+
+		objectMeta, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		targetHostname, ok := targets[objectMeta.GetName()]
+		if !ok {
+			continue
+		}
+
+		annotations := objectMeta.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for key, value := range externalDNSAnnotationsFor(quay.Spec.ExternalDNS, targetHostname) {
+			annotations[key] = value
+		}
+		objectMeta.SetAnnotations(annotations)
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,70 @@
+package kustomize
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// ExternalRedisFile is the file in the config bundle with the `host`, `port` and `password` from
+// `spec.externalRedis`. It is not included in the rendered config bundle.
+const ExternalRedisFile = "external-redis"
+
+// defaultRedisPort is the port Redis serves on, which Quay connects to unless `spec.redis.port` or the `Secret` of an
+// external Redis sets another.
+const defaultRedisPort = 6379
+
+// ExternalRedisConfigFor returns the `BUILDLOGS_REDIS` and `USER_EVENTS_REDIS` connections to the Redis of
+// `spec.externalRedis` with the options of `spec.redis`, or nil if it is not set. The host, port and password are read
+// from `ExternalRedisFile` in the given config files, and replace those of the config bundle. The host and port are
+// replaced by the Sentinels of `spec.redis.sentinel` if it is set.
+func ExternalRedisConfigFor(quay *v1.QuayRegistry, configFiles map[string][]byte) (map[string]interface{}, error) {
+	if quay.Spec.ExternalRedis == nil {
+		return nil, nil
+	}
+
+	if err := validateRedisSettings(quay); err != nil {
+		return nil, err
+	}
+
+	var credentials map[string]string
+	if err := yaml.Unmarshal(configFiles[ExternalRedisFile], &credentials); err != nil {
+		return nil, errors.New("`spec.externalRedis.credentialsSecret` is invalid")
+	}
+
+	// NOTE: With Sentinels, Quay asks them for the host and port of the master instead.
+	host := strings.TrimSpace(credentials["host"])
+	if host == "" && (quay.Spec.Redis == nil || quay.Spec.Redis.Sentinel == nil) {
+		return nil, errors.New("`spec.externalRedis.credentialsSecret` requires `host`")
+	}
+	if strings.ContainsAny(host, "/ ") {
+		return nil, errors.New("`host` of `spec.externalRedis.credentialsSecret` must be a hostname or IP address")
+	}
+
+	port := defaultRedisPort
+	if value := strings.TrimSpace(credentials["port"]); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 65535 {
+			return nil, errors.New("`port` of `spec.externalRedis.credentialsSecret` must be between 1 and 65535")
+		}
+		port = parsed
+	}
+
+	config := map[string]interface{}{}
+	for _, field := range redisConfigFields {
+		connection := redisConnectionOptions(quay)
+		connection["host"] = host
+		connection["port"] = port
+		if password := credentials["password"]; password != "" {
+			connection["password"] = password
+		}
+		withRedisSentinelConnection(quay, connection)
+		config[field] = connection
+	}
+
+	return config, nil
+}
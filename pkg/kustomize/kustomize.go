@@ -9,18 +9,23 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 
 	objectbucket "github.com/kube-object-storage/lib-bucket-provisioner/pkg/apis/objectbucket.io/v1alpha1"
 	route "github.com/openshift/api/route/v1"
 	apps "k8s.io/api/apps/v1"
 	autoscaling "k8s.io/api/autoscaling/v2beta2"
+	batch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
 	rbac "k8s.io/api/rbac/v1beta1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/json"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"sigs.k8s.io/kustomize/api/filesys"
 	"sigs.k8s.io/kustomize/api/krusty"
 	"sigs.k8s.io/kustomize/api/resid"
@@ -76,56 +81,125 @@ func decode(bytes []byte) interface{} {
 }
 
 // ModelFor returns an empty Kubernetes object instance for the given `GroupVersionKind`.
-// Example: Calling with `core.v1.Secret` GVK returns an empty `corev1.Secret` instance.
-func ModelFor(gvk schema.GroupVersionKind) k8sruntime.Object {
+// Example: Calling with `core.v1.Secret` GVK returns an empty `corev1.Secret` instance. Returns an error for a kind
+// the Operator does not manage.
+func ModelFor(gvk schema.GroupVersionKind) (k8sruntime.Object, error) {
 	switch gvk.String() {
 	case schema.GroupVersionKind{Version: "v1", Kind: "Secret"}.String():
-		return &corev1.Secret{}
+		return &corev1.Secret{}, nil
 	case schema.GroupVersionKind{Version: "v1", Kind: "Service"}.String():
-		return &corev1.Service{}
+		return &corev1.Service{}, nil
 	case schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}.String():
-		return &corev1.ConfigMap{}
+		return &corev1.ConfigMap{}, nil
+	case schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"}.String():
+		return &corev1.ServiceAccount{}, nil
 	case schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}.String():
-		return &corev1.PersistentVolumeClaim{}
+		return &corev1.PersistentVolumeClaim{}, nil
 	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}.String():
-		return &apps.Deployment{}
+		return &apps.Deployment{}, nil
+	case schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}.String():
+		return &apps.StatefulSet{}, nil
 	case schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "Role"}.String():
-		return &rbac.Role{}
+		return &rbac.Role{}, nil
 	case schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Kind: "RoleBinding"}.String():
-		return &rbac.RoleBinding{}
+		return &rbac.RoleBinding{}, nil
 	case schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}.String():
-		return &route.Route{}
+		return &route.Route{}, nil
 	case schema.GroupVersionKind{Group: "objectbucket.io", Version: "v1alpha1", Kind: "ObjectBucketClaim"}.String():
-		return &objectbucket.ObjectBucketClaim{}
+		return &objectbucket.ObjectBucketClaim{}, nil
 	case schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"}.String():
-		return &autoscaling.HorizontalPodAutoscaler{}
+		return &autoscaling.HorizontalPodAutoscaler{}, nil
+	case schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}.String():
+		return &batch.Job{}, nil
+	case schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"}.String():
+		return &batchv1beta1.CronJob{}, nil
+	case schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"}.String():
+		return &policy.PodDisruptionBudget{}, nil
+	case PostgresClusterGVK.String():
+		model := &unstructured.Unstructured{}
+		model.SetGroupVersionKind(PostgresClusterGVK)
+
+		return model, nil
+	case ServiceMonitorGVK.String():
+		model := &unstructured.Unstructured{}
+		model.SetGroupVersionKind(ServiceMonitorGVK)
+
+		return model, nil
 	default:
-		panic(fmt.Sprintf("Missing model for GVK %s", gvk.String()))
+		return nil, fmt.Errorf("missing model for GVK %s", gvk.String())
 	}
 }
 
+// ComponentKindFor returns the kind of managed component (from `spec.components`) which the given object was
+// rendered for, or an empty string if it belongs to the base Quay application.
+func ComponentKindFor(obj k8sruntime.Object) string {
+	switch obj.GetObjectKind().GroupVersionKind().Kind {
+	case "Route":
+		return "route"
+	case "HorizontalPodAutoscaler":
+		return "horizontalpodautoscaler"
+	case "ObjectBucketClaim":
+		return "objectstorage"
+	}
+
+	objectMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+
+	switch objectMeta.GetLabels()["quay-component"] {
+	case "clair", "clair-postgres":
+		return "clair"
+	case "postgres":
+		return "postgres"
+	case "redis":
+		return "redis"
+	case "minio":
+		return "minio"
+	case "localstorage":
+		return "localstorage"
+	case "pgbouncer":
+		return "pgbouncer"
+	default:
+		return ""
+	}
+}
+
+// kustomizeFiles reads the Kustomize manifests shipped with the Operator into memory once, rather than on every render.
+var kustomizeFiles = func() func() map[string][]byte {
+	var once sync.Once
+	files := map[string][]byte{}
+
+	return func() map[string][]byte {
+		once.Do(func() {
+			err := filepath.Walk(kustomizeDir(), func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+
+				if !info.IsDir() {
+					f, err := ioutil.ReadFile(path)
+					if err != nil {
+						return err
+					}
+					files[path] = f
+				}
+				return nil
+			})
+			check(err)
+		})
+
+		return files
+	}
+}()
+
 // generate uses Kustomize as a library to build the runtime objects to be applied to a cluster.
 func generate(kustomization *types.Kustomization, overlay string, quayConfigFiles map[string][]byte) ([]k8sruntime.Object, error) {
 	fSys := filesys.MakeEmptyDirInMemory()
-	err := filepath.Walk(kustomizeDir(), func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() {
-			f, err := ioutil.ReadFile(path)
-			if err != nil {
-				return err
-			}
-
-			err = fSys.WriteFile(path, f)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	check(err)
+	for path, f := range kustomizeFiles() {
+		err := fSys.WriteFile(path, f)
+		check(err)
+	}
 
 	// Write `kustomization.yaml` to filesystem
 	kustomizationFile, err := yaml.Marshal(kustomization)
@@ -147,20 +221,17 @@ func generate(kustomization *types.Kustomization, overlay string, quayConfigFile
 
 	output := []k8sruntime.Object{}
 	for _, resource := range resMap.Resources() {
-		resourceJSON, err := resource.MarshalJSON()
-		check(err)
-
-		obj := ModelFor(schema.GroupVersionKind{
+		obj, err := ModelFor(schema.GroupVersionKind{
 			Group:   resource.GetGvk().Group,
 			Version: resource.GetGvk().Version,
 			Kind:    resource.GetGvk().Kind,
 		})
-
-		if obj == nil {
-			panic("TODO(alecmerdler): Not implemented for GroupVersionKind: " + resource.GetGvk().String())
+		if err != nil {
+			return nil, err
 		}
 
-		err = json.Unmarshal(resourceJSON, obj)
+		// Convert directly into the typed object instead of marshalling to JSON and back.
+		err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(resource.Map(), obj)
 		check(err)
 
 		output = append(output, obj)
@@ -196,32 +267,58 @@ func KustomizationFor(quay *v1.QuayRegistry, quayConfigFiles map[string][]byte)
 	componentPaths := []string{}
 	managedFieldGroups := []string{}
 	for _, component := range quay.Spec.Components {
-		if component.Managed {
-			componentPaths = append(componentPaths, filepath.Join("..", "components", component.Kind))
-			managedFieldGroups = append(managedFieldGroups, fieldGroupFor(component.Kind))
+		if !component.Managed {
+			continue
+		}
 
-			componentConfigFiles, err := componentConfigFilesFor(component.Kind, quay)
-			if componentConfigFiles == nil || err != nil {
-				continue
-			}
+		provider, err := ComponentProviderFor(component.Kind)
+		if err != nil {
+			return nil, err
+		}
+		fieldGroupName, _, err := provider.FieldGroup(quay)
+		if err != nil {
+			return nil, err
+		}
+		resources, err := provider.Resources(quay)
+		if err != nil {
+			return nil, err
+		}
 
-			sources := []string{}
-			for filename, fileValue := range componentConfigFiles {
-				sources = append(sources, strings.Join([]string{filename, string(fileValue)}, "="))
-			}
+		if resources.Path != "" {
+			componentPaths = append(componentPaths, filepath.Join("..", resources.Path))
+		}
+		managedFieldGroups = append(managedFieldGroups, fieldGroupName)
 
-			generatedSecrets = append(generatedSecrets, types.SecretArgs{
-				GeneratorArgs: types.GeneratorArgs{
-					Name:     component.Kind + "-config-secret",
-					Behavior: "merge",
-					KvPairSources: types.KvPairSources{
-						LiteralSources: sources,
-					},
-				},
-			})
+		if len(resources.SecretFiles) == 0 {
+			continue
 		}
+
+		sources := []string{}
+		for filename, fileValue := range resources.SecretFiles {
+			sources = append(sources, strings.Join([]string{filename, string(fileValue)}, "="))
+		}
+
+		generatedSecrets = append(generatedSecrets, types.SecretArgs{
+			GeneratorArgs: types.GeneratorArgs{
+				Name:     component.Kind + "-config-secret",
+				Behavior: "merge",
+				KvPairSources: types.KvPairSources{
+					LiteralSources: sources,
+				},
+			},
+		})
 	}
 
+	patches := profilePatchesFor(quay)
+	patches = append(patches, antiAffinityPatchesFor(quay)...)
+	patches = append(patches, storageAffinityPatchesFor(quay)...)
+	patches = append(patches, routePatchesFor(quay)...)
+	patches = append(patches, servicePatchesFor(quay)...)
+	patches = append(patches, endpointPatchesFor(quay)...)
+	patches = append(patches, storageClassPatchesFor(quay)...)
+	patches = append(patches, tempStoragePatchesFor(quay)...)
+	patches = append(patches, ephemeralStoragePatchesFor(quay)...)
+
 	return &types.Kustomization{
 		TypeMeta: types.TypeMeta{
 			APIVersion: types.KustomizationVersion,
@@ -231,6 +328,7 @@ func KustomizationFor(quay *v1.QuayRegistry, quayConfigFiles map[string][]byte)
 		NamePrefix:      quay.GetName() + "-",
 		Resources:       []string{"../base"},
 		Components:      componentPaths,
+		Patches:         patches,
 		SecretGenerator: generatedSecrets,
 		CommonAnnotations: map[string]string{
 			managedFieldGroupsKey: strings.Join(managedFieldGroups, ","),
@@ -285,6 +383,17 @@ func flattenSecret(configBundle *corev1.Secret) (*corev1.Secret, error) {
 	return flattenedSecret, nil
 }
 
+// ConfigSecretFor returns the rendered Quay config bundle `Secret` from the given objects, or nil if there is none.
+func ConfigSecretFor(objects []k8sruntime.Object) *corev1.Secret {
+	for _, obj := range objects {
+		if secret, ok := obj.(*corev1.Secret); ok && strings.Contains(secret.GetName(), configSecretPrefix+"-") {
+			return secret
+		}
+	}
+
+	return nil
+}
+
 // Inflate takes a `QuayRegistry` object and returns a set of Kubernetes objects representing a Quay deployment.
 func Inflate(quay *v1.QuayRegistry, baseConfigBundle *corev1.Secret, secretKeysSecret *corev1.Secret, log logr.Logger) ([]k8sruntime.Object, error) {
 	// Each `managedComponent` brings in their own generated `config.yaml` fields which are added to the base `Secret`
@@ -295,33 +404,279 @@ func Inflate(quay *v1.QuayRegistry, baseConfigBundle *corev1.Secret, secretKeysS
 	err := yaml.Unmarshal(componentConfigFiles["config.yaml"], &parsedUserConfig)
 	check(err)
 
-	// Generate or pull out the SECRET_KEY and DATABASE_SECRET_KEY. Since these must be stable across
-	// runs of the same config, we store them (and re-read them) from a specialized Secret.
-	secretKey, databaseSecretKey, secretKeysSecret := handleSecretKeys(parsedUserConfig, secretKeysSecret, quay, log)
+	// Fields from the `QuayOperatorConfig` are set first, so they may also use substitution variables.
+	parsedUserConfig, forcedFields, inherited, err := WithOperatorConfig(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedUserConfig, substituted, err := ResolveConfigVariables(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+	migrated := migrateRenamedConfig(quay, parsedUserConfig)
+	if inherited || substituted || migrated {
+		componentConfigFiles["config.yaml"] = encode(parsedUserConfig)
+	}
+
+	if err := validateStorageBackends(quay, parsedUserConfig); err != nil {
+		return nil, err
+	}
+
+	if err := validateCloudFrontSigningKey(quay, componentConfigFiles); err != nil {
+		return nil, err
+	}
+
+	if err := validateStorageCABundleFile(quay, componentConfigFiles); err != nil {
+		return nil, err
+	}
+
+	if err := validateRegistryAPIRoute(quay, serverHostnameFor(quay, parsedUserConfig)); err != nil {
+		return nil, err
+	}
+
+	if err := validateRouteSourceRanges(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validateTempStorage(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validateObjectStorageManaged(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validateEphemeralStorage(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validatePostgresResources(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validatePostgresMetrics(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validatePodOverrides(quay); err != nil {
+		return nil, err
+	}
+
+	if err := validatePersistentVolumeRetention(quay); err != nil {
+		return nil, err
+	}
+
+	if errs := v1.ValidateCapabilities(quay, parsedUserConfig); len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	exposureConfig, err := exposureConfigFor(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+	if exposureConfig != nil {
+		componentConfigFiles["exposure.config.yaml"] = encode(exposureConfig)
+	}
+
+	hostname, _ := parsedUserConfig["SERVER_HOSTNAME"].(string)
+	if exposureHostname, ok := exposureConfig["SERVER_HOSTNAME"].(string); ok {
+		hostname = exposureHostname
+	}
+	endpointConfig, err := endpointConfigFor(quay, parsedUserConfig, hostname)
+	if err != nil {
+		return nil, err
+	}
+	if endpointConfig != nil {
+		// Includes the hostname from the exposure config fields, which would otherwise conflict when flattened.
+		delete(componentConfigFiles, "exposure.config.yaml")
+		componentConfigFiles["endpoint.config.yaml"] = encode(endpointConfig)
+	}
+
+	// Generate or pull out the SECRET_KEY, DATABASE_SECRET_KEY and managed database and Redis passwords. Since these must be
+	// stable across runs of the same config, we store them (and re-read them) from a specialized Secret.
+	secretKeys, secretKeysSecret := handleSecretKeys(parsedUserConfig, secretKeysSecret, quay, log)
+	quay = withDatabasePassword(quay, v1.DatabasePasswordAnnotation, secretKeys[DatabasePasswordKey])
+	quay = withDatabasePassword(quay, v1.ClairDatabasePasswordAnnotation, secretKeys[ClairDatabasePasswordKey])
+	quay = withDatabasePassword(quay, v1.RedisPasswordAnnotation, secretKeys[RedisPasswordKey])
 
 	quayConfig := map[string]interface{}{
 		"SETUP_COMPLETE":      true,
-		"DATABASE_SECRET_KEY": databaseSecretKey,
-		"SECRET_KEY":          secretKey,
+		"DATABASE_SECRET_KEY": secretKeys["DATABASE_SECRET_KEY"],
+		"SECRET_KEY":          secretKeys["SECRET_KEY"],
 	}
 	for field, value := range BaseConfig() {
 		if _, ok := parsedUserConfig[field]; !ok {
 			quayConfig[field] = value
 		}
 	}
+	for field, value := range profileConfigFor(quay) {
+		if _, ok := parsedUserConfig[field]; !ok {
+			quayConfig[field] = value
+		}
+	}
 	componentConfigFiles["quay.config.yaml"] = encode(quayConfig)
 
 	for _, component := range quay.Spec.Components {
-		if component.Managed {
-			for name, contents := range configFilesFor(component.Kind, quay, parsedUserConfig) {
-				componentConfigFiles[name] = contents
-			}
+		if !component.Managed {
+			continue
 		}
+
+		provider, err := ComponentProviderFor(component.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if err := provider.Validate(quay); err != nil {
+			return nil, err
+		}
+
+		configFiles, err := provider.ConfigFiles(quay, parsedUserConfig)
+		if err != nil {
+			return nil, err
+		}
+		for name, contents := range configFiles {
+			componentConfigFiles[name] = contents
+		}
+	}
+
+	authenticationConfig, err := authenticationConfigFor(quay, parsedUserConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	if authenticationConfig != nil {
+		componentConfigFiles["authentication.config.yaml"] = encode(authenticationConfig)
+	}
+
+	buildTriggersConfig, err := buildTriggersConfigFor(quay, parsedUserConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	// The credentials are only included in the rendered config bundle as config fields.
+	delete(componentConfigFiles, BuildTriggerCredentialsFile)
+	if buildTriggersConfig != nil {
+		componentConfigFiles["buildtriggers.config.yaml"] = encode(buildTriggersConfig)
+	}
+
+	tagPolicyConfig, err := tagPolicyConfigFor(quay)
+	if err != nil {
+		return nil, err
+	}
+	if tagPolicyConfig != nil {
+		componentConfigFiles["tagpolicy.config.yaml"] = encode(tagPolicyConfig)
+	}
+
+	repositoryConfig, err := repositoryConfigFor(quay)
+	if err != nil {
+		return nil, err
+	}
+	if repositoryConfig != nil {
+		componentConfigFiles["repositories.config.yaml"] = encode(repositoryConfig)
+	}
+
+	mirrorWorkersConfig, err := mirrorWorkersConfigFor(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+	if mirrorWorkersConfig != nil {
+		componentConfigFiles["mirror.config.yaml"] = encode(mirrorWorkersConfig)
+	}
+
+	redisConfig, err := redisConfigFor(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+	if redisConfig != nil {
+		componentConfigFiles["redis.config.yaml"] = encode(redisConfig)
+	}
+
+	externalRedisConfig, err := ExternalRedisConfigFor(quay, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	// The password is only included in the rendered config bundle as a config field.
+	delete(componentConfigFiles, ExternalRedisFile)
+	if externalRedisConfig != nil {
+		componentConfigFiles["redis.config.yaml"] = encode(externalRedisConfig)
+	}
+
+	externalDatabaseConfig, err := ExternalDatabaseConfigFor(quay, parsedUserConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	// The `DB_URI` is only included in the rendered config bundle as a config field.
+	delete(componentConfigFiles, ExternalDatabaseURIFile)
+	if externalDatabaseConfig != nil {
+		componentConfigFiles["externaldatabase.config.yaml"] = encode(externalDatabaseConfig)
+	}
+
+	postgresClusterConfig, err := PostgresClusterConfigFor(quay, parsedUserConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	delete(componentConfigFiles, PostgresClusterURIFile)
+	if postgresClusterConfig != nil {
+		componentConfigFiles["postgres.config.yaml"] = encode(postgresClusterConfig)
+	}
+
+	primaryConfig := externalDatabaseConfig
+	if postgresClusterConfig != nil {
+		primaryConfig = postgresClusterConfig
+	}
+	readReplicasConfig, err := DatabaseReadReplicasConfigFor(quay, parsedUserConfig, primaryConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	delete(componentConfigFiles, DatabaseReadReplicaURIsFile)
+	if readReplicasConfig != nil {
+		componentConfigFiles["readreplicas.config.yaml"] = encode(readReplicasConfig)
+	}
+
+	pgBouncer, err := pgBouncerConfigFor(quay, parsedUserConfig, quayConfig, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	var pgBouncerQuayConfig map[string]interface{}
+	if pgBouncer != nil {
+		// Replaces the connection to the database, which would otherwise conflict when flattened.
+		delete(componentConfigFiles, "postgres.config.yaml")
+		delete(componentConfigFiles, "externaldatabase.config.yaml")
+		pgBouncerQuayConfig = pgBouncer.quayConfig
+		componentConfigFiles["pgbouncer.config.yaml"] = encode(pgBouncerQuayConfig)
+	}
+
+	// Fields set from the spec replace the defaults, since the order config files are flattened in is not defined.
+	for _, specConfig := range []map[string]interface{}{authenticationConfig, tagPolicyConfig, repositoryConfig, externalDatabaseConfig, postgresClusterConfig, readReplicasConfig, pgBouncerQuayConfig} {
+		for field := range specConfig {
+			delete(quayConfig, field)
+		}
+	}
+	componentConfigFiles["quay.config.yaml"] = encode(quayConfig)
+
+	storageMigrationConfig, err := storageMigrationConfigFor(quay, parsedUserConfig)
+	if err != nil {
+		return nil, err
+	}
+	if storageMigrationConfig != nil {
+		// Includes the managed object storage fields, which would otherwise conflict when flattened.
+		delete(componentConfigFiles, "objectstorage.config.yaml")
+		componentConfigFiles["storagemigration.config.yaml"] = encode(storageMigrationConfig)
+	}
+
+	if err := withStorageUploads(quay, componentConfigFiles); err != nil {
+		return nil, err
+	}
+
+	if err := withoutConfigFields(componentConfigFiles, forcedFields); err != nil {
+		return nil, err
 	}
 
 	_, quayCertExists := componentConfigFiles["ssl.cert"]
 	_, quayKeyExists := componentConfigFiles["ssl.key"]
-	if !quayCertExists || !quayKeyExists {
+	if quayCertExists && quayKeyExists {
+		if err := validateTLSFor(quay, parsedUserConfig, componentConfigFiles["ssl.cert"], componentConfigFiles["ssl.key"]); err != nil {
+			return nil, err
+		}
+	} else {
 		log.Info("Generating missing `ssl.cert` and `ssl.key` pair for Quay app TLS")
 
 		cert, key, err := CustomTLSFor(quay, parsedUserConfig)
@@ -335,7 +690,8 @@ func Inflate(quay *v1.QuayRegistry, baseConfigBundle *corev1.Secret, secretKeysS
 	check(err)
 
 	var overlay string
-	if quay.Spec.DesiredVersion == quay.Status.CurrentVersion || quay.Spec.DesiredVersion == v1.QuayVersionDev {
+	// Mirror workers never run the upgrade, since the database belongs to the existing registry.
+	if quay.Spec.DesiredVersion == quay.Status.CurrentVersion || quay.Spec.DesiredVersion == v1.QuayVersionDev || mirrorWorkersConfig != nil {
 		overlay = overlayDir(quay.Spec.DesiredVersion)
 	} else {
 		overlay = upgradeOverlayDir(quay.Spec.DesiredVersion)
@@ -356,13 +712,100 @@ func Inflate(quay *v1.QuayRegistry, baseConfigBundle *corev1.Secret, secretKeysS
 		}
 	}
 
-	secretKeysSecret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
-	resources = append(resources, secretKeysSecret)
+	if mirrorWorkersConfig != nil {
+		resources = mirrorWorkersFor(quay, resources)
+	}
+	resources = withPruneWorkers(quay, resources)
+
+	resources = withBuildTriggerRoute(quay, resources, componentConfigFiles["ssl.cert"])
+	resources = withRegistryAPIRoute(quay, resources, componentConfigFiles["ssl.cert"], componentConfigFiles["ssl.key"])
+	resources = withTempStorageClaims(quay, resources)
+	resources = withS3WebIdentity(quay, resources)
+	resources = withPostgresImage(quay, resources)
+	resources = withPostgresResources(quay, resources)
+	resources, err = withPostgresCluster(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+	resources, err = withPostgresMetrics(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err = withClairTLS(quay, resources, componentConfigFiles[ClairCABundleFile])
+	if err != nil {
+		return nil, err
+	}
+	resources = withClairReplicas(quay, resources)
+
+	resources, err = withDatabaseTLS(quay, resources, componentConfigFiles)
+	if err != nil {
+		return nil, err
+	}
+	resources = withPgBouncerConfig(quay, resources, pgBouncer)
+
+	resources, err = withExternalDNS(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: The secret keys `Secret` is only included when keys were generated, and so never when both keys are
+	// provided in the config bundle.
+	if secretKeysSecret != nil {
+		secretKeysSecret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+		resources = append(resources, secretKeysSecret)
+	}
+
+	if job := storageMigrationJobFor(quay, resources); job != nil {
+		resources = append(resources, job)
+	}
+
+	backupJob, err := postgresBackupJobFor(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+	if backupJob != nil {
+		resources = append(resources, backupJob)
+	}
+	restoreJob, err := postgresRestoreJobFor(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+	if restoreJob != nil {
+		resources = append(resources, restoreJob)
+	}
+	scheduledBackup, err := postgresScheduledBackupFor(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+	if scheduledBackup != nil {
+		resources = append(resources, scheduledBackup)
+	}
+	blobVerification, err := blobVerificationFor(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+	if blobVerification != nil {
+		resources = append(resources, blobVerification)
+	}
+	resources = withPodOverrides(quay, resources)
+	resources = withRedisSentinel(quay, resources)
+	resources = withRedisPort(quay, resources)
+	resources = withoutDatabaseClients(quay, resources)
+	resources, err = withSuspendedComponents(quay, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	resources = withImageOverrides(quay, resources)
 
 	for _, resource := range resources {
 		objectMeta, err := meta.Accessor(resource)
 		check(err)
 
+		if retainsVolume(quay, resource) {
+			continue
+		}
 		objectMeta.SetOwnerReferences([]metav1.OwnerReference{
 			{
 				APIVersion: v1.GroupVersion.String(),
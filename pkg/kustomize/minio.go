@@ -0,0 +1,52 @@
+package kustomize
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// MinIOAccessKey is the key of the MinIO root user in the credentials `Secret` of the `minio` component.
+	MinIOAccessKey = "accessKey"
+	// MinIOSecretKey is the key of the MinIO root password in the credentials `Secret` of the `minio` component.
+	MinIOSecretKey = "secretKey"
+)
+
+// MinIOCredentialsFor generates the credentials `Secret` of the managed `minio` component. It is owned by the
+// `QuayRegistry` but not rendered by `Inflate`, so the credentials are kept when the MinIO pod is recreated.
+func MinIOCredentialsFor(quay *v1.QuayRegistry) (*corev1.Secret, error) {
+	accessKey, err := generateRandomString(20)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := generateRandomString(40)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v1.MinIOCredentialsSecretFor(quay),
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": "minio"},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "QuayRegistry",
+					Name:       quay.GetName(),
+					UID:        quay.GetUID(),
+				},
+			},
+		},
+		Data: map[string][]byte{
+			MinIOAccessKey: []byte(accessKey),
+			MinIOSecretKey: []byte(secretKey),
+		},
+	}
+	secret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+
+	return secret, nil
+}
@@ -0,0 +1,117 @@
+package kustomize
+
+import (
+	"errors"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// MirrorWorkersComponent is the `quay-component` label value of the repository mirroring workers `Deployment`.
+const MirrorWorkersComponent = "quay-mirror"
+
+// mirrorWorkersRequiredFields must be copied from the existing registry's config, so the workers share its database,
+// storage and encryption keys.
+var mirrorWorkersRequiredFields = []string{
+	"SERVER_HOSTNAME",
+	"DB_URI",
+	"DISTRIBUTED_STORAGE_CONFIG",
+	"SECRET_KEY",
+	"DATABASE_SECRET_KEY",
+}
+
+// mirrorWorkersConfigFor returns the Quay config fields needed by mirror workers, or nil if `spec.mode` is not
+// `MirrorWorkers`.
+func mirrorWorkersConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	if quay.Spec.Mode != v1.RegistryModeMirrorWorkers {
+		return nil, nil
+	}
+
+	for _, component := range quay.Spec.Components {
+		if component.Managed {
+			return nil, errors.New("`" + component.Kind + "` component must be unmanaged when `spec.mode` is `MirrorWorkers`")
+		}
+	}
+	if quay.Spec.StorageMigration != nil {
+		return nil, errors.New("`spec.storageMigration` cannot be used when `spec.mode` is `MirrorWorkers`")
+	}
+	for _, field := range mirrorWorkersRequiredFields {
+		if _, ok := userConfig[field]; !ok {
+			return nil, errors.New("`" + field + "` of the existing registry is required in the config bundle when `spec.mode` is `MirrorWorkers`")
+		}
+	}
+
+	return map[string]interface{}{"FEATURE_REPO_MIRROR": true}, nil
+}
+
+// mirrorWorkersFor replaces the rendered registry with a `Deployment` of repository mirroring workers, keeping only
+// the objects it mounts.
+func mirrorWorkersFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	var quayApp *apps.Deployment
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-app" {
+			quayApp = deployment
+		}
+	}
+	if quayApp == nil || len(quayApp.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+
+	mirror := workersDeploymentFor(quay, quayApp, MirrorWorkersComponent)
+	mirror.Spec.Template.Spec.Containers[0].Args = []string{"repomirror"}
+
+	workers := []k8sruntime.Object{mirror}
+	for _, resource := range resources {
+		switch obj := resource.(type) {
+		case *corev1.Secret:
+			if strings.Contains(obj.GetName(), configSecretPrefix+"-") {
+				workers = append(workers, obj)
+			}
+		case *corev1.ConfigMap:
+			workers = append(workers, obj)
+		}
+	}
+
+	return workers
+}
+
+// workersDeploymentFor returns a copy of the Quay app `Deployment` for Quay workers, labelled with the given
+// component and without the ports and probes of the Quay app.
+func workersDeploymentFor(quay *v1.QuayRegistry, quayApp *apps.Deployment, component string) *apps.Deployment {
+	labels := map[string]string{}
+	for key, value := range quayApp.Spec.Template.GetLabels() {
+		labels[key] = value
+	}
+	labels["quay-component"] = component
+
+	workers := quayApp.DeepCopy()
+	workers.SetName(quay.GetName() + "-" + component)
+	workers.SetLabels(labels)
+	workers.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	workers.Spec.Template.SetLabels(labels)
+
+	container := workers.Spec.Template.Spec.Containers[0]
+	container.Name = component
+	container.Ports = nil
+	container.ReadinessProbe = nil
+	container.LivenessProbe = nil
+	workers.Spec.Template.Spec.Containers = []corev1.Container{container}
+
+	// Spread the workers like the Quay app replicas they are copied from.
+	if affinity := workers.Spec.Template.Spec.Affinity; affinity != nil && affinity.PodAntiAffinity != nil {
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"quay-component": component}}
+		for i := range affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution {
+			affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution[i].LabelSelector = selector
+		}
+		for i := range affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[i].PodAffinityTerm.LabelSelector = selector
+		}
+	}
+
+	return workers
+}
@@ -0,0 +1,645 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/distributedstorage"
+	"github.com/quay/config-tool/pkg/lib/shared"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// s3RoleARNAnnotation is the annotation of a `ServiceAccount` with the IAM role its pods assume on EKS.
+const s3RoleARNAnnotation = "eks.amazonaws.com/role-arn"
+
+// defaultStoragePath is the prefix of the objects Quay stores in a managed bucket.
+const defaultStoragePath = "/datastorage/registry"
+
+// minioBucketName is the bucket which the managed `minio` component creates for Quay.
+const minioBucketName = "quay-datastore"
+
+// CloudFrontSigningKeyFile is the file in the config bundle which Quay reads the private key it signs CloudFront URLs
+// with from.
+const CloudFrontSigningKeyFile = "cloudfront-signing-key.pem"
+
+// StorageCABundleFile is the file in the config bundle with the CA bundle of the storage endpoints. Quay trusts every
+// `extra_ca_cert_` file in its config.
+const StorageCABundleFile = "extra_ca_cert_objectstorage.crt"
+
+// existingBucketFieldGroup is the `DistributedStorage` field group for an existing bucket in `spec.objectStorage`. The
+// field group of the config-tool cannot be used, since its storage arguments only include those of `RadosGWStorage`.
+type existingBucketFieldGroup struct {
+	FeatureProxyStorage                bool                     `json:"FEATURE_PROXY_STORAGE"`
+	FeatureStorageReplication          bool                     `json:"FEATURE_STORAGE_REPLICATION,omitempty"`
+	DistributedStorageConfig           map[string][]interface{} `json:"DISTRIBUTED_STORAGE_CONFIG"`
+	DistributedStoragePreference       []string                 `json:"DISTRIBUTED_STORAGE_PREFERENCE"`
+	DistributedStorageDefaultLocations []string                 `json:"DISTRIBUTED_STORAGE_DEFAULT_LOCATIONS"`
+}
+
+func (fg *existingBucketFieldGroup) Fields() []string {
+	return (&distributedstorage.DistributedStorageFieldGroup{}).Fields()
+}
+
+// Validate does nothing, since `spec.objectStorage` is validated by the `objectstorage` component.
+func (fg *existingBucketFieldGroup) Validate(opts shared.Options) []shared.ValidationError {
+	return nil
+}
+
+// storageCredentials are the credentials of a storage location, which the controller copies into the `QuayRegistry`
+// annotations from its `credentialsSecret`.
+type storageCredentials struct {
+	accessKey  string
+	secretKey  string
+	sasToken   string
+	tempURLKey string
+}
+
+// storageCredentialsFor returns the credentials of the given location.
+func storageCredentialsFor(quay *v1.QuayRegistry, location string) storageCredentials {
+	annotation := func(name string) string {
+		if location != v1.PrimaryStorageLocation {
+			name = v1.StorageLocationAnnotation(name, location)
+		}
+
+		return quay.GetAnnotations()[name]
+	}
+
+	return storageCredentials{
+		accessKey:  annotation(v1.StorageAccessKeyAnnotation),
+		secretKey:  annotation(v1.StorageSecretKeyAnnotation),
+		sasToken:   annotation(v1.StorageSASTokenAnnotation),
+		tempURLKey: annotation(v1.StorageTempURLKeyAnnotation),
+	}
+}
+
+// existingBucketLocationFor returns the `DISTRIBUTED_STORAGE_CONFIG` entry of the given location.
+func existingBucketLocationFor(quay *v1.QuayRegistry, location v1.StorageLocation) []interface{} {
+	credentials := storageCredentialsFor(quay, location.Name)
+	switch {
+	case location.S3 != nil:
+		return s3StorageLocationFor(location.S3, credentials)
+	case location.GCS != nil:
+		return gcsStorageLocationFor(location.GCS, credentials)
+	case location.Azure != nil:
+		return azureStorageLocationFor(location.Azure, credentials)
+	case location.Swift != nil:
+		return swiftStorageLocationFor(location.Swift, credentials)
+	case location.IBMCOS != nil:
+		return ibmcosStorageLocationFor(location.IBMCOS, credentials)
+	}
+
+	return nil
+}
+
+// s3StorageLocationFor returns the storage location for the given bucket, using the given credentials if there are
+// any.
+func s3StorageLocationFor(s3 *v1.S3Storage, credentials storageCredentials) []interface{} {
+	storagePath := s3.StoragePath
+	if storagePath == "" {
+		storagePath = defaultStoragePath
+	}
+
+	if driver := s3StorageDriverFor(s3); driver == "RadosGWStorage" {
+		return []interface{}{driver, map[string]interface{}{
+			"hostname":     s3.Endpoint,
+			"is_secure":    !s3.Insecure,
+			"port":         s3PortFor(s3),
+			"storage_path": storagePath,
+			"bucket_name":  s3.Bucket,
+			"access_key":   credentials.accessKey,
+			"secret_key":   credentials.secretKey,
+		}}
+	} else {
+		args := map[string]interface{}{
+			"storage_path": storagePath,
+			"s3_bucket":    s3.Bucket,
+		}
+		if s3.Region != "" {
+			args["s3_region"] = s3.Region
+		}
+		if s3.Endpoint != "" {
+			args["host"] = s3.Endpoint
+		}
+		if s3.Port != nil {
+			args["port"] = *s3.Port
+		}
+		if driver == "STSS3Storage" {
+			args["sts_role_arn"] = s3.RoleARN
+			args["sts_user_access_key"] = credentials.accessKey
+			args["sts_user_secret_key"] = credentials.secretKey
+		} else if credentials.accessKey != "" {
+			args["s3_access_key"] = credentials.accessKey
+			args["s3_secret_key"] = credentials.secretKey
+		}
+		if cloudFront := s3.CloudFront; cloudFront != nil {
+			args["cloudfront_distribution_domain"] = cloudFront.Domain
+			args["cloudfront_key_id"] = cloudFront.KeyID
+			args["cloudfront_privatekey_filename"] = CloudFrontSigningKeyFile
+		}
+
+		return []interface{}{driver, args}
+	}
+}
+
+// s3PortFor returns the port of the endpoint of the given bucket.
+func s3PortFor(s3 *v1.S3Storage) int32 {
+	switch {
+	case s3.Port != nil:
+		return *s3.Port
+	case s3.Insecure:
+		return 80
+	}
+
+	return 443
+}
+
+// gcsStorageLocationFor returns the storage location for the given bucket, using the given HMAC key.
+func gcsStorageLocationFor(gcs *v1.GCSStorage, credentials storageCredentials) []interface{} {
+	storagePath := gcs.StoragePath
+	if storagePath == "" {
+		storagePath = defaultStoragePath
+	}
+
+	return []interface{}{"GoogleCloudStorage", map[string]interface{}{
+		"access_key":   credentials.accessKey,
+		"secret_key":   credentials.secretKey,
+		"bucket_name":  gcs.Bucket,
+		"storage_path": storagePath,
+	}}
+}
+
+// azureStorageLocationFor returns the storage location for the given container, using the given account key or SAS
+// token.
+func azureStorageLocationFor(azure *v1.AzureStorage, credentials storageCredentials) []interface{} {
+	storagePath := azure.StoragePath
+	if storagePath == "" {
+		storagePath = defaultStoragePath
+	}
+
+	args := map[string]interface{}{
+		"azure_account_name": azure.AccountName,
+		"azure_container":    azure.Container,
+		"storage_path":       storagePath,
+	}
+	if credentials.secretKey != "" {
+		args["azure_account_key"] = credentials.secretKey
+	}
+	if credentials.sasToken != "" {
+		args["sas_token"] = credentials.sasToken
+	}
+
+	return []interface{}{"AzureStorage", args}
+}
+
+// swiftStorageLocationFor returns the storage location for the given container, using the given account credentials
+// and temp URL key.
+func swiftStorageLocationFor(swift *v1.SwiftStorage, credentials storageCredentials) []interface{} {
+	storagePath := swift.StoragePath
+	if storagePath == "" {
+		storagePath = defaultStoragePath
+	}
+	authVersion := int32(2)
+	if swift.AuthVersion != nil {
+		authVersion = *swift.AuthVersion
+	}
+
+	args := map[string]interface{}{
+		"auth_url":        swift.AuthURL,
+		"auth_version":    authVersion,
+		"swift_container": swift.Container,
+		"storage_path":    storagePath,
+		"swift_user":      credentials.accessKey,
+		"swift_password":  credentials.secretKey,
+	}
+	if len(swift.OSOptions) > 0 {
+		args["os_options"] = swift.OSOptions
+	}
+	if credentials.tempURLKey != "" {
+		args["temp_url_key"] = credentials.tempURLKey
+	}
+
+	return []interface{}{"SwiftStorage", args}
+}
+
+// ibmcosStorageLocationFor returns the storage location for the given bucket, using the given HMAC credentials.
+func ibmcosStorageLocationFor(ibmcos *v1.IBMCOSStorage, credentials storageCredentials) []interface{} {
+	storagePath := ibmcos.StoragePath
+	if storagePath == "" {
+		storagePath = defaultStoragePath
+	}
+	port := int32(443)
+	if ibmcos.Port != nil {
+		port = *ibmcos.Port
+	}
+
+	return []interface{}{"IBMCloudStorage", map[string]interface{}{
+		"hostname":     ibmcos.Endpoint,
+		"is_secure":    true,
+		"port":         port,
+		"storage_path": storagePath,
+		"bucket_name":  ibmcos.Bucket,
+		"access_key":   credentials.accessKey,
+		"secret_key":   credentials.secretKey,
+	}}
+}
+
+// claimedBucketLocationFor returns the storage location for the bucket claimed with an `ObjectBucketClaim`, using the
+// endpoint and credentials copied into the `QuayRegistry` annotations.
+func claimedBucketLocationFor(quay *v1.QuayRegistry) []interface{} {
+	credentials := storageCredentialsFor(quay, v1.PrimaryStorageLocation)
+
+	return []interface{}{"RadosGWStorage", map[string]interface{}{
+		"hostname":     quay.GetAnnotations()[v1.StorageHostnameAnnotation],
+		"is_secure":    true,
+		"port":         443,
+		"storage_path": defaultStoragePath,
+		"bucket_name":  quay.GetAnnotations()[v1.StorageBucketNameAnnotation],
+		"access_key":   credentials.accessKey,
+		"secret_key":   credentials.secretKey,
+	}}
+}
+
+// existingBucketFieldGroupFor returns a field group with the given location as the only one. Clients download
+// images directly from the bucket, since it is reachable from outside of the cluster.
+func existingBucketFieldGroupFor(location []interface{}) *existingBucketFieldGroup {
+	return &existingBucketFieldGroup{
+		FeatureProxyStorage:                false,
+		DistributedStorageConfig:           map[string][]interface{}{v1.PrimaryStorageLocation: location},
+		DistributedStoragePreference:       []string{v1.PrimaryStorageLocation},
+		DistributedStorageDefaultLocations: []string{v1.PrimaryStorageLocation},
+	}
+}
+
+// withStorageLocations adds the locations in `spec.objectStorage.locations` to the field group, ordered as declared,
+// and replicates blobs between them.
+func withStorageLocations(quay *v1.QuayRegistry, fieldGroup *existingBucketFieldGroup) *existingBucketFieldGroup {
+	locations := v1.StorageLocationsFor(quay)
+	if len(locations) == 0 {
+		return fieldGroup
+	}
+
+	for _, location := range locations {
+		fieldGroup.DistributedStorageConfig[location.Name] = existingBucketLocationFor(quay, location)
+	}
+	fieldGroup.DistributedStoragePreference = v1.StoragePreferenceFor(quay)
+	fieldGroup.DistributedStorageDefaultLocations = v1.StorageDefaultLocationsFor(quay)
+	fieldGroup.FeatureStorageReplication = true
+
+	return fieldGroup
+}
+
+// s3StorageDriverFor returns the Quay storage driver used for the given bucket. Quay's `S3Storage` driver always
+// requests server-side encryption, so the generic `RadosGWStorage` driver is used if it is disabled. A bucket behind
+// CloudFront uses `CloudFrontedS3Storage`, and a role assumed with the keys of an IAM user `STSS3Storage`, which both
+// extend `S3Storage`.
+func s3StorageDriverFor(s3 *v1.S3Storage) string {
+	if s3.ServerSideEncryption != nil && !*s3.ServerSideEncryption {
+		return "RadosGWStorage"
+	}
+	if s3.CloudFront != nil {
+		return "CloudFrontedS3Storage"
+	}
+	if s3.RoleARN != "" && s3.CredentialsSecret != "" {
+		return "STSS3Storage"
+	}
+
+	return "S3Storage"
+}
+
+// storageDriverFor returns the Quay storage driver used for the given location.
+func storageDriverFor(location v1.StorageLocation) string {
+	switch {
+	case location.S3 != nil:
+		return s3StorageDriverFor(location.S3)
+	case location.GCS != nil:
+		return "GoogleCloudStorage"
+	case location.Azure != nil:
+		return "AzureStorage"
+	case location.Swift != nil:
+		return "SwiftStorage"
+	case location.IBMCOS != nil:
+		return "IBMCloudStorage"
+	}
+
+	return ""
+}
+
+// validateObjectStorageManaged returns an error if `spec.objectStorage` is set but would be ignored, since the
+// `objectstorage` component is not managed.
+func validateObjectStorageManaged(quay *v1.QuayRegistry) error {
+	if quay.Spec.ObjectStorage != nil && !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		return errors.New("`spec.objectStorage` requires the `objectstorage` component to be managed")
+	}
+
+	return nil
+}
+
+// validateS3Storage returns an error if the bucket in the `s3` field at the given path cannot be configured.
+func validateS3Storage(path string, s3 *v1.S3Storage, credentials storageCredentials) error {
+	if s3.Bucket == "" {
+		return errors.New("`" + path + ".s3.bucket` is required")
+	}
+	if s3.Region == "" && s3.Endpoint == "" {
+		return errors.New("`" + path + ".s3` requires a `region` or an `endpoint`")
+	}
+	if s3StorageDriverFor(s3) == "RadosGWStorage" {
+		if s3.Endpoint == "" {
+			return errors.New("`" + path + ".s3.serverSideEncryption` can only be disabled with an `endpoint`")
+		}
+		if credentials.accessKey == "" {
+			return errors.New("`" + path + ".s3.serverSideEncryption` can only be disabled with a `credentialsSecret`")
+		}
+	}
+	if s3.Port != nil && s3.Endpoint == "" {
+		return errors.New("`" + path + ".s3.port` can only be set with an `endpoint`")
+	}
+	if s3.Insecure && s3StorageDriverFor(s3) != "RadosGWStorage" {
+		return errors.New("`" + path + ".s3.insecure` can only be set with `serverSideEncryption` disabled")
+	}
+	if s3.CredentialsSecret != "" && (credentials.accessKey == "" || credentials.secretKey == "") {
+		return errors.New("`" + path + ".s3.credentialsSecret` requires `accessKey` and `secretKey`")
+	}
+	if s3.RoleARN != "" {
+		if !strings.HasPrefix(s3.RoleARN, "arn:") {
+			return errors.New("`" + path + ".s3.roleARN` must be the ARN of an IAM role")
+		}
+		if s3StorageDriverFor(s3) == "RadosGWStorage" {
+			return errors.New("`" + path + ".s3.roleARN` cannot be used with `serverSideEncryption` disabled")
+		}
+		if s3.CloudFront != nil && s3.CredentialsSecret != "" {
+			return errors.New("`" + path + ".s3.cloudFront` cannot assume `roleARN` with a `credentialsSecret`")
+		}
+	}
+	if cloudFront := s3.CloudFront; cloudFront != nil {
+		if s3.ServerSideEncryption != nil && !*s3.ServerSideEncryption {
+			return errors.New("`" + path + ".s3.cloudFront` cannot be used with `serverSideEncryption` disabled")
+		}
+		if cloudFront.Domain == "" || cloudFront.KeyID == "" || cloudFront.SigningKeySecret == "" {
+			return errors.New("`" + path + ".s3.cloudFront` requires `domain`, `keyID` and `signingKeySecret`")
+		}
+	}
+
+	return nil
+}
+
+// validateGCSStorage returns an error if the bucket in the `gcs` field at the given path cannot be configured.
+func validateGCSStorage(path string, gcs *v1.GCSStorage, credentials storageCredentials) error {
+	if gcs.Bucket == "" {
+		return errors.New("`" + path + ".gcs.bucket` is required")
+	}
+	if gcs.CredentialsSecret == "" {
+		return errors.New("`" + path + ".gcs.credentialsSecret` is required")
+	}
+	if credentials.accessKey == "" || credentials.secretKey == "" {
+		return errors.New("`" + path + ".gcs.credentialsSecret` requires `accessKey` and `secretKey`")
+	}
+
+	return nil
+}
+
+// validateAzureStorage returns an error if the container in the `azure` field at the given path cannot be configured.
+func validateAzureStorage(path string, azure *v1.AzureStorage, credentials storageCredentials) error {
+	if azure.AccountName == "" {
+		return errors.New("`" + path + ".azure.accountName` is required")
+	}
+	if azure.Container == "" {
+		return errors.New("`" + path + ".azure.container` is required")
+	}
+	if azure.CredentialsSecret == "" {
+		return errors.New("`" + path + ".azure.credentialsSecret` is required")
+	}
+	if (credentials.secretKey == "") == (credentials.sasToken == "") {
+		return errors.New("`" + path + ".azure.credentialsSecret` requires exactly one of `accountKey` and `sasToken`")
+	}
+
+	return nil
+}
+
+// validateCloudFrontSigningKey returns an error if the managed bucket is served through CloudFront, but the private
+// key URLs are signed with is missing from the config bundle.
+func validateCloudFrontSigningKey(quay *v1.QuayRegistry, configFiles map[string][]byte) error {
+	s3 := v1.S3StorageFor(quay)
+	if !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") || s3 == nil || s3.CloudFront == nil {
+		return nil
+	}
+	if _, ok := configFiles[CloudFrontSigningKeyFile]; !ok {
+		return errors.New("`" + CloudFrontSigningKeyFile + "` not found in `spec.objectStorage.s3.cloudFront.signingKeySecret`")
+	}
+
+	return nil
+}
+
+// validateStorageCABundle returns an error unless the CA bundle of the storage endpoints references exactly one of a
+// `Secret` and a `ConfigMap`.
+func validateStorageCABundle(caBundle *v1.StorageCABundle) error {
+	if caBundle != nil && (caBundle.Secret == "") == (caBundle.ConfigMap == "") {
+		return errors.New("`spec.objectStorage.caBundle` requires exactly one of `secret` and `configMap`")
+	}
+
+	return nil
+}
+
+// validateStorageCABundleFile returns an error if the storage endpoints have a CA bundle, but it is missing from the
+// config bundle.
+func validateStorageCABundleFile(quay *v1.QuayRegistry, configFiles map[string][]byte) error {
+	caBundle := v1.StorageCABundleFor(quay)
+	if caBundle == nil {
+		return nil
+	}
+	if _, ok := configFiles[StorageCABundleFile]; !ok {
+		return errors.New("`" + caBundle.KeyOrDefault() + "` not found in `spec.objectStorage.caBundle`")
+	}
+
+	return nil
+}
+
+// validateIBMCOSStorage returns an error if the bucket in the `ibmcos` field at the given path cannot be configured.
+func validateIBMCOSStorage(path string, ibmcos *v1.IBMCOSStorage, credentials storageCredentials) error {
+	if ibmcos.Bucket == "" {
+		return errors.New("`" + path + ".ibmcos.bucket` is required")
+	}
+	if ibmcos.Endpoint == "" {
+		return errors.New("`" + path + ".ibmcos.endpoint` is required")
+	}
+	if ibmcos.CredentialsSecret == "" {
+		return errors.New("`" + path + ".ibmcos.credentialsSecret` is required")
+	}
+	if credentials.accessKey == "" || credentials.secretKey == "" {
+		return errors.New("`" + path + ".ibmcos.credentialsSecret` requires `accessKey` and `secretKey`")
+	}
+
+	return nil
+}
+
+// validateSwiftStorage returns an error if the container in the `swift` field at the given path cannot be configured.
+func validateSwiftStorage(path string, swift *v1.SwiftStorage, credentials storageCredentials) error {
+	if swift.AuthURL == "" {
+		return errors.New("`" + path + ".swift.authURL` is required")
+	}
+	if swift.AuthVersion != nil && (*swift.AuthVersion < 1 || *swift.AuthVersion > 3) {
+		return errors.New("`" + path + ".swift.authVersion` must be 1, 2 or 3")
+	}
+	if swift.Container == "" {
+		return errors.New("`" + path + ".swift.container` is required")
+	}
+	if swift.CredentialsSecret == "" {
+		return errors.New("`" + path + ".swift.credentialsSecret` is required")
+	}
+	if credentials.accessKey == "" || credentials.secretKey == "" {
+		return errors.New("`" + path + ".swift.credentialsSecret` requires `user` and `password`")
+	}
+
+	return nil
+}
+
+// validateStorageLocation returns an error unless the location at the given path describes exactly one existing
+// bucket or container which can be configured.
+func validateStorageLocation(quay *v1.QuayRegistry, path string, location v1.StorageLocation) error {
+	buckets := 0
+	for _, set := range []bool{location.S3 != nil, location.GCS != nil, location.Azure != nil, location.Swift != nil, location.IBMCOS != nil} {
+		if set {
+			buckets++
+		}
+	}
+	if buckets > 1 {
+		return errors.New("`" + path + "` can only set one of `s3`, `gcs`, `azure`, `swift` and `ibmcos`")
+	}
+
+	credentials := storageCredentialsFor(quay, location.Name)
+	switch {
+	case location.S3 != nil:
+		return validateS3Storage(path, location.S3, credentials)
+	case location.GCS != nil:
+		return validateGCSStorage(path, location.GCS, credentials)
+	case location.Azure != nil:
+		return validateAzureStorage(path, location.Azure, credentials)
+	case location.Swift != nil:
+		return validateSwiftStorage(path, location.Swift, credentials)
+	case location.IBMCOS != nil:
+		return validateIBMCOSStorage(path, location.IBMCOS, credentials)
+	}
+
+	return nil
+}
+
+// storageLocationNamePattern matches names of locations which can be used in annotations and Quay's database.
+var storageLocationNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9_]*[a-z0-9])?$`)
+
+// validateStorageLocations returns an error if the locations in `spec.objectStorage.locations`, or their order,
+// cannot be configured.
+func validateStorageLocations(quay *v1.QuayRegistry) error {
+	locations := v1.StorageLocationsFor(quay)
+	if len(locations) == 0 {
+		if quay.Spec.ObjectStorage != nil && (len(quay.Spec.ObjectStorage.Preference) > 0 || len(quay.Spec.ObjectStorage.DefaultLocations) > 0 || len(quay.Spec.ObjectStorage.DegradedLocations) > 0) {
+			return errors.New("`spec.objectStorage.preference`, `defaultLocations` and `degradedLocations` require `locations`")
+		}
+
+		return nil
+	}
+
+	names := map[string]bool{v1.PrimaryStorageLocation: true}
+	for i, location := range locations {
+		path := fmt.Sprintf("spec.objectStorage.locations[%d]", i)
+		if !storageLocationNamePattern.MatchString(location.Name) {
+			return errors.New("`" + path + ".name` must consist of lowercase letters, digits and underscores")
+		}
+		if names[location.Name] {
+			return errors.New("`" + path + ".name` " + location.Name + " is already used by another location")
+		}
+		names[location.Name] = true
+
+		if storageDriverFor(location) == "" {
+			return errors.New("`" + path + "` requires one of `s3`, `gcs`, `azure`, `swift` and `ibmcos`")
+		}
+		if s3 := location.S3; s3 != nil {
+			if s3.RoleARN != "" && s3.CredentialsSecret == "" {
+				return errors.New("`" + path + ".s3.roleARN` can only be assumed with a `credentialsSecret`")
+			}
+			if s3.CloudFront != nil {
+				return errors.New("`" + path + ".s3.cloudFront` is only supported for `spec.objectStorage.s3`")
+			}
+		}
+		if err := validateStorageLocation(quay, path, location); err != nil {
+			return err
+		}
+	}
+
+	for field, values := range map[string][]string{
+		"preference":        v1.StoragePreferenceFor(quay),
+		"defaultLocations":  v1.StorageDefaultLocationsFor(quay),
+		"degradedLocations": quay.Spec.ObjectStorage.DegradedLocations,
+	} {
+		listed := map[string]bool{}
+		for _, name := range values {
+			if !names[name] {
+				return errors.New("`spec.objectStorage." + field + "` contains unknown location " + name)
+			}
+			if listed[name] {
+				return errors.New("`spec.objectStorage." + field + "` contains location " + name + " more than once")
+			}
+			listed[name] = true
+		}
+	}
+	if len(v1.StorageDefaultLocationsFor(quay)) == 0 {
+		return errors.New("`spec.objectStorage.degradedLocations` cannot include every default location")
+	}
+
+	return nil
+}
+
+// s3WebIdentityServiceAccountName returns the name of the `ServiceAccount` of the Quay pods which assume the IAM role
+// in `spec.objectStorage.s3.roleARN` with their web identity.
+func s3WebIdentityServiceAccountName(quay *v1.QuayRegistry) string {
+	return quay.GetName() + "-quay-app"
+}
+
+// withS3WebIdentity runs the Quay pods which access the bucket as a `ServiceAccount` annotated with the IAM role in
+// `spec.objectStorage.s3.roleARN`, so that EKS injects a web identity token for it. The `ServiceAccount` is bound to
+// the same `Role` as the default one.
+func withS3WebIdentity(quay *v1.QuayRegistry, objects []k8sruntime.Object) []k8sruntime.Object {
+	roleARN := v1.S3WebIdentityRoleFor(quay)
+	if roleARN == "" || !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		return objects
+	}
+
+	name := s3WebIdentityServiceAccountName(quay)
+	deployments := map[string]bool{
+		quay.GetName() + "-quay-app":                  true,
+		quay.GetName() + "-quay-app-upgrade":          true,
+		quay.GetName() + "-" + MirrorWorkersComponent: true,
+		quay.GetName() + "-" + PruneWorkersComponent:  true,
+	}
+	for _, obj := range objects {
+		switch obj := obj.(type) {
+		case *appsv1.Deployment:
+			if deployments[obj.GetName()] {
+				obj.Spec.Template.Spec.ServiceAccountName = name
+			}
+		case *rbac.RoleBinding:
+			if obj.GetName() == quay.GetName()+"-quay-secret-writer" {
+				obj.Subjects = append(obj.Subjects, rbac.Subject{Kind: "ServiceAccount", Name: name})
+			}
+		}
+	}
+
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   quay.GetNamespace(),
+			Labels:      map[string]string{"quay-component": "quay-app"},
+			Annotations: map[string]string{s3RoleARNAnnotation: roleARN},
+		},
+	}
+	serviceAccount.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"})
+
+	return append(objects, serviceAccount)
+}
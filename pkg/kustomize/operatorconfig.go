@@ -0,0 +1,233 @@
+package kustomize
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// componentVolumeClaims are the `PersistentVolumeClaims` of each component whose `StorageClass` is set by the
+// `QuayOperatorConfig`.
+var componentVolumeClaims = map[string][]string{
+	"postgres":     {"quay-postgres", "quay-postgres-backup"},
+	"clair":        {"clair-postgres"},
+	"minio":        {"quay-minio"},
+	"localstorage": {"quay-datastorage"},
+}
+
+// imageOverridesFor returns the images from the `QuayOperatorConfig`, keyed by the repository of the default image
+// in the manifests which they replace.
+func imageOverridesFor(quay *v1.QuayRegistry) map[string]string {
+	overrides := map[string]string{}
+	if quay.Status.OperatorConfig == nil || quay.Status.OperatorConfig.Images == nil {
+		return overrides
+	}
+
+	images := quay.Status.OperatorConfig.Images
+	for repository, image := range map[string]string{
+		"quay.io/projectquay/quay":  images.Quay,
+		"quay.io/projectquay/clair": images.Clair,
+		"postgres":                  images.Postgres,
+		"redis":                     images.Redis,
+	} {
+		if image != "" {
+			overrides[repository] = image
+		}
+	}
+
+	return overrides
+}
+
+// imageRepository returns the given image reference without its tag or digest.
+func imageRepository(image string) string {
+	image = strings.SplitN(image, "@", 2)[0]
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+
+	return image
+}
+
+// withImageOverrides replaces the images of the containers in the given objects with those from the
+// `QuayOperatorConfig`. This is done after rendering, since the overlays set the tag of the default images.
+func withImageOverrides(quay *v1.QuayRegistry, objects []k8sruntime.Object) []k8sruntime.Object {
+	overrides := imageOverridesFor(quay)
+	if len(overrides) == 0 {
+		return objects
+	}
+
+	for _, obj := range objects {
+		var podSpec *corev1.PodSpec
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			podSpec = &o.Spec.Template.Spec
+		case *appsv1.StatefulSet:
+			podSpec = &o.Spec.Template.Spec
+		case *batchv1.Job:
+			podSpec = &o.Spec.Template.Spec
+		case *batchv1beta1.CronJob:
+			podSpec = &o.Spec.JobTemplate.Spec.Template.Spec
+		default:
+			continue
+		}
+
+		for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+			for i := range containers {
+				if image, ok := overrides[imageRepository(containers[i].Image)]; ok {
+					containers[i].Image = image
+				}
+			}
+		}
+	}
+
+	return objects
+}
+
+// storageClassPatchesFor returns the Kustomize patches which set the `StorageClass` from the `QuayOperatorConfig` on
+// the volumes of managed databases.
+func storageClassPatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	if quay.Status.OperatorConfig == nil || quay.Status.OperatorConfig.StorageClassName == "" {
+		return patches
+	}
+
+	for _, component := range quay.Spec.Components {
+		if !component.Managed {
+			continue
+		}
+
+		for _, claim := range componentVolumeClaims[component.Kind] {
+			patches = append(patches, types.Patch{
+				Patch: string(encode(map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "PersistentVolumeClaim",
+					"metadata":   map[string]interface{}{"name": claim},
+					"spec":       map[string]interface{}{"storageClassName": quay.Status.OperatorConfig.StorageClassName},
+				})),
+			})
+		}
+	}
+
+	return patches
+}
+
+// WithOperatorConfig returns a copy of the given config bundle with the config fields from the `QuayOperatorConfig`,
+// along with the fields which the registry cannot override. Returns true if any field was set.
+func WithOperatorConfig(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, []string, bool, error) {
+	fields, err := quay.Status.OperatorConfig.ConfigFields()
+	if err != nil {
+		return nil, nil, false, errors.New("invalid `spec.config` in `QuayOperatorConfig`: " + err.Error())
+	}
+	if len(fields) == 0 {
+		return userConfig, nil, false, nil
+	}
+
+	config := make(map[string]interface{}, len(userConfig)+len(fields))
+	for field, value := range userConfig {
+		config[field] = value
+	}
+
+	forced := []string{}
+	for field, value := range fields {
+		overridable := quay.Status.OperatorConfig.ConfigFieldOverridable(field)
+		if !overridable {
+			forced = append(forced, field)
+		}
+		if _, ok := userConfig[field]; ok && overridable {
+			continue
+		}
+		config[field] = value
+	}
+	sort.Strings(forced)
+
+	return config, forced, true, nil
+}
+
+// validateStorageBackends returns an error if the registry uses a storage driver the `QuayOperatorConfig` does not
+// allow.
+func validateStorageBackends(quay *v1.QuayRegistry, userConfig map[string]interface{}) error {
+	drivers := []string{}
+	if v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		if s3 := v1.S3StorageFor(quay); s3 != nil {
+			drivers = append(drivers, s3StorageDriverFor(s3))
+		} else if v1.GCSStorageFor(quay) != nil {
+			drivers = append(drivers, "GoogleCloudStorage")
+		} else if v1.AzureStorageFor(quay) != nil {
+			drivers = append(drivers, "AzureStorage")
+		} else if v1.SwiftStorageFor(quay) != nil {
+			drivers = append(drivers, "SwiftStorage")
+		} else if v1.IBMCOSStorageFor(quay) != nil {
+			drivers = append(drivers, "IBMCloudStorage")
+		} else {
+			drivers = append(drivers, "RadosGWStorage")
+		}
+		for _, location := range v1.StorageLocationsFor(quay) {
+			drivers = append(drivers, storageDriverFor(location))
+		}
+	}
+	if v1.ComponentIsManaged(quay.Spec.Components, "minio") {
+		drivers = append(drivers, "RadosGWStorage")
+	}
+	if v1.ComponentIsManaged(quay.Spec.Components, "localstorage") {
+		drivers = append(drivers, "LocalStorage")
+	}
+	if locations, ok := userConfig["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{}); ok {
+		for _, location := range locations {
+			if definition, ok := location.([]interface{}); ok && len(definition) > 0 {
+				if driver, ok := definition[0].(string); ok {
+					drivers = append(drivers, driver)
+				}
+			}
+		}
+	}
+
+	for _, driver := range drivers {
+		if !quay.Status.OperatorConfig.StorageBackendAllowed(driver) {
+			return errors.New("storage driver `" + driver + "` is not allowed by the `QuayOperatorConfig`")
+		}
+	}
+
+	return nil
+}
+
+// withoutConfigFields removes the given fields from every config file other than `config.yaml`, so that the fields
+// forced by the `QuayOperatorConfig` are not replaced by those from managed components when flattened.
+func withoutConfigFields(configFiles map[string][]byte, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	for name, contents := range configFiles {
+		if !strings.HasSuffix(name, ".config.yaml") {
+			continue
+		}
+
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(contents, &config); err != nil {
+			return err
+		}
+
+		removed := false
+		for _, field := range fields {
+			if _, ok := config[field]; ok {
+				delete(config, field)
+				removed = true
+			}
+		}
+		if removed {
+			configFiles[name] = encode(config)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// PgBouncerConfigChecksumAnnotation is the pod template annotation of the managed `pgbouncer` component with a
+	// checksum of its config, so that PgBouncer is restarted when the database it pools connections to changes.
+	PgBouncerConfigChecksumAnnotation = "quay-operator/pgbouncer-config-checksum"
+
+	// pgBouncerPort is the port PgBouncer accepts connections from Quay on.
+	pgBouncerPort = 6432
+	// pgBouncerCertsDir is the directory the managed `pgbouncer` component mounts the CA of the database in.
+	pgBouncerCertsDir = "/var/run/certs"
+	// pgBouncerMaxClientConnections matches the `max_connections` of the managed database.
+	pgBouncerMaxClientConnections = 2000
+)
+
+type pgBouncerComponent struct {
+	baseComponent
+}
+
+// ConfigFiles adds nothing, since the connection of Quay to the pooler depends on the database it replaces, and is
+// added by `pgBouncerConfigFor` once every other component has added its config.
+func (c pgBouncerComponent) ConfigFiles(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string][]byte, error) {
+	return nil, nil
+}
+
+// pgBouncerConfig is the config of the managed `pgbouncer` component.
+type pgBouncerConfig struct {
+	// quayConfig connects Quay to the pooler instead of the database.
+	quayConfig map[string]interface{}
+	// files are the contents of the `pgbouncer-config-secret`.
+	files map[string][]byte
+	// caFile is the file of the config bundle with the CA the certificate of the database is verified with, if any.
+	caFile string
+}
+
+// databaseConfigFor returns the `DB_URI` and `DB_CONNECTION_ARGS` Quay would use without the pooler, taking the
+// config files of managed components and the spec over those of the config bundle.
+func databaseConfigFor(userConfig, quayConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	sources := []map[string]interface{}{userConfig, quayConfig}
+	for _, file := range []string{"postgres.config.yaml", "externaldatabase.config.yaml"} {
+		contents, ok := configFiles[file]
+		if !ok {
+			continue
+		}
+
+		var fileConfig map[string]interface{}
+		if err := yaml.Unmarshal(contents, &fileConfig); err != nil {
+			return nil, err
+		}
+		sources = append(sources, fileConfig)
+	}
+
+	for _, source := range sources {
+		for _, field := range []string{"DB_URI", "DB_CONNECTION_ARGS"} {
+			if value, ok := source[field]; ok {
+				config[field] = value
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// pgBouncerConfigFor returns the config of the managed `pgbouncer` component, or nil if it is not managed. PgBouncer
+// connects to the database Quay would otherwise use, verifying its certificate as Quay would, and Quay connects to
+// PgBouncer with the same credentials.
+func pgBouncerConfigFor(quay *v1.QuayRegistry, userConfig, quayConfig map[string]interface{}, configFiles map[string][]byte) (*pgBouncerConfig, error) {
+	if !v1.ComponentIsManaged(quay.Spec.Components, "pgbouncer") {
+		return nil, nil
+	}
+
+	databaseConfig, err := databaseConfigFor(userConfig, quayConfig, configFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	dbURI, _ := databaseConfig["DB_URI"].(string)
+	if dbURI == "" {
+		return nil, errors.New("`pgbouncer` component requires the managed `postgres` component, `spec.externalDatabase` or `DB_URI`")
+	}
+	database, err := url.Parse(dbURI)
+	if err != nil || database.Host == "" || database.User == nil {
+		return nil, errors.New("`DB_URI` used by the `pgbouncer` component is invalid")
+	}
+
+	args := map[string]interface{}{}
+	if existingArgs, ok := databaseConfig["DB_CONNECTION_ARGS"].(map[string]interface{}); ok {
+		for key, value := range existingArgs {
+			args[key] = value
+		}
+	}
+
+	// NOTE: PgBouncer is only reached from within the cluster, so Quay connects to it without TLS.
+	sslMode, _ := args["sslmode"].(string)
+	if sslMode == "" {
+		sslMode = "prefer"
+	}
+	caFile := ""
+	if rootCert, ok := args["sslrootcert"].(string); ok {
+		caFile = filepath.Base(rootCert)
+		if _, ok := configFiles[caFile]; !ok {
+			return nil, errors.New("`sslrootcert` must be a file of the config bundle to be used with the `pgbouncer` component")
+		}
+	}
+	delete(args, "sslmode")
+	delete(args, "sslrootcert")
+
+	poolMode := v1.PgBouncerPoolModeTransaction
+	poolSize := v1.DefaultPgBouncerPoolSize
+	if settings := quay.Spec.PgBouncer; settings != nil {
+		if settings.PoolMode != "" {
+			poolMode = settings.PoolMode
+		}
+		if settings.DefaultPoolSize != nil {
+			poolSize = *settings.DefaultPoolSize
+		}
+	}
+
+	port := database.Port()
+	if port == "" {
+		port = "5432"
+	}
+	name := strings.TrimPrefix(database.Path, "/")
+
+	ini := []string{
+		"[databases]",
+		fmt.Sprintf("%s = host=%s port=%s dbname=%s", name, database.Hostname(), port, name),
+		"",
+		"[pgbouncer]",
+		"listen_addr = 0.0.0.0",
+		fmt.Sprintf("listen_port = %d", pgBouncerPort),
+		"auth_type = md5",
+		"auth_file = /etc/pgbouncer/userlist.txt",
+		"pool_mode = " + string(poolMode),
+		fmt.Sprintf("default_pool_size = %d", poolSize),
+		fmt.Sprintf("max_client_conn = %d", pgBouncerMaxClientConnections),
+		// Set by the Postgres driver of Quay on every connection, which PgBouncer otherwise rejects.
+		"ignore_startup_parameters = extra_float_digits",
+		"server_tls_sslmode = " + sslMode,
+	}
+	if caFile != "" {
+		ini = append(ini, "server_tls_ca_file = "+filepath.Join(pgBouncerCertsDir, caFile))
+	}
+
+	password, _ := database.User.Password()
+	quoted := func(value string) string {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+
+	pooler := url.URL{
+		Scheme: database.Scheme,
+		User:   database.User,
+		Host:   fmt.Sprintf("%s-quay-pgbouncer:%d", quay.GetName(), pgBouncerPort),
+		Path:   database.Path,
+	}
+
+	return &pgBouncerConfig{
+		quayConfig: map[string]interface{}{
+			"DB_URI":             pooler.String(),
+			"DB_CONNECTION_ARGS": args,
+		},
+		files: map[string][]byte{
+			"pgbouncer.ini": []byte(strings.Join(ini, "\n") + "\n"),
+			"userlist.txt":  []byte(quoted(database.User.Username()) + " " + quoted(password) + "\n"),
+		},
+		caFile: caFile,
+	}, nil
+}
+
+// withPgBouncerConfig adds the config to the `pgbouncer-config-secret`, and mounts the CA of the database from the
+// Quay config bundle into PgBouncer.
+func withPgBouncerConfig(quay *v1.QuayRegistry, resources []k8sruntime.Object, config *pgBouncerConfig) []k8sruntime.Object {
+	if config == nil {
+		return resources
+	}
+
+	checksum := sha256.New()
+	for _, file := range []string{"pgbouncer.ini", "userlist.txt"} {
+		checksum.Write(config.files[file])
+	}
+
+	for _, resource := range resources {
+		switch resource := resource.(type) {
+		case *corev1.Secret:
+			if resource.GetName() == quay.GetName()+"-pgbouncer-config-secret" {
+				resource.Data = config.files
+			}
+		case *apps.Deployment:
+			if resource.GetName() != quay.GetName()+"-quay-pgbouncer" {
+				continue
+			}
+
+			template := &resource.Spec.Template
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[PgBouncerConfigChecksumAnnotation] = fmt.Sprintf("%x", checksum.Sum(nil))
+
+			configSecret := ConfigSecretFor(resources)
+			if config.caFile == "" || configSecret == nil {
+				continue
+			}
+
+			template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+				Name: "certs",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: configSecret.GetName(),
+						Items:      []corev1.KeyToPath{{Key: config.caFile, Path: config.caFile}},
+					},
+				},
+			})
+			for i := range template.Spec.Containers {
+				template.Spec.Containers[i].VolumeMounts = append(template.Spec.Containers[i].VolumeMounts, corev1.VolumeMount{
+					Name:      "certs",
+					MountPath: pgBouncerCertsDir,
+					ReadOnly:  true,
+				})
+			}
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,56 @@
+package kustomize
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// validatePodOverrides returns an error if `spec.podOverrides` overrides the pods of a `Deployment` more than once.
+func validatePodOverrides(quay *v1.QuayRegistry) error {
+	seen := map[string]bool{}
+	for _, override := range quay.Spec.PodOverrides {
+		if seen[override.Component] {
+			return fmt.Errorf("`spec.podOverrides` overrides component `%s` more than once", override.Component)
+		}
+		seen[override.Component] = true
+	}
+
+	return nil
+}
+
+// withPodOverrides replaces the termination grace period of the pods of each `Deployment` in `spec.podOverrides`,
+// and the lifecycle hooks of their main container. Sidecars, such as a database exporter, keep their own hooks.
+// Overrides of components which are not deployed are ignored.
+func withPodOverrides(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	if len(quay.Spec.PodOverrides) == 0 {
+		return resources
+	}
+
+	for _, resource := range resources {
+		deployment, ok := resource.(*apps.Deployment)
+		if !ok {
+			continue
+		}
+
+		template := &deployment.Spec.Template
+		for _, override := range quay.Spec.PodOverrides {
+			if template.GetLabels()["quay-component"] != override.Component {
+				continue
+			}
+
+			if override.TerminationGracePeriodSeconds != nil {
+				gracePeriod := *override.TerminationGracePeriodSeconds
+				template.Spec.TerminationGracePeriodSeconds = &gracePeriod
+			}
+			if override.Lifecycle != nil && len(template.Spec.Containers) > 0 {
+				template.Spec.Containers[0].Lifecycle = override.Lifecycle.DeepCopy()
+			}
+		}
+	}
+
+	return resources
+}
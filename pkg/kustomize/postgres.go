@@ -0,0 +1,411 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// PostgresBackupComponent is the `quay-component` label value of the `Jobs` which back up the managed database before
+// its image is updated.
+const PostgresBackupComponent = "quay-postgres-backup"
+
+// PostgresRestoreComponent is the `quay-component` label value of the `Jobs` which restore the backup of the managed
+// database with the image of its new major version.
+const PostgresRestoreComponent = "quay-postgres-restore"
+
+// postgresBackupJobDeadline is how long a backup may run before it is considered failed.
+const postgresBackupJobDeadline = int64(60 * 60 * 6)
+
+// postgresBackupScript dumps the database to the backup volume. The dump is only renamed once complete, so a partial
+// dump is never mistaken for a backup.
+const postgresBackupScript = `
+set -e
+backup=/backup/quay-$(date -u +%Y%m%dT%H%M%SZ).dump
+pg_dump --format=custom --file="$backup.partial"
+mv "$backup.partial" "$backup"
+echo "backed up database to $backup"
+`
+
+// postgresUpgradeBackupScript waits for the clients of the database, which are stopped for a major version upgrade,
+// to disconnect, so that no write is missing from the backup which is restored.
+const postgresUpgradeBackupScript = `
+set -e
+until [ "$(psql -tAc "SELECT count(*) FROM pg_stat_activity WHERE datname = current_database() AND pid <> pg_backend_pid()")" = "0" ]; do
+  echo "waiting for clients to disconnect"
+  sleep 5
+done
+` + postgresBackupScript
+
+// postgresRestoreScript restores the latest backup once the database runs the new major version. The database is
+// initialized with the `pg_trgm` extension, which the backup creates only if it does not exist.
+const postgresRestoreScript = `
+set -e
+until [ "$(psql -tAc "SHOW server_version_num" | cut -c1-2)" = "$POSTGRES_VERSION" ]; do
+  echo "waiting for Postgres $POSTGRES_VERSION"
+  sleep 5
+done
+backup=$(ls -t /backup/quay-*.dump | head -n 1)
+pg_restore --clean --if-exists --no-owner --exit-on-error --dbname="$PGDATABASE" "$backup"
+echo "restored database from $backup"
+`
+
+const (
+	// ClairDatabaseCAFile is the file in the config bundle with the CA of the generated certificate of the managed Clair
+	// database. Clair mounts the config bundle in `clairCertsDir`.
+	ClairDatabaseCAFile = "clair-database.pem"
+	// DatabaseCertificateChecksumAnnotation is the pod template annotation of a managed database with a checksum of the
+	// CA of its generated certificate, so that rotating the certificate restarts the database, which only reads it on
+	// startup.
+	DatabaseCertificateChecksumAnnotation = "quay-operator/database-certificate-checksum"
+
+	// databaseTLSDir is the directory the generated certificate of a managed database is mounted in.
+	databaseTLSDir = "/var/run/postgres-tls"
+	// clairCertsDir is the directory the managed Clair mounts the Quay config bundle in.
+	clairCertsDir = "/var/run/certs"
+	// postgresDataVolumeDir is the directory the volume of the managed database is mounted in.
+	postgresDataVolumeDir = "/var/lib/postgresql/data"
+)
+
+// databaseClientDeployments are the `Deployments` which connect to the managed database, and are stopped while it is
+// upgraded to a new major version.
+var databaseClientDeployments = []string{
+	"quay-app",
+	"quay-app-upgrade",
+	MirrorWorkersComponent,
+	PruneWorkersComponent,
+	"quay-pgbouncer",
+}
+
+// postgresClientEnv maps the environment of the Postgres container to that of the `pg_dump` client connecting to it.
+var postgresClientEnv = map[string]string{
+	"POSTGRES_USER":     "PGUSER",
+	"POSTGRES_PASSWORD": "PGPASSWORD",
+	"POSTGRES_DB":       "PGDATABASE",
+}
+
+// validatePostgresVersion returns an error if the spec lowers the major version of an existing managed database, whose
+// backup cannot be restored into an older version.
+func validatePostgresVersion(quay *v1.QuayRegistry) error {
+	status := quay.Status.Postgres
+	if status == nil || status.Version == "" {
+		return nil
+	}
+
+	existing, err := strconv.Atoi(string(status.Version))
+	if err != nil {
+		return nil
+	}
+	desired, err := strconv.Atoi(string(v1.PostgresVersionFor(quay)))
+	if err != nil || desired >= existing {
+		return nil
+	}
+
+	return fmt.Errorf("cannot lower `spec.postgres.version` of the existing database from %s to %s", status.Version, v1.PostgresVersionFor(quay))
+}
+
+// validatePostgresResources returns an error if `spec.postgres.resources` cannot be applied, or requests more of a
+// resource than its limit.
+func validatePostgresResources(quay *v1.QuayRegistry) error {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Resources == nil {
+		return nil
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		return errors.New("`spec.postgres.resources` requires the `postgres` component to be managed")
+	}
+
+	resources := quay.Spec.Postgres.Resources
+	for name, request := range resources.Requests {
+		if limit, ok := resources.Limits[name]; ok && request.Cmp(limit) > 0 {
+			return fmt.Errorf("`spec.postgres.resources` request of `%s` cannot be larger than its limit", name)
+		}
+	}
+
+	return nil
+}
+
+// withDatabasePassword returns a copy of the `QuayRegistry` with the password of a managed database in the given
+// annotation, which its component passes to both the database and its clients.
+func withDatabasePassword(quay *v1.QuayRegistry, annotation, password string) *v1.QuayRegistry {
+	updatedQuay := quay.DeepCopy()
+	annotations := updatedQuay.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if password == "" {
+		delete(annotations, annotation)
+	} else {
+		annotations[annotation] = password
+	}
+	updatedQuay.SetAnnotations(annotations)
+
+	return updatedQuay
+}
+
+// ManagedDatabaseCAFiles returns the config bundle file with the CA of the generated certificate of each managed
+// database which is served over TLS, keyed by database.
+func ManagedDatabaseCAFiles(quay *v1.QuayRegistry) map[string]string {
+	caFiles := map[string]string{}
+	if !v1.PostgresTLSEnabled(quay) {
+		return caFiles
+	}
+
+	// NOTE: The Crunchy Postgres Operator generates the certificate of a `PostgresCluster` itself.
+	if v1.ComponentIsManaged(quay.Spec.Components, "postgres") && !v1.UsesPostgresCluster(quay) {
+		caFiles["quay-postgres"] = DatabaseCAFile
+	}
+	if v1.ComponentIsManaged(quay.Spec.Components, "clair") {
+		caFiles["clair-postgres"] = ClairDatabaseCAFile
+	}
+
+	return caFiles
+}
+
+// withDatabaseTLS mounts the generated certificate into each managed database which is served over TLS, and enables
+// TLS in Postgres.
+func withDatabaseTLS(quay *v1.QuayRegistry, resources []k8sruntime.Object, configFiles map[string][]byte) ([]k8sruntime.Object, error) {
+	for database, caFile := range ManagedDatabaseCAFiles(quay) {
+		caBundle := configFiles[caFile]
+		if len(caBundle) == 0 {
+			return nil, errors.New("certificate for managed database `" + database + "` has not been generated")
+		}
+
+		for _, resource := range resources {
+			deployment, ok := resource.(*apps.Deployment)
+			if !ok || deployment.GetName() != quay.GetName()+"-"+database {
+				continue
+			}
+
+			template := &deployment.Spec.Template
+			if template.Annotations == nil {
+				template.Annotations = map[string]string{}
+			}
+			template.Annotations[DatabaseCertificateChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(caBundle))
+
+			// NOTE: Postgres refuses a private key which others can read, and a key owned by root may at most be
+			// readable by its group, which the `fsGroup` of the pod gives the database access to.
+			mode := int32(0640)
+			template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+				Name: "postgres-tls",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: v1.DatabaseTLSSecretFor(quay, database), DefaultMode: &mode},
+				},
+			})
+			for i := range template.Spec.Containers {
+				container := &template.Spec.Containers[i]
+				if container.Name != "postgres" {
+					continue
+				}
+
+				container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+					Name:      "postgres-tls",
+					MountPath: databaseTLSDir,
+					ReadOnly:  true,
+				})
+				container.Args = append(container.Args,
+					"-c", "ssl=on",
+					"-c", "ssl_cert_file="+databaseTLSDir+"/tls.crt",
+					"-c", "ssl_key_file="+databaseTLSDir+"/tls.key",
+				)
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// postgresDeploymentFor returns the rendered `Deployment` of the managed database, if any.
+func postgresDeploymentFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) *apps.Deployment {
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-postgres" {
+			return deployment
+		}
+	}
+
+	return nil
+}
+
+// withPostgresImage sets the image and data directory of the managed database, which only change to the desired ones
+// once the database has been backed up.
+func withPostgresImage(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	deployment := postgresDeploymentFor(quay, resources)
+	if deployment == nil {
+		return resources
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name != "postgres" {
+			continue
+		}
+
+		containers[i].Image = v1.PostgresImageFor(quay)
+		for j := range containers[i].Env {
+			if containers[i].Env[j].Name == "PGDATA" {
+				containers[i].Env[j].Value = postgresDataVolumeDir + "/" + v1.PostgresDataDirectoryFor(quay)
+			}
+		}
+	}
+
+	return resources
+}
+
+// withPostgresResources sets the compute resources of the managed database from `spec.postgres.resources`.
+func withPostgresResources(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Resources == nil {
+		return resources
+	}
+
+	deployment := postgresDeploymentFor(quay, resources)
+	if deployment == nil {
+		return resources
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == "postgres" {
+			containers[i].Resources = *quay.Spec.Postgres.Resources.DeepCopy()
+		}
+	}
+
+	return resources
+}
+
+// withoutDatabaseClients stops every `Deployment` connecting to the managed database while it is upgraded to a new
+// major version, so that it is not written to after it has been backed up.
+func withoutDatabaseClients(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	if !v1.PostgresMajorUpgradePending(quay) {
+		return resources
+	}
+
+	for _, resource := range resources {
+		deployment, ok := resource.(*apps.Deployment)
+		if !ok {
+			continue
+		}
+
+		for _, name := range databaseClientDeployments {
+			if deployment.GetName() == quay.GetName()+"-"+name {
+				replicas := int32(0)
+				deployment.Spec.Replicas = &replicas
+			}
+		}
+	}
+
+	return resources
+}
+
+// postgresBackupJobFor returns the `Job` which backs up the managed database with the image it currently runs, before
+// it is updated to the desired image. Returns nil if no update is pending.
+func postgresBackupJobFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) (*batch.Job, error) {
+	phase := v1.PostgresUpdatePhaseFor(quay)
+	if phase != v1.PostgresUpdatePhaseBackingUp && phase != v1.PostgresUpdatePhaseFailed && phase != v1.PostgresUpdatePhaseBlocked {
+		return nil, nil
+	}
+
+	script := postgresBackupScript
+	if v1.PostgresMajorUpgradePending(quay) {
+		script = postgresUpgradeBackupScript
+	}
+
+	return postgresClientJobFor(quay, resources, PostgresBackupComponent, quay.Status.Postgres.Image, script, nil)
+}
+
+// postgresRestoreJobFor returns the `Job` which restores the backup of the managed database once it runs the image of
+// its new major version. Returns nil unless the backup of a major version upgrade is being, or failed to be, restored.
+func postgresRestoreJobFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) (*batch.Job, error) {
+	phase := v1.PostgresUpdatePhaseFor(quay)
+	if phase != v1.PostgresUpdatePhaseRestoring && phase != v1.PostgresUpdatePhaseRestoreFailed {
+		return nil, nil
+	}
+
+	env := []corev1.EnvVar{{Name: "POSTGRES_VERSION", Value: string(v1.PostgresVersionFor(quay))}}
+
+	return postgresClientJobFor(quay, resources, PostgresRestoreComponent, v1.DesiredPostgresImageFor(quay), postgresRestoreScript, env)
+}
+
+// postgresClientEnvFor returns the environment of a Postgres client connecting to the managed database as the user of
+// the given Postgres container.
+func postgresClientEnvFor(quay *v1.QuayRegistry, postgres corev1.Container) []corev1.EnvVar {
+	env := []corev1.EnvVar{{Name: "PGHOST", Value: quay.GetName() + "-quay-postgres"}}
+	for _, variable := range postgres.Env {
+		if name, ok := postgresClientEnv[variable.Name]; ok {
+			env = append(env, corev1.EnvVar{Name: name, Value: variable.Value, ValueFrom: variable.ValueFrom})
+		}
+	}
+
+	return env
+}
+
+// postgresClientJobFor returns a `Job` running the given script with the given image, connected to the managed
+// database and with its backup volume mounted in `/backup`.
+func postgresClientJobFor(quay *v1.QuayRegistry, resources []k8sruntime.Object, component, image, script string, extraEnv []corev1.EnvVar) (*batch.Job, error) {
+	deployment := postgresDeploymentFor(quay, resources)
+	if deployment == nil || len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return nil, errors.New("cannot back up managed database without its `Deployment`")
+	}
+	postgres := deployment.Spec.Template.Spec.Containers[0]
+	env := append(postgresClientEnvFor(quay, postgres), extraEnv...)
+
+	// Name the `Job` after the target image, so each update is backed up once, and deleting a failed `Job` retries it.
+	target := fnv.New32a()
+	target.Write([]byte(v1.DesiredPostgresImageFor(quay)))
+
+	deadline := postgresBackupJobDeadline
+	backoffLimit := int32(2)
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%x", quay.GetName(), component, target.Sum32()),
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": component},
+		},
+		Spec: batch.JobSpec{
+			ActiveDeadlineSeconds: &deadline,
+			BackoffLimit:          &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"quay-component": component},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Volumes: []corev1.Volume{
+						{
+							Name: "postgres-backup",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: quay.GetName() + "-quay-postgres-backup",
+								},
+							},
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:            strings.TrimPrefix(component, "quay-"),
+							Image:           image,
+							ImagePullPolicy: postgres.ImagePullPolicy,
+							Command:         []string{"/bin/sh", "-c", script},
+							Env:             env,
+							VolumeMounts:    []corev1.VolumeMount{{Name: "postgres-backup", MountPath: "/backup"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	job.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"})
+
+	return job, nil
+}
@@ -0,0 +1,194 @@
+package kustomize
+
+import (
+	"errors"
+	"strconv"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// PostgresScheduledBackupComponent is the `quay-component` label value of the `CronJob` which backs up the managed
+// database on the schedule of `spec.postgres.backups`, and of the `Jobs` it creates.
+const PostgresScheduledBackupComponent = "quay-postgres-scheduled-backup"
+
+// postgresScheduledBackupHistory is how many succeeded and failed `Jobs` of the scheduled backups are kept.
+const postgresScheduledBackupHistory = int32(3)
+
+// postgresScheduledDumpScript dumps the database to the volume shared with the container uploading it.
+const postgresScheduledDumpScript = `
+set -e
+pg_dump --format=custom --file=/dump/quay.dump.partial
+mv /dump/quay.dump.partial /dump/quay.dump
+`
+
+// postgresBackupUploadScript uploads the dump to the preferred storage location of Quay, which must be S3-compatible,
+// and deletes the oldest backups beyond the retention. It runs in the Quay image, which includes `boto3`.
+const postgresBackupUploadScript = `
+import datetime
+import os
+import sys
+
+import boto3
+import yaml
+from botocore.client import Config
+
+prefix = os.environ["BACKUP_PREFIX"]
+retention = int(os.environ["BACKUP_RETENTION"])
+
+with open("/conf/stack/config.yaml") as f:
+    config = yaml.safe_load(f)
+
+locations = config.get("DISTRIBUTED_STORAGE_CONFIG") or {}
+preference = config.get("DISTRIBUTED_STORAGE_PREFERENCE") or sorted(locations)
+if not preference or preference[0] not in locations:
+    sys.exit("no storage location to upload the backup to in DISTRIBUTED_STORAGE_CONFIG")
+
+location = preference[0]
+driver, args = locations[location]
+if driver in ("S3Storage", "CloudFrontedS3Storage"):
+    bucket = args["s3_bucket"]
+    endpoint = None
+    if args.get("host"):
+        endpoint = "https://" + args["host"]
+        if args.get("port"):
+            endpoint += ":%s" % args["port"]
+    client = boto3.client("s3", region_name=args.get("s3_region"), endpoint_url=endpoint,
+                          aws_access_key_id=args.get("s3_access_key"), aws_secret_access_key=args.get("s3_secret_key"))
+elif driver in ("RadosGWStorage", "IBMCloudStorage"):
+    bucket = args["bucket_name"]
+    scheme = "https" if args.get("is_secure", True) else "http"
+    endpoint = "%s://%s" % (scheme, args["hostname"])
+    if args.get("port"):
+        endpoint += ":%s" % args["port"]
+    client = boto3.client("s3", endpoint_url=endpoint, config=Config(signature_version="s3v4"),
+                          aws_access_key_id=args["access_key"], aws_secret_access_key=args["secret_key"])
+else:
+    sys.exit("cannot upload the backup to storage location %s, since %s is not S3-compatible" % (location, driver))
+
+key = prefix + datetime.datetime.utcnow().strftime("quay-%Y%m%dT%H%M%SZ.dump")
+client.upload_file("/dump/quay.dump", bucket, key)
+print("uploaded backup of database to %s/%s" % (bucket, key))
+
+backups = []
+for page in client.get_paginator("list_objects_v2").paginate(Bucket=bucket, Prefix=prefix + "quay-"):
+    backups.extend(obj["Key"] for obj in page.get("Contents", []) if obj["Key"].endswith(".dump"))
+for expired in sorted(backups)[:-retention]:
+    client.delete_object(Bucket=bucket, Key=expired)
+    print("deleted expired backup %s" % expired)
+`
+
+// PostgresBackupPrefixFor returns the prefix of the keys the scheduled backups of the managed database are uploaded
+// to in the bucket of Quay.
+func PostgresBackupPrefixFor(quay *v1.QuayRegistry) string {
+	return "quay-postgres-backups/" + quay.GetNamespace() + "/" + quay.GetName() + "/"
+}
+
+// postgresScheduledBackupFor returns the `CronJob` which dumps the managed database on the schedule of
+// `spec.postgres.backups` and uploads the dump with the image and config of the rendered Quay app `Deployment`. It is
+// suspended while the database is upgraded to a new major version, so that a partially restored database does not
+// replace a backup. Returns nil if no schedule is declared.
+func postgresScheduledBackupFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) (*batchv1beta1.CronJob, error) {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Backups == nil {
+		return nil, nil
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		return nil, errors.New("`spec.postgres.backups` requires the `postgres` component to be managed")
+	}
+	if v1.UsesPostgresCluster(quay) {
+		return nil, errors.New("`spec.postgres.backups` cannot be set with the `PostgresCluster` provider, which backs up the database with pgBackRest")
+	}
+
+	postgresDeployment := postgresDeploymentFor(quay, resources)
+	if postgresDeployment == nil || len(postgresDeployment.Spec.Template.Spec.Containers) == 0 {
+		return nil, errors.New("cannot back up managed database without its `Deployment`")
+	}
+	postgres := postgresDeployment.Spec.Template.Spec.Containers[0]
+
+	var quayApp *apps.Deployment
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-app" {
+			quayApp = deployment
+		}
+	}
+	if quayApp == nil || len(quayApp.Spec.Template.Spec.Containers) == 0 {
+		return nil, errors.New("cannot upload backups of managed database without the Quay app `Deployment`")
+	}
+
+	retention := v1.DefaultPostgresBackupRetention
+	if quay.Spec.Postgres.Backups.Retention != nil {
+		retention = *quay.Spec.Postgres.Backups.Retention
+	}
+
+	template := quayApp.Spec.Template.DeepCopy()
+	template.ObjectMeta.Labels = map[string]string{"quay-component": PostgresScheduledBackupComponent}
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name:         "dump",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	dumpMount := corev1.VolumeMount{Name: "dump", MountPath: "/dump"}
+
+	template.Spec.InitContainers = []corev1.Container{
+		{
+			Name:            "dump",
+			Image:           postgres.Image,
+			ImagePullPolicy: postgres.ImagePullPolicy,
+			Command:         []string{"/bin/sh", "-c", postgresScheduledDumpScript},
+			Env:             postgresClientEnvFor(quay, postgres),
+			VolumeMounts:    []corev1.VolumeMount{dumpMount},
+		},
+	}
+
+	upload := template.Spec.Containers[0]
+	upload.Name = "upload"
+	upload.Command = []string{"python", "-c", postgresBackupUploadScript}
+	upload.Env = append(upload.Env,
+		corev1.EnvVar{Name: "BACKUP_PREFIX", Value: PostgresBackupPrefixFor(quay)},
+		corev1.EnvVar{Name: "BACKUP_RETENTION", Value: strconv.Itoa(int(retention))},
+	)
+	upload.VolumeMounts = append(upload.VolumeMounts, dumpMount)
+	upload.Ports = nil
+	upload.ReadinessProbe = nil
+	upload.LivenessProbe = nil
+	template.Spec.Containers = []corev1.Container{upload}
+
+	suspend := v1.PostgresMajorUpgradePending(quay)
+	deadline := postgresBackupJobDeadline
+	backoffLimit := int32(2)
+	history := postgresScheduledBackupHistory
+	cronJob := &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quay.GetName() + "-" + PostgresScheduledBackupComponent,
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": PostgresScheduledBackupComponent},
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   quay.Spec.Postgres.Backups.Schedule,
+			ConcurrencyPolicy:          batchv1beta1.ForbidConcurrent,
+			Suspend:                    &suspend,
+			SuccessfulJobsHistoryLimit: &history,
+			FailedJobsHistoryLimit:     &history,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"quay-component": PostgresScheduledBackupComponent},
+				},
+				Spec: batch.JobSpec{
+					ActiveDeadlineSeconds: &deadline,
+					BackoffLimit:          &backoffLimit,
+					Template:              *template,
+				},
+			},
+		},
+	}
+	cronJob.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch", Version: "v1beta1", Kind: "CronJob"})
+
+	return cronJob, nil
+}
@@ -0,0 +1,202 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// PostgresClusterURIFile is the file in the config bundle with the `DB_URI` of the user the Crunchy Postgres Operator
+// created for Quay. It is not included in the rendered config bundle.
+const PostgresClusterURIFile = "postgrescluster-uri"
+
+// PostgresClusterGVK is the kind of the cluster the Crunchy Postgres Operator deploys the managed database with.
+var PostgresClusterGVK = schema.GroupVersionKind{Group: "postgres-operator.crunchydata.com", Version: "v1beta1", Kind: "PostgresCluster"}
+
+const (
+	// postgresClusterUser is the user and database the Crunchy Postgres Operator creates for Quay.
+	postgresClusterUser = "quay"
+	// postgresClusterInitSQL prepares the database for Quay once the cluster is initialized.
+	postgresClusterInitSQL = "\\c quay\nCREATE EXTENSION IF NOT EXISTS pg_trgm;\n"
+)
+
+// PostgresClusterUserSecretFor returns the name of the `Secret` the Crunchy Postgres Operator stores the credentials
+// of the user of Quay in.
+func PostgresClusterUserSecretFor(quay *v1.QuayRegistry) string {
+	return v1.PostgresClusterNameFor(quay) + "-pguser-" + postgresClusterUser
+}
+
+// PostgresClusterCertSecretFor returns the name of the `Secret` with the CA the Crunchy Postgres Operator signs the
+// certificate of the cluster with.
+func PostgresClusterCertSecretFor(quay *v1.QuayRegistry) string {
+	return v1.PostgresClusterNameFor(quay) + "-cluster-cert"
+}
+
+// withDatabaseCA returns the given `DB_CONNECTION_ARGS` of the config bundle or profile, verifying the certificate of
+// the database with `DatabaseCAFile` in the given `sslmode`.
+func withDatabaseCA(quay *v1.QuayRegistry, userConfig map[string]interface{}, sslMode v1.DatabaseSSLMode) map[string]interface{} {
+	existingArgs, ok := userConfig["DB_CONNECTION_ARGS"].(map[string]interface{})
+	if !ok {
+		existingArgs, _ = profileConfigFor(quay)["DB_CONNECTION_ARGS"].(map[string]interface{})
+	}
+	args := map[string]interface{}{}
+	for key, value := range existingArgs {
+		args[key] = value
+	}
+	args["sslmode"] = string(sslMode)
+	args["sslrootcert"] = "/conf/stack/" + DatabaseCAFile
+
+	return args
+}
+
+// PostgresClusterConfigFor returns the Quay config fields connecting to the `PostgresCluster` of the managed `postgres`
+// component, or nil if it is not used. The `DB_URI` is read from `PostgresClusterURIFile` in the given config files,
+// which is missing until the Crunchy Postgres Operator has created the user of Quay, in which case Quay connects to
+// the primary without credentials until it has.
+func PostgresClusterConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	if !v1.UsesPostgresCluster(quay) {
+		return nil, nil
+	}
+
+	dbURI := strings.TrimSpace(string(configFiles[PostgresClusterURIFile]))
+	if dbURI == "" {
+		primary := url.URL{
+			Scheme: "postgresql",
+			User:   url.User(postgresClusterUser),
+			Host:   fmt.Sprintf("%s-primary.%s.svc:5432", v1.PostgresClusterNameFor(quay), quay.GetNamespace()),
+			Path:   "/" + postgresClusterUser,
+		}
+		dbURI = primary.String()
+	} else if uri, err := url.Parse(dbURI); err != nil || uri.Host == "" {
+		return nil, errors.New("`uri` of `Secret` " + PostgresClusterUserSecretFor(quay) + " is invalid")
+	}
+
+	config := map[string]interface{}{"DB_URI": dbURI}
+	if _, ok := configFiles[DatabaseCAFile]; ok {
+		config["DB_CONNECTION_ARGS"] = withDatabaseCA(quay, userConfig, v1.DatabaseSSLModeVerifyFull)
+	}
+
+	return config, nil
+}
+
+// postgresClusterFor returns the `PostgresCluster` of the managed `postgres` component, with a replicated volume for
+// each instance and a pgBackRest repository for its backups.
+func postgresClusterFor(quay *v1.QuayRegistry) (*unstructured.Unstructured, error) {
+	version, err := strconv.Atoi(string(v1.PostgresVersionFor(quay)))
+	if err != nil {
+		return nil, err
+	}
+
+	instances := v1.DefaultPostgresClusterInstances
+	if quay.Spec.Postgres.Instances != nil {
+		instances = *quay.Spec.Postgres.Instances
+	}
+
+	volumeClaimSpec := map[string]interface{}{
+		"accessModes": []interface{}{string(corev1.ReadWriteOnce)},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"storage": "50Gi"},
+		},
+	}
+	if quay.Status.OperatorConfig != nil && quay.Status.OperatorConfig.StorageClassName != "" {
+		volumeClaimSpec["storageClassName"] = quay.Status.OperatorConfig.StorageClassName
+	}
+
+	instance := map[string]interface{}{
+		"name":                "instance1",
+		"replicas":            int64(instances),
+		"dataVolumeClaimSpec": volumeClaimSpec,
+	}
+	if quay.Spec.Postgres.Resources != nil {
+		resources, err := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(quay.Spec.Postgres.Resources)
+		if err != nil {
+			return nil, err
+		}
+		instance["resources"] = resources
+	}
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(PostgresClusterGVK)
+	cluster.SetName(v1.PostgresClusterNameFor(quay))
+	cluster.SetNamespace(quay.GetNamespace())
+	cluster.SetLabels(map[string]string{"quay-component": "postgres"})
+	cluster.Object["spec"] = map[string]interface{}{
+		"postgresVersion": int64(version),
+		"instances":       []interface{}{instance},
+		"users": []interface{}{
+			map[string]interface{}{
+				"name":      postgresClusterUser,
+				"databases": []interface{}{postgresClusterUser},
+			},
+		},
+		"databaseInitSQL": map[string]interface{}{
+			"name": v1.PostgresClusterNameFor(quay) + "-init",
+			"key":  "init.sql",
+		},
+		"backups": map[string]interface{}{
+			"pgbackrest": map[string]interface{}{
+				"repos": []interface{}{
+					map[string]interface{}{
+						"name":   "repo1",
+						"volume": map[string]interface{}{"volumeClaimSpec": volumeClaimSpec},
+					},
+				},
+			},
+		},
+	}
+
+	return cluster, nil
+}
+
+// withPostgresCluster replaces the `Deployment` of the managed `postgres` component and the objects it uses with a
+// `PostgresCluster`, if `spec.postgres.provider` is `PostgresCluster`.
+func withPostgresCluster(quay *v1.QuayRegistry, resources []k8sruntime.Object) ([]k8sruntime.Object, error) {
+	if !v1.UsesPostgresCluster(quay) {
+		return resources, nil
+	}
+
+	cluster, err := postgresClusterFor(quay)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []k8sruntime.Object{}
+	for _, resource := range resources {
+		objectMeta, err := meta.Accessor(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		name := objectMeta.GetName()
+		if objectMeta.GetLabels()["quay-component"] == "postgres" ||
+			strings.HasPrefix(name, quay.GetName()+"-postgres-config-secret") ||
+			strings.HasPrefix(name, quay.GetName()+"-postgres-bootstrap") {
+			continue
+		}
+
+		filtered = append(filtered, resource)
+	}
+
+	initSQL := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v1.PostgresClusterNameFor(quay) + "-init",
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": "postgres"},
+		},
+		Data: map[string]string{"init.sql": postgresClusterInitSQL},
+	}
+	initSQL.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+
+	return append(filtered, initSQL, cluster), nil
+}
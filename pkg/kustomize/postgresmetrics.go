@@ -0,0 +1,154 @@
+package kustomize
+
+import (
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// PostgresMetricsComponent is the `quay-component` label value of the `Service` and `ServiceMonitor` exposing the
+// metrics of the managed database.
+const PostgresMetricsComponent = "quay-postgres-metrics"
+
+// ServiceMonitorGVK is the kind the Prometheus Operator discovers the `Services` to scrape with.
+var ServiceMonitorGVK = schema.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"}
+
+const (
+	// postgresExporterImage exports the metrics of the managed `Deployment` of the database. The Crunchy Postgres
+	// Operator runs its own exporter in each instance of a `PostgresCluster`.
+	postgresExporterImage = "quay.io/prometheuscommunity/postgres-exporter:v0.15.0"
+	// postgresExporterPort is the port both exporters serve their metrics on.
+	postgresExporterPort = int32(9187)
+)
+
+// validatePostgresMetrics returns an error if `spec.postgres.metrics` cannot be applied.
+func validatePostgresMetrics(quay *v1.QuayRegistry) error {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Metrics == nil {
+		return nil
+	}
+	if !v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		return errors.New("`spec.postgres.metrics` requires the `postgres` component to be managed")
+	}
+
+	return nil
+}
+
+// postgresExporterFor returns the container exporting the metrics of the given Postgres container from the same pod,
+// connecting as its user.
+func postgresExporterFor(postgres corev1.Container) corev1.Container {
+	env := []corev1.EnvVar{}
+	database := "postgres"
+	for _, variable := range postgres.Env {
+		switch variable.Name {
+		case "POSTGRES_USER":
+			env = append(env, corev1.EnvVar{Name: "DATA_SOURCE_USER", Value: variable.Value, ValueFrom: variable.ValueFrom})
+		case "POSTGRES_PASSWORD":
+			env = append(env, corev1.EnvVar{Name: "DATA_SOURCE_PASS", Value: variable.Value, ValueFrom: variable.ValueFrom})
+		case "POSTGRES_DB":
+			database = variable.Value
+		}
+	}
+	// NOTE: The exporter connects over the loopback interface, which needs no TLS even when the database serves it.
+	env = append(env, corev1.EnvVar{Name: "DATA_SOURCE_URI", Value: "localhost:5432/" + database + "?sslmode=disable"})
+
+	return corev1.Container{
+		Name:            "postgres-exporter",
+		Image:           postgresExporterImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Env:             env,
+		Ports:           []corev1.ContainerPort{{Name: "metrics", ContainerPort: postgresExporterPort, Protocol: corev1.ProtocolTCP}},
+	}
+}
+
+// withPostgresMetrics exports the metrics of the managed database if `spec.postgres.metrics` is set, with a
+// postgres_exporter sidecar in its `Deployment` or the exporter of the Crunchy Postgres Operator in each instance of
+// its `PostgresCluster`. The exporters are exposed by a `Service`, which a `ServiceMonitor` scrapes if the Prometheus
+// Operator is installed. Must be called after `withPostgresCluster`.
+func withPostgresMetrics(quay *v1.QuayRegistry, resources []k8sruntime.Object) ([]k8sruntime.Object, error) {
+	if quay.Spec.Postgres == nil || quay.Spec.Postgres.Metrics == nil {
+		return resources, nil
+	}
+
+	var selector map[string]string
+	if v1.UsesPostgresCluster(quay) {
+		for _, resource := range resources {
+			cluster, ok := resource.(*unstructured.Unstructured)
+			if !ok || cluster.GroupVersionKind() != PostgresClusterGVK {
+				continue
+			}
+
+			exporter := map[string]interface{}{"pgmonitor": map[string]interface{}{"exporter": map[string]interface{}{}}}
+			if err := unstructured.SetNestedField(cluster.Object, exporter, "spec", "monitoring"); err != nil {
+				return nil, err
+			}
+		}
+
+		selector = map[string]string{
+			"postgres-operator.crunchydata.com/cluster": v1.PostgresClusterNameFor(quay),
+			"postgres-operator.crunchydata.com/data":    "postgres",
+		}
+	} else {
+		deployment := postgresDeploymentFor(quay, resources)
+		if deployment == nil || len(deployment.Spec.Template.Spec.Containers) == 0 {
+			return nil, errors.New("`spec.postgres.metrics` requires the managed database `Deployment`")
+		}
+
+		template := &deployment.Spec.Template
+		template.Spec.Containers = append(template.Spec.Containers, postgresExporterFor(template.Spec.Containers[0]))
+		selector = deployment.Spec.Selector.MatchLabels
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      quay.GetName() + "-" + PostgresMetricsComponent,
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": PostgresMetricsComponent},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "metrics",
+					Port:       postgresExporterPort,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(int(postgresExporterPort)),
+				},
+			},
+		},
+	}
+	service.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+	resources = append(resources, service)
+
+	if _, ok := quay.GetAnnotations()[v1.SupportsServiceMonitorsAnnotation]; !ok {
+		return resources, nil
+	}
+
+	interval := v1.DefaultPostgresMetricsInterval
+	if quay.Spec.Postgres.Metrics.Interval != "" {
+		interval = quay.Spec.Postgres.Metrics.Interval
+	}
+
+	serviceMonitor := &unstructured.Unstructured{}
+	serviceMonitor.SetGroupVersionKind(ServiceMonitorGVK)
+	serviceMonitor.SetName(quay.GetName() + "-" + PostgresMetricsComponent)
+	serviceMonitor.SetNamespace(quay.GetNamespace())
+	serviceMonitor.SetLabels(map[string]string{"quay-component": PostgresMetricsComponent})
+	serviceMonitor.Object["spec"] = map[string]interface{}{
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"quay-component": PostgresMetricsComponent},
+		},
+		"endpoints": []interface{}{
+			map[string]interface{}{"port": "metrics", "interval": interval},
+		},
+	}
+
+	return append(resources, serviceMonitor), nil
+}
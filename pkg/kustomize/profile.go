@@ -0,0 +1,224 @@
+package kustomize
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// profileDefaults are the values chosen for a registry size profile.
+type profileDefaults struct {
+	replicas         int32
+	maxReplicas      int32
+	webWorkers       int32
+	registryWorkers  int32
+	secscanWorkers   int32
+	dbMaxConnections int32
+	cpu              string
+	memory           string
+}
+
+var profiles = map[v1.Profile]profileDefaults{
+	v1.ProfileSmall: {
+		replicas:         1,
+		maxReplicas:      3,
+		webWorkers:       2,
+		registryWorkers:  4,
+		secscanWorkers:   1,
+		dbMaxConnections: 10,
+		cpu:              "1000m",
+		memory:           "4Gi",
+	},
+	v1.ProfileMedium: {
+		replicas:         2,
+		maxReplicas:      10,
+		webWorkers:       4,
+		registryWorkers:  8,
+		secscanWorkers:   2,
+		dbMaxConnections: 20,
+		cpu:              "2000m",
+		memory:           "8Gi",
+	},
+	v1.ProfileLarge: {
+		replicas:         4,
+		maxReplicas:      20,
+		webWorkers:       8,
+		registryWorkers:  16,
+		secscanWorkers:   4,
+		dbMaxConnections: 50,
+		cpu:              "4000m",
+		memory:           "16Gi",
+	},
+}
+
+// profileValues is the result of applying any `spec.profileOverrides` to the defaults of a profile.
+type profileValues struct {
+	replicas    int32
+	maxReplicas int32
+	workers     map[string]int32
+	resources   corev1.ResourceRequirements
+}
+
+func valueOr(override *int32, value int32) int32 {
+	if override != nil {
+		return *override
+	}
+
+	return value
+}
+
+// profileValuesFor returns the values for the profile of the given `QuayRegistry`, or nil if it has no profile.
+func profileValuesFor(quay *v1.QuayRegistry) *profileValues {
+	defaults, ok := profiles[quay.Spec.Profile]
+	if !ok {
+		return nil
+	}
+
+	overrides := quay.Spec.ProfileOverrides
+	if overrides == nil {
+		overrides = &v1.ProfileOverrides{}
+	}
+	workerCounts := overrides.WorkerCounts
+	if workerCounts == nil {
+		workerCounts = &v1.WorkerCounts{}
+	}
+
+	compute := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(defaults.cpu),
+		corev1.ResourceMemory: resource.MustParse(defaults.memory),
+	}
+	resources := corev1.ResourceRequirements{Requests: compute, Limits: compute}
+	if overrides.Resources != nil {
+		resources = *overrides.Resources
+	}
+
+	return &profileValues{
+		replicas:    valueOr(overrides.Replicas, defaults.replicas),
+		maxReplicas: valueOr(overrides.MaxReplicas, defaults.maxReplicas),
+		workers: map[string]int32{
+			"WORKER_COUNT_WEB":      valueOr(workerCounts.Web, defaults.webWorkers),
+			"WORKER_COUNT_REGISTRY": valueOr(workerCounts.Registry, defaults.registryWorkers),
+			"WORKER_COUNT_SECSCAN":  valueOr(workerCounts.Secscan, defaults.secscanWorkers),
+		},
+		resources: resources,
+	}
+}
+
+// profileConfigFor returns the Quay config fields chosen by the profile. Fields set in the config bundle take precedence.
+func profileConfigFor(quay *v1.QuayRegistry) map[string]interface{} {
+	defaults, ok := profiles[quay.Spec.Profile]
+	if !ok {
+		return map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"DB_CONNECTION_ARGS": map[string]interface{}{
+			"max_connections": defaults.dbMaxConnections,
+		},
+	}
+}
+
+// profilePatchesFor returns the Kustomize patches which apply the profile to the Quay app `Deployment`
+// (and `HorizontalPodAutoscaler`, if managed).
+func profilePatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	values := profileValuesFor(quay)
+	if values == nil {
+		return []types.Patch{}
+	}
+
+	env := []interface{}{}
+	for _, name := range []string{"WORKER_COUNT_WEB", "WORKER_COUNT_REGISTRY", "WORKER_COUNT_SECSCAN"} {
+		env = append(env, map[string]interface{}{"name": name, "value": strconv.Itoa(int(values.workers[name]))})
+	}
+
+	deploymentSpec := map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{
+						"name":      "quay-app",
+						"env":       env,
+						"resources": values.resources,
+					},
+				},
+			},
+		},
+	}
+
+	autoscaled := v1.ComponentIsManaged(quay.Spec.Components, "horizontalpodautoscaler")
+	// Replicas are left to the `HorizontalPodAutoscaler` when it is managed, to avoid fighting over the field.
+	if !autoscaled {
+		deploymentSpec["replicas"] = values.replicas
+	}
+	// NOTE: Only a single Quay app pod can mount the `ReadWriteOnce` volume of the `localstorage` component.
+	if v1.ComponentIsManaged(quay.Spec.Components, "localstorage") {
+		deploymentSpec["replicas"] = 1
+	}
+
+	patches := []types.Patch{
+		{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "quay-app"},
+				"spec":       deploymentSpec,
+			})),
+		},
+	}
+
+	if autoscaled {
+		patches = append(patches, types.Patch{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "autoscaling/v2beta2",
+				"kind":       "HorizontalPodAutoscaler",
+				"metadata":   map[string]interface{}{"name": "quay-app"},
+				"spec": map[string]interface{}{
+					"minReplicas": values.replicas,
+					"maxReplicas": values.maxReplicas,
+				},
+			})),
+		})
+	}
+
+	return patches
+}
+
+// appliedReplicasAnnotation is the replica count of the Quay app `Deployment` the Operator last rendered, so that
+// manual scaling can be told apart from a change to the `QuayRegistry`.
+const appliedReplicasAnnotation = "quay-applied-replicas"
+
+// WithPreservedReplicas returns the desired Quay app `Deployment` with the replica count of the live one, unless the
+// Operator should set it. Replicas are left to a managed `HorizontalPodAutoscaler`, and otherwise a manual scaling is
+// kept until the `QuayRegistry` changes the replica count it renders. The given `Deployment` is not modified.
+func WithPreservedReplicas(desired, live *appsv1.Deployment, autoscaled bool) *appsv1.Deployment {
+	var replicas int32 = 1
+	if desired.Spec.Replicas != nil {
+		replicas = *desired.Spec.Replicas
+	}
+
+	preserved := desired.DeepCopy()
+	annotations := preserved.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[appliedReplicasAnnotation] = strconv.Itoa(int(replicas))
+	preserved.SetAnnotations(annotations)
+
+	if live == nil || live.Spec.Replicas == nil {
+		return preserved
+	}
+
+	applied, err := strconv.Atoi(live.GetAnnotations()[appliedReplicasAnnotation])
+	scaledManually := err == nil && int32(applied) == replicas && *live.Spec.Replicas != replicas
+	// NOTE: A `HorizontalPodAutoscaler` does not scale up a `Deployment` stopped during a database upgrade.
+	if (autoscaled && *live.Spec.Replicas > 0) || scaledManually {
+		preserved.Spec.Replicas = live.Spec.Replicas
+	}
+
+	return preserved
+}
@@ -0,0 +1,50 @@
+package kustomize
+
+import (
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// PruneWorkersComponent is the `quay-component` label value of the dedicated auto-prune workers `Deployment`.
+const PruneWorkersComponent = "quay-pruner"
+
+// pruneWorkerService is the supervisord service of Quay which runs the auto-prune worker.
+const pruneWorkerService = "autopruneworker"
+
+// withPruneWorkers adds a `Deployment` running only the auto-prune worker if `spec.tagPolicy.autoPrune.workers` is
+// set, and stops the Quay app pods from running it.
+func withPruneWorkers(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	policy := quay.Spec.TagPolicy
+	if policy == nil || policy.AutoPrune == nil || policy.AutoPrune.Workers == nil || quay.Spec.Mode == v1.RegistryModeMirrorWorkers {
+		return resources
+	}
+
+	var quayApp *apps.Deployment
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-app" {
+			quayApp = deployment
+		}
+	}
+	if quayApp == nil || len(quayApp.Spec.Template.Spec.Containers) == 0 {
+		return resources
+	}
+
+	pruner := workersDeploymentFor(quay, quayApp, PruneWorkersComponent)
+	// NOTE: The workers are scaled down with the Quay app while the database is upgraded.
+	replicas := *policy.AutoPrune.Workers
+	if quayApp.Spec.Replicas != nil && *quayApp.Spec.Replicas == 0 {
+		replicas = 0
+	}
+	pruner.Spec.Replicas = &replicas
+	container := &pruner.Spec.Template.Spec.Containers[0]
+	container.Args = []string{"registry-nomigrate"}
+	container.Env = append(container.Env, corev1.EnvVar{Name: "QUAY_SERVICES", Value: pruneWorkerService})
+
+	app := &quayApp.Spec.Template.Spec.Containers[0]
+	app.Env = append(app.Env, corev1.EnvVar{Name: "QUAY_OVERRIDE_SERVICES", Value: pruneWorkerService + "=false"})
+
+	return append(resources, pruner)
+}
@@ -0,0 +1,98 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// DatabaseReadReplicaURIsFile is the file in the config bundle with the `DB_URI` of each read replica of
+// `spec.databaseReadReplicas`, one per line. It is not included in the rendered config bundle.
+const DatabaseReadReplicaURIsFile = "database-read-replica-uris"
+
+// postgresClusterReplicasURIFor returns the `DB_URI` of the user of Quay in the `PostgresCluster` of the managed
+// database, connecting to the `Service` of its replicas instead of its primary, or "" if the Crunchy Postgres Operator
+// has not created the user yet.
+func postgresClusterReplicasURIFor(quay *v1.QuayRegistry, configFiles map[string][]byte) (string, error) {
+	dbURI := strings.TrimSpace(string(configFiles[PostgresClusterURIFile]))
+	if dbURI == "" {
+		return "", nil
+	}
+
+	uri, err := url.Parse(dbURI)
+	if err != nil || uri.Host == "" {
+		return "", errors.New("`uri` of `Secret` " + PostgresClusterUserSecretFor(quay) + " is invalid")
+	}
+	uri.Host = fmt.Sprintf("%s-replicas.%s.svc:5432", v1.PostgresClusterNameFor(quay), quay.GetNamespace())
+
+	return uri.String(), nil
+}
+
+// DatabaseReadReplicasConfigFor returns the `DB_READ_REPLICAS` Quay spreads read queries across, or nil if
+// `spec.databaseReadReplicas` is not set. The `DB_URI` of each replica is read from `DatabaseReadReplicaURIsFile` in
+// the given config files, or discovered from the `PostgresCluster` of the managed database if no `Secrets` are
+// referenced. Each replica is connected to with the `DB_CONNECTION_ARGS` of the given config of the primary, or of
+// the config bundle.
+func DatabaseReadReplicasConfigFor(quay *v1.QuayRegistry, userConfig, primaryConfig map[string]interface{}, configFiles map[string][]byte) (map[string]interface{}, error) {
+	replicas := quay.Spec.DatabaseReadReplicas
+	if replicas == nil {
+		return nil, nil
+	}
+
+	var uris []string
+	if len(replicas.CredentialsSecrets) > 0 {
+		if v1.ComponentIsManaged(quay.Spec.Components, "postgres") && !v1.UsesPostgresCluster(quay) {
+			return nil, errors.New("`spec.databaseReadReplicas` cannot be used with the managed `postgres` component, which has no replicas, unless it uses the `PostgresCluster` provider")
+		}
+
+		for _, line := range strings.Split(string(configFiles[DatabaseReadReplicaURIsFile]), "\n") {
+			if dbURI := strings.TrimSpace(line); dbURI != "" {
+				uris = append(uris, dbURI)
+			}
+		}
+		if len(uris) != len(replicas.CredentialsSecrets) {
+			return nil, errors.New("each of `spec.databaseReadReplicas.credentialsSecrets` requires `DB_URI`")
+		}
+		for i, dbURI := range uris {
+			if uri, err := url.Parse(dbURI); err != nil || uri.Host == "" {
+				return nil, fmt.Errorf("`DB_URI` of `Secret` %s of `spec.databaseReadReplicas.credentialsSecrets` is invalid", replicas.CredentialsSecrets[i])
+			}
+		}
+	} else {
+		if !v1.UsesPostgresCluster(quay) {
+			return nil, errors.New("`spec.databaseReadReplicas.credentialsSecrets` is required unless the managed `postgres` component uses the `PostgresCluster` provider")
+		}
+		if quay.Spec.Postgres.Instances != nil && *quay.Spec.Postgres.Instances < 2 {
+			return nil, errors.New("`spec.databaseReadReplicas` requires `spec.postgres.instances` to be greater than 1 to discover the replicas of the `PostgresCluster`")
+		}
+
+		dbURI, err := postgresClusterReplicasURIFor(quay, configFiles)
+		if err != nil {
+			return nil, err
+		}
+		// NOTE: Quay reads from the primary until the Crunchy Postgres Operator has created its user.
+		if dbURI == "" {
+			return nil, nil
+		}
+		uris = append(uris, dbURI)
+	}
+
+	connectionArgs, ok := primaryConfig["DB_CONNECTION_ARGS"]
+	if !ok {
+		connectionArgs, ok = userConfig["DB_CONNECTION_ARGS"]
+	}
+
+	readReplicas := []interface{}{}
+	for _, dbURI := range uris {
+		replica := map[string]interface{}{"DB_URI": dbURI}
+		if ok {
+			replica["DB_CONNECTION_ARGS"] = connectionArgs
+		}
+		readReplicas = append(readReplicas, replica)
+	}
+
+	return map[string]interface{}{"DB_READ_REPLICAS": readReplicas}, nil
+}
@@ -0,0 +1,199 @@
+package kustomize
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/redis"
+	"github.com/quay/config-tool/pkg/lib/shared"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// redisConfigFields are the config fields which each configure a connection to Redis.
+var redisConfigFields = []string{"BUILDLOGS_REDIS", "USER_EVENTS_REDIS"}
+
+// redisFieldGroup is the `Redis` field group including the connection options of `spec.redis`. The field group of
+// the config-tool cannot be used, since its connections only include the host, port and password.
+type redisFieldGroup struct {
+	BuildlogsRedis  map[string]interface{} `json:"BUILDLOGS_REDIS"`
+	UserEventsRedis map[string]interface{} `json:"USER_EVENTS_REDIS"`
+}
+
+func (fg *redisFieldGroup) Fields() []string {
+	return (&redis.RedisFieldGroup{}).Fields()
+}
+
+// Validate does nothing, since `spec.redis` is validated by `validateRedisSettings`.
+func (fg *redisFieldGroup) Validate(opts shared.Options) []shared.ValidationError {
+	return nil
+}
+
+// validateRedisSettings returns an error if `spec.redis` sets a duration which is not positive, TLS for the managed
+// `redis` component, a port which is set elsewhere, or Sentinels which cannot be used with it.
+func validateRedisSettings(quay *v1.QuayRegistry) error {
+	settings := quay.Spec.Redis
+	if settings == nil {
+		return nil
+	}
+
+	for field, duration := range map[string]*metav1.Duration{
+		"connectTimeout":      settings.ConnectTimeout,
+		"timeout":             settings.Timeout,
+		"healthCheckInterval": settings.HealthCheckInterval,
+	} {
+		if duration != nil && duration.Duration <= 0 {
+			return errors.New("`spec.redis." + field + "` must be positive")
+		}
+	}
+
+	if settings.TLS != nil && v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		return errors.New("`spec.redis.tls` cannot be used with the managed `redis` component")
+	}
+
+	if settings.Port != nil {
+		if quay.Spec.ExternalRedis != nil {
+			return errors.New("`spec.redis.port` cannot be used with `spec.externalRedis`, set `port` in its `Secret` instead")
+		}
+		if settings.Sentinel != nil {
+			return errors.New("`spec.redis.port` cannot be used with `spec.redis.sentinel`, which reports the port of the master")
+		}
+	}
+
+	return validateRedisSentinel(quay)
+}
+
+// durationOrDefault returns the given duration, or the default if it is omitted.
+func durationOrDefault(duration *metav1.Duration, defaultDuration time.Duration) time.Duration {
+	if duration == nil {
+		return defaultDuration
+	}
+
+	return duration.Duration
+}
+
+// redisConnectionOptions returns the options Quay passes to the Redis client of each connection. Without timeouts, a
+// request waits on a connection to a Redis which has disappeared until the kernel gives up on it.
+func redisConnectionOptions(quay *v1.QuayRegistry) map[string]interface{} {
+	settings := quay.Spec.Redis
+	if settings == nil {
+		settings = &v1.RedisSettings{}
+	}
+
+	retryOnTimeout := true
+	if settings.RetryOnTimeout != nil {
+		retryOnTimeout = *settings.RetryOnTimeout
+	}
+
+	options := map[string]interface{}{
+		"socket_connect_timeout": durationOrDefault(settings.ConnectTimeout, v1.DefaultRedisConnectTimeout).Seconds(),
+		"socket_timeout":         durationOrDefault(settings.Timeout, v1.DefaultRedisTimeout).Seconds(),
+		"health_check_interval":  int(durationOrDefault(settings.HealthCheckInterval, v1.DefaultRedisHealthCheckInterval).Seconds()),
+		"retry_on_timeout":       retryOnTimeout,
+	}
+
+	if settings.Database != nil {
+		options["db"] = int(*settings.Database)
+	}
+
+	if settings.TLS != nil {
+		options["ssl"] = true
+		if settings.TLS.InsecureSkipVerify {
+			options["ssl_cert_reqs"] = "none"
+		}
+	}
+
+	return options
+}
+
+// redisPortFor returns the port of `spec.redis.port`, or the default port of Redis if it is omitted.
+func redisPortFor(quay *v1.QuayRegistry) int {
+	if quay.Spec.Redis == nil || quay.Spec.Redis.Port == nil {
+		return defaultRedisPort
+	}
+
+	return int(*quay.Spec.Redis.Port)
+}
+
+// withRedisPort exposes the managed `redis` component on the port of `spec.redis.port` through its `Service`, which
+// forwards to the port Redis serves on.
+func withRedisPort(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	if quay.Spec.Redis == nil || quay.Spec.Redis.Port == nil || !v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		return resources
+	}
+
+	for _, resource := range resources {
+		service, ok := resource.(*corev1.Service)
+		if !ok || service.GetName() != quay.GetName()+"-quay-redis" {
+			continue
+		}
+
+		for i := range service.Spec.Ports {
+			service.Spec.Ports[i].Port = *quay.Spec.Redis.Port
+			service.Spec.Ports[i].TargetPort = intstr.FromInt(defaultRedisPort)
+		}
+	}
+
+	return resources
+}
+
+// managedRedisFieldGroupFor returns the `Redis` field group connecting to the managed `redis` component with its
+// generated password, through its Sentinels if `spec.redis.sentinel` is set.
+func managedRedisFieldGroupFor(quay *v1.QuayRegistry) *redisFieldGroup {
+	connection := func() map[string]interface{} {
+		fields := redisConnectionOptions(quay)
+		fields["host"] = strings.Join([]string{quay.GetName(), "quay-redis"}, "-")
+		fields["port"] = redisPortFor(quay)
+		fields["password"] = quay.GetAnnotations()[v1.RedisPasswordAnnotation]
+		withRedisSentinelConnection(quay, fields)
+
+		return fields
+	}
+
+	return &redisFieldGroup{BuildlogsRedis: connection(), UserEventsRedis: connection()}
+}
+
+// redisConfigFor returns the Redis connections of the config bundle with the options and port of `spec.redis` added,
+// and its Sentinels in place of the host and port if `spec.redis.sentinel` is set, or nil if the `redis` component is
+// managed, `spec.redis` is not set or the connections are replaced by `spec.externalRedis`.
+func redisConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	if quay.Spec.Redis == nil || quay.Spec.ExternalRedis != nil || v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		return nil, nil
+	}
+
+	if err := validateRedisSettings(quay); err != nil {
+		return nil, err
+	}
+
+	config := map[string]interface{}{}
+	for _, field := range redisConfigFields {
+		connection := map[string]interface{}{}
+		if value, ok := userConfig[field]; ok {
+			if connection, ok = value.(map[string]interface{}); !ok {
+				return nil, errors.New("`" + field + "` in config bundle must be an object")
+			}
+		} else if quay.Spec.Redis.Sentinel == nil {
+			continue
+		}
+
+		fields := map[string]interface{}{}
+		for key, value := range connection {
+			fields[key] = value
+		}
+		for key, value := range redisConnectionOptions(quay) {
+			fields[key] = value
+		}
+		if quay.Spec.Redis.Port != nil {
+			fields["port"] = redisPortFor(quay)
+		}
+		withRedisSentinelConnection(quay, fields)
+		config[field] = fields
+	}
+
+	return config, nil
+}
@@ -0,0 +1,287 @@
+package kustomize
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// redisSentinelPort is the port Sentinel serves on.
+	redisSentinelPort = 26379
+	// redisSentinelLabel tells apart the pods of the replicated Redis from those of a `Deployment` rendered before
+	// `spec.redis.sentinel` was set, which is left in place.
+	redisSentinelLabel = "quay-redis-sentinel"
+	// redisSentinelDownAfter is how many milliseconds the master may be unreachable before the Sentinels fail over.
+	redisSentinelDownAfter = 5000
+)
+
+// redisSentinelServerScript runs Redis as a replica of the master the Sentinels report, or of the first pod while
+// there are no Sentinels yet, in which case the first pod becomes the master.
+const redisSentinelServerScript = `
+set -e
+self="${HOSTNAME}.${HEADLESS_SERVICE}"
+master="$(redis-cli -h "${SENTINEL_SERVICE}" -p 26379 --raw SENTINEL get-master-addr-by-name "${MASTER_NAME}" 2>/dev/null | head -n 1 || true)"
+if [ -z "${master}" ]; then
+  master="${HOSTNAME%-*}-0.${HEADLESS_SERVICE}"
+fi
+set -- redis-server --requirepass "${REDIS_PASSWORD}" --masterauth "${REDIS_PASSWORD}" --replica-announce-ip "${self}"
+if [ "${master}" != "${self}" ]; then
+  set -- "$@" --replicaof "${master}" 6379
+fi
+exec "$@"
+`
+
+// redisSentinelScript runs a Sentinel monitoring the master the other Sentinels report, or the first pod while there
+// are none yet. Sentinel rewrites its config file, so it is written to a writable path on each start.
+const redisSentinelScript = `
+set -e
+master="$(redis-cli -h "${SENTINEL_SERVICE}" -p 26379 --raw SENTINEL get-master-addr-by-name "${MASTER_NAME}" 2>/dev/null | head -n 1 || true)"
+if [ -z "${master}" ]; then
+  master="${HOSTNAME%-*}-0.${HEADLESS_SERVICE}"
+fi
+cat > /tmp/sentinel.conf <<EOF
+port 26379
+sentinel resolve-hostnames yes
+sentinel announce-hostnames yes
+sentinel announce-ip ${HOSTNAME}.${HEADLESS_SERVICE}
+sentinel monitor ${MASTER_NAME} ${master} 6379 ${QUORUM}
+sentinel auth-pass ${MASTER_NAME} ${REDIS_PASSWORD}
+sentinel down-after-milliseconds ${MASTER_NAME} ${DOWN_AFTER_MILLISECONDS}
+EOF
+exec redis-sentinel /tmp/sentinel.conf
+`
+
+// redisSentinelServiceName returns the name of the `Service` of the Sentinels of the managed `redis` component.
+func redisSentinelServiceName(quay *v1.QuayRegistry) string {
+	return quay.GetName() + "-quay-redis-sentinel"
+}
+
+// redisHeadlessServiceName returns the name of the headless `Service` giving each pod of the managed `redis`
+// component a stable hostname.
+func redisHeadlessServiceName(quay *v1.QuayRegistry) string {
+	return quay.GetName() + "-quay-redis-headless"
+}
+
+// redisSentinelMasterNameFor returns the name the Sentinels monitor the master under.
+func redisSentinelMasterNameFor(quay *v1.QuayRegistry) string {
+	if name := quay.Spec.Redis.Sentinel.MasterName; name != "" {
+		return name
+	}
+
+	return v1.DefaultRedisSentinelMasterName
+}
+
+// redisSentinelAddressFor returns the host and port of the given Sentinel address, whose port may be omitted.
+func redisSentinelAddressFor(address string) (string, int, error) {
+	host, port := address, strconv.Itoa(redisSentinelPort)
+	if splitHost, splitPort, err := net.SplitHostPort(address); err == nil {
+		host, port = splitHost, splitPort
+	}
+
+	parsed, err := strconv.Atoi(port)
+	if host == "" || strings.ContainsAny(host, "/ ") || err != nil || parsed < 1 || parsed > 65535 {
+		return "", 0, errors.New("`spec.redis.sentinel.hosts` entry `" + address + "` must be a `host:port` address")
+	}
+
+	return host, parsed, nil
+}
+
+// validateRedisSentinel returns an error if `spec.redis.sentinel` cannot be used with the `redis` component. The
+// managed component runs its own Sentinels, which an unmanaged Redis must declare instead.
+func validateRedisSentinel(quay *v1.QuayRegistry) error {
+	if quay.Spec.Redis == nil || quay.Spec.Redis.Sentinel == nil {
+		return nil
+	}
+	sentinel := quay.Spec.Redis.Sentinel
+
+	if v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		if len(sentinel.Hosts) > 0 {
+			return errors.New("`spec.redis.sentinel.hosts` cannot be used with the managed `redis` component, which runs its own Sentinels")
+		}
+
+		return nil
+	}
+
+	if sentinel.Replicas != nil {
+		return errors.New("`spec.redis.sentinel.replicas` requires the managed `redis` component")
+	}
+	if len(sentinel.Hosts) == 0 {
+		return errors.New("`spec.redis.sentinel.hosts` is required when the `redis` component is unmanaged")
+	}
+	if sentinel.MasterName == "" {
+		return errors.New("`spec.redis.sentinel.masterName` is required when the `redis` component is unmanaged")
+	}
+	for _, address := range sentinel.Hosts {
+		if _, _, err := redisSentinelAddressFor(address); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withRedisSentinelConnection replaces the host and port of the given Redis connection with the Sentinels of
+// `spec.redis.sentinel` and the name of their master, which Quay asks the Sentinels for. Must be called after
+// `validateRedisSentinel`.
+func withRedisSentinelConnection(quay *v1.QuayRegistry, connection map[string]interface{}) {
+	if quay.Spec.Redis == nil || quay.Spec.Redis.Sentinel == nil {
+		return
+	}
+
+	sentinels := []interface{}{}
+	if v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		sentinels = append(sentinels, []interface{}{redisSentinelServiceName(quay), redisSentinelPort})
+	} else {
+		for _, address := range quay.Spec.Redis.Sentinel.Hosts {
+			host, port, _ := redisSentinelAddressFor(address)
+			sentinels = append(sentinels, []interface{}{host, port})
+		}
+	}
+
+	delete(connection, "host")
+	delete(connection, "port")
+	connection["sentinels"] = sentinels
+	connection["service_name"] = redisSentinelMasterNameFor(quay)
+}
+
+// redisSentinelAffinityFor returns the anti-affinity spreading the pods of the replicated Redis across nodes, which
+// is required if `spec.podAntiAffinity` is `Required`.
+func redisSentinelAffinityFor(quay *v1.QuayRegistry) *corev1.Affinity {
+	term := corev1.PodAffinityTerm{
+		TopologyKey:   "kubernetes.io/hostname",
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{redisSentinelLabel: quay.GetName()}},
+	}
+
+	if quay.Spec.PodAntiAffinity == v1.PodAntiAffinityRequired {
+		return &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+		}}
+	}
+
+	return &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{Weight: 100, PodAffinityTerm: term}},
+	}}
+}
+
+// withRedisSentinel replaces the `Deployment` of the managed `redis` component with a `StatefulSet` of Redis pods,
+// each running a Sentinel alongside, if `spec.redis.sentinel` is set. The pods are copied from the `Deployment`, and
+// reached by Quay through the `Service` of the Sentinels. Must be called after `withPodOverrides`.
+func withRedisSentinel(quay *v1.QuayRegistry, resources []k8sruntime.Object) []k8sruntime.Object {
+	if quay.Spec.Redis == nil || quay.Spec.Redis.Sentinel == nil || !v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		return resources
+	}
+
+	var deployment *apps.Deployment
+	filtered := []k8sruntime.Object{}
+	for _, resource := range resources {
+		if d, ok := resource.(*apps.Deployment); ok && d.GetName() == quay.GetName()+"-quay-redis" {
+			deployment = d
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	if deployment == nil || len(deployment.Spec.Template.Spec.Containers) == 0 {
+		return resources
+	}
+
+	replicas := v1.DefaultRedisSentinelReplicas
+	if quay.Spec.Redis.Sentinel.Replicas != nil {
+		replicas = *quay.Spec.Redis.Sentinel.Replicas
+	}
+	selector := map[string]string{"quay-component": "redis", redisSentinelLabel: quay.GetName()}
+
+	template := deployment.Spec.Template.DeepCopy()
+	for key, value := range selector {
+		template.Labels[key] = value
+	}
+	template.Spec.Affinity = redisSentinelAffinityFor(quay)
+
+	env := []corev1.EnvVar{
+		{Name: "SENTINEL_SERVICE", Value: redisSentinelServiceName(quay)},
+		{Name: "HEADLESS_SERVICE", Value: redisHeadlessServiceName(quay)},
+		{Name: "MASTER_NAME", Value: redisSentinelMasterNameFor(quay)},
+	}
+
+	redis := template.Spec.Containers[0]
+	redis.Command = []string{"sh", "-c", redisSentinelServerScript}
+	redis.Env = append(redis.Env, env...)
+
+	sentinelEnv := append([]corev1.EnvVar{}, redis.Env...)
+	sentinelEnv = append(sentinelEnv,
+		corev1.EnvVar{Name: "QUORUM", Value: strconv.Itoa(int(replicas/2 + 1))},
+		corev1.EnvVar{Name: "DOWN_AFTER_MILLISECONDS", Value: strconv.Itoa(redisSentinelDownAfter)},
+	)
+	sentinel := corev1.Container{
+		Name:            "sentinel",
+		Image:           redis.Image,
+		ImagePullPolicy: redis.ImagePullPolicy,
+		Command:         []string{"sh", "-c", redisSentinelScript},
+		Env:             sentinelEnv,
+		Ports:           []corev1.ContainerPort{{Name: "sentinel", ContainerPort: redisSentinelPort, Protocol: corev1.ProtocolTCP}},
+	}
+	template.Spec.Containers = []corev1.Container{redis, sentinel}
+
+	statefulSet := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        deployment.GetName(),
+			Namespace:   deployment.GetNamespace(),
+			Labels:      deployment.GetLabels(),
+			Annotations: deployment.GetAnnotations(),
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: redisHeadlessServiceName(quay),
+			Selector:    &metav1.LabelSelector{MatchLabels: selector},
+			Template:    *template,
+		},
+	}
+	statefulSet.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"})
+
+	// NOTE: The hostnames of the pods must resolve before they are ready, since Redis and Sentinel announce them.
+	headless := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisHeadlessServiceName(quay),
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": "redis"},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:                corev1.ClusterIPNone,
+			PublishNotReadyAddresses: true,
+			Selector:                 selector,
+			Ports: []corev1.ServicePort{
+				{Name: "redis", Port: 6379, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(6379)},
+				{Name: "sentinel", Port: redisSentinelPort, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(redisSentinelPort)},
+			},
+		},
+	}
+	headless.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+
+	sentinels := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redisSentinelServiceName(quay),
+			Namespace: quay.GetNamespace(),
+			Labels:    map[string]string{"quay-component": "redis"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: selector,
+			Ports: []corev1.ServicePort{
+				{Name: "sentinel", Port: redisSentinelPort, Protocol: corev1.ProtocolTCP, TargetPort: intstr.FromInt(redisSentinelPort)},
+			},
+		},
+	}
+	sentinels.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "Service"})
+
+	return append(filtered, statefulSet, headless, sentinels)
+}
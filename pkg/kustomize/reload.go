@@ -0,0 +1,198 @@
+package kustomize
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigChecksumAnnotation is the pod template annotation of the Quay `Deployments` with a checksum of the config
+// bundle updated in place, so that changing it restarts their pods.
+const ConfigChecksumAnnotation = "quay-operator/config-checksum"
+
+// restartOnlyConfigFields are the Quay config fields which only require the Quay pods to restart, rather than a new
+// config bundle which also rolls out the upgrade `Deployment` and the config editor.
+var restartOnlyConfigFields = map[string]bool{
+	"BRANDING":                          true,
+	"CONTACT_INFO":                      true,
+	"FOOTER_LINKS":                      true,
+	"REGISTRY_TITLE":                    true,
+	"REGISTRY_TITLE_SHORT":              true,
+	"SEARCH_MAX_RESULT_PAGE_COUNT":      true,
+	"SEARCH_RESULTS_PER_PAGE":           true,
+	"FEATURE_ANONYMOUS_ACCESS":          true,
+	"FEATURE_CHANGE_TAG_EXPIRATION":     true,
+	"FEATURE_DIRECT_LOGIN":              true,
+	"FEATURE_PARTIAL_USER_AUTOCOMPLETE": true,
+	"FEATURE_PUBLIC_CATALOG":            true,
+	"FEATURE_READER_BUILD_LOGS":         true,
+	"FEATURE_USER_CREATION":             true,
+	"FEATURE_USERNAME_CONFIRMATION":     true,
+	"FEATURE_REQUIRE_TEAM_INVITE":       true,
+	"FEATURE_INVITE_ONLY_USER_CREATION": true,
+	"FEATURE_USER_METADATA":             true,
+}
+
+// ConfigRestartOnly returns true if the only differences between the deployed and rendered config bundles, if any, are
+// in fields which only require the Quay pods to restart. Any other changed file, such as a certificate, requires a
+// new config bundle to be rolled out.
+func ConfigRestartOnly(deployed, rendered map[string][]byte) bool {
+	if len(deployed) != len(rendered) {
+		return false
+	}
+	for name, contents := range rendered {
+		deployedContents, ok := deployed[name]
+		if !ok {
+			return false
+		}
+		if name != "config.yaml" && string(contents) != string(deployedContents) {
+			return false
+		}
+	}
+
+	var deployedConfig, renderedConfig map[string]interface{}
+	if err := yaml.Unmarshal(deployed["config.yaml"], &deployedConfig); err != nil {
+		return false
+	}
+	if err := yaml.Unmarshal(rendered["config.yaml"], &renderedConfig); err != nil {
+		return false
+	}
+
+	for _, fields := range []map[string]interface{}{deployedConfig, renderedConfig} {
+		for field := range fields {
+			if !restartOnlyConfigFields[field] && !reflect.DeepEqual(deployedConfig[field], renderedConfig[field]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ConfigSecretNameFor returns the name of the config bundle `Secret` mounted by the given Quay `Deployment`, or an
+// empty string if there is none.
+func ConfigSecretNameFor(deployment *appsv1.Deployment) string {
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil && strings.Contains(volume.Secret.SecretName, configSecretPrefix+"-") {
+			return volume.Secret.SecretName
+		}
+	}
+
+	return ""
+}
+
+// WithConfigSecretName returns the objects with the rendered config bundle `Secret` renamed, and every reference to it
+// replaced, so that the `Secret` of the same name is updated in place instead of rolling out the `Deployments`. The
+// given objects are not modified.
+func WithConfigSecretName(objects []k8sruntime.Object, name string) []k8sruntime.Object {
+	rendered := ConfigSecretFor(objects)
+	if rendered == nil || rendered.GetName() == name {
+		return objects
+	}
+	previous := rendered.GetName()
+
+	renamed := []k8sruntime.Object{}
+	for _, obj := range objects {
+		var podSpec *corev1.PodSpec
+		switch o := obj.(type) {
+		case *corev1.Secret:
+			if o.GetName() != previous {
+				break
+			}
+			secret := o.DeepCopy()
+			secret.SetName(name)
+			obj = secret
+		case *appsv1.Deployment:
+			deployment := o.DeepCopy()
+			podSpec = &deployment.Spec.Template.Spec
+			obj = deployment
+		case *batchv1.Job:
+			job := o.DeepCopy()
+			podSpec = &job.Spec.Template.Spec
+			obj = job
+		}
+
+		if podSpec != nil {
+			for i := range podSpec.Volumes {
+				if secret := podSpec.Volumes[i].Secret; secret != nil && secret.SecretName == previous {
+					secret.SecretName = name
+				}
+			}
+			for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+				for i := range containers {
+					for j := range containers[i].Env {
+						if containers[i].Env[j].Value == previous {
+							containers[i].Env[j].Value = name
+						}
+					}
+				}
+			}
+		}
+
+		renamed = append(renamed, obj)
+	}
+
+	return renamed
+}
+
+// restartedComponents are the `quay-component` label values of the `Deployments` which read the config bundle, and
+// so are restarted when it is updated in place.
+var restartedComponents = map[string]bool{
+	"quay-app":             true,
+	MirrorWorkersComponent: true,
+	PruneWorkersComponent:  true,
+}
+
+// configChecksum returns a checksum of the files of the given config bundle.
+func configChecksum(data map[string][]byte) string {
+	names := []string{}
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checksum := sha256.New()
+	for _, name := range names {
+		checksum.Write([]byte(name))
+		checksum.Write(data[name])
+	}
+
+	return fmt.Sprintf("%x", checksum.Sum(nil))
+}
+
+// WithConfigChecksum returns the objects with a checksum of the rendered config bundle added to the pod templates of
+// the Quay `Deployments`, so that their pods are restarted to read a config bundle updated in place. Quay only reads
+// its config at startup. The given objects are not modified.
+func WithConfigChecksum(objects []k8sruntime.Object) []k8sruntime.Object {
+	rendered := ConfigSecretFor(objects)
+	if rendered == nil {
+		return objects
+	}
+	checksum := configChecksum(rendered.Data)
+
+	annotated := []k8sruntime.Object{}
+	for _, obj := range objects {
+		if deployment, ok := obj.(*appsv1.Deployment); ok && restartedComponents[deployment.GetLabels()["quay-component"]] {
+			deployment = deployment.DeepCopy()
+			annotations := deployment.Spec.Template.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[ConfigChecksumAnnotation] = checksum
+			deployment.Spec.Template.SetAnnotations(annotations)
+			obj = deployment
+		}
+
+		annotated = append(annotated, obj)
+	}
+
+	return annotated
+}
@@ -0,0 +1,96 @@
+// Package render returns the objects the Operator deploys for a `QuayRegistry`, without a cluster. It is intended for
+// tools and tests which check a `QuayRegistry` against the Operator's behavior, and is kept stable across releases.
+package render
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// Cluster describes the APIs and settings of the cluster which the Operator detects before rendering.
+type Cluster struct {
+	// SupportsRoutes is true if the OpenShift `Routes` API is available.
+	SupportsRoutes bool
+	// SupportsObjectBucketClaims is true if the `ObjectBucketClaims` API is available.
+	SupportsObjectBucketClaims bool
+	// Hostname is the ingress domain of the cluster, used to build the hostnames of `Routes`.
+	Hostname string
+}
+
+// Options are the inputs to `Render` other than the `QuayRegistry`.
+type Options struct {
+	// ConfigBundle is the data of the `Secret` referenced by `spec.configBundleSecret`. If it is nil, the base config
+	// bundle the Operator creates for a new registry is used.
+	ConfigBundle map[string][]byte
+	// SecretKeys are the secret keys from a previous render. They are generated if nil, so objects which contain
+	// them differ between renders.
+	SecretKeys map[string][]byte
+	// OperatorConfig is the cluster-wide `QuayOperatorConfig`, if any.
+	OperatorConfig *v1.QuayOperatorConfigSpec
+	// Cluster is the cluster the registry is deployed to.
+	Cluster Cluster
+	// Log receives the messages logged while rendering. Discarded if nil.
+	Log logr.Logger
+}
+
+// Render returns the full set of objects the Operator would create or update for the given `QuayRegistry`. The
+// `QuayRegistry` is defaulted as the Operator does first, setting `spec.desiredVersion` and `spec.components`. An
+// error is returned for a `QuayRegistry` the Operator would refuse to deploy.
+//
+// Credentials the Operator copies from `Secrets` referenced by the `QuayRegistry`, such as those of
+// `spec.objectStorage`, are not resolved, so they are rendered empty unless set in the annotations of the
+// `QuayRegistry` as the Operator sets them.
+func Render(quay *v1.QuayRegistry, opts Options) ([]k8sruntime.Object, error) {
+	prepared, err := Prepare(quay, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	configBundle := &corev1.Secret{Data: opts.ConfigBundle}
+	if configBundle.Data == nil {
+		baseConfig, err := yaml.Marshal(kustomize.BaseConfig())
+		if err != nil {
+			return nil, err
+		}
+		configBundle.Data = map[string][]byte{"config.yaml": baseConfig}
+	}
+
+	logger := opts.Log
+	if logger == nil {
+		logger = log.NullLogger{}
+	}
+
+	return kustomize.Inflate(prepared, configBundle, &corev1.Secret{Data: opts.SecretKeys}, logger)
+}
+
+// Prepare returns a copy of the `QuayRegistry` defaulted and annotated as the Operator does before rendering it.
+func Prepare(quay *v1.QuayRegistry, opts Options) (*v1.QuayRegistry, error) {
+	prepared := quay.DeepCopy()
+
+	annotations := prepared.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if opts.Cluster.SupportsRoutes {
+		annotations[v1.SupportsRoutesAnnotation] = "true"
+		prepared.Status.ClusterHostname = opts.Cluster.Hostname
+	}
+	if opts.Cluster.SupportsObjectBucketClaims {
+		annotations[v1.SupportsObjectStorageAnnotation] = "true"
+	}
+	prepared.SetAnnotations(annotations)
+	prepared.Status.OperatorConfig = opts.OperatorConfig
+
+	prepared, err := v1.EnsureDesiredVersion(prepared)
+	if err != nil {
+		return nil, err
+	}
+
+	return v1.EnsureDefaultComponents(prepared)
+}
@@ -0,0 +1,100 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+func namesOf(objects []k8sruntime.Object) []string {
+	names := []string{}
+	for _, obj := range objects {
+		objectMeta, _ := meta.Accessor(obj)
+		names = append(names, objectMeta.GetName())
+	}
+
+	return names
+}
+
+var renderTests = []struct {
+	name        string
+	quay        *v1.QuayRegistry
+	opts        Options
+	expected    []string
+	notExpected []string
+	expectedErr string
+}{
+	{
+		"ObjectBucketClaims",
+		&v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "quay"}},
+		Options{Cluster: Cluster{SupportsObjectBucketClaims: true}},
+		[]string{"skynet-quay-app", "skynet-quay-postgres", "skynet-clair", "skynet-quay-redis", "skynet-quay-datastore"},
+		[]string{"skynet-quay-minio"},
+		"",
+	},
+	{
+		"BareKubernetes",
+		&v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{
+			Name:        "skynet",
+			Namespace:   "quay",
+			Annotations: map[string]string{v1.MinIOAccessKeyAnnotation: "minio-user", v1.MinIOSecretKeyAnnotation: "minio-password"},
+		}},
+		Options{},
+		[]string{"skynet-quay-app", "skynet-quay-minio"},
+		[]string{"skynet-quay-datastore"},
+		"",
+	},
+	{
+		"ObjectStorageWithoutObjectBucketClaims",
+		&v1.QuayRegistry{
+			ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "quay"},
+			Spec: v1.QuayRegistrySpec{
+				Components: []v1.Component{{Kind: "objectstorage", Managed: true}},
+			},
+		},
+		Options{},
+		nil,
+		nil,
+		"cannot use `objectstorage` component when `ObjectBucketClaims` API not available",
+	},
+}
+
+func TestRender(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range renderTests {
+		objects, err := Render(test.quay, test.opts)
+
+		if test.expectedErr != "" {
+			assert.EqualError(err, test.expectedErr, test.name)
+			continue
+		}
+
+		assert.Nil(err, test.name)
+		names := namesOf(objects)
+		for _, name := range test.expected {
+			assert.Contains(names, name, test.name)
+		}
+		for _, name := range test.notExpected {
+			assert.NotContains(names, name, test.name)
+		}
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "skynet", Namespace: "quay"}}
+	prepared, err := Prepare(quay, Options{Cluster: Cluster{SupportsRoutes: true, Hostname: "apps.example.com"}})
+
+	assert.Nil(err)
+	assert.NotEqual(v1.QuayVersion(""), prepared.Spec.DesiredVersion)
+	assert.True(v1.ComponentIsManaged(prepared.Spec.Components, "route"))
+	assert.Equal("apps.example.com", prepared.Status.ClusterHostname)
+	assert.Nil(quay.GetAnnotations(), "input is not modified")
+}
@@ -0,0 +1,31 @@
+package kustomize
+
+import (
+	"errors"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// repositoryConfigFor returns the Quay config fields for `spec.repositories`, or nil if it is not set.
+func repositoryConfigFor(quay *v1.QuayRegistry) (map[string]interface{}, error) {
+	policy := quay.Spec.Repositories
+	if policy == nil {
+		return nil, nil
+	}
+
+	config := map[string]interface{}{}
+
+	switch policy.DefaultVisibility {
+	case "":
+	case v1.RepositoryVisibilityPublic, v1.RepositoryVisibilityPrivate:
+		config["CREATE_PRIVATE_REPO_ON_PUSH"] = policy.DefaultVisibility == v1.RepositoryVisibilityPrivate
+	default:
+		return nil, errors.New("unsupported `spec.repositories.defaultVisibility`: " + string(policy.DefaultVisibility))
+	}
+
+	if policy.CreateNamespaceOnPush != nil {
+		config["CREATE_NAMESPACE_ON_PUSH"] = *policy.CreateNamespaceOnPush
+	}
+
+	return config, nil
+}
@@ -0,0 +1,208 @@
+package kustomize
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	route "github.com/openshift/api/route/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	routeTimeoutAnnotation               = "haproxy.router.openshift.io/timeout"
+	routeHSTSAnnotation                  = "haproxy.router.openshift.io/hsts_header"
+	routeRateLimitAnnotation             = "haproxy.router.openshift.io/rate-limit-connections"
+	routeConcurrentConnectionsAnnotation = "haproxy.router.openshift.io/rate-limit-connections.concurrent-tcp"
+	routeConnectionRateAnnotation        = "haproxy.router.openshift.io/rate-limit-connections.rate-tcp"
+	routeIPWhitelistAnnotation           = "haproxy.router.openshift.io/ip_whitelist"
+
+	// routeSurfaceLabel tells apart the web UI and registry API `Routes`, so policies can select either of them.
+	routeSurfaceLabel = "quay-route-surface"
+	// registryAPIPath is the path of the registry API served by its own `Route`.
+	registryAPIPath = "/v2"
+)
+
+// routeAnnotationsFor returns the HAProxy annotations of the managed Quay `Route` for the given `spec.route`.
+func routeAnnotationsFor(settings *v1.RouteSettings) map[string]string {
+	annotations := map[string]string{}
+	if settings == nil {
+		return annotations
+	}
+
+	if settings.Timeout != nil && settings.Timeout.Duration > 0 {
+		annotations[routeTimeoutAnnotation] = strconv.FormatInt(int64(settings.Timeout.Seconds()), 10) + "s"
+	}
+
+	if hsts := settings.HSTS; hsts != nil {
+		directives := []string{"max-age=" + strconv.FormatInt(int64(hsts.MaxAge.Seconds()), 10)}
+		if hsts.IncludeSubDomains {
+			directives = append(directives, "includeSubDomains")
+		}
+		if hsts.Preload {
+			directives = append(directives, "preload")
+		}
+		annotations[routeHSTSAnnotation] = strings.Join(directives, ";")
+	}
+
+	if limit := settings.RateLimit; limit != nil && (limit.ConcurrentConnections != nil || limit.ConnectionRate != nil) {
+		annotations[routeRateLimitAnnotation] = "true"
+		if limit.ConcurrentConnections != nil {
+			annotations[routeConcurrentConnectionsAnnotation] = strconv.Itoa(int(*limit.ConcurrentConnections))
+		}
+		if limit.ConnectionRate != nil {
+			annotations[routeConnectionRateAnnotation] = strconv.Itoa(int(*limit.ConnectionRate))
+		}
+	}
+
+	if len(settings.AllowedSourceRanges) > 0 {
+		annotations[routeIPWhitelistAnnotation] = strings.Join(settings.AllowedSourceRanges, " ")
+	}
+
+	return annotations
+}
+
+// validateRouteSourceRanges checks that each of `spec.route.allowedSourceRanges` is an IP address or CIDR range, and
+// that there is a managed `Route` to restrict.
+func validateRouteSourceRanges(quay *v1.QuayRegistry) error {
+	if quay.Spec.Route == nil || len(quay.Spec.Route.AllowedSourceRanges) == 0 {
+		return nil
+	}
+
+	if !v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		return errors.New("`spec.route.allowedSourceRanges` requires the `route` component to be managed")
+	}
+	for _, sourceRange := range quay.Spec.Route.AllowedSourceRanges {
+		if _, _, err := net.ParseCIDR(sourceRange); err == nil {
+			continue
+		}
+		if net.ParseIP(sourceRange) == nil {
+			return errors.New("`spec.route.allowedSourceRanges` entry `" + sourceRange + "` is not an IP address or CIDR range")
+		}
+	}
+
+	return nil
+}
+
+// routePatchesFor returns the Kustomize patches which annotate the managed Quay `Route` with `spec.route`.
+func routePatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+
+	annotations := routeAnnotationsFor(quay.Spec.Route)
+	if len(annotations) == 0 || !v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		return patches
+	}
+
+	return append(patches, types.Patch{
+		Patch: string(encode(map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata": map[string]interface{}{
+				"name":        "quay",
+				"annotations": annotations,
+			},
+		})),
+	})
+}
+
+// registryAPIHostnameFor returns the hostname of the registry API `Route` if it differs from `SERVER_HOSTNAME`, or an
+// empty string otherwise.
+func registryAPIHostnameFor(quay *v1.QuayRegistry) string {
+	if quay.Spec.Route == nil || quay.Spec.Route.RegistryAPI == nil {
+		return ""
+	}
+
+	return quay.Spec.Route.RegistryAPI.Hostname
+}
+
+// validateRegistryAPIRoute checks that `spec.route.registryAPI` can be used with the given `SERVER_HOSTNAME`.
+func validateRegistryAPIRoute(quay *v1.QuayRegistry, hostname string) error {
+	if quay.Spec.Route == nil || quay.Spec.Route.RegistryAPI == nil {
+		return nil
+	}
+
+	if !v1.ComponentIsManaged(quay.Spec.Components, "route") {
+		return errors.New("`spec.route.registryAPI` requires the `route` component to be managed")
+	}
+	if apiHostname := registryAPIHostnameFor(quay); apiHostname != "" && apiHostname == strings.Split(hostname, ":")[0] {
+		return errors.New("`spec.route.registryAPI.hostname` must differ from `SERVER_HOSTNAME`, or be omitted to split the registry API by path")
+	}
+
+	return nil
+}
+
+// withRouteSurface labels the given `Route` as serving the given surface.
+func withRouteSurface(r *route.Route, surface string) {
+	labels := r.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[routeSurfaceLabel] = surface
+	r.SetLabels(labels)
+}
+
+// withRegistryAPIRoute adds a `Route` for the registry API from `spec.route.registryAPI`, copied from the managed
+// Quay `Route`. Since the router must terminate TLS to route by path, both `Routes` sharing a hostname re-encrypt to
+// Quay, presenting and trusting the given certificate of Quay.
+func withRegistryAPIRoute(quay *v1.QuayRegistry, objects []k8sruntime.Object, cert, key []byte) []k8sruntime.Object {
+	settings := quay.Spec.Route
+	if settings == nil || settings.RegistryAPI == nil {
+		return objects
+	}
+
+	for _, obj := range objects {
+		uiRoute, ok := obj.(*route.Route)
+		if !ok || uiRoute.GetName() != quay.GetName()+"-quay" {
+			continue
+		}
+
+		tls := &route.TLSConfig{
+			Termination:                   route.TLSTerminationReencrypt,
+			Certificate:                   string(cert),
+			Key:                           string(key),
+			DestinationCACertificate:      string(cert),
+			InsecureEdgeTerminationPolicy: route.InsecureEdgeTerminationPolicyRedirect,
+		}
+
+		apiRoute := uiRoute.DeepCopy()
+		apiRoute.SetName(quay.GetName() + "-quay-registry-api")
+		apiRoute.Spec.Path = registryAPIPath
+		apiRoute.Spec.TLS = tls
+		if hostname := registryAPIHostnameFor(quay); hostname != "" {
+			apiRoute.Spec.Host = hostname
+		} else {
+			uiRoute.Spec.TLS = tls.DeepCopy()
+		}
+
+		// The settings of the web UI are replaced by those of the registry API, except for HSTS and the allowed source
+		// ranges which are shared.
+		uiAnnotations := routeAnnotationsFor(settings)
+		annotations := map[string]string{}
+		for key, value := range apiRoute.GetAnnotations() {
+			if _, ok := uiAnnotations[key]; !ok {
+				annotations[key] = value
+			}
+		}
+		apiSettings := &v1.RouteSettings{
+			Timeout:             settings.RegistryAPI.Timeout,
+			HSTS:                settings.HSTS,
+			RateLimit:           settings.RegistryAPI.RateLimit,
+			AllowedSourceRanges: settings.AllowedSourceRanges,
+		}
+		for key, value := range routeAnnotationsFor(apiSettings) {
+			annotations[key] = value
+		}
+		apiRoute.SetAnnotations(annotations)
+
+		withRouteSurface(uiRoute, "ui")
+		withRouteSurface(apiRoute, "registry-api")
+
+		return append(objects, apiRoute)
+	}
+
+	return objects
+}
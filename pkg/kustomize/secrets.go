@@ -2,6 +2,7 @@ package kustomize
 
 import (
 	"crypto/rand"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -10,14 +11,11 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/quay/clair/v4/config"
 	"github.com/quay/clair/v4/notifier/webhook"
-	"github.com/quay/config-tool/pkg/lib/fieldgroups/database"
-	"github.com/quay/config-tool/pkg/lib/fieldgroups/distributedstorage"
 	"github.com/quay/config-tool/pkg/lib/fieldgroups/hostsettings"
-	"github.com/quay/config-tool/pkg/lib/fieldgroups/redis"
-	"github.com/quay/config-tool/pkg/lib/fieldgroups/securityscanner"
 	"github.com/quay/config-tool/pkg/lib/shared"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/util/cert"
 	"sigs.k8s.io/yaml"
 
@@ -27,7 +25,21 @@ import (
 const (
 	// secretKeySecretName is the name of the Secret in which generated secret keys are stored.
 	secretKeySecretName = "quay-registry-managed-secret-keys"
-	secretKeyLength     = 80
+	// secretKeysBackupName is the name of the Secret in which the encrypted backup of the secret keys is stored.
+	secretKeysBackupName = "quay-registry-secret-keys-backup"
+	secretKeyLength      = 80
+
+	// DatabasePasswordKey is the key of the secret keys Secret in which the password of the managed database is stored.
+	DatabasePasswordKey = "DATABASE_PASSWORD"
+	// ClairDatabasePasswordKey is the key of the secret keys Secret in which the password of the database of the
+	// managed `clair` component is stored.
+	ClairDatabasePasswordKey = "CLAIR_DATABASE_PASSWORD"
+	// RedisPasswordKey is the key of the secret keys Secret in which the password of the managed `redis` component is
+	// stored.
+	RedisPasswordKey = "REDIS_PASSWORD"
+	// legacyDatabasePassword is the password of managed databases created before their password was generated.
+	legacyDatabasePassword = "postgres"
+	databasePasswordLength = 32
 )
 
 // SecretKeySecretName returns the name of the Secret in which generated secret keys are stored.
@@ -35,155 +47,169 @@ func SecretKeySecretName(quay *v1.QuayRegistry) string {
 	return quay.GetName() + "-" + secretKeySecretName
 }
 
-// generateKeyIfMissing checks if the given key is in the parsed config. If not, the secretKeysSecret
-// is checked for the key. If not present, a new key is generated.
-func generateKeyIfMissing(parsedConfig map[string]interface{}, secretKeysSecret *corev1.Secret, keyName string, quay *v1.QuayRegistry, log logr.Logger) (string, *corev1.Secret) {
+// SecretKeysBackupName returns the name of the Secret in which the encrypted backup of the secret keys is stored.
+func SecretKeysBackupName(quay *v1.QuayRegistry) string {
+	return quay.GetName() + "-" + secretKeysBackupName
+}
+
+// DatabaseSecretKeyFor returns the `DATABASE_SECRET_KEY` of the rendered config bundle, or an empty string if it is
+// not set.
+func DatabaseSecretKeyFor(objects []k8sruntime.Object) string {
+	configSecret := ConfigSecretFor(objects)
+	if configSecret == nil {
+		return ""
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configSecret.Data["config.yaml"], &config); err != nil {
+		return ""
+	}
+	key, _ := config["DATABASE_SECRET_KEY"].(string)
+
+	return key
+}
+
+// secretKeyNames are the config fields which are generated and stored in the secret keys `Secret` when they are
+// not provided in the config bundle.
+var secretKeyNames = []string{"SECRET_KEY", "DATABASE_SECRET_KEY"}
+
+// generateKeyIfMissing checks if the given key is in the parsed config. If not, the stored keys are checked for the
+// key. If not present, a new key is generated and added to the stored keys. Returns true if a key was generated.
+func generateKeyIfMissing(parsedConfig map[string]interface{}, storedKeys map[string][]byte, keyName string, log logr.Logger) (string, bool) {
 	// Check for the user-given key in config.
 	found, ok := parsedConfig[keyName]
 	if ok {
 		log.Info("Secret key found in provided config", "keyName", keyName)
-		return found.(string), secretKeysSecret
+		return found.(string), false
 	}
 
-	// If not found in the given config, check the secret keys Secret.
-	if secretKeysSecret == nil {
-		log.Info("Creating a new secret key Secret")
-		secretKeysSecret = &corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      SecretKeySecretName(quay),
-				Namespace: quay.Namespace,
-			},
-			StringData: map[string]string{},
-		}
+	if foundSecretKey, ok := storedKeys[keyName]; ok {
+		log.Info("Secret key found in managed secret", "keyName", keyName)
+		return string(foundSecretKey), false
 	}
 
-	foundSecretKey, ok := secretKeysSecret.Data[keyName]
-	if ok {
-		log.Info("Secret key found in managed secret", "keyName", keyName)
-		return string(foundSecretKey), secretKeysSecret
-	} else {
-		log.Info("Generating secret key", "keyName", keyName)
-		generatedSecretKey, err := generateRandomString(secretKeyLength)
-		check(err)
+	log.Info("Generating secret key", "keyName", keyName)
+	generatedSecretKey, err := generateRandomString(secretKeyLength)
+	check(err)
+	storedKeys[keyName] = []byte(generatedSecretKey)
 
-		stringData := secretKeysSecret.StringData
-		if stringData == nil {
-			stringData = map[string]string{}
-		}
+	return generatedSecretKey, true
+}
 
-		secretKeysSecret = &corev1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      SecretKeySecretName(quay),
-				Namespace: quay.Namespace,
-			},
-			Data:       secretKeysSecret.Data,
-			StringData: stringData,
+// DatabasePasswordFor returns the password of the managed database stored in the secret keys `Secret`. A database
+// created before passwords were generated keeps the password of the `postgres` image, since it is only set when the
+// database is initialized. Returns an empty string if a password has yet to be generated.
+func DatabasePasswordFor(quay *v1.QuayRegistry, secretKeysSecret *corev1.Secret) string {
+	if secretKeysSecret != nil {
+		if password, ok := secretKeysSecret.Data[DatabasePasswordKey]; ok {
+			return string(password)
 		}
+	}
+	if quay.Status.Postgres != nil {
+		return legacyDatabasePassword
+	}
+
+	return ""
+}
 
-		secretKeysSecret.StringData[keyName] = generatedSecretKey
-		return generatedSecretKey, secretKeysSecret
+// ClairDatabasePasswordFor returns the password of the database of the managed `clair` component stored in the secret
+// keys `Secret`. A database deployed before passwords were generated for Clair keeps the password of the `postgres`
+// image, since it is only set when the database is initialized. Returns an empty string if a password has yet to be
+// generated.
+func ClairDatabasePasswordFor(quay *v1.QuayRegistry, secretKeysSecret *corev1.Secret) string {
+	if secretKeysSecret != nil {
+		if password, ok := secretKeysSecret.Data[ClairDatabasePasswordKey]; ok {
+			return string(password)
+		}
+	}
+	if quay.Status.ClairPostgresImage != "" {
+		return legacyDatabasePassword
 	}
+
+	return ""
 }
 
-// handleSecretKeys generates any secret keys not already present in the config bundle and adds them
-// to the specialized secretKeysSecret.
-func handleSecretKeys(parsedConfig map[string]interface{}, secretKeysSecret *corev1.Secret, quay *v1.QuayRegistry, log logr.Logger) (string, string, *corev1.Secret) {
-	// Check for SECRET_KEY and DATABASE_SECRET_KEY. If not present, generate them
-	// and place them into their own Secret.
-	secretKey, secretKeysSecret := generateKeyIfMissing(parsedConfig, secretKeysSecret, "SECRET_KEY", quay, log)
-	databaseSecretKey, secretKeysSecret := generateKeyIfMissing(parsedConfig, secretKeysSecret, "DATABASE_SECRET_KEY", quay, log)
-	return secretKey, databaseSecretKey, secretKeysSecret
+// handleDatabasePassword returns the given stored password of a managed database, or generates one if there is none,
+// and stores it under the given key. Returns true if the password was not stored yet.
+func handleDatabasePassword(storedKeys map[string][]byte, keyName, password string, log logr.Logger) (string, bool) {
+	if password == "" {
+		log.Info("Generating password for managed database", "keyName", keyName)
+		generatedPassword, err := generateRandomString(databasePasswordLength)
+		check(err)
+		password = generatedPassword
+	}
+	if _, ok := storedKeys[keyName]; ok {
+		return password, false
+	}
+	storedKeys[keyName] = []byte(password)
+
+	return password, true
 }
 
-// FieldGroupFor generates and returns the correct config field group for the given component.
-func FieldGroupFor(component string, quay *v1.QuayRegistry) (shared.FieldGroup, error) {
-	switch component {
-	case "clair":
-		fieldGroup, err := securityscanner.NewSecurityScannerFieldGroup(map[string]interface{}{})
-		if err != nil {
-			return nil, err
+// handleSecretKeys generates any secret keys not already present in the config bundle and adds them
+// to the specialized secretKeysSecret, along with the passwords of the managed databases of Quay and Clair and of the
+// managed Redis, which are generated independently. Returns the keys by name.
+// The `Secret` is only returned if a key was generated, so an unchanged `Secret` is never rewritten.
+func handleSecretKeys(parsedConfig map[string]interface{}, secretKeysSecret *corev1.Secret, quay *v1.QuayRegistry, log logr.Logger) (map[string]string, *corev1.Secret) {
+	// NOTE: Keys which are no longer used (because they are now in the config bundle) are kept, in case they return.
+	storedKeys := map[string][]byte{}
+	if secretKeysSecret != nil {
+		for keyName, value := range secretKeysSecret.Data {
+			storedKeys[keyName] = value
 		}
+	}
 
-		fieldGroup.FeatureSecurityScanner = true
-		fieldGroup.SecurityScannerV4Endpoint = "http://" + quay.GetName() + "-" + "clair:80"
-		fieldGroup.SecurityScannerV4NamespaceWhitelist = []string{"admin"}
+	keys := map[string]string{}
+	generated := false
+	for _, keyName := range secretKeyNames {
+		key, keyGenerated := generateKeyIfMissing(parsedConfig, storedKeys, keyName, log)
+		keys[keyName] = key
+		generated = generated || keyGenerated
+	}
 
-		return fieldGroup, nil
-	case "redis":
-		fieldGroup, err := redis.NewRedisFieldGroup(map[string]interface{}{})
-		if err != nil {
-			return nil, err
-		}
+	if v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		password, passwordGenerated := handleDatabasePassword(storedKeys, DatabasePasswordKey, DatabasePasswordFor(quay, secretKeysSecret), log)
+		keys[DatabasePasswordKey] = password
+		generated = generated || passwordGenerated
+	}
+	if v1.ComponentIsManaged(quay.Spec.Components, "clair") {
+		password, passwordGenerated := handleDatabasePassword(storedKeys, ClairDatabasePasswordKey, ClairDatabasePasswordFor(quay, secretKeysSecret), log)
+		keys[ClairDatabasePasswordKey] = password
+		generated = generated || passwordGenerated
+	}
+	if v1.ComponentIsManaged(quay.Spec.Components, "redis") {
+		// NOTE: Redis only holds transient data, so a registry created before its password was generated switches to
+		// a generated one, which Redis and Quay are both rolled out with.
+		password, passwordGenerated := handleDatabasePassword(storedKeys, RedisPasswordKey, string(storedKeys[RedisPasswordKey]), log)
+		keys[RedisPasswordKey] = password
+		generated = generated || passwordGenerated
+	}
 
-		fieldGroup.BuildlogsRedis = &redis.BuildlogsRedisStruct{
-			Host: strings.Join([]string{quay.GetName(), "quay-redis"}, "-"),
-			Port: 6379,
-		}
-		fieldGroup.UserEventsRedis = &redis.UserEventsRedisStruct{
-			Host: strings.Join([]string{quay.GetName(), "quay-redis"}, "-"),
-			Port: 6379,
-		}
+	if !generated {
+		return keys, nil
+	}
 
-		return fieldGroup, nil
-	case "postgres":
-		fieldGroup, err := database.NewDatabaseFieldGroup(map[string]interface{}{})
-		if err != nil {
-			return nil, err
-		}
-		user := "postgres"
-		// FIXME(alecmerdler): Make this more secure...
-		password := "postgres"
-		host := strings.Join([]string{quay.GetName(), "quay-postgres"}, "-")
-		port := "5432"
-		name := "quay"
-		fieldGroup.DbUri = fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", user, password, host, port, name)
-
-		return fieldGroup, nil
-	case "objectstorage":
-		hostname := quay.GetAnnotations()[v1.StorageHostnameAnnotation]
-		bucketName := quay.GetAnnotations()[v1.StorageBucketNameAnnotation]
-		accessKey := quay.GetAnnotations()[v1.StorageAccessKeyAnnotation]
-		secretKey := quay.GetAnnotations()[v1.StorageSecretKeyAnnotation]
-
-		fieldGroup := &distributedstorage.DistributedStorageFieldGroup{
-			FeatureProxyStorage:                true,
-			DistributedStoragePreference:       []string{"local_us"},
-			DistributedStorageDefaultLocations: []string{"local_us"},
-			DistributedStorageConfig: map[string]*distributedstorage.DistributedStorageDefinition{
-				"local_us": {
-					Name: "RadosGWStorage",
-					Args: &shared.DistributedStorageArgs{
-						Hostname:    hostname,
-						IsSecure:    true,
-						Port:        443,
-						StoragePath: "/datastorage/registry",
-						BucketName:  bucketName,
-						AccessKey:   accessKey,
-						SecretKey:   secretKey,
-					},
-				},
-			},
-		}
+	log.Info("Updating secret keys Secret with generated keys")
+	updatedSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SecretKeySecretName(quay),
+			Namespace: quay.GetNamespace(),
+		},
+		Data: storedKeys,
+	}
 
-		return fieldGroup, nil
-	case "route":
-		clusterHostname := quay.GetAnnotations()[v1.ClusterHostnameAnnotation]
-
-		fieldGroup := &hostsettings.HostSettingsFieldGroup{
-			ExternalTlsTermination: false,
-			PreferredUrlScheme:     "https",
-			ServerHostname: strings.Join([]string{
-				strings.Join([]string{quay.GetName(), "quay", quay.GetNamespace()}, "-"),
-				clusterHostname},
-				"."),
-		}
+	return keys, updatedSecret
+}
 
-		return fieldGroup, nil
-	case "horizontalpodautoscaler":
-		return nil, nil
-	default:
-		return nil, errors.New("unknown component: " + component)
+// FieldGroupFor generates and returns the correct config field group for the given component.
+func FieldGroupFor(component string, quay *v1.QuayRegistry) (shared.FieldGroup, error) {
+	provider, err := ComponentProviderFor(component)
+	if err != nil {
+		return nil, err
 	}
+	_, fieldGroup, err := provider.FieldGroup(quay)
+
+	return fieldGroup, err
 }
 
 // BaseConfig returns a minimum config bundle with values that Quay doesn't have defaults for.
@@ -205,69 +231,44 @@ func BaseConfig() map[string]interface{} {
 
 // CustomTLSFor generates a TLS certificate/key pair for the Quay registry to use for secure communication with clients.
 func CustomTLSFor(quay *v1.QuayRegistry, baseConfig map[string]interface{}) ([]byte, []byte, error) {
-	routeConfigFiles := configFilesFor("route", quay, baseConfig)
-	var fieldGroup hostsettings.HostSettingsFieldGroup
-	if err := yaml.Unmarshal(routeConfigFiles["route.config.yaml"], &fieldGroup); err != nil {
-		return nil, nil, err
-	}
-
-	return cert.GenerateSelfSignedCertKey(fieldGroup.ServerHostname, []net.IP{}, []string{})
-}
-
-func configFilesFor(component string, quay *v1.QuayRegistry, baseConfig map[string]interface{}) map[string][]byte {
-	configFiles := map[string][]byte{}
-	fieldGroup, err := FieldGroupFor(component, quay)
-	check(err)
-
-	switch component {
-	case "clair":
-	case "postgres":
-	case "redis":
-	case "objectstorage":
-	case "horizontalpodautoscaler":
-	case "route":
-		hostSettings := fieldGroup.(*hostsettings.HostSettingsFieldGroup)
-
-		if hostname, ok := baseConfig["SERVER_HOSTNAME"]; ok {
-			configFiles[registryHostnameKey] = []byte(hostname.(string))
-			hostSettings.ServerHostname = hostname.(string)
+	hostname := serverHostnameFor(quay, baseConfig)
+	if hostname == "" {
+		fieldGroup, err := FieldGroupFor("route", quay)
+		if err != nil {
+			return nil, nil, err
 		}
-	default:
-		panic("unknown component: " + component)
+		hostname = fieldGroup.(*hostsettings.HostSettingsFieldGroup).ServerHostname
 	}
 
-	configFiles[component+".config.yaml"] = encode(fieldGroup)
+	alternateDNS := []string{}
+	if apiHostname := registryAPIHostnameFor(quay); apiHostname != "" {
+		alternateDNS = append(alternateDNS, apiHostname)
+	}
 
-	return configFiles
+	return cert.GenerateSelfSignedCertKey(hostname, []net.IP{}, alternateDNS)
 }
 
-func fieldGroupFor(component string) string {
-	switch component {
-	case "clair":
-		return "SecurityScanner"
-	case "postgres":
-		return "Database"
-	case "redis":
-		return "Redis"
-	case "objectstorage":
-		return "DistributedStorage"
-	case "route":
-		return "HostSettings"
-	case "horizontalpodautoscaler":
-		return ""
-	default:
-		panic("unknown component: " + component)
+// validateTLSFor checks the TLS certificate/key pair provided in the config bundle match each other and, if the
+// registry hostname is known, the hostname.
+func validateTLSFor(quay *v1.QuayRegistry, userConfig map[string]interface{}, cert, key []byte) error {
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return v1.NewReasonedError(v1.ErrorReasonCertInvalid, errors.New("invalid `ssl.cert`/`ssl.key` in config bundle: "+err.Error()))
 	}
-}
 
-// componentConfigFilesFor returns specific config files for managed components of a Quay registry.
-func componentConfigFilesFor(component string, quay *v1.QuayRegistry) (map[string][]byte, error) {
-	switch component {
-	case "clair":
-		return map[string][]byte{"config.yaml": clairConfigFor(quay)}, nil
-	default:
-		return nil, nil
+	hostname := serverHostnameFor(quay, userConfig)
+	if hostname == "" {
+		return nil
+	}
+	if ok, validationErr := shared.ValidateCertPairWithHostname(cert, key, strings.Split(hostname, ":")[0], "HostSettings"); !ok {
+		return v1.NewReasonedError(v1.ErrorReasonCertInvalid, errors.New("`ssl.cert` in config bundle is not valid for `SERVER_HOSTNAME`: "+validationErr.Message))
+	}
+	if apiHostname := registryAPIHostnameFor(quay); apiHostname != "" {
+		if ok, validationErr := shared.ValidateCertPairWithHostname(cert, key, apiHostname, "HostSettings"); !ok {
+			return v1.NewReasonedError(v1.ErrorReasonCertInvalid, errors.New("`ssl.cert` in config bundle is not valid for `spec.route.registryAPI.hostname`: "+validationErr.Message))
+		}
 	}
+
+	return nil
 }
 
 // clairConfigFor returns a Clair v4 config with the correct values.
@@ -275,22 +276,29 @@ func clairConfigFor(quay *v1.QuayRegistry) []byte {
 	host := strings.Join([]string{quay.GetName(), "clair-postgres"}, "-")
 	dbname := "clair"
 	user := "postgres"
-	// FIXME(alecmerdler): Make this more secure...
-	password := "postgres"
+	password := quay.GetAnnotations()[v1.ClairDatabasePasswordAnnotation]
+
+	sslmode := "sslmode=disable"
+	if v1.PostgresTLSEnabled(quay) {
+		sslmode = "sslmode=verify-full sslrootcert=" + clairCertsDir + "/" + ClairDatabaseCAFile
+	}
 
-	dbConn := fmt.Sprintf("host=%s port=5432 dbname=%s user=%s password=%s sslmode=disable", host, dbname, user, password)
+	dbConn := fmt.Sprintf("host=%s port=5432 dbname=%s user=%s password=%s %s", host, dbname, user, password, sslmode)
 	config := config.Config{
-		HTTPListenAddr: ":8080",
-		LogLevel:       "debug",
+		HTTPListenAddr:    ":8080",
+		IntrospectionAddr: ":8089",
+		LogLevel:          "debug",
 		Indexer: config.Indexer{
 			ConnString:           dbConn,
 			ScanLockRetry:        10,
 			LayerScanConcurrency: 5,
 			Migrations:           true,
 		},
+		// NOTE: Replicas take a lock in the shared database before indexing a manifest or running an updater, so no
+		// replica needs to be elected to run them.
 		Matcher: config.Matcher{
 			ConnString:  dbConn,
-			MaxConnPool: 100,
+			MaxConnPool: clairMatcherConnectionsFor(quay),
 			Migrations:  true,
 		},
 		Notifier: config.Notifier{
@@ -301,19 +309,30 @@ func clairConfigFor(quay *v1.QuayRegistry) []byte {
 			Webhook: &webhook.Config{
 				// FIXME(alecmerdler): Need to use HTTPS when Quay has a custom hostname + SSL cert/keys...
 				Target:   "http://" + quay.GetName() + "-quay-app/secscan/notification",
-				Callback: "http://" + quay.GetName() + "-clair/notifier/api/v1/notifications",
+				Callback: clairSchemeFor(quay) + "://" + quay.GetName() + "-clair/notifier/api/v1/notifications",
 			},
 		},
 		// FIXME(alecmerdler): Create pre-shared key for JWT auth between Quay/Clair...
 		// Auth: config.Auth{},
-		Metrics: config.Metrics{
-			Name: "prometheus",
-		},
+		Metrics: clairMetricsFor(quay),
+	}
+
+	if updaters := quay.Spec.ClairUpdaters; updaters != nil {
+		for _, set := range updaters.Sets {
+			config.Updaters.Sets = append(config.Updaters.Sets, string(set))
+		}
+		if updaters.Period != nil {
+			config.Matcher.Period = &updaters.Period.Duration
+		}
 	}
 
 	marshalled, err := yaml.Marshal(config)
 	check(err)
 
+	if v1.ClairTLSEnabled(quay) {
+		return withClairTLSConfig(marshalled)
+	}
+
 	return marshalled
 }
 
@@ -0,0 +1,218 @@
+package kustomize
+
+import (
+	"errors"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// StorageMigrationComponent is the `quay-component` label value of the `Jobs` which run each step of a storage migration.
+const StorageMigrationComponent = "quay-storage-migration"
+
+// storageMigrationJobDeadline is how long a migration step may run before it is considered failed.
+const storageMigrationJobDeadline = int64(60 * 60 * 24)
+
+var storageFields = []string{
+	"DISTRIBUTED_STORAGE_CONFIG",
+	"DISTRIBUTED_STORAGE_PREFERENCE",
+	"DISTRIBUTED_STORAGE_DEFAULT_LOCATIONS",
+	"FEATURE_STORAGE_REPLICATION",
+}
+
+// verifyReplicationScript polls the Quay database until every uploaded blob has a placement in the target location.
+const verifyReplicationScript = `
+import sys
+import time
+
+from app import app
+from data.database import ImageStorage, ImageStorageLocation, ImageStoragePlacement
+
+target = sys.argv[1]
+
+while True:
+    total = ImageStorage.select().where(ImageStorage.uploading == False).count()
+    replicated = (ImageStoragePlacement.select()
+        .join(ImageStorageLocation)
+        .switch(ImageStoragePlacement)
+        .join(ImageStorage)
+        .where(ImageStorageLocation.name == target, ImageStorage.uploading == False)
+        .count())
+
+    print("%d of %d blobs replicated to %s" % (replicated, total, target))
+    sys.stdout.flush()
+    if replicated >= total:
+        sys.exit(0)
+
+    time.sleep(30)
+`
+
+// storageConfigFor returns the storage fields of the config which Quay would otherwise run with, including any
+// locations generated for managed object storage.
+func storageConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	storage := map[string]interface{}{}
+	for _, field := range storageFields {
+		if value, ok := userConfig[field]; ok {
+			storage[field] = value
+		}
+	}
+
+	if v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		fieldGroup, err := FieldGroupFor("objectstorage", quay)
+		if err != nil {
+			return nil, err
+		}
+
+		var managed map[string]interface{}
+		if err := yaml.Unmarshal(encode(fieldGroup), &managed); err != nil {
+			return nil, err
+		}
+
+		// Keep locations defined in the config bundle (such as the migration target) alongside the managed one.
+		userLocations, _ := storage["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+		for field, value := range managed {
+			storage[field] = value
+		}
+		locations, _ := storage["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+		for name, location := range userLocations {
+			if _, ok := locations[name]; !ok {
+				locations[name] = location
+			}
+		}
+	}
+
+	return storage, nil
+}
+
+func stringsFrom(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := []string{}
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+
+	return strs
+}
+
+func without(values []string, value string) []string {
+	filtered := []string{}
+	for _, v := range values {
+		if v != value {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// storageMigrationConfigFor returns the storage config fields for the current phase of the storage migration,
+// or nil if no migration is declared.
+func storageMigrationConfigFor(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, error) {
+	phase := v1.StorageMigrationPhaseFor(quay)
+	if phase == "" {
+		return nil, nil
+	}
+
+	storage, err := storageConfigFor(quay, userConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	target := quay.Spec.StorageMigration.TargetLocation
+	locations, _ := storage["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+	if _, ok := locations[target]; !ok {
+		return nil, errors.New("storage migration target location `" + target + "` is not defined in `DISTRIBUTED_STORAGE_CONFIG`")
+	}
+
+	preference := without(stringsFrom(storage["DISTRIBUTED_STORAGE_PREFERENCE"]), target)
+	defaultLocations := without(stringsFrom(storage["DISTRIBUTED_STORAGE_DEFAULT_LOCATIONS"]), target)
+
+	if phase == v1.StorageMigrationPhaseComplete {
+		storage["DISTRIBUTED_STORAGE_PREFERENCE"] = append([]string{target}, preference...)
+		storage["DISTRIBUTED_STORAGE_DEFAULT_LOCATIONS"] = []string{target}
+		storage["FEATURE_STORAGE_REPLICATION"] = false
+	} else {
+		// Existing locations remain preferred for reads until every blob has been copied.
+		storage["DISTRIBUTED_STORAGE_PREFERENCE"] = append(preference, target)
+		storage["DISTRIBUTED_STORAGE_DEFAULT_LOCATIONS"] = append(defaultLocations, target)
+		storage["FEATURE_STORAGE_REPLICATION"] = true
+	}
+
+	return storage, nil
+}
+
+// storageMigrationJobFor returns the `Job` which runs the current step of the storage migration, using the same
+// image and config as the rendered Quay app `Deployment`. Returns nil if no step needs to run.
+func storageMigrationJobFor(quay *v1.QuayRegistry, resources []k8sruntime.Object) *batch.Job {
+	var step string
+	var command []string
+	switch v1.StorageMigrationPhaseFor(quay) {
+	case v1.StorageMigrationPhaseReplicating:
+		step = "replicate"
+		command = []string{"python", "-m", "util.backfillreplication"}
+	case v1.StorageMigrationPhaseVerifying:
+		step = "verify"
+		command = []string{"python", "-c", verifyReplicationScript, quay.Spec.StorageMigration.TargetLocation}
+	default:
+		return nil
+	}
+
+	var quayApp *apps.Deployment
+	for _, resource := range resources {
+		if deployment, ok := resource.(*apps.Deployment); ok && deployment.GetName() == quay.GetName()+"-quay-app" {
+			quayApp = deployment
+		}
+	}
+	if quayApp == nil || len(quayApp.Spec.Template.Spec.Containers) == 0 {
+		return nil
+	}
+
+	// Name the `Job` after the generated config `Secret`, so a config change runs the step again with the new config
+	// instead of attempting to modify the immutable pod template.
+	configHash := ""
+	for _, volume := range quayApp.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil && strings.Contains(volume.Secret.SecretName, configSecretPrefix+"-") {
+			parts := strings.Split(volume.Secret.SecretName, "-")
+			configHash = "-" + parts[len(parts)-1]
+		}
+	}
+
+	template := quayApp.Spec.Template.DeepCopy()
+	template.ObjectMeta.Labels = map[string]string{"quay-component": StorageMigrationComponent}
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+
+	container := template.Spec.Containers[0]
+	container.Name = "quay-storage-" + step
+	container.Command = command
+	container.Ports = nil
+	container.ReadinessProbe = nil
+	container.LivenessProbe = nil
+	template.Spec.Containers = []corev1.Container{container}
+
+	deadline := storageMigrationJobDeadline
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        quay.GetName() + "-quay-storage-" + step + configHash,
+			Namespace:   quay.GetNamespace(),
+			Labels:      map[string]string{"quay-component": StorageMigrationComponent},
+			Annotations: quayApp.GetAnnotations(),
+		},
+		Spec: batch.JobSpec{
+			ActiveDeadlineSeconds: &deadline,
+			Template:              *template,
+		},
+	}
+	job.SetGroupVersionKind(schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"})
+
+	return job
+}
@@ -0,0 +1,134 @@
+package kustomize
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// chunkedUploadDrivers are the storage drivers which upload image layers as S3 multipart uploads, and accept the
+// chunked upload arguments.
+var chunkedUploadDrivers = map[string]bool{
+	"S3Storage":             true,
+	"CloudFrontedS3Storage": true,
+	"STSS3Storage":          true,
+	"RadosGWStorage":        true,
+	"IBMCloudStorage":       true,
+}
+
+const (
+	minimumS3PartSizeMB = 5
+	maximumS3PartSizeMB = 5120
+)
+
+// storageUploadArgsFor returns the storage arguments for the given settings.
+func storageUploadArgsFor(settings v1.StorageUploadSettings) map[string]interface{} {
+	args := map[string]interface{}{}
+	if settings.MinimumChunkSizeMB != nil {
+		args["minimum_chunk_size_mb"] = *settings.MinimumChunkSizeMB
+	}
+	if settings.MaximumChunkSizeMB != nil {
+		args["maximum_chunk_size_mb"] = *settings.MaximumChunkSizeMB
+	}
+	if settings.ServerSideAssembly != nil {
+		args["server_side_assembly"] = *settings.ServerSideAssembly
+	}
+
+	return args
+}
+
+// validateStorageUploads returns an error if any of the settings in `spec.storageUploads` would be rejected by S3.
+func validateStorageUploads(uploads []v1.StorageUploadSettings) error {
+	locations := map[string]bool{}
+	for _, settings := range uploads {
+		if locations[settings.Location] {
+			return fmt.Errorf("`spec.storageUploads` lists location `%s` more than once", settings.Location)
+		}
+		locations[settings.Location] = true
+
+		minimum, maximum := settings.MinimumChunkSizeMB, settings.MaximumChunkSizeMB
+		if minimum != nil && *minimum < minimumS3PartSizeMB {
+			return fmt.Errorf("`spec.storageUploads` `minimumChunkSizeMB` must be at least %d", minimumS3PartSizeMB)
+		}
+		if maximum != nil && (*maximum < minimumS3PartSizeMB || *maximum > maximumS3PartSizeMB) {
+			return fmt.Errorf("`spec.storageUploads` `maximumChunkSizeMB` must be between %d and %d", minimumS3PartSizeMB, maximumS3PartSizeMB)
+		}
+		if minimum != nil && maximum != nil && *minimum > *maximum {
+			return fmt.Errorf("`spec.storageUploads` `minimumChunkSizeMB` cannot be larger than `maximumChunkSizeMB`")
+		}
+	}
+
+	return nil
+}
+
+// withStorageUploads sets the arguments from `spec.storageUploads` on the S3-compatible locations in every config
+// file which defines `DISTRIBUTED_STORAGE_CONFIG`, replacing those set in the config bundle. Settings for a named
+// location take precedence over those for every location.
+func withStorageUploads(quay *v1.QuayRegistry, configFiles map[string][]byte) error {
+	uploads := quay.Spec.StorageUploads
+	if len(uploads) == 0 {
+		return nil
+	}
+	if err := validateStorageUploads(uploads); err != nil {
+		return err
+	}
+
+	defaults := map[string]interface{}{}
+	settingsFor := map[string]map[string]interface{}{}
+	for _, settings := range uploads {
+		if settings.Location == "" {
+			defaults = storageUploadArgsFor(settings)
+		} else {
+			settingsFor[settings.Location] = storageUploadArgsFor(settings)
+		}
+	}
+
+	found := map[string]bool{}
+	for name, file := range configFiles {
+		if name != "config.yaml" && !strings.HasSuffix(name, ".config.yaml") {
+			continue
+		}
+
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(file, &config); err != nil {
+			return err
+		}
+		locations, ok := config["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for locationName, location := range locations {
+			definition, ok := location.([]interface{})
+			if !ok || len(definition) < 2 {
+				continue
+			}
+			driver, _ := definition[0].(string)
+			args, ok := definition[1].(map[string]interface{})
+			if !ok || !chunkedUploadDrivers[driver] {
+				continue
+			}
+
+			found[locationName] = true
+			for arg, value := range defaults {
+				args[arg] = value
+			}
+			for arg, value := range settingsFor[locationName] {
+				args[arg] = value
+			}
+		}
+
+		configFiles[name] = encode(config)
+	}
+
+	for _, settings := range uploads {
+		if settings.Location != "" && !found[settings.Location] {
+			return fmt.Errorf("`spec.storageUploads` location `%s` is not an S3-compatible location in `DISTRIBUTED_STORAGE_CONFIG`", settings.Location)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package kustomize
+
+import (
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// withSuspendedComponents scales every `Deployment` and `StatefulSet` to zero, suspends every `CronJob` and shuts down
+// the `PostgresCluster` of the managed database while `spec.suspend` is set. `Jobs` are left out, since the components
+// they connect to are not running, and are created once the registry is resumed. Every other object, including
+// `Secrets` and volumes, is kept.
+func withSuspendedComponents(quay *v1.QuayRegistry, resources []k8sruntime.Object) ([]k8sruntime.Object, error) {
+	if !quay.Spec.Suspend {
+		return resources, nil
+	}
+
+	suspended := []k8sruntime.Object{}
+	for _, resource := range resources {
+		switch resource := resource.(type) {
+		case *batch.Job:
+			continue
+		case *apps.Deployment:
+			replicas := int32(0)
+			resource.Spec.Replicas = &replicas
+		case *apps.StatefulSet:
+			replicas := int32(0)
+			resource.Spec.Replicas = &replicas
+		case *batchv1beta1.CronJob:
+			suspend := true
+			resource.Spec.Suspend = &suspend
+		case *unstructured.Unstructured:
+			if resource.GroupVersionKind() == PostgresClusterGVK {
+				if err := unstructured.SetNestedField(resource.Object, true, "spec", "shutdown"); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		suspended = append(suspended, resource)
+	}
+
+	return suspended, nil
+}
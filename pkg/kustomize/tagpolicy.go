@@ -0,0 +1,74 @@
+package kustomize
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// tagPolicyConfigFor returns the Quay config fields for `spec.tagPolicy`, or nil if it is not set. Support for each
+// field by the desired Quay version is checked by `v1.ValidateCapabilities`.
+func tagPolicyConfigFor(quay *v1.QuayRegistry) (map[string]interface{}, error) {
+	policy := quay.Spec.TagPolicy
+	if policy == nil {
+		return nil, nil
+	}
+
+	config := map[string]interface{}{}
+
+	if policy.DefaultExpiration != "" {
+		if !expirationPattern.MatchString(policy.DefaultExpiration) {
+			return nil, errors.New("invalid `spec.tagPolicy.defaultExpiration`: " + policy.DefaultExpiration)
+		}
+		config["DEFAULT_TAG_EXPIRATION"] = policy.DefaultExpiration
+	}
+
+	if len(policy.ExpirationOptions) > 0 {
+		for _, option := range policy.ExpirationOptions {
+			if !expirationPattern.MatchString(option) {
+				return nil, errors.New("invalid `spec.tagPolicy.expirationOptions` value: " + option)
+			}
+		}
+		config["TAG_EXPIRATION_OPTIONS"] = policy.ExpirationOptions
+		config["FEATURE_CHANGE_TAG_EXPIRATION"] = true
+	}
+
+	if len(policy.ImmutablePatterns) > 0 {
+		for _, pattern := range policy.ImmutablePatterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, errors.New("invalid `spec.tagPolicy.immutablePatterns` value: " + err.Error())
+			}
+		}
+		config["FEATURE_IMMUTABLE_TAGS"] = true
+		config["DEFAULT_IMMUTABLE_TAG_PATTERNS"] = policy.ImmutablePatterns
+	}
+
+	if prune := policy.AutoPrune; prune != nil {
+		var value interface{}
+		switch prune.Method {
+		case v1.AutoPruneMethodNumberOfTags:
+			count, err := strconv.Atoi(prune.Value)
+			if err != nil || count < 1 {
+				return nil, errors.New("`spec.tagPolicy.autoPrune.value` must be a positive number of tags")
+			}
+			value = count
+		case v1.AutoPruneMethodCreationDate:
+			if !expirationPattern.MatchString(prune.Value) {
+				return nil, errors.New("`spec.tagPolicy.autoPrune.value` must be a duration, such as `30d`")
+			}
+			value = prune.Value
+		default:
+			return nil, errors.New("unsupported `spec.tagPolicy.autoPrune.method`: " + string(prune.Method))
+		}
+
+		config["FEATURE_AUTO_PRUNE"] = true
+		config["DEFAULT_NAMESPACE_AUTOPRUNE_POLICY"] = map[string]interface{}{
+			"method": string(prune.Method),
+			"value":  value,
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,159 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/api/types"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	tempStorageVolume    = "temp-storage"
+	tempStorageMountPath = "/scratch"
+)
+
+// tempStorageDeployments are the `Deployment` (and the component which renders it, and its container) of each
+// component in `spec.tempStorage` and `spec.ephemeralStorage`.
+var tempStorageDeployments = map[string]struct {
+	component string
+	name      string
+	container string
+}{
+	"quay":  {"", "quay-app", "quay-app"},
+	"clair": {"clair", "clair", "clair"},
+}
+
+// tempStorageClaimName returns the name of the `PersistentVolumeClaim` for the temporary files of the given
+// `Deployment`.
+func tempStorageClaimName(quay *v1.QuayRegistry, deployment string) string {
+	return quay.GetName() + "-" + deployment + "-" + tempStorageVolume
+}
+
+// validateTempStorage returns an error if `spec.tempStorage` cannot be rendered.
+func validateTempStorage(quay *v1.QuayRegistry) error {
+	seen := map[string]bool{}
+	for _, storage := range quay.Spec.TempStorage {
+		deployment, ok := tempStorageDeployments[storage.Component]
+		if !ok {
+			return fmt.Errorf("`spec.tempStorage` component `%s` must be `quay` or `clair`", storage.Component)
+		}
+		if seen[storage.Component] {
+			return fmt.Errorf("`spec.tempStorage` lists component `%s` more than once", storage.Component)
+		}
+		seen[storage.Component] = true
+
+		if deployment.component != "" && !v1.ComponentIsManaged(quay.Spec.Components, deployment.component) {
+			return fmt.Errorf("`spec.tempStorage` component `%s` requires it to be managed", storage.Component)
+		}
+
+		switch storage.Backing {
+		case "", v1.TempStorageBackingEmptyDir:
+			if storage.StorageClassName != "" {
+				return errors.New("`spec.tempStorage` `storageClassName` requires `backing: PersistentVolumeClaim`")
+			}
+		case v1.TempStorageBackingPersistentVolumeClaim:
+			if storage.Size == nil {
+				return errors.New("`spec.tempStorage` `backing: PersistentVolumeClaim` requires a `size`")
+			}
+		default:
+			return fmt.Errorf("`spec.tempStorage` `backing` must be `%s` or `%s`", v1.TempStorageBackingEmptyDir, v1.TempStorageBackingPersistentVolumeClaim)
+		}
+	}
+
+	return nil
+}
+
+// tempStoragePatchesFor returns the Kustomize patches which mount the volume for the temporary files of each
+// component in `spec.tempStorage`, and point `TMPDIR` at it.
+func tempStoragePatchesFor(quay *v1.QuayRegistry) []types.Patch {
+	patches := []types.Patch{}
+	for _, storage := range quay.Spec.TempStorage {
+		deployment, ok := tempStorageDeployments[storage.Component]
+		if !ok {
+			continue
+		}
+
+		volume := map[string]interface{}{"name": tempStorageVolume}
+		container := map[string]interface{}{
+			"name":         deployment.container,
+			"env":          []interface{}{map[string]interface{}{"name": "TMPDIR", "value": tempStorageMountPath}},
+			"volumeMounts": []interface{}{map[string]interface{}{"name": tempStorageVolume, "mountPath": tempStorageMountPath}},
+		}
+
+		if storage.Backing == v1.TempStorageBackingPersistentVolumeClaim {
+			volume["persistentVolumeClaim"] = map[string]interface{}{"claimName": tempStorageClaimName(quay, deployment.name)}
+		} else {
+			emptyDir := map[string]interface{}{}
+			if storage.Size != nil {
+				emptyDir["sizeLimit"] = storage.Size.String()
+				// Requesting the space schedules the pods on nodes which have it, rather than evicting them mid-push.
+				container["resources"] = map[string]interface{}{
+					"requests": map[string]interface{}{"ephemeral-storage": storage.Size.String()},
+				}
+			}
+			volume["emptyDir"] = emptyDir
+		}
+
+		patches = append(patches, types.Patch{
+			Patch: string(encode(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": deployment.name},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"volumes":    []interface{}{volume},
+							"containers": []interface{}{container},
+						},
+					},
+				},
+			})),
+		})
+	}
+
+	return patches
+}
+
+// withTempStorageClaims adds the `PersistentVolumeClaims` for the components in `spec.tempStorage` backed by one.
+// Since the replicas of a component share the claim, it is `ReadWriteMany`.
+func withTempStorageClaims(quay *v1.QuayRegistry, objects []k8sruntime.Object) []k8sruntime.Object {
+	for _, storage := range quay.Spec.TempStorage {
+		deployment, ok := tempStorageDeployments[storage.Component]
+		if !ok || storage.Backing != v1.TempStorageBackingPersistentVolumeClaim || storage.Size == nil {
+			continue
+		}
+
+		storageClassName := storage.StorageClassName
+		if storageClassName == "" && quay.Status.OperatorConfig != nil {
+			storageClassName = quay.Status.OperatorConfig.StorageClassName
+		}
+
+		claim := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      tempStorageClaimName(quay, deployment.name),
+				Namespace: quay.GetNamespace(),
+				Labels:    map[string]string{"quay-component": deployment.name},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: *storage.Size},
+				},
+			},
+		}
+		if storageClassName != "" {
+			claim.Spec.StorageClassName = &storageClassName
+		}
+		claim.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"})
+
+		objects = append(objects, claim)
+	}
+
+	return objects
+}
@@ -0,0 +1,92 @@
+package kustomize
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// configVariablePattern matches a `${NAME}` substitution variable in a string value of the config bundle.
+var configVariablePattern = regexp.MustCompile(`\$\{([A-Z_]+)\}`)
+
+const serverHostnameVariable = "SERVER_HOSTNAME"
+
+// ResolveConfigVariables replaces the substitution variables `${NAMESPACE}`, `${REGISTRY_NAME}` and
+// `${SERVER_HOSTNAME}` in the string values of the given config bundle, so the same bundle can be used for
+// registries in different environments. Unknown variables are left as they are. Returns true if any variable
+// was replaced.
+func ResolveConfigVariables(quay *v1.QuayRegistry, userConfig map[string]interface{}) (map[string]interface{}, bool, error) {
+	variables := map[string]string{
+		"NAMESPACE":     quay.GetNamespace(),
+		"REGISTRY_NAME": quay.GetName(),
+	}
+
+	// `SERVER_HOSTNAME` itself may use the other variables, so it is resolved after them.
+	resolved, replaced := substituteVariables(userConfig, variables)
+	config := resolved.(map[string]interface{})
+
+	if hostname, ok := config["SERVER_HOSTNAME"].(string); ok && strings.Contains(hostname, "${"+serverHostnameVariable+"}") {
+		return nil, false, errors.New("`SERVER_HOSTNAME` cannot refer to `${SERVER_HOSTNAME}`")
+	}
+
+	if hostname := serverHostnameFor(quay, config); hostname != "" {
+		variables[serverHostnameVariable] = hostname
+		var replacedHostname bool
+		resolved, replacedHostname = substituteVariables(config, variables)
+		config = resolved.(map[string]interface{})
+		replaced = replaced || replacedHostname
+	} else if usesVariable(config, serverHostnameVariable) {
+		return nil, false, errors.New("`${SERVER_HOSTNAME}` is used in the config bundle, but `SERVER_HOSTNAME` is not set")
+	}
+
+	return config, replaced, nil
+}
+
+// substituteVariables returns a copy of the given config value with the variables replaced in every string.
+func substituteVariables(value interface{}, variables map[string]string) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		replaced := false
+		substituted := configVariablePattern.ReplaceAllStringFunc(v, func(match string) string {
+			if resolved, ok := variables[configVariablePattern.FindStringSubmatch(match)[1]]; ok {
+				replaced = true
+				return resolved
+			}
+
+			return match
+		})
+
+		return substituted, replaced
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(v))
+		replaced := false
+		for key, field := range v {
+			var fieldReplaced bool
+			substituted[key], fieldReplaced = substituteVariables(field, variables)
+			replaced = replaced || fieldReplaced
+		}
+
+		return substituted, replaced
+	case []interface{}:
+		substituted := make([]interface{}, len(v))
+		replaced := false
+		for i, item := range v {
+			var itemReplaced bool
+			substituted[i], itemReplaced = substituteVariables(item, variables)
+			replaced = replaced || itemReplaced
+		}
+
+		return substituted, replaced
+	default:
+		return value, false
+	}
+}
+
+// usesVariable returns true if any string in the given config value contains the variable.
+func usesVariable(value interface{}, variable string) bool {
+	_, replaced := substituteVariables(value, map[string]string{variable: ""})
+
+	return replaced
+}
@@ -0,0 +1,77 @@
+package kustomize
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// databaseVolumeClaims are the `PersistentVolumeClaims` of each database component, without the prefix of the
+// registry name.
+var databaseVolumeClaims = map[string][]string{
+	"postgres": {"quay-postgres", "quay-postgres-backup"},
+	"clair":    {"clair-postgres"},
+}
+
+// DatabaseVolumeClaimsFor returns the names of the `PersistentVolumeClaims` of the given database component.
+func DatabaseVolumeClaimsFor(quay *v1.QuayRegistry, component string) []string {
+	claims := []string{}
+	for _, claim := range databaseVolumeClaims[component] {
+		claims = append(claims, quay.GetName()+"-"+claim)
+	}
+
+	return claims
+}
+
+// DatabaseComponents are the components with a retention policy for their `PersistentVolumeClaims`.
+func DatabaseComponents() []string {
+	return []string{"postgres", "clair"}
+}
+
+// validatePersistentVolumeRetention returns an error if `spec.persistentVolumeRetentionPolicy` cannot be applied.
+func validatePersistentVolumeRetention(quay *v1.QuayRegistry) error {
+	seen := map[string]bool{}
+	for _, retention := range quay.Spec.PersistentVolumeRetentionPolicy {
+		if _, ok := databaseVolumeClaims[retention.Component]; !ok {
+			return fmt.Errorf("`spec.persistentVolumeRetentionPolicy` component `%s` must be `postgres` or `clair`", retention.Component)
+		}
+		if seen[retention.Component] {
+			return fmt.Errorf("`spec.persistentVolumeRetentionPolicy` lists component `%s` more than once", retention.Component)
+		}
+		seen[retention.Component] = true
+
+		switch retention.Policy {
+		case v1.PersistentVolumeRetentionPolicyRetain, v1.PersistentVolumeRetentionPolicyDelete:
+		default:
+			return fmt.Errorf("`spec.persistentVolumeRetentionPolicy` policy of `%s` must be `%s` or `%s`", retention.Component,
+				v1.PersistentVolumeRetentionPolicyRetain, v1.PersistentVolumeRetentionPolicyDelete)
+		}
+	}
+
+	return nil
+}
+
+// retainsVolume returns true if the given object is a `PersistentVolumeClaim` of a database whose volumes are
+// retained, which must not be owned by the `QuayRegistry` so that it is not garbage collected with it.
+func retainsVolume(quay *v1.QuayRegistry, obj runtime.Object) bool {
+	claim, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return false
+	}
+
+	for component := range databaseVolumeClaims {
+		if v1.PersistentVolumeRetentionPolicyFor(quay, component) != v1.PersistentVolumeRetentionPolicyRetain {
+			continue
+		}
+		for _, name := range DatabaseVolumeClaimsFor(quay, component) {
+			if claim.GetName() == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// SignatureHeader is the HTTP header containing the HMAC-SHA256 signature of the request body.
+	SignatureHeader = "X-Quay-Operator-Signature"
+	// SigningKey is the key of the `Secret` referenced by `signingSecret` which holds the HMAC key.
+	SigningKey = "key"
+
+	requestTimeout = time.Second * 5
+)
+
+// internalNetworks are the address ranges which webhooks may only use if they set `allowInternal`, since they may
+// reach services inside the cluster or the cloud provider metadata endpoint.
+var internalNetworks = []*net.IPNet{
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("fc00::/7"),
+}
+
+// internalHostnameSuffixes are the suffixes of hostnames resolved inside the cluster or the cloud provider network.
+var internalHostnameSuffixes = []string{".localhost", ".svc", ".local", ".internal"}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// internalIP returns true if the given address is a loopback, link-local, private or unspecified address.
+func internalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range internalNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// internalHostname returns true if the given hostname is resolved inside the cluster, including names without a
+// dot which are completed using the search domains of the Operator pod.
+func internalHostname(hostname string) bool {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	if hostname == "localhost" || !strings.Contains(hostname, ".") {
+		return true
+	}
+	for _, suffix := range internalHostnameSuffixes {
+		if strings.HasSuffix(hostname, suffix) {
+			return true
+		}
+	}
+
+	// Services are resolved as `<service>.<namespace>.svc.<cluster domain>`, whatever the cluster domain is.
+	return strings.Contains(hostname, ".svc.")
+}
+
+// ValidateURL returns an error if the URL of the webhook does not use HTTPS or points to an internal host, unless the
+// webhook sets `allowInternal`.
+func ValidateURL(webhook v1.NotificationWebhook) error {
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil {
+		return err
+	}
+	if parsed.Hostname() == "" {
+		return fmt.Errorf("webhook URL %s has no host", webhook.URL)
+	}
+	if webhook.AllowInternal {
+		if parsed.Scheme != "https" && parsed.Scheme != "http" {
+			return fmt.Errorf("webhook URL %s must use HTTP or HTTPS", webhook.URL)
+		}
+
+		return nil
+	}
+
+	if parsed.Scheme != "https" {
+		return fmt.Errorf("webhook URL %s must use HTTPS, set `allowInternal` to use HTTP", webhook.URL)
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && internalIP(ip) {
+		return fmt.Errorf("webhook URL %s points to an internal address, set `allowInternal` to use it", webhook.URL)
+	}
+	if internalHostname(parsed.Hostname()) {
+		return fmt.Errorf("webhook URL %s points to a cluster-internal host, set `allowInternal` to use it", webhook.URL)
+	}
+
+	return nil
+}
+
+// clientFor returns the HTTP client used to call the given webhook. Unless it sets `allowInternal`, the client refuses
+// to connect to internal addresses, which catches hostnames resolving to them, and does not follow redirects.
+func clientFor(webhook v1.NotificationWebhook) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !webhook.AllowInternal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || internalIP(ip) {
+				return errors.New("refusing to connect to internal address " + host)
+			}
+
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Payload is the body sent to notification webhooks.
+type Payload struct {
+	Event     v1.NotificationEvent `json:"event"`
+	Name      string               `json:"name"`
+	Namespace string               `json:"namespace"`
+	Version   v1.QuayVersion       `json:"version,omitempty"`
+	Endpoint  string               `json:"registryEndpoint,omitempty"`
+	Message   string               `json:"message,omitempty"`
+	Timestamp string               `json:"timestamp"`
+}
+
+// PayloadFor returns the webhook payload describing the given event for a `QuayRegistry`.
+func PayloadFor(quay *v1.QuayRegistry, event v1.NotificationEvent, message string) Payload {
+	return Payload{
+		Event:     event,
+		Name:      quay.GetName(),
+		Namespace: quay.GetNamespace(),
+		Version:   quay.Status.CurrentVersion,
+		Endpoint:  quay.Status.RegistryEndpoint,
+		Message:   message,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// Subscribed returns true if the given webhook should receive the given event.
+func Subscribed(webhook v1.NotificationWebhook, event v1.NotificationEvent) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 signature of the body using the given key.
+func Sign(body, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send delivers the payload to the webhook, signing the body if a key is given. The webhook URL is validated first.
+func Send(ctx context.Context, webhook v1.NotificationWebhook, key []byte, payload Payload) error {
+	if err := ValidateURL(webhook); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if len(key) > 0 {
+		req.Header.Set(SignatureHeader, Sign(body, key))
+	}
+
+	resp, err := clientFor(webhook).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s returned status %d", webhook.URL, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Dispatcher sends notifications in the background with a bounded number of requests in flight, so that slow
+// webhooks never block reconciliation.
+type Dispatcher struct {
+	inFlight chan struct{}
+}
+
+// NewDispatcher returns a `Dispatcher` sending at most the given number of notifications at once.
+func NewDispatcher(concurrency int) *Dispatcher {
+	return &Dispatcher{inFlight: make(chan struct{}, concurrency)}
+}
+
+// Dispatch sends the payload to the webhook in the background and calls `done` with the result. Returns false without
+// sending anything if too many notifications are already in flight.
+func (d *Dispatcher) Dispatch(webhook v1.NotificationWebhook, key []byte, payload Payload, done func(error)) bool {
+	select {
+	case d.inFlight <- struct{}{}:
+	default:
+		return false
+	}
+
+	go func() {
+		defer func() { <-d.inFlight }()
+
+		done(Send(context.Background(), webhook, key, payload))
+	}()
+
+	return true
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var subscribedTests = []struct {
+	name     string
+	webhook  v1.NotificationWebhook
+	event    v1.NotificationEvent
+	expected bool
+}{
+	{
+		"AllEvents",
+		v1.NotificationWebhook{URL: "https://example.com"},
+		v1.NotificationEventDegraded,
+		true,
+	},
+	{
+		"SubscribedEvent",
+		v1.NotificationWebhook{URL: "https://example.com", Events: []v1.NotificationEvent{v1.NotificationEventUpgradeComplete}},
+		v1.NotificationEventUpgradeComplete,
+		true,
+	},
+	{
+		"UnsubscribedEvent",
+		v1.NotificationWebhook{URL: "https://example.com", Events: []v1.NotificationEvent{v1.NotificationEventUpgradeComplete}},
+		v1.NotificationEventAvailable,
+		false,
+	},
+}
+
+func TestSubscribed(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range subscribedTests {
+		assert.Equal(test.expected, Subscribed(test.webhook, test.event), test.name)
+	}
+}
+
+func TestSend(t *testing.T) {
+	assert := assert.New(t)
+
+	key := []byte("super-secret")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(err)
+		assert.Equal(Sign(body, key), r.Header.Get(SignatureHeader))
+
+		var payload Payload
+		assert.Nil(json.Unmarshal(body, &payload))
+		assert.Equal(v1.NotificationEventAvailable, payload.Event)
+		assert.Equal("test", payload.Name)
+	}))
+	defer server.Close()
+
+	quay := &v1.QuayRegistry{}
+	quay.SetName("test")
+	quay.SetNamespace("ns-1")
+
+	err := Send(context.Background(), v1.NotificationWebhook{URL: server.URL, AllowInternal: true}, key, PayloadFor(quay, v1.NotificationEventAvailable, ""))
+
+	assert.Nil(err)
+}
+
+func TestSendUnsigned(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := Send(context.Background(), v1.NotificationWebhook{URL: server.URL, AllowInternal: true}, nil, Payload{Event: v1.NotificationEventDegraded})
+
+	assert.NotNil(err)
+}
+
+var validateURLTests = []struct {
+	name        string
+	webhook     v1.NotificationWebhook
+	expectedErr bool
+}{
+	{"HTTPS", v1.NotificationWebhook{URL: "https://provisioner.example.com/hooks/quay"}, false},
+	{"HTTP", v1.NotificationWebhook{URL: "http://provisioner.example.com/hooks/quay"}, true},
+	{"NoHost", v1.NotificationWebhook{URL: "https:///hooks/quay"}, true},
+	{"Loopback", v1.NotificationWebhook{URL: "https://127.0.0.1/hooks/quay"}, true},
+	{"Localhost", v1.NotificationWebhook{URL: "https://localhost:8443/hooks/quay"}, true},
+	{"LinkLocal", v1.NotificationWebhook{URL: "https://169.254.169.254/latest/meta-data"}, true},
+	{"LinkLocalIPv6", v1.NotificationWebhook{URL: "https://[fe80::1]/hooks/quay"}, true},
+	{"Private", v1.NotificationWebhook{URL: "https://10.0.0.12/hooks/quay"}, true},
+	{"Public", v1.NotificationWebhook{URL: "https://203.0.113.10/hooks/quay"}, false},
+	{"Service", v1.NotificationWebhook{URL: "https://provisioner.ns-1.svc/hooks/quay"}, true},
+	{"ServiceWithClusterDomain", v1.NotificationWebhook{URL: "https://provisioner.ns-1.svc.cluster.local/hooks/quay"}, true},
+	{"ServiceShortName", v1.NotificationWebhook{URL: "https://provisioner/hooks/quay"}, true},
+	{"CloudMetadata", v1.NotificationWebhook{URL: "https://metadata.google.internal/computeMetadata/v1"}, true},
+	{"AllowInternalService", v1.NotificationWebhook{URL: "http://provisioner.ns-1.svc:8080/hooks/quay", AllowInternal: true}, false},
+	{"AllowInternalOtherScheme", v1.NotificationWebhook{URL: "ftp://provisioner.ns-1.svc/hooks/quay", AllowInternal: true}, true},
+}
+
+func TestValidateURL(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range validateURLTests {
+		err := ValidateURL(test.webhook)
+
+		assert.Equal(test.expectedErr, err != nil, test.name)
+	}
+}
+
+func TestSendRejectsInternal(t *testing.T) {
+	assert := assert.New(t)
+
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	err := Send(context.Background(), v1.NotificationWebhook{URL: server.URL}, nil, Payload{Event: v1.NotificationEventDegraded})
+
+	assert.NotNil(err)
+	assert.False(called)
+}
+
+func TestDispatch(t *testing.T) {
+	assert := assert.New(t)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(1)
+	webhook := v1.NotificationWebhook{URL: server.URL, AllowInternal: true}
+	results := make(chan error, 1)
+
+	assert.True(dispatcher.Dispatch(webhook, nil, Payload{Event: v1.NotificationEventAvailable}, func(err error) { results <- err }))
+	assert.False(dispatcher.Dispatch(webhook, nil, Payload{Event: v1.NotificationEventAvailable}, func(err error) { results <- err }), "dropped while a notification is in flight")
+
+	close(release)
+	assert.Nil(<-results)
+}
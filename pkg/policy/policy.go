@@ -0,0 +1,153 @@
+// Package policy checks a `QuayRegistry` against the security policies enforced by the `QuayOperatorConfig`.
+package policy
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// defaultDatabasePassword is the password of the managed database, and of the `postgres` image by convention.
+const defaultDatabasePassword = "postgres"
+
+// checks are the functions which return the violations of each policy.
+var checks = map[v1.SecurityPolicy]func(quay *v1.QuayRegistry, config map[string]interface{}) []error{
+	v1.SecurityPolicyRequireClairTLS:               checkClairTLS,
+	v1.SecurityPolicyForbidDefaultDatabasePassword: checkDatabasePassword,
+	v1.SecurityPolicyRequireTLS:                    checkTLS,
+	v1.SecurityPolicyRequireStorageEncryption:      checkStorageEncryption,
+}
+
+// Check returns every violation of the given policies by the registry with the given Quay config.
+func Check(policies []v1.SecurityPolicy, quay *v1.QuayRegistry, config map[string]interface{}) []error {
+	violations := []error{}
+	for _, policy := range policies {
+		check, ok := checks[policy]
+		if !ok {
+			violations = append(violations, errors.New("unknown policy: "+string(policy)))
+			continue
+		}
+
+		for _, err := range check(quay, config) {
+			violations = append(violations, errors.New(string(policy)+": "+err.Error()))
+		}
+	}
+
+	return violations
+}
+
+func checkClairTLS(quay *v1.QuayRegistry, config map[string]interface{}) []error {
+	if v1.ComponentIsManaged(quay.Spec.Components, "clair") {
+		if v1.ClairTLSEnabled(quay) {
+			return nil
+		}
+
+		return []error{errors.New("managed `clair` must set `spec.clair.tls`")}
+	}
+
+	if endpoint, ok := config["SECURITY_SCANNER_V4_ENDPOINT"].(string); ok && isPlainHTTP(endpoint) {
+		return []error{errors.New("`SECURITY_SCANNER_V4_ENDPOINT` must use HTTPS")}
+	}
+
+	return nil
+}
+
+func checkDatabasePassword(quay *v1.QuayRegistry, config map[string]interface{}) []error {
+	// NOTE: Only managed databases created before their password was generated still use the default password.
+	if v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		if quay.GetAnnotations()[v1.DatabasePasswordAnnotation] == defaultDatabasePassword {
+			return []error{errors.New("managed `postgres` uses the default password")}
+		}
+
+		return nil
+	}
+
+	dbURI, ok := config["DB_URI"].(string)
+	if !ok || dbURI == "" {
+		return nil
+	}
+
+	uri, err := url.Parse(dbURI)
+	if err != nil {
+		return []error{errors.New("`DB_URI` is invalid")}
+	}
+	if password, _ := uri.User.Password(); password == "" || password == defaultDatabasePassword {
+		return []error{errors.New("`DB_URI` must not use an empty or default password")}
+	}
+
+	return nil
+}
+
+func checkTLS(quay *v1.QuayRegistry, config map[string]interface{}) []error {
+	if v1.TLSTerminationFor(quay) == v1.TLSTerminationNone {
+		return []error{errors.New("`spec.endpoint.tlsTermination` must not be `None`")}
+	}
+
+	if scheme, ok := config["PREFERRED_URL_SCHEME"].(string); ok && scheme != "https" {
+		return []error{errors.New("`PREFERRED_URL_SCHEME` must be `https`")}
+	}
+
+	return nil
+}
+
+// checkStorageEncryption requires every unmanaged storage location to be accessed over TLS. `LocalStorage` is never
+// allowed, since it writes blobs unencrypted to a volume of the Quay pod.
+func checkStorageEncryption(quay *v1.QuayRegistry, config map[string]interface{}) []error {
+	if v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		return nil
+	}
+
+	locations, ok := config["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+	for name := range locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	violations := []error{}
+	for _, name := range names {
+		driverAndArgs, ok := locations[name].([]interface{})
+		if !ok || len(driverAndArgs) != 2 {
+			continue
+		}
+		driver, _ := driverAndArgs[0].(string)
+		args, _ := driverAndArgs[1].(map[string]interface{})
+
+		if !storageEncrypted(driver, args) {
+			violations = append(violations, errors.New("storage location `"+name+"` ("+driver+") is not encrypted"))
+		}
+	}
+
+	return violations
+}
+
+// storageEncrypted returns true if the given storage driver and arguments connect over TLS.
+func storageEncrypted(driver string, args map[string]interface{}) bool {
+	switch driver {
+	case "LocalStorage":
+		return false
+	case "RadosGWStorage", "IBMCloudStorage":
+		if secure, _ := args["is_secure"].(bool); !secure {
+			return false
+		}
+	}
+
+	for _, value := range args {
+		if str, ok := value.(string); ok && isPlainHTTP(str) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isPlainHTTP(endpoint string) bool {
+	return strings.HasPrefix(strings.ToLower(endpoint), "http://")
+}
@@ -0,0 +1,192 @@
+// Package preflight checks that the backing services of a `QuayRegistry` which are not managed by the Operator
+// can be reached with the credentials in the config bundle.
+package preflight
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quay/config-tool/pkg/lib/fieldgroups/database"
+	"github.com/quay/config-tool/pkg/lib/shared"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// checkTimeout bounds each connection attempt, since an unreachable host may otherwise block until the TCP timeout.
+const checkTimeout = time.Second * 10
+
+// storageAuthErrors are parts of the S3 error messages returned for rejected credentials (`AccessDenied`,
+// `InvalidAccessKeyId` and `SignatureDoesNotMatch`), since only the message is returned by the storage client.
+var storageAuthErrors = []string{"access denied", "access key", "signature"}
+
+// withTimeout runs the check, giving up once the timeout has passed.
+func withTimeout(timeout time.Duration, check func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- check() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// Check verifies the unmanaged database and object storage in the given Quay config can be connected to, using the
+// certificates among the given files of the config bundle. The returned error has an `ErrorReason` describing what
+// failed.
+func Check(quay *v1.QuayRegistry, config map[string]interface{}, configFiles map[string][]byte) error {
+	if !v1.ComponentIsManaged(quay.Spec.Components, "postgres") {
+		if err := checkDatabase(config, configFiles); err != nil {
+			return v1.NewReasonedError(v1.ErrorReasonDBUnreachable, errors.New("could not connect to `DB_URI`: "+err.Error()))
+		}
+	}
+
+	if !v1.ComponentIsManaged(quay.Spec.Components, "objectstorage") {
+		if location, err := checkStorage(config); err != nil {
+			reason := v1.ErrorReasonUnknown
+			for _, code := range storageAuthErrors {
+				if strings.Contains(strings.ToLower(err.Error()), code) {
+					reason = v1.ErrorReasonStorageAuthFailed
+				}
+			}
+
+			return v1.NewReasonedError(reason, errors.New("could not access storage location `"+location+"`: "+err.Error()))
+		}
+	}
+
+	return nil
+}
+
+// checkDatabase pings the database at `DB_URI`, verifying its certificate with the `sslrootcert` of
+// `DB_CONNECTION_ARGS` if it is a file of the config bundle. Databases which require other client TLS settings are
+// skipped, since the certificates are only available to Quay.
+func checkDatabase(config map[string]interface{}, configFiles map[string][]byte) error {
+	dbURI, ok := config["DB_URI"].(string)
+	if !ok || dbURI == "" {
+		return nil
+	}
+
+	args, _ := config["DB_CONNECTION_ARGS"].(map[string]interface{})
+	if args["ssl"] != nil {
+		return nil
+	}
+
+	caCert := ""
+	if rootCert, ok := args["sslrootcert"].(string); ok {
+		bundle, ok := configFiles[path.Base(rootCert)]
+		if !ok {
+			return nil
+		}
+
+		file, err := ioutil.TempFile("", "database-ca-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+
+		_, err = file.Write(bundle)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		caCert = file.Name()
+	}
+
+	uri, err := url.Parse(dbURI)
+	if err != nil {
+		return errors.New("invalid URI")
+	}
+
+	return withTimeout(checkTimeout, func() error {
+		return database.ValidateDatabaseConnection(shared.Options{}, uri, caCert)
+	})
+}
+
+// storageArgsFor returns the connection details of an S3-compatible storage location, or nil if the driver is not
+// S3-compatible.
+func storageArgsFor(driver string, args map[string]interface{}) *shared.DistributedStorageArgs {
+	str := func(key string) string {
+		value, _ := args[key].(string)
+		return value
+	}
+
+	switch driver {
+	case "RadosGWStorage", "IBMCloudStorage":
+		hostname := str("hostname")
+		if port, ok := args["port"].(float64); ok {
+			hostname = hostname + ":" + strconv.Itoa(int(port))
+		}
+		secure, _ := args["is_secure"].(bool)
+
+		return &shared.DistributedStorageArgs{
+			Hostname:   hostname,
+			IsSecure:   secure,
+			AccessKey:  str("access_key"),
+			SecretKey:  str("secret_key"),
+			BucketName: str("bucket_name"),
+		}
+	case "S3Storage", "CloudFrontedS3Storage":
+		hostname := str("host")
+		if hostname == "" {
+			hostname = "s3.amazonaws.com"
+		}
+		if port, ok := args["port"].(float64); ok {
+			hostname = hostname + ":" + strconv.Itoa(int(port))
+		}
+
+		return &shared.DistributedStorageArgs{
+			Hostname:   hostname,
+			IsSecure:   true,
+			AccessKey:  str("s3_access_key"),
+			SecretKey:  str("s3_secret_key"),
+			BucketName: str("s3_bucket"),
+		}
+	default:
+		return nil
+	}
+}
+
+// checkStorage lists the buckets of every S3-compatible location in `DISTRIBUTED_STORAGE_CONFIG`, returning the name
+// of the first location which fails.
+func checkStorage(config map[string]interface{}) (string, error) {
+	locations, ok := config["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+
+	for name, location := range locations {
+		driverAndArgs, ok := location.([]interface{})
+		if !ok || len(driverAndArgs) != 2 {
+			continue
+		}
+		driver, _ := driverAndArgs[0].(string)
+		rawArgs, _ := driverAndArgs[1].(map[string]interface{})
+
+		args := storageArgsFor(driver, rawArgs)
+		if args == nil || args.AccessKey == "" {
+			continue
+		}
+
+		err := withTimeout(checkTimeout, func() error {
+			if ok, validationErr := shared.ValidateMinioStorage(shared.Options{}, args, "DistributedStorage"); !ok {
+				return errors.New(validationErr.Message)
+			}
+			return nil
+		})
+		if err != nil {
+			return name, err
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,139 @@
+package quay
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// buildListLimit is how many of the most recent builds of a repository are checked for unfinished builds.
+const buildListLimit = 50
+
+// terminalBuildPhases are the phases of builds which have finished.
+var terminalBuildPhases = map[string]bool{
+	"complete":      true,
+	"error":         true,
+	"internalerror": true,
+	"cancelled":     true,
+	"expired":       true,
+}
+
+// Repository identifies a repository of the registry.
+type Repository struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// Build is a build of a repository, as returned by the Quay API.
+type Build struct {
+	ID         string     `json:"id"`
+	Phase      string     `json:"phase"`
+	Repository Repository `json:"repository"`
+}
+
+// Finished returns true if the build is complete, failed or was cancelled.
+func (b Build) Finished() bool {
+	return terminalBuildPhases[b.Phase]
+}
+
+// Queued returns true if the build is waiting for a builder.
+func (b Build) Queued() bool {
+	return b.Phase == "waiting"
+}
+
+// ListRepositories returns every repository of the given namespace.
+func (c *Client) ListRepositories(ctx context.Context, namespace string) ([]Repository, error) {
+	repositories := []Repository{}
+	page := ""
+	for {
+		query := url.Values{"namespace": {namespace}}
+		if page != "" {
+			query.Set("next_page", page)
+		}
+
+		var resp struct {
+			Repositories []Repository `json:"repositories"`
+			NextPage     string       `json:"next_page"`
+		}
+		if err := c.Get(ctx, "/repository?"+query.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, resp.Repositories...)
+
+		if resp.NextPage == "" {
+			return repositories, nil
+		}
+		page = resp.NextPage
+	}
+}
+
+// ListBuilds returns the most recent builds of the given repository.
+func (c *Client) ListBuilds(ctx context.Context, repository Repository) ([]Build, error) {
+	var resp struct {
+		Builds []Build `json:"builds"`
+	}
+	path := "/repository/" + url.PathEscape(repository.Namespace) + "/" + url.PathEscape(repository.Name) + "/build/?limit=" + strconv.Itoa(buildListLimit)
+	if err := c.Get(ctx, path, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Builds, nil
+}
+
+// CancelBuild cancels the given queued or running build. The build manager of Quay then stops its builder, whichever
+// executor runs it.
+func (c *Client) CancelBuild(ctx context.Context, build Build) error {
+	return c.Delete(ctx, "/repository/"+url.PathEscape(build.Repository.Namespace)+"/"+url.PathEscape(build.Repository.Name)+"/build/"+url.PathEscape(build.ID))
+}
+
+// ListUnfinishedBuilds returns the queued and running builds of every repository of the registry. Quay has no
+// registry-wide listing of builds, so this makes a request per user, organization and repository. Requires a
+// superuser token.
+func (c *Client) ListUnfinishedBuilds(ctx context.Context) ([]Build, error) {
+	users, err := c.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs, err := c.ListOrganizations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	unfinished := []Build{}
+	for _, namespace := range append(users, orgs...) {
+		repositories, err := c.ListRepositories(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repository := range repositories {
+			builds, err := c.ListBuilds(ctx, repository)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, build := range builds {
+				if !build.Finished() {
+					build.Repository = repository
+					unfinished = append(unfinished, build)
+				}
+			}
+		}
+	}
+
+	return unfinished, nil
+}
+
+// BuildQueueFor returns the sizes of the build queue made up by the given unfinished builds.
+func BuildQueueFor(builds []Build) *BuildQueueMetrics {
+	metrics := &BuildQueueMetrics{}
+	for _, build := range builds {
+		if build.Queued() {
+			metrics.Queued++
+		} else {
+			metrics.Active++
+		}
+	}
+
+	return metrics
+}
@@ -0,0 +1,49 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListUnfinishedBuilds(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/superuser/users/":
+			_, _ = w.Write([]byte(`{"users": [{"username": "quayadmin"}]}`))
+		case "/api/v1/superuser/organizations/":
+			_, _ = w.Write([]byte(`{"organizations": [{"name": "org"}]}`))
+		case "/api/v1/repository":
+			switch r.URL.Query().Get("namespace") + "/" + r.URL.Query().Get("next_page") {
+			case "org/":
+				_, _ = w.Write([]byte(`{"repositories": [{"namespace": "org", "name": "app"}], "next_page": "2"}`))
+			case "org/2":
+				_, _ = w.Write([]byte(`{"repositories": [{"namespace": "org", "name": "web"}]}`))
+			default:
+				_, _ = w.Write([]byte(`{"repositories": []}`))
+			}
+		case "/api/v1/repository/org/app/build/":
+			assert.Equal("50", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"builds": [{"id": "1", "phase": "building"}, {"id": "2", "phase": "complete"}]}`))
+		case "/api/v1/repository/org/web/build/":
+			_, _ = w.Write([]byte(`{"builds": [{"id": "3", "phase": "waiting"}, {"id": "4", "phase": "cancelled"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	builds, err := testClient(server, "abc123").ListUnfinishedBuilds(context.Background())
+
+	assert.Nil(err)
+	assert.Equal([]Build{
+		{ID: "1", Phase: "building", Repository: Repository{Namespace: "org", Name: "app"}},
+		{ID: "3", Phase: "waiting", Repository: Repository{Namespace: "org", Name: "web"}},
+	}, builds)
+	assert.Equal(&BuildQueueMetrics{Queued: 1, Active: 1}, BuildQueueFor(builds))
+}
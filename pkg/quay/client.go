@@ -0,0 +1,254 @@
+package quay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	apiPrefix = "/api/v1"
+
+	defaultTimeout   = time.Second * 30
+	defaultRateLimit = 10
+	defaultBurst     = 20
+)
+
+// defaultBackoff is used to retry requests which fail because Quay is unavailable or rate limiting us.
+var defaultBackoff = wait.Backoff{
+	Duration: time.Millisecond * 500,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// Client talks to the API of a deployed Quay registry using an OAuth access token.
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+	limiter    flowcontrol.RateLimiter
+	backoff    wait.Backoff
+}
+
+// APIError is returned when the Quay API responds with an unsuccessful status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("quay API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the given error is a Quay API `404` response.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+
+	return false
+}
+
+// NewClient returns a `Client` for the Quay registry at the given endpoint (for example `https://quay.example.com`).
+// The token may be empty if the client is only going to be used to bootstrap the first user.
+func NewClient(endpoint, token string, insecureSkipVerify bool) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// NOTE: Quay is frequently deployed with a self-signed certificate generated by the Operator.
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureSkipVerify} // nolint:gosec
+
+	return &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		token:      token,
+		httpClient: &http.Client{Transport: transport, Timeout: defaultTimeout},
+		limiter:    flowcontrol.NewTokenBucketRateLimiter(defaultRateLimit, defaultBurst),
+		backoff:    defaultBackoff,
+	}
+}
+
+// Endpoint returns the base URL of the Quay registry this client talks to.
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// HasToken returns true if the client has an access token to authenticate requests with.
+func (c *Client) HasToken() bool {
+	return c.token != ""
+}
+
+// bootstrapRequest is the body of the Quay endpoint used to create the first user.
+type bootstrapRequest struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	Email       string `json:"email,omitempty"`
+	AccessToken bool   `json:"access_token"`
+}
+
+// bootstrapResponse is the body returned by the Quay endpoint used to create the first user.
+type bootstrapResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// Bootstrap creates the first user on a freshly deployed Quay registry and stores the returned access token
+// on the client for use in subsequent requests. Requires `FEATURE_USER_INITIALIZE` to be enabled in Quay.
+func (c *Client) Bootstrap(ctx context.Context, username, password, email string) (string, error) {
+	var resp bootstrapResponse
+	req := bootstrapRequest{
+		Username:    username,
+		Password:    password,
+		Email:       email,
+		AccessToken: true,
+	}
+
+	if err := c.Do(ctx, http.MethodPost, "/user/initialize", req, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.AccessToken == "" {
+		return "", errors.New("quay did not return an access token for bootstrapped user: " + username)
+	}
+	c.token = resp.AccessToken
+
+	return c.token, nil
+}
+
+// Get performs a `GET` request against the given API path and decodes the response into `out`.
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post performs a `POST` request against the given API path and decodes the response into `out`.
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, body, out)
+}
+
+// Put performs a `PUT` request against the given API path and decodes the response into `out`.
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPut, path, body, out)
+}
+
+// Delete performs a `DELETE` request against the given API path.
+func (c *Client) Delete(ctx context.Context, path string) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// Do sends a request to the Quay API, waiting on the rate limiter and retrying with backoff if Quay
+// is unavailable or throttling requests. The path is relative to `/api/v1`.
+func (c *Client) Do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	backoff := c.backoff
+	for {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		retry, err := c.do(ctx, method, path, payload, out)
+		if err == nil || !retry {
+			return err
+		}
+		lastErr = err
+
+		if backoff.Steps <= 1 {
+			return lastErr
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// do performs a single request and returns whether it is safe to retry on failure.
+func (c *Client) do(ctx context.Context, method, path string, payload []byte, out interface{}) (bool, error) {
+	req, err := http.NewRequest(method, c.endpoint+apiPrefix+path, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: errorMessage(respBody)}
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+		return retry, apiErr
+	}
+
+	if out != nil && len(respBody) > 0 {
+		return false, json.Unmarshal(respBody, out)
+	}
+
+	return false, nil
+}
+
+// get performs a single unauthenticated `GET` request against the given in-cluster endpoint of Quay. The caller must
+// close the body of the returned response.
+func get(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: defaultTimeout}
+
+	return client.Do(req.WithContext(ctx))
+}
+
+// errorMessage extracts a human-readable message from a Quay API error response body.
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Message          string `json:"message"`
+		Detail           string `json:"detail"`
+		ErrorMessage     string `json:"error_message"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		for _, msg := range []string{parsed.ErrorMessage, parsed.Detail, parsed.Message, parsed.ErrorDescription} {
+			if msg != "" {
+				return msg
+			}
+		}
+	}
+
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,151 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func testClient(server *httptest.Server, token string) *Client {
+	client := NewClient(server.URL, token, true)
+	client.backoff = wait.Backoff{Duration: time.Millisecond, Factor: 1.0, Steps: 3}
+
+	return client
+}
+
+func TestBootstrap(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v1/user/initialize", r.URL.Path)
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Empty(r.Header.Get("Authorization"))
+
+		var body bootstrapRequest
+		assert.Nil(json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal("quayadmin", body.Username)
+		assert.True(body.AccessToken)
+
+		_, _ = w.Write([]byte(`{"access_token": "abc123"}`))
+	}))
+	defer server.Close()
+
+	client := testClient(server, "")
+	token, err := client.Bootstrap(context.Background(), "quayadmin", "password", "admin@example.com")
+
+	assert.Nil(err)
+	assert.Equal("abc123", token)
+	assert.True(client.HasToken())
+}
+
+func TestDoAuthenticates(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("Bearer abc123", r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte(`{"name": "quayadmin"}`))
+	}))
+	defer server.Close()
+
+	var user struct {
+		Name string `json:"name"`
+	}
+	err := testClient(server, "abc123").Get(context.Background(), "/user/", &user)
+
+	assert.Nil(err)
+	assert.Equal("quayadmin", user.Name)
+}
+
+var doRetryTests = []struct {
+	name          string
+	statusCode    int
+	expectedCalls int
+}{
+	{"ServiceUnavailable", http.StatusServiceUnavailable, 3},
+	{"TooManyRequests", http.StatusTooManyRequests, 3},
+	{"NotFound", http.StatusNotFound, 1},
+	{"Unauthorized", http.StatusUnauthorized, 1},
+}
+
+func TestDoRetries(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range doRetryTests {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(test.statusCode)
+			_, _ = w.Write([]byte(`{"error_message": "nope"}`))
+		}))
+
+		err := testClient(server, "abc123").Delete(context.Background(), "/repository/org/repo")
+		server.Close()
+
+		assert.NotNil(err, test.name)
+		assert.Equal(test.expectedCalls, calls, test.name)
+		assert.Equal(test.statusCode == http.StatusNotFound, IsNotFound(err), test.name)
+		assert.Contains(err.Error(), "nope", test.name)
+	}
+}
+
+func TestDoRecoversAfterRetry(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	err := testClient(server, "abc123").Post(context.Background(), "/organization/", map[string]string{"name": "org"}, nil)
+
+	assert.Nil(err)
+	assert.Equal(2, calls)
+}
+
+func TestAutoPrunePolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	var created []AutoPrunePolicy
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/superuser/organizations/":
+			_, _ = w.Write([]byte(`{"organizations": [{"name": "org"}, {"name": "other"}]}`))
+		case "GET /api/v1/organization/org/autoprunepolicy/":
+			_, _ = w.Write([]byte(`{"policies": [{"uuid": "1", "method": "creation_date", "value": "30d"}]}`))
+		case "POST /api/v1/organization/other/autoprunepolicy/":
+			var policy AutoPrunePolicy
+			assert.Nil(json.NewDecoder(r.Body).Decode(&policy))
+			created = append(created, policy)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := testClient(server, "abc123")
+
+	orgs, err := client.ListOrganizations(context.Background())
+	assert.Nil(err)
+	assert.Equal([]string{"org", "other"}, orgs)
+
+	policies, err := client.ListAutoPrunePolicies(context.Background(), "org")
+	assert.Nil(err)
+	assert.Equal([]AutoPrunePolicy{{Method: "creation_date", Value: "30d"}}, policies)
+
+	assert.Nil(client.CreateAutoPrunePolicy(context.Background(), "other", AutoPrunePolicy{Method: "number_of_tags", Value: 10}))
+	assert.Equal([]AutoPrunePolicy{{Method: "number_of_tags", Value: float64(10)}}, created)
+}
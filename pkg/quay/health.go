@@ -0,0 +1,66 @@
+package quay
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// HealthCheckInstance reports the health of a single Quay process and the services it depends on.
+	HealthCheckInstance = "instance"
+	// HealthCheckEndToEnd reports the health of the whole registry, including database, Redis and storage.
+	HealthCheckEndToEnd = "endtoend"
+)
+
+// HealthEndpointFor returns the in-cluster URL of the given health check of the Quay app of the given
+// `QuayRegistry`.
+func HealthEndpointFor(quay *v1.QuayRegistry, check string) string {
+	_, httpPort := v1.ServicePortsFor(quay)
+
+	return "http://" + hostWithPort(v1.InternalHostnameFor(quay, "quay-app"), httpPort, 80) + "/health/" + check
+}
+
+// HealthReport is the result of a Quay health check.
+type HealthReport struct {
+	// Services maps each service checked (for example `database` or `redis`) to whether it is healthy.
+	Services map[string]bool
+}
+
+// FetchHealth calls a Quay health check endpoint. Quay responds with `503` if any service is unhealthy, which is
+// still reported as a `HealthReport` rather than an error.
+func FetchHealth(ctx context.Context, endpoint string) (*HealthReport, error) {
+	resp, err := get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: "could not check health at " + endpoint}
+	}
+
+	return ParseHealth(resp.Body)
+}
+
+// ParseHealth reads the JSON response of a Quay health check.
+func ParseHealth(r io.Reader) (*HealthReport, error) {
+	var body struct {
+		Data struct {
+			Services map[string]bool `json:"services"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	services := body.Data.Services
+	if services == nil {
+		services = map[string]bool{}
+	}
+
+	return &HealthReport{Services: services}, nil
+}
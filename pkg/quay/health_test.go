@@ -0,0 +1,132 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+var parseHealthTests = []struct {
+	name        string
+	body        string
+	expected    *HealthReport
+	expectedErr bool
+}{
+	{
+		"Healthy",
+		`{"data": {"services": {"auth": true, "database": true, "redis": true, "storage": true}, "notes": [], "is_testing": false}, "status_code": 200}`,
+		&HealthReport{Services: map[string]bool{"auth": true, "database": true, "redis": true, "storage": true}},
+		false,
+	},
+	{
+		"Unhealthy",
+		`{"data": {"services": {"database": false, "redis": true}}, "status_code": 503}`,
+		&HealthReport{Services: map[string]bool{"database": false, "redis": true}},
+		false,
+	},
+	{
+		"NoServices",
+		`{"status_code": 200}`,
+		&HealthReport{Services: map[string]bool{}},
+		false,
+	},
+	{
+		"InvalidJSON",
+		`<html>Bad Gateway</html>`,
+		nil,
+		true,
+	},
+}
+
+func TestParseHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range parseHealthTests {
+		report, err := ParseHealth(strings.NewReader(test.body))
+
+		if test.expectedErr {
+			assert.NotNil(err, test.name)
+		} else {
+			assert.Nil(err, test.name)
+			assert.Equal(test.expected, report, test.name)
+		}
+	}
+}
+
+var fetchHealthTests = []struct {
+	name        string
+	statusCode  int
+	body        string
+	expected    *HealthReport
+	expectedErr bool
+}{
+	{
+		"Healthy",
+		http.StatusOK,
+		`{"data": {"services": {"database": true}}}`,
+		&HealthReport{Services: map[string]bool{"database": true}},
+		false,
+	},
+	{
+		"Unhealthy",
+		http.StatusServiceUnavailable,
+		`{"data": {"services": {"database": false}}}`,
+		&HealthReport{Services: map[string]bool{"database": false}},
+		false,
+	},
+	{
+		"NotFound",
+		http.StatusNotFound,
+		``,
+		nil,
+		true,
+	},
+}
+
+func TestFetchHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range fetchHealthTests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal("/health/endtoend", r.URL.Path, test.name)
+
+			w.WriteHeader(test.statusCode)
+			_, _ = w.Write([]byte(test.body))
+		}))
+
+		report, err := FetchHealth(context.Background(), server.URL+"/health/"+HealthCheckEndToEnd)
+		server.Close()
+
+		if test.expectedErr {
+			assert.NotNil(err, test.name)
+		} else {
+			assert.Nil(err, test.name)
+			assert.Equal(test.expected, report, test.name)
+		}
+	}
+}
+
+func TestHealthEndpointFor(t *testing.T) {
+	quay := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns-1"}}
+
+	assert.Equal(t, "http://registry-quay-app.ns-1.svc/health/instance", HealthEndpointFor(quay, HealthCheckInstance))
+
+	quay.Spec.Endpoint = &v1.EndpointSettings{Port: 8080, TLSTermination: v1.TLSTerminationExternal}
+	assert.Equal(t, "http://registry-quay-app.ns-1.svc:8080/health/instance", HealthEndpointFor(quay, HealthCheckInstance))
+}
+
+func TestMetricsEndpointFor(t *testing.T) {
+	quay := &v1.QuayRegistry{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "ns-1"}}
+
+	assert.Equal(t, "http://registry-quay-app.ns-1.svc:9091/metrics", MetricsEndpointFor(quay))
+
+	quay.Spec.Endpoint = &v1.EndpointSettings{Port: 8080, TLSTermination: v1.TLSTerminationExternal}
+	assert.Equal(t, "http://registry-quay-app.ns-1.svc:9091/metrics", MetricsEndpointFor(quay))
+}
@@ -0,0 +1,95 @@
+package quay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	metricsPort = "9091"
+	// buildQueueName is the Quay work queue which builds wait in until a builder picks them up.
+	buildQueueName = "dockerfilebuild"
+
+	queueItemsAvailableUnlocked = "quay_queue_items_available_unlocked"
+	queueItemsLocked            = "quay_queue_items_locked"
+)
+
+// MetricsEndpointFor returns the in-cluster URL of the Prometheus metrics exposed by the Quay app of the given
+// `QuayRegistry`.
+func MetricsEndpointFor(quay *v1.QuayRegistry) string {
+	return "http://" + v1.InternalHostnameFor(quay, "quay-app") + ":" + metricsPort + "/metrics"
+}
+
+// BuildQueueMetrics are the sizes of the build queue.
+type BuildQueueMetrics struct {
+	// Queued is the number of builds waiting for a builder.
+	Queued int32
+	// Active is the number of builds claimed by a builder.
+	Active int32
+}
+
+// FetchBuildQueueMetrics scrapes the Quay metrics endpoint for the state of the build queue.
+func FetchBuildQueueMetrics(ctx context.Context, endpoint string) (*BuildQueueMetrics, error) {
+	resp, err := get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: "could not scrape metrics from " + endpoint}
+	}
+
+	return ParseBuildQueueMetrics(resp.Body)
+}
+
+// ParseBuildQueueMetrics reads the build queue sizes from Quay metrics in the Prometheus text format. Every Quay
+// process reports the same queue, so the largest value of each metric is used.
+func ParseBuildQueueMetrics(r io.Reader) (*BuildQueueMetrics, error) {
+	metrics := &BuildQueueMetrics{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var target *int32
+		switch {
+		case strings.HasPrefix(line, queueItemsAvailableUnlocked+"{"):
+			target = &metrics.Queued
+		case strings.HasPrefix(line, queueItemsLocked+"{"):
+			target = &metrics.Active
+		default:
+			continue
+		}
+
+		end := strings.LastIndex(line, "}")
+		if end < 0 || !strings.Contains(line[:end], `queue_name="`+buildQueueName+`"`) {
+			continue
+		}
+
+		fields := strings.Fields(line[end+1:])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("missing value in metric: %s", line)
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in metric: %s", line)
+		}
+
+		if int32(value) > *target {
+			*target = int32(value)
+		}
+	}
+
+	return metrics, scanner.Err()
+}
@@ -0,0 +1,77 @@
+package quay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var parseBuildQueueMetricsTests = []struct {
+	name        string
+	metrics     string
+	expected    *BuildQueueMetrics
+	expectedErr bool
+}{
+	{
+		"NoBuildQueue",
+		`# HELP quay_queue_items_locked number of items that have been acquired
+# TYPE quay_queue_items_locked gauge
+quay_queue_items_locked{host="quay-app-1",queue_name="chunk_cleanup"} 3
+`,
+		&BuildQueueMetrics{},
+		false,
+	},
+	{
+		"MultipleProcesses",
+		`quay_queue_items_available_unlocked{host="quay-app-1",queue_name="dockerfilebuild"} 4
+quay_queue_items_available_unlocked{host="quay-app-2",queue_name="dockerfilebuild"} 5
+quay_queue_items_locked{host="quay-app-1",queue_name="dockerfilebuild"} 2
+quay_queue_items_locked{host="quay-app-2",queue_name="dockerfilebuild"} 2
+quay_queue_items_locked{host="quay-app-1",queue_name="secscanv4"} 7
+`,
+		&BuildQueueMetrics{Queued: 5, Active: 2},
+		false,
+	},
+	{
+		"InvalidValue",
+		`quay_queue_items_locked{queue_name="dockerfilebuild"} many
+`,
+		nil,
+		true,
+	},
+}
+
+func TestParseBuildQueueMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range parseBuildQueueMetricsTests {
+		metrics, err := ParseBuildQueueMetrics(strings.NewReader(test.metrics))
+
+		if test.expectedErr {
+			assert.NotNil(err, test.name)
+		} else {
+			assert.Nil(err, test.name)
+			assert.Equal(test.expected, metrics, test.name)
+		}
+	}
+}
+
+func TestFetchBuildQueueMetrics(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/metrics", r.URL.Path)
+
+		_, _ = w.Write([]byte(`quay_queue_items_available_unlocked{queue_name="dockerfilebuild"} 1` + "\n"))
+	}))
+	defer server.Close()
+
+	metrics, err := FetchBuildQueueMetrics(context.Background(), server.URL+"/metrics")
+
+	assert.Nil(err)
+	assert.Equal(&BuildQueueMetrics{Queued: 1}, metrics)
+}
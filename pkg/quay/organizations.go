@@ -0,0 +1,69 @@
+package quay
+
+import (
+	"context"
+	"net/url"
+)
+
+// AutoPrunePolicy is a policy deleting old tags of an organization, as accepted by the Quay API.
+type AutoPrunePolicy struct {
+	// Method is either `number_of_tags` or `creation_date`.
+	Method string `json:"method"`
+	// Value is the number of tags to keep, or the maximum age of tags such as `30d`.
+	Value interface{} `json:"value"`
+}
+
+// ListOrganizations returns the names of every organization of the registry. Requires a superuser token.
+func (c *Client) ListOrganizations(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Organizations []struct {
+			Name string `json:"name"`
+		} `json:"organizations"`
+	}
+	if err := c.Get(ctx, "/superuser/organizations/", &resp); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(resp.Organizations))
+	for _, org := range resp.Organizations {
+		names = append(names, org.Name)
+	}
+
+	return names, nil
+}
+
+// ListUsers returns the usernames of every user of the registry. Requires a superuser token.
+func (c *Client) ListUsers(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Users []struct {
+			Username string `json:"username"`
+		} `json:"users"`
+	}
+	if err := c.Get(ctx, "/superuser/users/", &resp); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(resp.Users))
+	for _, user := range resp.Users {
+		names = append(names, user.Username)
+	}
+
+	return names, nil
+}
+
+// ListAutoPrunePolicies returns the auto-prune policies of the given organization.
+func (c *Client) ListAutoPrunePolicies(ctx context.Context, org string) ([]AutoPrunePolicy, error) {
+	var resp struct {
+		Policies []AutoPrunePolicy `json:"policies"`
+	}
+	if err := c.Get(ctx, "/organization/"+url.PathEscape(org)+"/autoprunepolicy/", &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Policies, nil
+}
+
+// CreateAutoPrunePolicy adds the given auto-prune policy to the given organization.
+func (c *Client) CreateAutoPrunePolicy(ctx context.Context, org string, policy AutoPrunePolicy) error {
+	return c.Post(ctx, "/organization/"+url.PathEscape(org)+"/autoprunepolicy/", policy, nil)
+}
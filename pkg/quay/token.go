@@ -0,0 +1,97 @@
+package quay
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+const (
+	// tokenSecretName is the name of the Secret in which the bootstrapped Quay API token is stored.
+	tokenSecretName = "quay-registry-api-token"
+	tokenKey        = "token"
+	usernameKey     = "username"
+	passwordKey     = "password"
+)
+
+// TokenSecretName returns the name of the Secret in which the bootstrapped Quay API token is stored.
+func TokenSecretName(quay *v1.QuayRegistry) string {
+	return quay.GetName() + "-" + tokenSecretName
+}
+
+// TokenFromSecret returns the Quay API token stored in the given Secret, or an empty string if there is none.
+func TokenFromSecret(secret *corev1.Secret) string {
+	if secret == nil {
+		return ""
+	}
+
+	return string(secret.Data[tokenKey])
+}
+
+// TokenSecretFor returns a Secret storing the credentials of the bootstrapped user and its Quay API token for later
+// reconciles. The token is empty if the user could not be bootstrapped, in which case one can be added by hand.
+func TokenSecretFor(quay *v1.QuayRegistry, username, password, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TokenSecretName(quay),
+			Namespace: quay.GetNamespace(),
+			Labels: map[string]string{
+				"quay-registry": quay.GetName(),
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       "QuayRegistry",
+					Name:       quay.GetName(),
+					UID:        quay.GetUID(),
+				},
+			},
+		},
+		Data: map[string][]byte{
+			usernameKey: []byte(username),
+			passwordKey: []byte(password),
+			tokenKey:    []byte(token),
+		},
+	}
+}
+
+// GeneratePassword returns a random password for the bootstrapped user.
+func GeneratePassword() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// InternalEndpointFor returns the in-cluster URL of the Quay app `Service` for the given `QuayRegistry`, which uses
+// plain HTTP if Quay does not terminate TLS.
+func InternalEndpointFor(quay *v1.QuayRegistry) string {
+	httpsPort, httpPort := v1.ServicePortsFor(quay)
+	if httpsPort == 0 {
+		return "http://" + hostWithPort(v1.InternalHostnameFor(quay, "quay-app"), httpPort, 80)
+	}
+
+	return "https://" + hostWithPort(v1.InternalHostnameFor(quay, "quay-app"), httpsPort, 443)
+}
+
+// hostWithPort returns the given hostname with the port, unless it is the default port of the scheme.
+func hostWithPort(hostname string, port, defaultPort int32) string {
+	if port == defaultPort {
+		return hostname
+	}
+
+	return hostname + ":" + strconv.Itoa(int(port))
+}
+
+// NewClientFor returns a `Client` for the Quay app of the given `QuayRegistry`, authenticated using the
+// token stored in the given Secret (which may be nil if the registry has not been bootstrapped yet).
+func NewClientFor(quay *v1.QuayRegistry, tokenSecret *corev1.Secret) *Client {
+	return NewClient(InternalEndpointFor(quay), TokenFromSecret(tokenSecret), true)
+}
@@ -0,0 +1,29 @@
+package secretkeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Fingerprint identifies a secret key without revealing it, or returns an empty string if there is no key.
+func Fingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckDatabaseSecretKey returns an error if the fingerprint of the given `DATABASE_SECRET_KEY` differs from the
+// recorded fingerprint of the key the database was encrypted with, since Quay would silently fail to decrypt the
+// credentials stored in it. A change is allowed if its new fingerprint was explicitly accepted.
+func CheckDatabaseSecretKey(recorded, key, accepted string) error {
+	fingerprint := Fingerprint(key)
+	if recorded == "" || fingerprint == "" || fingerprint == recorded || fingerprint == accepted {
+		return nil
+	}
+
+	return fmt.Errorf("`DATABASE_SECRET_KEY` changed from the key the database is encrypted with (fingerprint %s) to one with fingerprint %s, which would make stored credentials unreadable", recorded, fingerprint)
+}
@@ -0,0 +1,80 @@
+package secretkeys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var checkDatabaseSecretKeyTests = []struct {
+	name        string
+	recorded    string
+	key         string
+	accepted    string
+	expectedErr bool
+}{
+	{
+		"NothingRecorded",
+		"",
+		"new-key",
+		"",
+		false,
+	},
+	{
+		"Unchanged",
+		Fingerprint("key"),
+		"key",
+		"",
+		false,
+	},
+	{
+		"Changed",
+		Fingerprint("key"),
+		"new-key",
+		"",
+		true,
+	},
+	{
+		"ChangeAccepted",
+		Fingerprint("key"),
+		"new-key",
+		Fingerprint("new-key"),
+		false,
+	},
+	{
+		"DifferentChangeAccepted",
+		Fingerprint("key"),
+		"new-key",
+		Fingerprint("other-key"),
+		true,
+	},
+	{
+		"NoKey",
+		Fingerprint("key"),
+		"",
+		"",
+		false,
+	},
+}
+
+func TestCheckDatabaseSecretKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range checkDatabaseSecretKeyTests {
+		err := CheckDatabaseSecretKey(test.recorded, test.key, test.accepted)
+		if test.expectedErr {
+			assert.Error(err, test.name)
+		} else {
+			assert.Nil(err, test.name)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", Fingerprint(""))
+	assert.Len(Fingerprint("key"), 16)
+	assert.NotContains(Fingerprint("key"), "key")
+	assert.NotEqual(Fingerprint("key"), Fingerprint("other-key"))
+}
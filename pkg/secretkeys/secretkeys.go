@@ -0,0 +1,105 @@
+// Package secretkeys protects the secret keys Quay uses to encrypt its database, with encrypted backups and checks
+// against accidental changes.
+//
+// Backups use the format of `openssl enc -aes-256-cbc -pbkdf2 -iter 100000 -md sha256`, so they can be decrypted
+// without the Operator.
+package secretkeys
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	"golang.org/x/crypto/pbkdf2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// saltHeader prefixes the salt of a backup, as written by OpenSSL.
+	saltHeader = "Salted__"
+	saltLength = 8
+	iterations = 100000
+	keyLength  = 32
+)
+
+// keyAndIV derives the AES key and initialization vector from the passphrase, like `openssl enc -pbkdf2`.
+func keyAndIV(passphrase, salt []byte) ([]byte, []byte) {
+	derived := pbkdf2.Key(passphrase, salt, iterations, keyLength+aes.BlockSize, sha256.New)
+
+	return derived[:keyLength], derived[keyLength:]
+}
+
+// Seal encrypts the given secret keys with the passphrase. The decrypted backup is the keys as Quay config fields.
+func Seal(keys map[string][]byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+
+	fields := map[string]string{}
+	for name, value := range keys {
+		fields[name] = string(value)
+	}
+	plaintext, err := yaml.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, iv := keyAndIV(passphrase, salt)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	sealed := append([]byte(saltHeader), salt...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	return append(sealed, ciphertext...), nil
+}
+
+// Open decrypts a backup made by `Seal` with the passphrase.
+func Open(sealed, passphrase []byte) (map[string][]byte, error) {
+	if !bytes.HasPrefix(sealed, []byte(saltHeader)) || len(sealed) < len(saltHeader)+saltLength+aes.BlockSize {
+		return nil, errors.New("not a secret keys backup")
+	}
+	salt := sealed[len(saltHeader) : len(saltHeader)+saltLength]
+	ciphertext := sealed[len(saltHeader)+saltLength:]
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("secret keys backup is truncated")
+	}
+
+	key, iv := keyAndIV(passphrase, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("could not decrypt secret keys backup, the passphrase may be wrong")
+	}
+
+	var fields map[string]string
+	if err := yaml.Unmarshal(plaintext[:len(plaintext)-padding], &fields); err != nil {
+		return nil, errors.New("could not decrypt secret keys backup, the passphrase may be wrong")
+	}
+
+	keys := map[string][]byte{}
+	for name, value := range fields {
+		keys[name] = []byte(value)
+	}
+
+	return keys, nil
+}
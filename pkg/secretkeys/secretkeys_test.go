@@ -0,0 +1,73 @@
+package secretkeys
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var keys = map[string][]byte{
+	"SECRET_KEY":          []byte("c2VjcmV0LWtleQ"),
+	"DATABASE_SECRET_KEY": []byte("ZGF0YWJhc2Utc2VjcmV0LWtleQ"),
+}
+
+var openTests = []struct {
+	name        string
+	passphrase  []byte
+	sealed      func(sealed []byte) []byte
+	expected    map[string][]byte
+	expectedErr string
+}{
+	{
+		"CorrectPassphrase",
+		[]byte("correct horse battery staple"),
+		func(sealed []byte) []byte { return sealed },
+		keys,
+		"",
+	},
+	{
+		"WrongPassphrase",
+		[]byte("wrong"),
+		func(sealed []byte) []byte { return sealed },
+		nil,
+		"could not decrypt secret keys backup, the passphrase may be wrong",
+	},
+	{
+		"NotABackup",
+		[]byte("correct horse battery staple"),
+		func(sealed []byte) []byte { return []byte("SECRET_KEY: plaintext") },
+		nil,
+		"not a secret keys backup",
+	},
+	{
+		"Truncated",
+		[]byte("correct horse battery staple"),
+		func(sealed []byte) []byte { return sealed[:len(sealed)-1] },
+		nil,
+		"secret keys backup is truncated",
+	},
+}
+
+func TestOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	sealed, err := Seal(keys, []byte("correct horse battery staple"))
+	assert.Nil(err)
+
+	for _, test := range openTests {
+		opened, err := Open(test.sealed(sealed), test.passphrase)
+		if test.expectedErr != "" {
+			assert.EqualError(err, test.expectedErr, test.name)
+			continue
+		}
+
+		assert.Nil(err, test.name)
+		assert.Equal(test.expected, opened, test.name)
+	}
+}
+
+func TestSealEmptyPassphrase(t *testing.T) {
+	_, err := Seal(keys, nil)
+
+	assert.EqualError(t, err, "passphrase is empty")
+}
@@ -0,0 +1,188 @@
+package statusapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+	"github.com/quay/quay-operator/pkg/kustomize"
+)
+
+// RegistriesPath is the path the status of every `QuayRegistry` is served on. The status of a single registry is
+// served on `<RegistriesPath>/<namespace>/<name>`.
+const RegistriesPath = "/api/v1/registries"
+
+// ConfigSummary describes the config rendered for a registry, without any credentials.
+type ConfigSummary struct {
+	ServerHostname     string            `json:"serverHostname,omitempty"`
+	PreferredURLScheme string            `json:"preferredURLScheme,omitempty"`
+	AuthenticationType string            `json:"authenticationType,omitempty"`
+	Features           []string          `json:"features"`
+	StorageDrivers     map[string]string `json:"storageDrivers"`
+}
+
+// RegistryStatus is the read-only state of a `QuayRegistry` served to dashboards.
+type RegistryStatus struct {
+	Name             string            `json:"name"`
+	Namespace        string            `json:"namespace"`
+	Health           v1.RegistryHealth `json:"health"`
+	DesiredVersion   v1.QuayVersion    `json:"desiredVersion,omitempty"`
+	CurrentVersion   v1.QuayVersion    `json:"currentVersion,omitempty"`
+	RegistryEndpoint string            `json:"registryEndpoint,omitempty"`
+	Conditions       []v1.Condition    `json:"conditions,omitempty"`
+	Config           *ConfigSummary    `json:"config,omitempty"`
+}
+
+// ConfigSummaryFor returns the summary of the given rendered Quay config. Only the names of enabled features and the
+// drivers of the storage locations are included, never their settings.
+func ConfigSummaryFor(config map[string]interface{}) *ConfigSummary {
+	summary := &ConfigSummary{Features: []string{}, StorageDrivers: map[string]string{}}
+	summary.ServerHostname, _ = config["SERVER_HOSTNAME"].(string)
+	summary.PreferredURLScheme, _ = config["PREFERRED_URL_SCHEME"].(string)
+	summary.AuthenticationType, _ = config["AUTHENTICATION_TYPE"].(string)
+
+	for field, value := range config {
+		if enabled, ok := value.(bool); ok && enabled && strings.HasPrefix(field, "FEATURE_") {
+			summary.Features = append(summary.Features, strings.TrimPrefix(field, "FEATURE_"))
+		}
+	}
+	sort.Strings(summary.Features)
+
+	locations, _ := config["DISTRIBUTED_STORAGE_CONFIG"].(map[string]interface{})
+	for name, location := range locations {
+		if definition, ok := location.([]interface{}); ok && len(definition) > 0 {
+			summary.StorageDrivers[name], _ = definition[0].(string)
+		}
+	}
+
+	return summary
+}
+
+// statusFor returns the status of the given `QuayRegistry`, including a summary of the config mounted by its Quay
+// app `Deployment` if it has been deployed.
+func statusFor(ctx context.Context, k8sClient client.Reader, quay *v1.QuayRegistry) (*RegistryStatus, error) {
+	status := &RegistryStatus{
+		Name:             quay.GetName(),
+		Namespace:        quay.GetNamespace(),
+		Health:           v1.HealthOf(quay),
+		DesiredVersion:   quay.Spec.DesiredVersion,
+		CurrentVersion:   quay.Status.CurrentVersion,
+		RegistryEndpoint: quay.Status.RegistryEndpoint,
+		Conditions:       quay.Status.Conditions,
+	}
+
+	var deployment appsv1.Deployment
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: quay.GetName() + "-quay-app"}, &deployment); err != nil {
+		return status, client.IgnoreNotFound(err)
+	}
+	configSecretName := kustomize.ConfigSecretNameFor(&deployment)
+	if configSecretName == "" {
+		return status, nil
+	}
+
+	var configSecret corev1.Secret
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: quay.GetNamespace(), Name: configSecretName}, &configSecret); err != nil {
+		return status, client.IgnoreNotFound(err)
+	}
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(configSecret.Data["config.yaml"], &config); err != nil {
+		return nil, err
+	}
+	status.Config = ConfigSummaryFor(config)
+
+	return status, nil
+}
+
+// authorized returns true if the request carries the given token in an `Authorization: Bearer <token>` header.
+func authorized(r *http.Request, token string) bool {
+	header := r.Header.Get("Authorization")
+	if token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	given := strings.TrimPrefix(header, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// Handler serves the status of every `QuayRegistry` to requests authenticated with the given bearer token, so that
+// dashboards can query registries without being allowed to read their `Secrets`. Only `GET` requests are allowed.
+func Handler(k8sClient client.Reader, token string) http.Handler {
+	log := ctrl.Log.WithName("server").WithName("Status")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := r.Context()
+		var response interface{}
+		switch path := strings.Trim(strings.TrimPrefix(r.URL.Path, RegistriesPath), "/"); {
+		case path == "":
+			var quays v1.QuayRegistryList
+			if err := k8sClient.List(ctx, &quays); err != nil {
+				log.Error(err, "failed to list QuayRegistries")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			statuses := []*RegistryStatus{}
+			for i := range quays.Items {
+				status, err := statusFor(ctx, k8sClient, &quays.Items[i])
+				if err != nil {
+					log.Error(err, "failed to summarize QuayRegistry", "quayregistry", quays.Items[i].GetNamespace()+"/"+quays.Items[i].GetName())
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				statuses = append(statuses, status)
+			}
+			response = statuses
+		case strings.Count(path, "/") == 1:
+			parts := strings.SplitN(path, "/", 2)
+
+			var quay v1.QuayRegistry
+			if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: parts[0], Name: parts[1]}, &quay); errors.IsNotFound(err) {
+				http.NotFound(w, r)
+				return
+			} else if err != nil {
+				log.Error(err, "failed to retrieve QuayRegistry", "quayregistry", path)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			status, err := statusFor(ctx, k8sClient, &quay)
+			if err != nil {
+				log.Error(err, "failed to summarize QuayRegistry", "quayregistry", path)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			response = status
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Error(err, "failed to write response")
+		}
+	})
+}
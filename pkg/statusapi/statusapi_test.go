@@ -0,0 +1,179 @@
+package statusapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/quay/quay-operator/api/v1"
+)
+
+// stubReader serves the given objects by type and namespaced name.
+type stubReader struct {
+	objects []runtime.Object
+}
+
+func (s *stubReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	for _, candidate := range s.objects {
+		meta := candidate.(metav1.Object)
+		if reflect.TypeOf(candidate) == reflect.TypeOf(obj) && meta.GetNamespace() == key.Namespace && meta.GetName() == key.Name {
+			reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(candidate).Elem())
+			return nil
+		}
+	}
+
+	return errors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func (s *stubReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	quays := list.(*v1.QuayRegistryList)
+	for _, candidate := range s.objects {
+		if quay, ok := candidate.(*v1.QuayRegistry); ok {
+			quays.Items = append(quays.Items, *quay)
+		}
+	}
+
+	return nil
+}
+
+var configSummaryForTests = []struct {
+	name     string
+	config   map[string]interface{}
+	expected *ConfigSummary
+}{
+	{
+		"Empty",
+		map[string]interface{}{},
+		&ConfigSummary{Features: []string{}, StorageDrivers: map[string]string{}},
+	},
+	{
+		"Full",
+		map[string]interface{}{
+			"SERVER_HOSTNAME":          "registry.example.com",
+			"PREFERRED_URL_SCHEME":     "https",
+			"AUTHENTICATION_TYPE":      "LDAP",
+			"LDAP_ADMIN_PASSWD":        "super-secret",
+			"FEATURE_MAILING":          false,
+			"FEATURE_SECURITY_SCANNER": true,
+			"FEATURE_BUILD_SUPPORT":    true,
+			"DISTRIBUTED_STORAGE_CONFIG": map[string]interface{}{
+				"local_us": []interface{}{"RHOCSStorage", map[string]interface{}{"secret_key": "super-secret"}},
+			},
+		},
+		&ConfigSummary{
+			ServerHostname:     "registry.example.com",
+			PreferredURLScheme: "https",
+			AuthenticationType: "LDAP",
+			Features:           []string{"BUILD_SUPPORT", "SECURITY_SCANNER"},
+			StorageDrivers:     map[string]string{"local_us": "RHOCSStorage"},
+		},
+	},
+}
+
+func TestConfigSummaryFor(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range configSummaryForTests {
+		assert.Equal(test.expected, ConfigSummaryFor(test.config), test.name)
+	}
+}
+
+var handlerAuthTests = []struct {
+	name     string
+	token    string
+	method   string
+	header   string
+	expected int
+}{
+	{"MissingToken", "super-secret", http.MethodGet, "", http.StatusUnauthorized},
+	{"WrongToken", "super-secret", http.MethodGet, "Bearer wrong", http.StatusUnauthorized},
+	{"TokenWithoutBearer", "super-secret", http.MethodGet, "super-secret", http.StatusUnauthorized},
+	{"TokenWithOtherScheme", "super-secret", http.MethodGet, "Basic super-secret", http.StatusUnauthorized},
+	{"UnconfiguredToken", "", http.MethodGet, "Bearer ", http.StatusUnauthorized},
+	{"WrongMethod", "super-secret", http.MethodPost, "Bearer super-secret", http.StatusMethodNotAllowed},
+}
+
+func TestHandlerAuth(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, test := range handlerAuthTests {
+		request := httptest.NewRequest(test.method, RegistriesPath, nil)
+		request.Header.Set("Authorization", test.header)
+		recorder := httptest.NewRecorder()
+
+		Handler(nil, test.token).ServeHTTP(recorder, request)
+
+		assert.Equal(test.expected, recorder.Code, test.name)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	quay := &v1.QuayRegistry{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns-1"},
+		Spec:       v1.QuayRegistrySpec{DesiredVersion: "vader"},
+		Status: v1.QuayRegistryStatus{
+			CurrentVersion:   "vader",
+			RegistryEndpoint: "https://registry.example.com",
+			Conditions:       []v1.Condition{{Type: v1.ConditionTypeAvailable, Status: metav1.ConditionTrue}},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-quay-app", Namespace: "ns-1"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name:         "config",
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "test-quay-config-secret-deployed"}},
+			}},
+		}}},
+	}
+	configSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-quay-config-secret-deployed", Namespace: "ns-1"},
+		Data:       map[string][]byte{"config.yaml": []byte("SERVER_HOSTNAME: registry.example.com\nDATABASE_SECRET_KEY: super-secret\n")},
+	}
+	handler := Handler(&stubReader{objects: []runtime.Object{quay, deployment, configSecret}}, "super-secret")
+
+	request := httptest.NewRequest(http.MethodGet, RegistriesPath+"/ns-1/test", nil)
+	request.Header.Set("Authorization", "Bearer super-secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.NotContains(recorder.Body.String(), "super-secret")
+	var status RegistryStatus
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(v1.RegistryHealthAvailable, status.Health)
+	assert.Equal("https://registry.example.com", status.RegistryEndpoint)
+	assert.Equal("registry.example.com", status.Config.ServerHostname)
+
+	request = httptest.NewRequest(http.MethodGet, RegistriesPath, nil)
+	request.Header.Set("Authorization", "Bearer super-secret")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(http.StatusOK, recorder.Code)
+	var statuses []RegistryStatus
+	assert.Nil(json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	assert.Len(statuses, 1)
+	assert.Equal("test", statuses[0].Name)
+
+	request = httptest.NewRequest(http.MethodGet, RegistriesPath+"/ns-1/missing", nil)
+	request.Header.Set("Authorization", "Bearer super-secret")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,162 @@
+// Package webhookcert generates the self-signed CA and serving certificate of the Operator's webhook server, and
+// decides when they must be rotated.
+package webhookcert
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+)
+
+const (
+	// CAKey is the key of the PEM encoded CA bundle which signs the serving certificate.
+	CAKey = "ca.crt"
+	// CertKey is the key of the PEM encoded serving certificate.
+	CertKey = "tls.crt"
+	// PrivateKeyKey is the key of the PEM encoded private key of the serving certificate.
+	PrivateKeyKey = "tls.key"
+
+	// Validity is how long generated certificates are valid for.
+	Validity = 365 * 24 * time.Hour
+	// RotationThreshold is how long before they expire certificates are rotated.
+	RotationThreshold = 30 * 24 * time.Hour
+)
+
+// DNSNamesFor returns the names the webhook `Service` is reached by from the API server.
+func DNSNamesFor(service, namespace string) []string {
+	return []string{
+		service,
+		service + "." + namespace,
+		service + "." + namespace + ".svc",
+		service + "." + namespace + ".svc.cluster.local",
+	}
+}
+
+// Generate returns a new CA and a serving certificate for the given `Service` signed by it. The CA bundle also
+// includes the CA of the previous certificates while it is valid, so the API server keeps trusting webhook servers
+// which have not loaded the new certificate yet.
+func Generate(service, namespace string, previous map[string][]byte, now time.Time) (map[string][]byte, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          serialNumber(now),
+		Subject:               pkix.Name{CommonName: service + "-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(Validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	dnsNames := DNSNamesFor(service, namespace)
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(now.Add(time.Nanosecond)),
+		Subject:      pkix.Name{CommonName: dnsNames[2]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(Validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	if previousCA := firstCertificate(previous[CAKey]); previousCA != nil && now.Before(previousCA.NotAfter) {
+		caBundle = append(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: previousCA.Raw})...)
+	}
+
+	return map[string][]byte{
+		CAKey:         caBundle,
+		CertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		PrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// NeedsRotation returns true if the given certificates are missing, invalid, not for the given `Service`, or expire
+// within the `RotationThreshold` of the given time.
+func NeedsRotation(data map[string][]byte, service, namespace string, now time.Time) bool {
+	if len(data[CAKey]) == 0 {
+		return true
+	}
+	pair, err := tls.X509KeyPair(data[CertKey], data[PrivateKeyKey])
+	if err != nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return true
+	}
+	if now.Add(RotationThreshold).After(cert.NotAfter) {
+		return true
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data[CAKey]) {
+		return true
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     DNSNamesFor(service, namespace)[2],
+		Roots:       roots,
+		CurrentTime: now,
+	})
+
+	return err != nil
+}
+
+// Equal returns true if both sets of certificates serve the same certificate with the same CA bundle.
+func Equal(first, second map[string][]byte) bool {
+	for _, key := range []string{CAKey, CertKey, PrivateKeyKey} {
+		if !bytes.Equal(first[key], second[key]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// firstCertificate returns the first certificate in the given PEM data, or nil if there is none.
+func firstCertificate(data []byte) *x509.Certificate {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+
+	return cert
+}
+
+// serialNumber returns a serial number unique to the given time, so that a rotated certificate never reuses one.
+func serialNumber(now time.Time) *big.Int {
+	return big.NewInt(now.UnixNano())
+}
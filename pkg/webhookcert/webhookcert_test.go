@@ -0,0 +1,118 @@
+package webhookcert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func certificatesIn(data []byte) []*x509.Certificate {
+	certs := []*x509.Certificate{}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			certs = append(certs, cert)
+		}
+	}
+
+	return certs
+}
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := Generate("quay-operator-webhook-service", "quay-operator-system", nil, now)
+	assert.Nil(err)
+	assert.False(NeedsRotation(data, "quay-operator-webhook-service", "quay-operator-system", now))
+
+	served := certificatesIn(data[CertKey])
+	assert.Equal(1, len(served))
+	assert.Contains(served[0].DNSNames, "quay-operator-webhook-service.quay-operator-system.svc")
+	assert.Equal(now.Add(Validity), served[0].NotAfter)
+	assert.Equal(1, len(certificatesIn(data[CAKey])))
+
+	rotated, err := Generate("quay-operator-webhook-service", "quay-operator-system", data, now.Add(Validity-RotationThreshold))
+	assert.Nil(err)
+	assert.False(Equal(data, rotated))
+	assert.Equal(2, len(certificatesIn(rotated[CAKey])), "previous CA is trusted until it expires")
+
+	expired, err := Generate("quay-operator-webhook-service", "quay-operator-system", rotated, now.Add(3*Validity))
+	assert.Nil(err)
+	assert.Equal(1, len(certificatesIn(expired[CAKey])), "expired CA is dropped")
+}
+
+func TestNeedsRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := Generate("quay-operator-webhook-service", "quay-operator-system", nil, now)
+	assert.Nil(err)
+	other, err := Generate("quay-operator-webhook-service", "quay-operator-system", nil, now)
+	assert.Nil(err)
+
+	var needsRotationTests = []struct {
+		name      string
+		data      map[string][]byte
+		service   string
+		namespace string
+		now       time.Time
+		expected  bool
+	}{
+		{
+			"Valid",
+			data,
+			"quay-operator-webhook-service",
+			"quay-operator-system",
+			now,
+			false,
+		},
+		{
+			"Missing",
+			map[string][]byte{},
+			"quay-operator-webhook-service",
+			"quay-operator-system",
+			now,
+			true,
+		},
+		{
+			"ExpiringSoon",
+			data,
+			"quay-operator-webhook-service",
+			"quay-operator-system",
+			now.Add(Validity - RotationThreshold + time.Hour),
+			true,
+		},
+		{
+			"OtherNamespace",
+			data,
+			"quay-operator-webhook-service",
+			"openshift-operators",
+			now,
+			true,
+		},
+		{
+			"UntrustedCA",
+			map[string][]byte{CAKey: other[CAKey], CertKey: data[CertKey], PrivateKeyKey: data[PrivateKeyKey]},
+			"quay-operator-webhook-service",
+			"quay-operator-system",
+			now,
+			true,
+		},
+		{
+			"MismatchedKey",
+			map[string][]byte{CAKey: data[CAKey], CertKey: data[CertKey], PrivateKeyKey: other[PrivateKeyKey]},
+			"quay-operator-webhook-service",
+			"quay-operator-system",
+			now,
+			true,
+		},
+	}
+
+	for _, test := range needsRotationTests {
+		assert.Equal(test.expected, NeedsRotation(test.data, test.service, test.namespace, test.now), test.name)
+	}
+}